@@ -3,19 +3,63 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/joho/godotenv"
 
+	"french-admin-etl/internal/extractors"
+	"french-admin-etl/internal/geometry"
 	"french-admin-etl/internal/infrastructure/config"
 	"french-admin-etl/internal/infrastructure/entities"
 	_ "french-admin-etl/internal/infrastructure/logger"
 	"french-admin-etl/internal/infrastructure/repository"
+	"french-admin-etl/internal/lineage"
+	"french-admin-etl/internal/melodi"
+	"french-admin-etl/internal/model"
 	"french-admin-etl/internal/processor"
+	"french-admin-etl/internal/validation"
 )
 
 func main() {
+	dryRun := flag.Bool("dry-run", false, "extract, transform and validate the data without connecting to the database")
+	layersFlag := flag.String("layers", "population", "comma-separated layers to load, among "+strings.Join(layerNames, ", "))
+	syncFlag := flag.String("sync", "", "remove the entities absent from the source of the geographic and COG layers: delete or soft-delete")
+	referencePolicyFlag := flag.String("reference-policy", "null", "what happens to the rows of the geographic and COG layers whose reference to another table (e.g. the EPCI of a commune) doesn't resolve: null, reject or defer")
+	referenceReport := flag.String("reference-report", "", "directory of the reports of the unresolved references, <table>_references.csv")
+	swap := flag.Bool("swap", false, "load each layer into a shadow table, swapped in once validated")
+	millesime := flag.Int("millesime", 2024, "vintage (millésime) of the administrative geography, year of the 1 January boundaries")
+	populationGeography := flag.Int("population-geography", 0, "millésime of the geography of the population file, when older than --millesime: its communes are recoded with the commune events (default: --millesime)")
+	populationYear := flag.Int("population-year", 2022, "year of the census (RP) of the population layer, read from data/<millésime>/DS_RP_POPULATION_PRINC_<year>_data.csv")
+	irisPopulationYear := flag.Int("iris-population-year", 2021, "year of the census (RP) of the population-iris layer, read from data/<millésime>/base-ic-evol-struct-pop-<year>.csv")
+	populationDecimals := flag.Bool("population-decimals", false, "keep the decimals of the population estimates instead of rounding them to integers")
+	populationPivot := flag.String("population-pivot", "", "JSON file mapping the age/sex combinations of the population layers to the columns of their tables (default: the columns of the migrations)")
+	validate := flag.Bool("validate", false, "check the consistency of the figures of the population layers (men + women = total, age groups adding up to the total)")
+	validationReport := flag.String("validation-report", "", "directory of the reports of the violations of the consistency rules, <layer>_violations.csv (requires --validate)")
+	validationMaxRatio := flag.Float64("validation-max-ratio", -1, "ratio (0 to 1) of violating records failing a population layer before its commit, negative to never fail (requires --validate)")
+	validationTolerance := flag.Float64("validation-tolerance", validation.DefaultTolerance.Ratio, "deviation accepted by the consistency rules, as a ratio of the total, on top of the rounding of the figures")
+	melodiDatasets := flag.String("melodi-datasets", "DS_RP_POPULATION_PRINC_2022", "comma-separated INSEE Melodi datasets of the melodi layer, read from data/<millésime>/<dataset>_data.csv and data/<millésime>/<dataset>_metadata.csv")
+	rollback := flag.Bool("rollback", false, "swap back the previous version of the tables of the layers, kept by the last swap")
+	checkIntegrity := flag.Bool("check-integrity", false, "cross-check the references between the régions, départements, EPCI and communes of the millésime instead of loading, failing on broken references")
+	integrityReport := flag.String("integrity-report", "integrity-report.json", "JSON file of the report of --check-integrity")
+	checkSpatial := flag.Bool("check-spatial", false, "check the commune geometries of the millésime with PostGIS instead of loading (union by département, overlaps, communes within their département and région), failing on inconsistencies")
+	spatialReport := flag.String("spatial-report", "spatial-report.json", "JSON file of the report of --check-spatial")
+	spatialResolution := flag.String("spatial-resolution", "", "resolution of the geometries checked by --check-spatial: 5m, 100m or 1000m (default: the geom column)")
+	spatialGapRatio := flag.Float64("spatial-gap-ratio", repository.DefaultSpatialTolerance.GapRatio, "area by which the union of the communes of a département may differ from its contour, as a ratio of its area (requires --check-spatial)")
+	spatialSliverArea := flag.Float64("spatial-sliver-area", repository.DefaultSpatialTolerance.SliverArea, "area in m² by which two communes may overlap (requires --check-spatial)")
+	neighbours := flag.Bool("neighbours", false, "update the adjacency graph ref_admin.communes_voisines after loading the communes and départements, for their changed geometries")
+	resolutionFlag := flag.String("resolution", "", "geometry column populated by the geographic layers: 5m, 100m or 1000m (default: the geom column)")
+	precisionFlag := flag.String("precision", "1000m", "precision of the Etalab contour files read, <layer>-<precision>.geojson: 5m, 100m or 1000m")
+	simplify := flag.Float64("simplify", 0, "tolerance in metres of the topology-aware simplification of the geometries of the geographic layers, 0 to load them as read")
+	simplifyAlgorithm := flag.String("simplify-algorithm", "douglas-peucker", "line simplification algorithm of --simplify: douglas-peucker or visvalingam")
+	dissolve := flag.Bool("dissolve", false, "rebuild the geometries of the régions, départements and EPCI of the millésime by dissolving the loaded commune geometries by their codes, once the layers are loaded")
+	flag.Parse()
 
 	// Charger les variables d'environnement
 	if err := godotenv.Load(); err != nil {
@@ -30,98 +74,608 @@ func main() {
 
 	ctx := context.Background()
 
-	const migrationsPath = "./migrations"
-	databaseManager, err := repository.NewDatabaseManager(
-		config,
-		repository.WithMigrations(migrationsPath),
-	)
+	loadStrategy, err := repository.ParseLoadStrategy(config.LoadStrategy)
+	if err != nil {
+		slog.Error("❌ Invalid load strategy", "error", err)
+		os.Exit(1)
+	}
+
+	syncMode, err := repository.ParseSyncMode(*syncFlag)
+	if err != nil {
+		slog.Error("❌ Invalid sync mode", "error", err)
+		os.Exit(1)
+	}
+
+	referencePolicy, err := repository.ParseReferencePolicy(*referencePolicyFlag)
+	if err != nil {
+		slog.Error("❌ Invalid reference policy", "error", err)
+		os.Exit(1)
+	}
+
+	selectedLayers, err := parseLayers(*layersFlag)
+	if err != nil {
+		slog.Error("❌ Invalid layers", "error", err)
+		os.Exit(1)
+	}
+
+	vintage, err := model.ParseVintage(*millesime)
+	if err != nil {
+		slog.Error("❌ Invalid millésime", "error", err)
+		os.Exit(1)
+	}
+
+	populationVintage := vintage
+	if *populationGeography != 0 {
+		if populationVintage, err = model.ParseVintage(*populationGeography); err != nil || populationVintage > vintage {
+			slog.Error("❌ Invalid population geography, must be a millésime up to --millesime", "population-geography", *populationGeography, "error", err)
+			os.Exit(1)
+		}
+	}
 
+	resolution, err := model.ParseResolution(*resolutionFlag)
 	if err != nil {
-		slog.Error("❌ Failed to create database manager or migrate database", "error", err)
+		slog.Error("❌ Invalid resolution", "error", err)
+		os.Exit(1)
+	}
+
+	precision, err := model.ParseResolution(*precisionFlag)
+	if err != nil || precision == model.DefaultResolution {
+		slog.Error("❌ Invalid precision, must be one of 5m, 100m, 1000m", "precision", *precisionFlag, "error", err)
+		os.Exit(1)
+	}
+
+	algorithm, err := geometry.ParseAlgorithm(*simplifyAlgorithm)
+	if err != nil || *simplify < 0 {
+		slog.Error("❌ Invalid simplification, the tolerance must be positive", "simplify", *simplify, "error", err)
+		os.Exit(1)
+	}
+
+	if *swap && syncMode != repository.NoSync {
+		slog.Error("❌ --swap and --sync are exclusive: a swapped table only holds the entities of the source")
 		os.Exit(1)
 	}
 
-	/*
-		regionProcess := processor.NewGeoJSONETLProcessor(
+	var databaseManager *repository.DatabaseManager
+	if *dryRun {
+		slog.Info("Dry run, nothing will be written to the database")
+		databaseManager = repository.NewDryRunDatabaseManager()
+	} else {
+		const migrationsPath = "./migrations"
+		databaseManager, err = repository.NewDatabaseManager(
 			config,
-			"Régions",
-			func() entities.RegionProperties {
-				return entities.RegionProperties{}
-			},
-			entities.NewRegionMapper(),
-			repository.NewRegionRepository(databaseManager),
+			repository.WithMigrations(migrationsPath),
+			repository.WithLoadStrategy(loadStrategy),
 		)
+		if err != nil {
+			slog.Error("❌ Failed to create database manager or migrate database", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	if *rollback {
+		// Parents last, as their foreign keys are restored on the tables of their children
+		for _, name := range slices.Backward(selectedLayers) {
+			for _, table := range layerTables[name] {
+				if err := repository.RollbackSwap(ctx, databaseManager, table); err != nil {
+					slog.Error("❌ Failed to roll back swap", "layer", name, "table", table, "error", err)
+					os.Exit(1)
+				}
+			}
+		}
+		slog.Info("Rollback completed")
+		return
+	}
+
+	if *checkIntegrity {
+		report, err := repository.CheckIntegrity(ctx, databaseManager, vintage)
+		if err != nil {
+			slog.Error("❌ Failed to check integrity", "error", err)
+			os.Exit(1)
+		}
+		if err := writeJSONReport(report, *integrityReport); err != nil {
+			slog.Error("❌ Failed to write integrity report", "error", err)
+			os.Exit(1)
+		}
+		if report.Failed() {
+			slog.Error("❌ Broken references between the ref_admin tables", "errors", report.Errors)
+			os.Exit(1)
+		}
+		return
+	}
 
-		err = regionProcess.Run(ctx, "./data/regions-1000m.geojson")
+	if *checkSpatial {
+		resolution, err := model.ParseResolution(*spatialResolution)
+		if err != nil {
+			slog.Error("❌ Invalid spatial resolution", "error", err)
+			os.Exit(1)
+		}
+		tolerance := repository.SpatialTolerance{GapRatio: *spatialGapRatio, SliverArea: *spatialSliverArea}
+		report, err := repository.CheckSpatialConsistency(ctx, databaseManager, vintage, resolution, tolerance)
 		if err != nil {
-			slog.Error("❌ Failed to run region process", "error", err)
+			slog.Error("❌ Failed to check spatial consistency", "error", err)
+			os.Exit(1)
+		}
+		if err := writeJSONReport(report, *spatialReport); err != nil {
+			slog.Error("❌ Failed to write spatial report", "error", err)
+			os.Exit(1)
+		}
+		if report.Failed() {
+			slog.Error("❌ Inconsistent commune geometries", "issues", len(report.Issues))
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Options of the ref_admin tables, geographic layers and COG
+	adminOpts := []repository.RepositoryOption{repository.WithReferencePolicy(referencePolicy, *referenceReport)}
+	var populationOpts []repository.RepositoryOption
+	if syncMode != repository.NoSync {
+		adminOpts = append(adminOpts, repository.WithSync(syncMode, config.SyncMaxRemovalRatio))
+	}
+	if *swap {
+		adminOpts = append(adminOpts, repository.WithShadowTable(config.SyncMaxRemovalRatio))
+		populationOpts = append(populationOpts, repository.WithShadowTable(config.SyncMaxRemovalRatio))
+	}
+	// Communes and départements also update their adjacency graph
+	neighbourOpts := adminOpts
+	if *neighbours {
+		neighbourOpts = append(slices.Clone(adminOpts), repository.WithAdjacency())
+	}
+
+	pivot := repository.DefaultPopulationPivot()
+	if *populationPivot != "" {
+		if pivot, err = repository.LoadPivotSpec(*populationPivot, repository.PopulationDimensions); err != nil {
+			slog.Error("❌ Invalid population pivot", "error", err)
 			os.Exit(1)
 		}
+		if err := repository.CheckPivotColumns(ctx, databaseManager, pivot); err != nil {
+			slog.Error("❌ Invalid population pivot", "error", err)
+			os.Exit(1)
+		}
+		populationOpts = append(populationOpts, repository.WithPivot(pivot))
+	}
 
-		departementProcess := processor.NewGeoJSONETLProcessor(
+	// Each population layer has its own validator and report
+	layerPopulationOpts := func(layer string) []repository.RepositoryOption {
+		if !*validate {
+			return populationOpts
+		}
+		var validatorOpts []validation.ValidatorOption
+		if *validationReport != "" {
+			validatorOpts = append(validatorOpts, validation.WithReport(filepath.Join(*validationReport, layer+"_violations.csv")))
+		}
+		if *validationMaxRatio >= 0 {
+			validatorOpts = append(validatorOpts, validation.WithMaxViolationRatio(*validationMaxRatio))
+		}
+		tolerance := validation.Tolerance{Ratio: *validationTolerance, Absolute: validation.DefaultTolerance.Absolute}
+		validator := validation.NewValidator(validation.PopulationRules(pivot.PopulationColumns()), tolerance, validatorOpts...)
+		return append(slices.Clone(populationOpts), repository.WithValidation(validator))
+	}
+
+	processorOpts := []processor.ProcessorOption{processor.WithVintage(vintage), processor.WithResolution(resolution)}
+	// Each geographic layer indexes its own borders, so that it gets a simplifier of its own
+	geoProcessorOpts := func() []processor.ProcessorOption {
+		if *simplify == 0 {
+			return processorOpts
+		}
+		return append(slices.Clone(processorOpts), processor.WithGeometrySimplifier(geometry.NewTopologySimplifier(algorithm, *simplify)))
+	}
+	dataDir := fmt.Sprintf("./data/%d", vintage)
+	cogFile := func(name string) string {
+		return fmt.Sprintf("%s/cog/v_%s_%d.csv", dataDir, name, vintage)
+	}
+	contourFile := func(name string) string {
+		return fmt.Sprintf("%s/%s-%s.geojson", dataDir, name, precision)
+	}
+	populationFile := fmt.Sprintf("%s/DS_RP_POPULATION_PRINC_%d_data.csv", dataDir, *populationYear)
+	irisPopulationFile := fmt.Sprintf("%s/base-ic-evol-struct-pop-%d.csv", dataDir, *irisPopulationYear)
+
+	// The régions, départements and EPCI are loaded from their file, or from the dissolved communes (--dissolve)
+	loadRegions := func(filePath string) error {
+		return processor.NewGeoJSONETLProcessor(
+			config,
+			"Régions",
+			func() entities.RegionProperties {
+				return entities.RegionProperties{}
+			},
+			entities.NewRegionMapper(),
+			repository.NewRegionRepository(databaseManager, adminOpts...),
+			geoProcessorOpts()...,
+		).Run(ctx, filePath)
+	}
+	loadDepartements := func(filePath string) error {
+		return processor.NewGeoJSONETLProcessor(
 			config,
 			"Departements",
 			func() entities.DepartementProperties {
 				return entities.DepartementProperties{}
 			},
 			entities.NewDepartementMapper(),
-			repository.NewDepartementRepository(databaseManager),
-		)
-
-		err = departementProcess.Run(ctx, "./data/departements-1000m.geojson")
-		if err != nil {
-			slog.Error("❌ Failed to run departement process", "error", err)
-			os.Exit(1)
-		}
-
-		epciProcess := processor.NewGeoJSONETLProcessor(
+			repository.NewDepartementRepository(databaseManager, neighbourOpts...),
+			geoProcessorOpts()...,
+		).Run(ctx, filePath)
+	}
+	loadEPCI := func(filePath string) error {
+		return processor.NewGeoJSONETLProcessor(
 			config,
 			"EPCI",
 			func() entities.EPCIProperties {
 				return entities.EPCIProperties{}
 			},
 			entities.NewEPCIMapper(),
-			repository.NewEPCIRepository(databaseManager),
-		)
+			repository.NewEPCIRepository(databaseManager, adminOpts...),
+			geoProcessorOpts()...,
+		).Run(ctx, filePath)
+	}
 
-		err = epciProcess.Run(ctx, "./data/epci-1000m.geojson")
-		if err != nil {
-			slog.Error("❌ Failed to run epci process", "error", err)
+	layers := map[string]func() error{
+		"cog-regions": func() error {
+			return processor.NewCsvETLProcessor(
+				config,
+				"COG régions",
+				',',
+				nil,
+				entities.NewCogRegionMapper(),
+				repository.NewTableRepository[entities.CogRegionEntity](databaseManager, adminOpts...),
+				processorOpts...,
+			).Run(ctx, cogFile("region"))
+		},
+		"cog-departements": func() error {
+			return processor.NewCsvETLProcessor(
+				config,
+				"COG départements",
+				',',
+				nil,
+				entities.NewCogDepartementMapper(),
+				repository.NewTableRepository[entities.CogDepartementEntity](databaseManager, adminOpts...),
+				processorOpts...,
+			).Run(ctx, cogFile("departement"))
+		},
+		"cog-arrondissements": func() error {
+			return processor.NewCsvETLProcessor(
+				config,
+				"COG arrondissements",
+				',',
+				nil,
+				entities.NewCogArrondissementMapper(),
+				repository.NewTableRepository[entities.CogArrondissementEntity](databaseManager, adminOpts...),
+				processorOpts...,
+			).Run(ctx, cogFile("arrondissement"))
+		},
+		"cog-cantons": func() error {
+			return processor.NewCsvETLProcessor(
+				config,
+				"COG cantons",
+				',',
+				nil,
+				entities.NewCogCantonMapper(),
+				repository.NewTableRepository[entities.CogCantonEntity](databaseManager, adminOpts...),
+				processorOpts...,
+			).Run(ctx, cogFile("canton"))
+		},
+		"cog-communes": func() error {
+			return processor.NewCsvETLProcessor(
+				config,
+				"COG communes",
+				',',
+				nil,
+				entities.NewCogCommuneMapper(),
+				repository.NewTableRepository[entities.CogCommuneEntity](databaseManager, adminOpts...),
+				processorOpts...,
+			).Run(ctx, cogFile("commune"))
+		},
+		"cog-comer": func() error {
+			return processor.NewCsvETLProcessor(
+				config,
+				"COG collectivités d'outre-mer",
+				',',
+				nil,
+				entities.NewCogComerMapper(),
+				repository.NewTableRepository[entities.CogComerEntity](databaseManager, adminOpts...),
+				processorOpts...,
+			).Run(ctx, cogFile("comer"))
+		},
+		"commune-events": func() error {
+			return processor.NewCsvETLProcessor(
+				config,
+				"Mouvements des communes",
+				',',
+				nil,
+				entities.NewCommuneEventMapper(),
+				repository.NewTableRepository[entities.CommuneEventEntity](databaseManager),
+				processorOpts...,
+			).Run(ctx, cogFile("mvt_commune"))
+		},
+		"regions": func() error {
+			return loadRegions(contourFile("regions"))
+		},
+		"departements": func() error {
+			return loadDepartements(contourFile("departements"))
+		},
+		"arrondissements": func() error {
+			return processor.NewGeoJSONETLProcessor(
+				config,
+				"Arrondissements",
+				func() entities.ArrondissementProperties {
+					return entities.ArrondissementProperties{}
+				},
+				entities.NewArrondissementMapper(),
+				repository.NewArrondissementRepository(databaseManager, adminOpts...),
+				geoProcessorOpts()...,
+			).Run(ctx, dataDir+"/arrondissements.geojson")
+		},
+		"cantons": func() error {
+			return processor.NewGeoJSONETLProcessor(
+				config,
+				"Cantons",
+				func() entities.CantonProperties {
+					return entities.CantonProperties{}
+				},
+				entities.NewCantonMapper(),
+				repository.NewCantonRepository(databaseManager, adminOpts...),
+				geoProcessorOpts()...,
+			).Run(ctx, dataDir+"/cantons.geojson")
+		},
+		"epci": func() error {
+			return loadEPCI(contourFile("epci"))
+		},
+		"communes": func() error {
+			// The arrondissement and canton of the communes come from the COG
+			parents, err := loadCogCommuneParents(ctx, cogFile("commune"))
+			if err != nil {
+				return err
+			}
+
+			return processor.NewGeoJSONETLProcessor(
+				config,
+				"Communes",
+				func() entities.CommuneProperties {
+					return entities.CommuneProperties{}
+				},
+				entities.NewCommuneMapperWithCog(parents),
+				repository.NewCommuneRepository(databaseManager, neighbourOpts...),
+				geoProcessorOpts()...,
+			).Run(ctx, contourFile("communes"))
+		},
+		"arrondissements-municipaux": func() error {
+			return processor.NewGeoJSONETLProcessor(
+				config,
+				"Arrondissements municipaux",
+				func() entities.ArrondissementMunicipalProperties {
+					return entities.ArrondissementMunicipalProperties{}
+				},
+				entities.NewArrondissementMunicipalMapper(),
+				repository.NewArrondissementMunicipalRepository(databaseManager, adminOpts...),
+				geoProcessorOpts()...,
+			).Run(ctx, contourFile("arrondissements-municipaux"))
+		},
+		"codes-postaux": func() error {
+			return processor.NewCsvETLProcessor(
+				config,
+				"Codes postaux",
+				';',
+				nil,
+				entities.NewCodePostalMapper(),
+				repository.NewCodePostalRepository(databaseManager, adminOpts...),
+				append(slices.Clone(processorOpts), processor.WithEncoding(extractors.Latin1))...,
+			).Run(ctx, dataDir+"/base-officielle-codes-postaux.csv")
+		},
+		"population": func() error {
+			var populationMapper model.Mapper[model.CSVRecord, entities.CommunePopulationPrincEntity] = entities.NewCommunePopulationMapper()
+			if *populationDecimals {
+				populationMapper = entities.NewCommunePopulationMapperWithDecimals()
+			}
+			if populationVintage != vintage {
+				// Communes merged or recoded since the geography of the file are recoded to the millésime of the run
+				resolver, err := lineage.LoadResolver(ctx, cogFile("mvt_commune"))
+				if err != nil {
+					return err
+				}
+				populationMapper = lineage.NewMapper(populationMapper, resolver, populationVintage.Date(), vintage.Date())
+			}
+
+			return processor.NewCsvETLProcessor(
+				config,
+				"Population des communes",
+				';',
+				entities.CommunePopulationPrincFilter,
+				populationMapper,
+				repository.NewCommunePopulationRepository(databaseManager, layerPopulationOpts("population")...),
+				processorOpts...,
+			).Run(ctx, populationFile)
+		},
+		"melodi": func() error {
+			// Datasets are upserted side by side into the same tables: neither synced nor swapped
+			for _, dataset := range strings.Split(*melodiDatasets, ",") {
+				dataset = strings.TrimSpace(dataset)
+				metadata, err := melodi.LoadMetadata(ctx, dataset, filepath.Join(dataDir, dataset+"_metadata.csv"))
+				if err != nil {
+					return err
+				}
+				if _, err := repository.NewTableRepository[entities.MelodiModalityEntity](databaseManager).Load(ctx, metadata.Modalities()); err != nil {
+					return fmt.Errorf("error loading modalities of dataset %s: %w", dataset, err)
+				}
+
+				err = processor.NewCsvETLProcessor(
+					config,
+					"Melodi "+dataset,
+					';',
+					nil,
+					melodi.NewObservationMapper(metadata),
+					repository.NewTableRepository[entities.MelodiObservationEntity](databaseManager),
+					processorOpts...,
+				).Run(ctx, filepath.Join(dataDir, dataset+"_data.csv"))
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		"iris": func() error {
+			return processor.NewGeoJSONETLProcessor(
+				config,
+				"IRIS",
+				func() entities.IrisProperties {
+					return entities.IrisProperties{}
+				},
+				entities.NewIrisMapper(),
+				repository.NewIrisRepository(databaseManager, adminOpts...),
+				geoProcessorOpts()...,
+			).Run(ctx, dataDir+"/iris.geojson")
+		},
+		"population-iris": func() error {
+			irisPopulationMapper := entities.NewIrisPopulationMapper()
+			if *populationDecimals {
+				irisPopulationMapper = entities.NewIrisPopulationMapperWithDecimals()
+			}
+
+			return processor.NewCsvETLProcessor(
+				config,
+				"Population des IRIS",
+				';',
+				nil,
+				irisPopulationMapper,
+				repository.NewIrisPopulationRepository(databaseManager, layerPopulationOpts("population-iris")...),
+				processorOpts...,
+			).Run(ctx, irisPopulationFile)
+		},
+	}
+
+	for _, name := range selectedLayers {
+		if err := layers[name](); err != nil {
+			slog.Error("❌ Failed to run process", "layer", name, "error", err)
 			os.Exit(1)
 		}
+	}
 
-		communesProcess := processor.NewGeoJSONETLProcessor(
-			config,
-			"Communes",
-			func() entities.CommuneProperties {
-				return entities.CommuneProperties{}
-			},
-			entities.NewCommuneMapper(),
-			repository.NewCommuneRepository(databaseManager),
-		)
+	if *dissolve {
+		dissolved := []struct {
+			level repository.DissolveLevel
+			load  func(filePath string) error
+		}{
+			{level: repository.DissolveRegion, load: loadRegions},
+			{level: repository.DissolveDepartement, load: loadDepartements},
+			{level: repository.DissolveEPCI, load: loadEPCI},
+		}
+		for _, d := range dissolved {
+			if err := loadDissolved(ctx, databaseManager, d.level, vintage, resolution, d.load); err != nil {
+				slog.Error("❌ Failed to dissolve communes", "level", d.level, "error", err)
+				os.Exit(1)
+			}
+		}
+	}
+
+	slog.Info("ETL completed")
+}
+
+// layerNames lists the layers in load order: parents first, so that references resolve
+var layerNames = []string{
+	"cog-regions", "cog-departements", "cog-arrondissements", "cog-cantons", "cog-communes", "cog-comer", "commune-events",
+	"regions", "departements", "arrondissements", "cantons", "epci", "communes", "arrondissements-municipaux", "codes-postaux", "population", "melodi", "iris", "population-iris",
+}
+
+// layerTables maps the layers to their tables, for rollback. The melodi layer is never swapped.
+var layerTables = map[string][]string{
+	"cog-regions":                {"ref_admin.cog_regions"},
+	"cog-departements":           {"ref_admin.cog_departements"},
+	"cog-arrondissements":        {"ref_admin.cog_arrondissements"},
+	"cog-cantons":                {"ref_admin.cog_cantons"},
+	"cog-communes":               {"ref_admin.cog_communes"},
+	"cog-comer":                  {"ref_admin.cog_comer"},
+	"commune-events":             {"ref_admin.commune_events"},
+	"regions":                    {"ref_admin.regions"},
+	"departements":               {"ref_admin.departements"},
+	"arrondissements":            {"ref_admin.arrondissements"},
+	"cantons":                    {"ref_admin.cantons"},
+	"epci":                       {"ref_admin.epci"},
+	"communes":                   {"ref_admin.communes"},
+	"arrondissements-municipaux": {"ref_admin.arrondissements_municipaux"},
+	"codes-postaux":              {"ref_admin.codes_postaux"},
+	"population":                 {"demography.population_commune", "demography.population_arrondissement_municipal"},
+	"iris":                       {"ref_admin.iris"},
+	"population-iris":            {"demography.population_iris"},
+}
+
+// loadDissolved loads the geometries of the level dissolved from the commune geometries of the vintage and
+// resolution, written to a temporary GeoJSON file read by the processor of the level
+func loadDissolved(ctx context.Context, dm *repository.DatabaseManager, level repository.DissolveLevel, vintage model.Vintage, resolution model.Resolution, load func(filePath string) error) error {
+	file, err := os.CreateTemp("", "dissolved-"+string(level)+"-*.geojson")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(file.Name()) }()
+
+	if _, err := repository.DissolveCommunes(ctx, dm, level, vintage, resolution, file); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return load(file.Name())
+}
+
+// loadCogCommuneParents reads the arrondissement and canton of the communes (TYPECOM COM) of a v_commune
+// file, by commune code
+func loadCogCommuneParents(ctx context.Context, filePath string) (map[string]entities.CogCommuneParents, error) {
+	const batchSize = 1000
 
-		err = communesProcess.Run(ctx, "./data/communes-1000m.geojson")
+	records, err := extractors.NewCSVExtractor(nil).Extract(ctx, filePath, batchSize)
+	if err != nil {
+		return nil, fmt.Errorf("error extracting COG communes: %w", err)
+	}
+
+	mapper := entities.NewCogCommuneMapper()
+	parents := make(map[string]entities.CogCommuneParents)
+	for record := range records {
+		commune, err := mapper.Map(record)
 		if err != nil {
-			slog.Error("❌ Failed to run communes process", "error", err)
-			os.Exit(1)
+			slog.Warn("Skip invalid COG commune", "error", err, "record", record)
+			continue
+		}
+		if commune.TypeCommune == "COM" {
+			parents[commune.Code] = entities.CogCommuneParents{Arrondissement: commune.CodeArrondissement, Canton: commune.CodeCanton}
 		}
-	*/
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-	communePopulationProcess := processor.NewCsvETLProcessor(
-		config,
-		"Population des communes",
-		';',
-		entities.CommunePopulationPrincFilter,
-		entities.NewCommunePopulationMapper(),
-		repository.NewCommunePopulationRepository(databaseManager),
-	)
+	slog.Info("COG communes loaded", "file", filePath, "communes", len(parents))
+	return parents, nil
+}
 
-	err = communePopulationProcess.Run(ctx, "./data/DS_RP_POPULATION_PRINC_2022_data.csv")
-	// err = communePopulationProcess.Run(ctx, "./data/test.csv")
+// writeJSONReport writes the report of a check to a JSON file
+func writeJSONReport(report interface{ WriteJSON(io.Writer) error }, filePath string) error {
+	// #nosec G304 -- filePath is controlled by the application, not user input
+	file, err := os.Create(filePath)
 	if err != nil {
-		slog.Error("❌ Failed to run commune population process", "error", err)
-		os.Exit(1)
+		return err
 	}
+	defer func() { _ = file.Close() }()
+	if err := report.WriteJSON(file); err != nil {
+		return err
+	}
+	slog.Info("Report written", "file", filePath)
+	return file.Close()
+}
 
-	slog.Info("ETL completed")
+// parseLayers parses the comma-separated list of layers, returned in load order
+func parseLayers(s string) ([]string, error) {
+	selected := make([]string, 0, len(layerNames))
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if !slices.Contains(layerNames, name) {
+			return nil, fmt.Errorf("unknown layer %q, must be one of %s", name, strings.Join(layerNames, ", "))
+		}
+		selected = append(selected, name)
+	}
+
+	layers := make([]string, 0, len(selected))
+	for _, name := range layerNames {
+		if slices.Contains(selected, name) {
+			layers = append(layers, name)
+		}
+	}
+	return layers, nil
 }
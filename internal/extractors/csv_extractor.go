@@ -15,6 +15,7 @@ import (
 // CSVExtractor extracts records from CSV files with configurable delimiters and filters.
 type CSVExtractor struct {
 	Delimiter rune
+	Encoding  Encoding // UTF8 by default
 	filter    model.CsvRecordFilter
 }
 
@@ -43,7 +44,7 @@ func (e *CSVExtractor) loadFile(filePath string) (file *os.File, reader *csv.Rea
 	}
 
 	// Create CSV reader
-	reader = csv.NewReader(file)
+	reader = csv.NewReader(e.Encoding.decode(file))
 	reader.Comma = e.Delimiter
 	reader.TrimLeadingSpace = true
 
@@ -5,6 +5,7 @@ import (
 	"french-admin-etl/internal/model"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -227,3 +228,48 @@ func TestCSVExtractor_MismatchedColumns(t *testing.T) {
 		t.Errorf("Expected 1 valid record, got %d", len(records))
 	}
 }
+
+func TestCSVExtractor_Latin1(t *testing.T) {
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, "latin1.csv")
+
+	// "Libellé_d_acheminement" and "SAINT-ÉTIENNE" encoded in ISO-8859-1
+	content := []byte("Code_postal;Libell\xe9_d_acheminement\n42000;SAINT-\xc9TIENNE\n")
+	if err := os.WriteFile(tmpFile, content, 0600); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	extractor := NewCSVExtractorWithDelimiter(nil, ';')
+	extractor.Encoding = Latin1
+	recordChan, err := extractor.Extract(context.Background(), tmpFile, 10)
+	if err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+
+	var records []model.CSVRecord
+	for record := range recordChan {
+		records = append(records, record)
+	}
+
+	if len(records) != 1 || records[0]["Libellé_d_acheminement"] != "SAINT-ÉTIENNE" {
+		t.Errorf("Expected the record decoded to UTF-8, got %v", records)
+	}
+}
+
+func TestLatin1Reader_ShortBuffer(t *testing.T) {
+	reader := Latin1.decode(strings.NewReader("\xe9t\xe9"))
+
+	var decoded []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := reader.Read(buf)
+		decoded = append(decoded, buf[:n]...)
+		if err != nil {
+			break
+		}
+	}
+
+	if string(decoded) != "été" {
+		t.Errorf("Expected %q, got %q", "été", decoded)
+	}
+}
@@ -0,0 +1,62 @@
+package extractors
+
+import (
+	"bufio"
+	"io"
+	"unicode/utf8"
+)
+
+// Encoding is the character encoding of a CSV file.
+type Encoding int
+
+const (
+	// UTF8 is the default encoding.
+	UTF8 Encoding = iota
+	// Latin1 is ISO-8859-1, used by some official files (e.g. La Poste base officielle des codes postaux).
+	Latin1
+)
+
+// decode returns a reader decoding r to UTF-8.
+func (e Encoding) decode(r io.Reader) io.Reader {
+	if e == Latin1 {
+		return &latin1Reader{r: bufio.NewReader(r)}
+	}
+	return r
+}
+
+// latin1Reader decodes ISO-8859-1 to UTF-8: every byte is the code point of its character.
+type latin1Reader struct {
+	r       *bufio.Reader
+	pending []byte // encoded character not yet returned, when p was too short
+}
+
+func (l *latin1Reader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(l.pending) > 0 {
+			copied := copy(p[n:], l.pending)
+			l.pending = l.pending[copied:]
+			n += copied
+			continue
+		}
+		// Return what is decoded rather than block on the underlying reader
+		if n > 0 && l.r.Buffered() == 0 {
+			break
+		}
+
+		b, err := l.r.ReadByte()
+		if err != nil {
+			if n > 0 {
+				return n, nil
+			}
+			return 0, err
+		}
+		if b < utf8.RuneSelf {
+			p[n] = b
+			n++
+			continue
+		}
+		l.pending = utf8.AppendRune(l.pending[:0], rune(b))
+	}
+	return n, nil
+}
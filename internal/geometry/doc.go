@@ -0,0 +1,2 @@
+// Package geometry provides geometry processing utilities for the ETL process, such as line simplification and topology-aware simplification of administrative boundaries.
+package geometry
@@ -0,0 +1,206 @@
+package geometry
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+
+	"github.com/twpayne/go-geom"
+)
+
+// metersPerDegree is the length of one degree of latitude on the mean Earth sphere.
+const metersPerDegree = 6371008.8 * math.Pi / 180
+
+// Algorithm selects the line simplification algorithm.
+type Algorithm int
+
+const (
+	// DouglasPeucker removes vertices closer than the tolerance to the simplified line.
+	DouglasPeucker Algorithm = iota
+	// Visvalingam removes vertices whose effective triangle area is below the squared tolerance.
+	Visvalingam
+)
+
+// ParseAlgorithm converts a name to an Algorithm: douglas-peucker or visvalingam, returning an error for
+// unknown names.
+func ParseAlgorithm(s string) (Algorithm, error) {
+	switch s {
+	case "douglas-peucker":
+		return DouglasPeucker, nil
+	case "visvalingam":
+		return Visvalingam, nil
+	default:
+		return DouglasPeucker, fmt.Errorf("invalid simplification algorithm %q, must be douglas-peucker or visvalingam", s)
+	}
+}
+
+// SimplifyFunc simplifies a polyline of longitude/latitude coordinates with a tolerance in metres.
+// The first and last coordinates are always kept.
+type SimplifyFunc func(coords []geom.Coord, tolerance float64) []geom.Coord
+
+// Func returns the simplification function implementing the algorithm.
+func (a Algorithm) Func() SimplifyFunc {
+	if a == Visvalingam {
+		return SimplifyVisvalingam
+	}
+	return SimplifyDouglasPeucker
+}
+
+// point is a coordinate projected on a local plane, in metres.
+type point struct{ x, y float64 }
+
+// project converts longitude/latitude coordinates to metres using an equirectangular
+// projection centred on the mean latitude of the polyline, which is accurate enough at the
+// scale of a commune border.
+func project(coords []geom.Coord) []point {
+	meanLat := 0.0
+	for _, c := range coords {
+		meanLat += c[1]
+	}
+	meanLat /= float64(len(coords))
+	kx := math.Cos(meanLat*math.Pi/180) * metersPerDegree
+
+	points := make([]point, len(coords))
+	for i, c := range coords {
+		points[i] = point{x: c[0] * kx, y: c[1] * metersPerDegree}
+	}
+	return points
+}
+
+// segmentDistance returns the distance from p to the segment [a, b].
+func segmentDistance(p, a, b point) float64 {
+	dx, dy := b.x-a.x, b.y-a.y
+	if dx == 0 && dy == 0 {
+		return math.Hypot(p.x-a.x, p.y-a.y)
+	}
+	t := ((p.x-a.x)*dx + (p.y-a.y)*dy) / (dx*dx + dy*dy)
+	t = math.Max(0, math.Min(1, t))
+	return math.Hypot(p.x-(a.x+t*dx), p.y-(a.y+t*dy))
+}
+
+// triangleArea returns the area of the triangle (a, b, c).
+func triangleArea(a, b, c point) float64 {
+	return math.Abs((b.x-a.x)*(c.y-a.y)-(c.x-a.x)*(b.y-a.y)) / 2
+}
+
+// keep returns the coordinates flagged as kept.
+func keep(coords []geom.Coord, kept []bool) []geom.Coord {
+	result := make([]geom.Coord, 0, len(coords))
+	for i, c := range coords {
+		if kept[i] {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// SimplifyDouglasPeucker simplifies a polyline with the Douglas-Peucker algorithm: no removed
+// vertex lies further than tolerance metres from the simplified line.
+func SimplifyDouglasPeucker(coords []geom.Coord, tolerance float64) []geom.Coord {
+	if len(coords) < 3 {
+		return coords
+	}
+	points := project(coords)
+	kept := make([]bool, len(coords))
+	kept[0], kept[len(coords)-1] = true, true
+
+	// Iterative version to avoid deep recursion on long borders
+	type span struct{ first, last int }
+	stack := []span{{0, len(coords) - 1}}
+	for len(stack) > 0 {
+		s := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		maxDistance, index := 0.0, -1
+		for i := s.first + 1; i < s.last; i++ {
+			if d := segmentDistance(points[i], points[s.first], points[s.last]); d > maxDistance {
+				maxDistance, index = d, i
+			}
+		}
+		if index >= 0 && maxDistance > tolerance {
+			kept[index] = true
+			stack = append(stack, span{s.first, index}, span{index, s.last})
+		}
+	}
+
+	return keep(coords, kept)
+}
+
+// vertex is an entry of the Visvalingam priority queue.
+type vertex struct {
+	index      int
+	area       float64
+	prev, next int
+	heapIndex  int
+}
+
+type vertexHeap []*vertex
+
+func (h vertexHeap) Len() int { return len(h) }
+func (h vertexHeap) Less(i, j int) bool {
+	if h[i].area != h[j].area {
+		return h[i].area < h[j].area
+	}
+	return h[i].index < h[j].index // deterministic order for equal areas
+}
+func (h vertexHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapIndex = i
+	h[j].heapIndex = j
+}
+func (h *vertexHeap) Push(x any) {
+	v := x.(*vertex)
+	v.heapIndex = len(*h)
+	*h = append(*h, v)
+}
+func (h *vertexHeap) Pop() any {
+	old := *h
+	v := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return v
+}
+
+// SimplifyVisvalingam simplifies a polyline with the Visvalingam-Whyatt algorithm: vertices are
+// removed while the smallest effective triangle area is below tolerance² square metres.
+func SimplifyVisvalingam(coords []geom.Coord, tolerance float64) []geom.Coord {
+	if len(coords) < 3 {
+		return coords
+	}
+	points := project(coords)
+	minArea := tolerance * tolerance
+
+	vertices := make([]*vertex, len(coords))
+	for i := range coords {
+		vertices[i] = &vertex{index: i, prev: i - 1, next: i + 1, area: math.Inf(1)}
+	}
+	h := make(vertexHeap, 0, len(coords)-2)
+	for i := 1; i < len(coords)-1; i++ {
+		vertices[i].area = triangleArea(points[i-1], points[i], points[i+1])
+		heap.Push(&h, vertices[i])
+	}
+
+	kept := make([]bool, len(coords))
+	for i := range kept {
+		kept[i] = true
+	}
+
+	maxArea := 0.0
+	for h.Len() > 0 && h[0].area < minArea {
+		v := heap.Pop(&h).(*vertex)
+		// Effective area never decreases, so that a vertex is not removed before its neighbours
+		maxArea = math.Max(maxArea, v.area)
+		kept[v.index] = false
+
+		prev, next := vertices[v.prev], vertices[v.next]
+		prev.next, next.prev = v.next, v.prev
+		for _, n := range []*vertex{prev, next} {
+			if n.prev < 0 || n.next >= len(coords) {
+				continue // first or last vertex
+			}
+			n.area = math.Max(maxArea, triangleArea(points[n.prev], points[n.index], points[n.next]))
+			heap.Fix(&h, n.heapIndex)
+		}
+	}
+
+	return keep(coords, kept)
+}
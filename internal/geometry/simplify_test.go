@@ -0,0 +1,135 @@
+package geometry
+
+import (
+	"testing"
+
+	"github.com/twpayne/go-geom"
+)
+
+// noisyLine returns a line along the equator with a zig-zag of about 1 metre
+func noisyLine() []geom.Coord {
+	coords := make([]geom.Coord, 0, 11)
+	for i := 0; i <= 10; i++ {
+		offset := 0.0
+		if i%2 == 1 {
+			offset = 0.00001
+		}
+		coords = append(coords, geom.Coord{float64(i) * 0.001, offset})
+	}
+	return coords
+}
+
+func TestSimplify_RemovesNoise(t *testing.T) {
+	tests := []struct {
+		name     string
+		simplify SimplifyFunc
+	}{
+		{name: "Douglas-Peucker", simplify: SimplifyDouglasPeucker},
+		{name: "Visvalingam", simplify: SimplifyVisvalingam},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			coords := noisyLine()
+			result := tt.simplify(coords, 20)
+
+			if len(result) > 3 {
+				t.Fatalf("Expected at most 3 coordinates, got %d: %v", len(result), result)
+			}
+			if !equal(result[0], coords[0]) || !equal(result[len(result)-1], coords[len(coords)-1]) {
+				t.Errorf("Expected endpoints to be kept, got %v", result)
+			}
+		})
+	}
+}
+
+func TestSimplify_KeepsSignificantVertices(t *testing.T) {
+	// A spike of about 1.1 km in the middle of a 2 km line
+	coords := []geom.Coord{{0, 0}, {0.005, 0.00001}, {0.009, 0.01}, {0.013, 0.00001}, {0.018, 0}}
+
+	tests := []struct {
+		name     string
+		simplify SimplifyFunc
+	}{
+		{name: "Douglas-Peucker", simplify: SimplifyDouglasPeucker},
+		{name: "Visvalingam", simplify: SimplifyVisvalingam},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.simplify(coords, 20)
+
+			found := false
+			for _, c := range result {
+				if equal(c, coords[2]) {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("Expected spike vertex to be kept, got %v", result)
+			}
+		})
+	}
+}
+
+func TestSimplify_ShortLines(t *testing.T) {
+	coords := []geom.Coord{{0, 0}, {1, 1}}
+
+	if result := SimplifyDouglasPeucker(coords, 1000); len(result) != 2 {
+		t.Errorf("Douglas-Peucker: expected 2 coordinates, got %d", len(result))
+	}
+	if result := SimplifyVisvalingam(coords, 1000); len(result) != 2 {
+		t.Errorf("Visvalingam: expected 2 coordinates, got %d", len(result))
+	}
+}
+
+func TestSimplify_DoesNotModifyInput(t *testing.T) {
+	coords := noisyLine()
+	before := len(coords)
+
+	SimplifyDouglasPeucker(coords, 10)
+	SimplifyVisvalingam(coords, 10)
+
+	if len(coords) != before {
+		t.Errorf("Input modified: expected %d coordinates, got %d", before, len(coords))
+	}
+	for i, c := range noisyLine() {
+		if !equal(coords[i], c) {
+			t.Fatalf("Input coordinate %d modified: %v", i, coords[i])
+		}
+	}
+}
+
+func TestAlgorithm_Func(t *testing.T) {
+	coords := noisyLine()
+
+	if got := len(DouglasPeucker.Func()(coords, 10)); got != len(SimplifyDouglasPeucker(coords, 10)) {
+		t.Errorf("DouglasPeucker.Func() does not match SimplifyDouglasPeucker")
+	}
+	if got := len(Visvalingam.Func()(coords, 10)); got != len(SimplifyVisvalingam(coords, 10)) {
+		t.Errorf("Visvalingam.Func() does not match SimplifyVisvalingam")
+	}
+}
+
+func TestParseAlgorithm(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Algorithm
+		wantErr bool
+	}{
+		{name: "douglas-peucker", want: DouglasPeucker},
+		{name: "visvalingam", want: Visvalingam},
+		{name: "", wantErr: true},
+		{name: "Visvalingam", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAlgorithm(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseAlgorithm(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("ParseAlgorithm(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
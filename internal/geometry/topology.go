@@ -0,0 +1,282 @@
+package geometry
+
+import (
+	"math"
+	"sync"
+
+	"github.com/twpayne/go-geom"
+)
+
+// vertexKey identifies a vertex by its longitude and latitude.
+type vertexKey [2]float64
+
+func keyOf(c geom.Coord) vertexKey {
+	return vertexKey{c[0], c[1]}
+}
+
+// neighbours records the distinct vertices adjacent to a vertex across all indexed rings, and
+// the number of rings the vertex belongs to. A vertex with three or more distinct neighbours
+// is a junction: a point where a border shared by two features starts or ends.
+type neighbours struct {
+	first, second vertexKey
+	count         uint8
+	rings         uint8
+}
+
+func (n *neighbours) add(k vertexKey) {
+	switch {
+	case n.count == 0:
+		n.first, n.count = k, 1
+	case n.count == 1 && k != n.first:
+		n.second, n.count = k, 2
+	case n.count == 2 && k != n.first && k != n.second:
+		n.count = 3
+	}
+}
+
+// TopologySimplifier simplifies polygons while keeping the borders they share consistent.
+//
+// Rings are cut into arcs at junctions, and each arc is simplified in a canonical direction,
+// so that a border shared by two neighbouring communes is simplified into exactly the same
+// vertices on both sides and no sliver or gap appears between them. All the geometries of a
+// dataset must be indexed with Index before the first call to Simplify.
+type TopologySimplifier struct {
+	mu        sync.RWMutex
+	simplify  SimplifyFunc
+	tolerance float64
+	vertices  map[vertexKey]neighbours
+}
+
+// NewTopologySimplifier creates a new topology-aware simplifier using the given algorithm and tolerance in metres.
+func NewTopologySimplifier(algorithm Algorithm, tolerance float64) *TopologySimplifier {
+	return &TopologySimplifier{
+		simplify:  algorithm.Func(),
+		tolerance: tolerance,
+		vertices:  make(map[vertexKey]neighbours),
+	}
+}
+
+// Index records the vertices of a geometry. Only polygons and multipolygons are indexed.
+func (s *TopologySimplifier) Index(g geom.T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, polygon := range polygonsOf(g) {
+		for _, ring := range polygon {
+			s.indexRing(openRing(ring))
+		}
+	}
+}
+
+func (s *TopologySimplifier) indexRing(ring []geom.Coord) {
+	n := len(ring)
+	for i, c := range ring {
+		k := keyOf(c)
+		v := s.vertices[k]
+		v.add(keyOf(ring[(i+n-1)%n]))
+		v.add(keyOf(ring[(i+1)%n]))
+		if v.rings < math.MaxUint8 {
+			v.rings++
+		}
+		s.vertices[k] = v
+	}
+}
+
+func (s *TopologySimplifier) isJunction(c geom.Coord) bool {
+	return s.vertices[keyOf(c)].count >= 3
+}
+
+// isShared reports whether an arc between junctions is a border shared with another ring,
+// its inner vertices belonging to several rings. An arc without inner vertex is never
+// simplified, so it needs no sharing.
+func (s *TopologySimplifier) isShared(arc []geom.Coord) bool {
+	return len(arc) > 2 && s.vertices[keyOf(arc[1])].rings >= 2
+}
+
+// Simplify returns the simplified geometry. A ring touching other rings that collapses below
+// four vertices keeps the simplified arcs it shares with them, so that its neighbours and it
+// still get the same vertices, and its own arcs unsimplified. Other rings collapsing are
+// dropped; when every polygon collapses, the original geometry is returned unchanged.
+func (s *TopologySimplifier) Simplify(g geom.T) (geom.T, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	switch g := g.(type) {
+	case *geom.Polygon:
+		coords, ok := s.simplifyPolygon(g.Coords())
+		if !ok {
+			return g, nil
+		}
+		polygon, err := geom.NewPolygon(g.Layout()).SetCoords(coords)
+		if err != nil {
+			return nil, err
+		}
+		return polygon.SetSRID(g.SRID()), nil
+	case *geom.MultiPolygon:
+		polygons := make([][][]geom.Coord, 0, g.NumPolygons())
+		for _, coords := range g.Coords() {
+			if simplified, ok := s.simplifyPolygon(coords); ok {
+				polygons = append(polygons, simplified)
+			}
+		}
+		if len(polygons) == 0 {
+			return g, nil
+		}
+		multiPolygon, err := geom.NewMultiPolygon(g.Layout()).SetCoords(polygons)
+		if err != nil {
+			return nil, err
+		}
+		return multiPolygon.SetSRID(g.SRID()), nil
+	default:
+		return g, nil
+	}
+}
+
+func (s *TopologySimplifier) simplifyPolygon(rings [][]geom.Coord) ([][]geom.Coord, bool) {
+	if len(rings) == 0 {
+		return nil, false
+	}
+	exterior := s.simplifyRing(rings[0])
+	if len(exterior) < 4 {
+		return nil, false
+	}
+
+	result := [][]geom.Coord{exterior}
+	for _, hole := range rings[1:] {
+		if simplified := s.simplifyRing(hole); len(simplified) >= 4 {
+			result = append(result, simplified)
+		}
+	}
+	return result, true
+}
+
+// simplifyRing simplifies a ring arc by arc and returns it closed.
+func (s *TopologySimplifier) simplifyRing(ring []geom.Coord) []geom.Coord {
+	ring = openRing(ring)
+	n := len(ring)
+	if n < 3 {
+		return ring
+	}
+
+	start := -1
+	for i, c := range ring {
+		if s.isJunction(c) {
+			start = i
+			break
+		}
+	}
+
+	if start < 0 {
+		// Ring without junction (island or enclave): start from a canonical vertex so that
+		// the ring is simplified identically wherever it appears
+		start = 0
+		for i, c := range ring {
+			if less(c, ring[start]) {
+				start = i
+			}
+		}
+		return s.simplifyArc(closeRing(rotate(ring, start)))
+	}
+
+	rotated := closeRing(rotate(ring, start))
+	var arcs [][]geom.Coord
+	arcStart := 0
+	for i := 1; i < len(rotated); i++ {
+		if i == len(rotated)-1 || s.isJunction(rotated[i]) {
+			arcs = append(arcs, rotated[arcStart:i+1])
+			arcStart = i
+		}
+	}
+
+	result := joinArcs(arcs, s.simplifyArc)
+	if len(result) < 4 {
+		// The ring collapses: its neighbours already got the simplified shared arcs, so only
+		// its own arcs are left unsimplified
+		result = joinArcs(arcs, func(arc []geom.Coord) []geom.Coord {
+			if s.isShared(arc) {
+				return s.simplifyArc(arc)
+			}
+			return arc
+		})
+	}
+	return result
+}
+
+// joinArcs returns the ring made of the consecutive arcs, each simplified with the given function.
+func joinArcs(arcs [][]geom.Coord, simplify func([]geom.Coord) []geom.Coord) []geom.Coord {
+	result := []geom.Coord{arcs[0][0]}
+	for _, arc := range arcs {
+		result = append(result, simplify(arc)[1:]...)
+	}
+	return result
+}
+
+// simplifyArc simplifies an arc in its canonical direction, so that both features sharing
+// the arc, which traverse it in opposite directions, get the same vertices.
+func (s *TopologySimplifier) simplifyArc(arc []geom.Coord) []geom.Coord {
+	if !isCanonical(arc) {
+		return reverse(s.simplify(reverse(arc), s.tolerance))
+	}
+	return s.simplify(arc, s.tolerance)
+}
+
+// polygonsOf returns the polygon coordinates of a polygon or multipolygon geometry.
+func polygonsOf(g geom.T) [][][]geom.Coord {
+	switch g := g.(type) {
+	case *geom.Polygon:
+		return [][][]geom.Coord{g.Coords()}
+	case *geom.MultiPolygon:
+		return g.Coords()
+	default:
+		return nil
+	}
+}
+
+func equal(a, b geom.Coord) bool {
+	return a[0] == b[0] && a[1] == b[1]
+}
+
+func less(a, b geom.Coord) bool {
+	if a[0] != b[0] {
+		return a[0] < b[0]
+	}
+	return a[1] < b[1]
+}
+
+// openRing removes the closing vertex of a ring.
+func openRing(ring []geom.Coord) []geom.Coord {
+	if n := len(ring); n > 1 && equal(ring[0], ring[n-1]) {
+		return ring[:n-1]
+	}
+	return ring
+}
+
+func closeRing(ring []geom.Coord) []geom.Coord {
+	return append(ring, ring[0])
+}
+
+// rotate returns a copy of an open ring starting at the given index.
+func rotate(ring []geom.Coord, start int) []geom.Coord {
+	rotated := make([]geom.Coord, 0, len(ring)+1)
+	rotated = append(rotated, ring[start:]...)
+	return append(rotated, ring[:start]...)
+}
+
+func reverse(coords []geom.Coord) []geom.Coord {
+	reversed := make([]geom.Coord, len(coords))
+	for i, c := range coords {
+		reversed[len(coords)-1-i] = c
+	}
+	return reversed
+}
+
+// isCanonical reports whether an arc is in canonical direction, comparing its vertices from
+// both ends until they differ.
+func isCanonical(arc []geom.Coord) bool {
+	for i, j := 0, len(arc)-1; i < j; i, j = i+1, j-1 {
+		if !equal(arc[i], arc[j]) {
+			return less(arc[i], arc[j])
+		}
+	}
+	return true
+}
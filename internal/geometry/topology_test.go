@@ -0,0 +1,228 @@
+package geometry
+
+import (
+	"testing"
+
+	"github.com/twpayne/go-geom"
+)
+
+// sharedBorder returns a noisy north-south border at longitude 0.01, from south to north
+func sharedBorder() []geom.Coord {
+	coords := make([]geom.Coord, 0, 21)
+	for i := 0; i <= 20; i++ {
+		offset := 0.0
+		if i%2 == 1 {
+			offset = 0.00001
+		}
+		coords = append(coords, geom.Coord{0.01 + offset, float64(i) * 0.0005})
+	}
+	return coords
+}
+
+// neighbourPolygons returns two polygons sharing a noisy border: west is traversed
+// counter-clockwise and east clockwise, so that they walk the border in opposite directions.
+func neighbourPolygons(t *testing.T) (*geom.Polygon, *geom.Polygon) {
+	t.Helper()
+	border := sharedBorder()
+
+	west := []geom.Coord{{0, 0}}
+	west = append(west, border...)
+	west = append(west, geom.Coord{0, 0.01}, geom.Coord{0, 0})
+
+	east := []geom.Coord{{0.02, 0}}
+	east = append(east, border...)
+	east = append(east, geom.Coord{0.02, 0.01}, geom.Coord{0.02, 0})
+
+	westPolygon, err := geom.NewPolygon(geom.XY).SetCoords([][]geom.Coord{west})
+	if err != nil {
+		t.Fatalf("Failed to create west polygon: %v", err)
+	}
+	eastPolygon, err := geom.NewPolygon(geom.XY).SetCoords([][]geom.Coord{reverse(east)})
+	if err != nil {
+		t.Fatalf("Failed to create east polygon: %v", err)
+	}
+	return westPolygon, eastPolygon
+}
+
+// borderVertices returns the vertices of a polygon lying on the shared border
+func borderVertices(p *geom.Polygon) map[vertexKey]bool {
+	vertices := make(map[vertexKey]bool)
+	for _, c := range p.Coords()[0] {
+		if c[0] > 0.005 && c[0] < 0.015 {
+			vertices[keyOf(c)] = true
+		}
+	}
+	return vertices
+}
+
+func TestTopologySimplifier_SharedBorderIsConsistent(t *testing.T) {
+	for _, algorithm := range []Algorithm{DouglasPeucker, Visvalingam} {
+		west, east := neighbourPolygons(t)
+
+		simplifier := NewTopologySimplifier(algorithm, 20)
+		simplifier.Index(west)
+		simplifier.Index(east)
+
+		simplifiedWest, err := simplifier.Simplify(west)
+		if err != nil {
+			t.Fatalf("Simplify(west) error = %v", err)
+		}
+		simplifiedEast, err := simplifier.Simplify(east)
+		if err != nil {
+			t.Fatalf("Simplify(east) error = %v", err)
+		}
+
+		westBorder := borderVertices(simplifiedWest.(*geom.Polygon))
+		eastBorder := borderVertices(simplifiedEast.(*geom.Polygon))
+
+		if len(westBorder) >= len(sharedBorder()) {
+			t.Errorf("Algorithm %d: expected shared border to be simplified, got %d vertices", algorithm, len(westBorder))
+		}
+		if len(westBorder) != len(eastBorder) {
+			t.Fatalf("Algorithm %d: border differs, west has %d vertices and east %d", algorithm, len(westBorder), len(eastBorder))
+		}
+		for k := range westBorder {
+			if !eastBorder[k] {
+				t.Errorf("Algorithm %d: west border vertex %v missing from east border", algorithm, k)
+			}
+		}
+	}
+}
+
+func TestTopologySimplifier_KeepsJunctions(t *testing.T) {
+	west, east := neighbourPolygons(t)
+
+	simplifier := NewTopologySimplifier(DouglasPeucker, 5000)
+	simplifier.Index(west)
+	simplifier.Index(east)
+
+	simplified, err := simplifier.Simplify(west)
+	if err != nil {
+		t.Fatalf("Simplify() error = %v", err)
+	}
+
+	border := borderVertices(simplified.(*geom.Polygon))
+	shared := sharedBorder()
+	for _, junction := range []geom.Coord{shared[0], shared[len(shared)-1]} {
+		if !border[keyOf(junction)] {
+			t.Errorf("Expected junction %v to be kept", junction)
+		}
+	}
+}
+
+func TestTopologySimplifier_EnclaveMatchesHole(t *testing.T) {
+	enclaveRing := []geom.Coord{{0.004, 0.004}, {0.006, 0.004}, {0.00601, 0.005}, {0.006, 0.006}, {0.004, 0.006}, {0.004, 0.004}}
+	outerRing := []geom.Coord{{0, 0}, {0.01, 0}, {0.01, 0.01}, {0, 0.01}, {0, 0}}
+
+	host, err := geom.NewPolygon(geom.XY).SetCoords([][]geom.Coord{outerRing, reverse(enclaveRing)})
+	if err != nil {
+		t.Fatalf("Failed to create host polygon: %v", err)
+	}
+	enclave, err := geom.NewMultiPolygon(geom.XY).SetCoords([][][]geom.Coord{{enclaveRing}})
+	if err != nil {
+		t.Fatalf("Failed to create enclave: %v", err)
+	}
+
+	simplifier := NewTopologySimplifier(DouglasPeucker, 50)
+	simplifier.Index(host)
+	simplifier.Index(enclave)
+
+	simplifiedHost, err := simplifier.Simplify(host)
+	if err != nil {
+		t.Fatalf("Simplify(host) error = %v", err)
+	}
+	simplifiedEnclave, err := simplifier.Simplify(enclave)
+	if err != nil {
+		t.Fatalf("Simplify(enclave) error = %v", err)
+	}
+
+	hole := simplifiedHost.(*geom.Polygon).Coords()[1]
+	exterior := simplifiedEnclave.(*geom.MultiPolygon).Coords()[0][0]
+	if len(hole) != len(exterior) {
+		t.Fatalf("Hole has %d vertices, enclave has %d", len(hole), len(exterior))
+	}
+	for i, c := range reverse(exterior) {
+		if !equal(c, hole[i]) {
+			t.Errorf("Vertex %d differs: hole %v, enclave %v", i, hole[i], c)
+		}
+	}
+}
+
+func TestTopologySimplifier_CollapsedGeometryIsKept(t *testing.T) {
+	tiny, err := geom.NewPolygon(geom.XY).SetCoords([][]geom.Coord{{{0, 0}, {0.00001, 0}, {0.00001, 0.00001}, {0, 0.00001}, {0, 0}}})
+	if err != nil {
+		t.Fatalf("Failed to create polygon: %v", err)
+	}
+
+	simplifier := NewTopologySimplifier(DouglasPeucker, 1000)
+	simplifier.Index(tiny)
+
+	simplified, err := simplifier.Simplify(tiny)
+	if err != nil {
+		t.Fatalf("Simplify() error = %v", err)
+	}
+	if got := len(simplified.(*geom.Polygon).Coords()[0]); got != 5 {
+		t.Errorf("Expected collapsed polygon to be returned unchanged, got %d vertices", got)
+	}
+}
+
+func TestTopologySimplifier_CollapsedNeighbourKeepsSharedBorder(t *testing.T) {
+	border := sharedBorder()
+	west, _ := neighbourPolygons(t)
+
+	// A sliver east of the border, whose own side is a 3m bump
+	sliver := append([]geom.Coord{}, border...)
+	sliver = append(sliver, geom.Coord{0.01003, 0.005}, border[0])
+	east, err := geom.NewPolygon(geom.XY).SetCoords([][]geom.Coord{sliver})
+	if err != nil {
+		t.Fatalf("Failed to create sliver: %v", err)
+	}
+
+	simplifier := NewTopologySimplifier(DouglasPeucker, 20)
+	simplifier.Index(west)
+	simplifier.Index(east)
+
+	simplifiedWest, err := simplifier.Simplify(west)
+	if err != nil {
+		t.Fatalf("Simplify(west) error = %v", err)
+	}
+	simplifiedEast, err := simplifier.Simplify(east)
+	if err != nil {
+		t.Fatalf("Simplify(east) error = %v", err)
+	}
+
+	eastRing := simplifiedEast.(*geom.Polygon).Coords()[0]
+	if len(eastRing) != 4 {
+		t.Fatalf("Expected the sliver reduced to its shared border and bump, got %v", eastRing)
+	}
+	westBorder := borderVertices(simplifiedWest.(*geom.Polygon))
+	eastBorder := make(map[vertexKey]bool)
+	for _, c := range eastRing {
+		if c[0] < 0.01002 {
+			eastBorder[keyOf(c)] = true
+		}
+	}
+	if len(westBorder) != len(eastBorder) {
+		t.Fatalf("Border differs, west has %d vertices and east %d", len(westBorder), len(eastBorder))
+	}
+	for k := range westBorder {
+		if !eastBorder[k] {
+			t.Errorf("West border vertex %v missing from east border", k)
+		}
+	}
+}
+
+func TestTopologySimplifier_IgnoresOtherGeometries(t *testing.T) {
+	point := geom.NewPointFlat(geom.XY, []float64{2.35, 48.85})
+
+	simplifier := NewTopologySimplifier(DouglasPeucker, 1000)
+	simplifier.Index(point)
+
+	simplified, err := simplifier.Simplify(point)
+	if err != nil {
+		t.Fatalf("Simplify() error = %v", err)
+	}
+	if simplified != point {
+		t.Errorf("Expected point to be returned unchanged")
+	}
+}
@@ -10,9 +10,11 @@ import (
 
 // Config holds the application configuration.
 type Config struct {
-	PostgresDatabase PostgresDatabase
-	Workers          int `env:"ETL_WORKERS" envDefault:"4"`
-	BatchSize        int `env:"ETL_BATCH_SIZE" envDefault:"1000"`
+	PostgresDatabase    PostgresDatabase
+	Workers             int     `env:"ETL_WORKERS" envDefault:"4"`
+	BatchSize           int     `env:"ETL_BATCH_SIZE" envDefault:"1000"`
+	LoadStrategy        string  `env:"ETL_LOAD_STRATEGY" envDefault:"pipeline"`
+	SyncMaxRemovalRatio float64 `env:"ETL_SYNC_MAX_REMOVAL_RATIO" envDefault:"0.05"`
 }
 
 // PostgresDatabase holds PostgreSQL database configuration.
@@ -23,6 +23,12 @@ func TestLoad_DefaultValues(t *testing.T) {
 	if config.BatchSize != 1000 {
 		t.Errorf("BatchSize = %d, want 1000", config.BatchSize)
 	}
+	if config.LoadStrategy != "pipeline" {
+		t.Errorf("LoadStrategy = %s, want pipeline", config.LoadStrategy)
+	}
+	if config.SyncMaxRemovalRatio != 0.05 {
+		t.Errorf("SyncMaxRemovalRatio = %v, want 0.05", config.SyncMaxRemovalRatio)
+	}
 
 	// Verify PostgresDatabase defaults
 	db := config.PostgresDatabase
@@ -69,6 +75,8 @@ func TestLoad_CustomValues(t *testing.T) {
 	setEnv(t, map[string]string{
 		"ETL_WORKERS":                   "8",
 		"ETL_BATCH_SIZE":                "500",
+		"ETL_LOAD_STRATEGY":             "savepoint",
+		"ETL_SYNC_MAX_REMOVAL_RATIO":    "0.2",
 		"POSTGRES_HOST":                 "db.example.com",
 		"POSTGRES_PORT":                 "5433",
 		"POSTGRES_USER":                 "testuser",
@@ -94,6 +102,12 @@ func TestLoad_CustomValues(t *testing.T) {
 	if config.BatchSize != 500 {
 		t.Errorf("BatchSize = %d, want 500", config.BatchSize)
 	}
+	if config.LoadStrategy != "savepoint" {
+		t.Errorf("LoadStrategy = %s, want savepoint", config.LoadStrategy)
+	}
+	if config.SyncMaxRemovalRatio != 0.2 {
+		t.Errorf("SyncMaxRemovalRatio = %v, want 0.2", config.SyncMaxRemovalRatio)
+	}
 
 	db := config.PostgresDatabase
 	if db.Host != "db.example.com" {
@@ -363,6 +377,8 @@ func cleanEnv(t *testing.T) {
 	envVars := []string{
 		"ETL_WORKERS",
 		"ETL_BATCH_SIZE",
+		"ETL_LOAD_STRATEGY",
+		"ETL_SYNC_MAX_REMOVAL_RATIO",
 		"POSTGRES_HOST",
 		"POSTGRES_PORT",
 		"POSTGRES_USER",
@@ -0,0 +1,56 @@
+package entities
+
+import (
+	"fmt"
+	"french-admin-etl/internal/model"
+)
+
+// ArrondissementProperties represents the properties of an arrondissement (départemental) in the GeoJSON file.
+type ArrondissementProperties struct {
+	Code        string `json:"code"`
+	Nom         string `json:"nom"`
+	Departement string `json:"departement"`
+	Region      string `json:"region"`
+}
+
+// GeoJSONArrondissementFeature is a type alias for a GeoJSON feature with ArrondissementProperties.
+type GeoJSONArrondissementFeature = model.GeoJSONFeature[ArrondissementProperties]
+
+// ArrondissementEntity represents the arrondissement entity to be stored in the database.
+type ArrondissementEntity struct {
+	_               struct{} `etl:"table=ref_admin.arrondissements,geometry=geom,deleted=supprime_le,vintage=millesime"`
+	Code            string   `json:"code_insee_arrondissement" etl:"code_insee_arrondissement,key"`
+	Nom             string   `json:"nom_arrondissement" etl:"nom_arrondissement"`
+	CodeDepartement string   `json:"code_insee_departement" etl:"code_insee_departement,ref=ref_admin.departements(code_insee_departement)"`
+	CodeRegion      string   `json:"code_insee_region" etl:"code_insee_region,ref=ref_admin.regions(code_insee_region)"`
+}
+
+// ArrondissementWithGeometry combines the arrondissement entity with its GeoJSON geometry for database insertion.
+type ArrondissementWithGeometry = model.EntityWithGeoJSONGeometry[ArrondissementEntity]
+
+// ArrondissementMapper is responsible for mapping ArrondissementProperties to ArrondissementEntity.
+type ArrondissementMapper struct{}
+
+// NewArrondissementMapper creates a new mapper for arrondissement data.
+func NewArrondissementMapper() *ArrondissementMapper {
+	return &ArrondissementMapper{}
+}
+
+var _ model.Mapper[ArrondissementProperties, ArrondissementEntity] = (*ArrondissementMapper)(nil)
+
+// Map converts ArrondissementProperties to an ArrondissementEntity for database insertion.
+func (m *ArrondissementMapper) Map(input ArrondissementProperties) (*ArrondissementEntity, error) {
+	if input.Code == "" {
+		return nil, fmt.Errorf("missing arrondissement code")
+	}
+	if input.Nom == "" {
+		return nil, fmt.Errorf("missing name of arrondissement %s", input.Code)
+	}
+
+	return &ArrondissementEntity{
+		Code:            input.Code,
+		Nom:             input.Nom,
+		CodeDepartement: input.Departement,
+		CodeRegion:      input.Region,
+	}, nil
+}
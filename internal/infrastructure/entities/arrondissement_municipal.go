@@ -0,0 +1,73 @@
+package entities
+
+import (
+	"fmt"
+	"french-admin-etl/internal/model"
+)
+
+// ArrondissementMunicipalProperties represents the properties of an arrondissement municipal (Paris, Lyon, Marseille) in the GeoJSON file.
+type ArrondissementMunicipalProperties struct {
+	Code    string `json:"code"`
+	Nom     string `json:"nom"`
+	Commune string `json:"commune"`
+}
+
+// GeoJSONArrondissementMunicipalFeature is a type alias for a GeoJSON feature with ArrondissementMunicipalProperties.
+type GeoJSONArrondissementMunicipalFeature = model.GeoJSONFeature[ArrondissementMunicipalProperties]
+
+// ArrondissementMunicipalEntity represents the arrondissement municipal entity to be stored in the database.
+type ArrondissementMunicipalEntity struct {
+	_           struct{} `etl:"table=ref_admin.arrondissements_municipaux,geometry=geom,deleted=supprime_le,vintage=millesime"`
+	Code        string   `json:"code_insee_arm" etl:"code_insee_arm,key"`
+	Nom         string   `json:"nom_arm" etl:"nom_arm"`
+	CodeCommune string   `json:"code_insee_commune" etl:"code_insee_commune,ref=ref_admin.communes(code_insee_commune)"`
+}
+
+// ArrondissementMunicipalWithGeometry combines the arrondissement municipal entity with its GeoJSON geometry for database insertion.
+type ArrondissementMunicipalWithGeometry = model.EntityWithGeoJSONGeometry[ArrondissementMunicipalEntity]
+
+// ArrondissementMunicipalMapper is responsible for mapping ArrondissementMunicipalProperties to ArrondissementMunicipalEntity.
+type ArrondissementMunicipalMapper struct{}
+
+// NewArrondissementMunicipalMapper creates a new mapper for arrondissement municipal data.
+func NewArrondissementMunicipalMapper() *ArrondissementMunicipalMapper {
+	return &ArrondissementMunicipalMapper{}
+}
+
+var _ model.Mapper[ArrondissementMunicipalProperties, ArrondissementMunicipalEntity] = (*ArrondissementMunicipalMapper)(nil)
+
+// Map converts ArrondissementMunicipalProperties to an ArrondissementMunicipalEntity for database insertion.
+// The code, name and commune are required.
+func (m *ArrondissementMunicipalMapper) Map(input ArrondissementMunicipalProperties) (*ArrondissementMunicipalEntity, error) {
+	if len(input.Code) != 5 {
+		return nil, fmt.Errorf("invalid arrondissement municipal code %q, must be 5 characters", input.Code)
+	}
+	if input.Nom == "" {
+		return nil, fmt.Errorf("missing name of arrondissement municipal %s", input.Code)
+	}
+	if input.Commune == "" {
+		return nil, fmt.Errorf("missing commune of arrondissement municipal %s", input.Code)
+	}
+
+	return &ArrondissementMunicipalEntity{
+		Code:        input.Code,
+		Nom:         input.Nom,
+		CodeCommune: input.Commune,
+	}, nil
+}
+
+// ArrondissementMunicipalCommune returns the code of the commune of an arrondissement municipal: Paris
+// (751xx), Lyon (6938x) or Marseille (132xx). ok is false for other codes.
+func ArrondissementMunicipalCommune(code string) (commune string, ok bool) {
+	switch {
+	case len(code) != 5:
+		return "", false
+	case code[:3] == "751" && code != "75100":
+		return "75056", true
+	case code[:4] == "6938" && code != "69380":
+		return "69123", true
+	case code[:3] == "132" && code != "13200":
+		return "13055", true
+	}
+	return "", false
+}
@@ -0,0 +1,36 @@
+package entities
+
+import "testing"
+
+func TestArrondissementMunicipalMapper_Map(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   ArrondissementMunicipalProperties
+		wantErr bool
+	}{
+		{name: "valid", input: ArrondissementMunicipalProperties{Code: "75101", Nom: "Paris 1er Arrondissement", Commune: "75056"}},
+		{name: "missing code", input: ArrondissementMunicipalProperties{Nom: "Paris 1er Arrondissement", Commune: "75056"}, wantErr: true},
+		{name: "invalid code", input: ArrondissementMunicipalProperties{Code: "7510", Nom: "Paris 1er Arrondissement", Commune: "75056"}, wantErr: true},
+		{name: "missing name", input: ArrondissementMunicipalProperties{Code: "75101", Commune: "75056"}, wantErr: true},
+		{name: "missing commune", input: ArrondissementMunicipalProperties{Code: "75101", Nom: "Paris 1er Arrondissement"}, wantErr: true},
+	}
+
+	mapper := NewArrondissementMunicipalMapper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entity, err := mapper.Map(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %+v", entity)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Map() error = %v", err)
+			}
+			if entity.Code != "75101" || entity.Nom != "Paris 1er Arrondissement" || entity.CodeCommune != "75056" {
+				t.Errorf("Unexpected entity %+v", entity)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package entities
+
+import "testing"
+
+func TestArrondissementMapper_Map(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   ArrondissementProperties
+		wantErr bool
+	}{
+		{name: "valid", input: ArrondissementProperties{Code: "011", Nom: "Belley", Departement: "01", Region: "84"}},
+		{name: "missing code", input: ArrondissementProperties{Nom: "Belley", Departement: "01", Region: "84"}, wantErr: true},
+		{name: "missing name", input: ArrondissementProperties{Code: "011", Departement: "01", Region: "84"}, wantErr: true},
+	}
+
+	mapper := NewArrondissementMapper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entity, err := mapper.Map(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %+v", entity)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Map() error = %v", err)
+			}
+			if entity.Code != "011" || entity.Nom != "Belley" || entity.CodeDepartement != "01" || entity.CodeRegion != "84" {
+				t.Errorf("Unexpected entity %+v", entity)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package entities
+
+import (
+	"fmt"
+	"french-admin-etl/internal/model"
+)
+
+// CantonProperties represents the properties of a canton in the GeoJSON file.
+type CantonProperties struct {
+	Code        string `json:"code"`
+	Nom         string `json:"nom"`
+	Departement string `json:"departement"`
+	Region      string `json:"region"`
+}
+
+// GeoJSONCantonFeature is a type alias for a GeoJSON feature with CantonProperties.
+type GeoJSONCantonFeature = model.GeoJSONFeature[CantonProperties]
+
+// CantonEntity represents the canton entity to be stored in the database.
+type CantonEntity struct {
+	_               struct{} `etl:"table=ref_admin.cantons,geometry=geom,deleted=supprime_le,vintage=millesime"`
+	Code            string   `json:"code_insee_canton" etl:"code_insee_canton,key"`
+	Nom             *string  `json:"nom_canton" etl:"nom_canton"` // IGN contours have no name, see ref_admin.cog_cantons
+	CodeDepartement string   `json:"code_insee_departement" etl:"code_insee_departement,ref=ref_admin.departements(code_insee_departement)"`
+	CodeRegion      string   `json:"code_insee_region" etl:"code_insee_region,ref=ref_admin.regions(code_insee_region)"`
+}
+
+// CantonWithGeometry combines the canton entity with its GeoJSON geometry for database insertion.
+type CantonWithGeometry = model.EntityWithGeoJSONGeometry[CantonEntity]
+
+// CantonMapper is responsible for mapping CantonProperties to CantonEntity.
+type CantonMapper struct{}
+
+// NewCantonMapper creates a new mapper for canton data.
+func NewCantonMapper() *CantonMapper {
+	return &CantonMapper{}
+}
+
+var _ model.Mapper[CantonProperties, CantonEntity] = (*CantonMapper)(nil)
+
+// Map converts CantonProperties to a CantonEntity for database insertion.
+func (m *CantonMapper) Map(input CantonProperties) (*CantonEntity, error) {
+	if input.Code == "" {
+		return nil, fmt.Errorf("missing canton code")
+	}
+
+	entity := &CantonEntity{
+		Code:            input.Code,
+		CodeDepartement: input.Departement,
+		CodeRegion:      input.Region,
+	}
+	if input.Nom != "" {
+		entity.Nom = &input.Nom
+	}
+	return entity, nil
+}
@@ -0,0 +1,42 @@
+package entities
+
+import "testing"
+
+func TestCantonMapper_Map(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   CantonProperties
+		wantNom *string
+		wantErr bool
+	}{
+		{name: "without name", input: CantonProperties{Code: "0101", Departement: "01", Region: "84"}},
+		{name: "with name", input: CantonProperties{Code: "0101", Nom: "Ambérieu-en-Bugey", Departement: "01", Region: "84"}, wantNom: ptr("Ambérieu-en-Bugey")},
+		{name: "missing code", input: CantonProperties{Nom: "Ambérieu-en-Bugey", Departement: "01", Region: "84"}, wantErr: true},
+	}
+
+	mapper := NewCantonMapper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entity, err := mapper.Map(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %+v", entity)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Map() error = %v", err)
+			}
+			if entity.Code != "0101" || entity.CodeDepartement != "01" || entity.CodeRegion != "84" {
+				t.Errorf("Unexpected entity %+v", entity)
+			}
+			if (entity.Nom == nil) != (tt.wantNom == nil) || (entity.Nom != nil && *entity.Nom != *tt.wantNom) {
+				t.Errorf("Expected name %v, got %v", tt.wantNom, entity.Nom)
+			}
+		})
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
@@ -0,0 +1,62 @@
+package entities
+
+import (
+	"fmt"
+	"french-admin-etl/internal/model"
+	"strings"
+)
+
+// CodePostalEntity represents a line of the La Poste base officielle des codes postaux: a postal code
+// serves several communes, a commune may have several postal codes.
+type CodePostalEntity struct {
+	_                   struct{} `etl:"table=ref_admin.codes_postaux,deleted=supprime_le,vintage=millesime"`
+	CodeCommune         string   `json:"code_insee_commune" etl:"code_insee_commune,key"`
+	CodePostal          string   `json:"code_postal" etl:"code_postal,key"`
+	Ligne5              string   `json:"ligne_5" etl:"ligne_5,key,empty"` // lieu-dit or commune déléguée, mostly empty
+	NomCommune          string   `json:"nom_commune" etl:"nom_commune"`
+	LibelleAcheminement string   `json:"libelle_acheminement" etl:"libelle_acheminement"`
+	// CodeArrondissementMunicipal is set for Paris, Lyon and Marseille, listed by arrondissement
+	CodeArrondissementMunicipal *string `json:"code_insee_arm" etl:"code_insee_arm,ref=ref_admin.arrondissements_municipaux(code_insee_arm)"`
+}
+
+// CodePostalMapper maps the rows of the La Poste CSV (base-officielle-codes-postaux, also known as
+// laposte_hexasmal) to CodePostalEntity.
+type CodePostalMapper struct{}
+
+// NewCodePostalMapper creates a new mapper for La Poste postal codes.
+func NewCodePostalMapper() *CodePostalMapper {
+	return &CodePostalMapper{}
+}
+
+var _ model.Mapper[model.CSVRecord, CodePostalEntity] = (*CodePostalMapper)(nil)
+
+// Map converts a CSV record to a CodePostalEntity. The arrondissements municipaux of Paris, Lyon and
+// Marseille are attached to their commune.
+func (m *CodePostalMapper) Map(record model.CSVRecord) (*CodePostalEntity, error) {
+	// The first column is commented out in some editions (#Code_commune_INSEE)
+	codeCommune, ok := record["#Code_commune_INSEE"]
+	if !ok {
+		codeCommune = record["Code_commune_INSEE"]
+	}
+	if len(codeCommune) != 5 {
+		return nil, fmt.Errorf("invalid Code_commune_INSEE %q, must be 5 characters", codeCommune)
+	}
+
+	codePostal := record["Code_postal"]
+	if len(codePostal) != 5 || strings.Trim(codePostal, "0123456789") != "" {
+		return nil, fmt.Errorf("invalid Code_postal %q, must be 5 digits", codePostal)
+	}
+
+	entity := &CodePostalEntity{
+		CodeCommune:         codeCommune,
+		CodePostal:          codePostal,
+		Ligne5:              strings.TrimSpace(record["Ligne_5"]),
+		NomCommune:          record["Nom_de_la_commune"],
+		LibelleAcheminement: record["Libellé_d_acheminement"],
+	}
+	if commune, ok := ArrondissementMunicipalCommune(codeCommune); ok {
+		entity.CodeArrondissementMunicipal = &codeCommune
+		entity.CodeCommune = commune
+	}
+	return entity, nil
+}
@@ -0,0 +1,340 @@
+package entities
+
+import (
+	"fmt"
+	"french-admin-etl/internal/model"
+	"slices"
+	"strconv"
+)
+
+// Entities of the INSEE Code Officiel Géographique (COG), published every year as CSV files
+// (v_region_<année>.csv, v_commune_<année>.csv, ...). Every COG entity carries the naming variants:
+//   - TNCC: type of the name, giving its article and charnière (e.g. 0: no article, "de"; 5: "La", "de La")
+//   - NCC: name in upper case, without article nor accents
+//   - NCCENR: name with its case and accents, without article
+//   - LIBELLE: name with its case, accents and article
+
+// CogCommuneEntity represents a commune of the COG (v_commune), along with the communes associées,
+// communes déléguées and arrondissements municipaux, which share their code with their parent commune.
+type CogCommuneEntity struct {
+	_                  struct{} `etl:"table=ref_admin.cog_communes,deleted=supprime_le,vintage=millesime"`
+	TypeCommune        string   `json:"type_commune" etl:"type_commune,key"`
+	Code               string   `json:"code_insee_commune" etl:"code_insee_commune,key"`
+	CodeRegion         *string  `json:"code_insee_region" etl:"code_insee_region"`
+	CodeDepartement    *string  `json:"code_insee_departement" etl:"code_insee_departement"`
+	CodeCTCD           *string  `json:"code_ctcd" etl:"code_ctcd"`
+	CodeArrondissement *string  `json:"code_insee_arrondissement" etl:"code_insee_arrondissement"`
+	TNCC               int      `json:"tncc" etl:"tncc"`
+	NCC                string   `json:"ncc" etl:"ncc"`
+	NCCENR             string   `json:"nccenr" etl:"nccenr"`
+	Libelle            string   `json:"libelle" etl:"libelle"`
+	CodeCanton         *string  `json:"code_insee_canton" etl:"code_insee_canton"`
+	CodeParent         *string  `json:"code_insee_commune_parente" etl:"code_insee_commune_parente"`
+}
+
+// CogDepartementEntity represents a département of the COG (v_departement).
+type CogDepartementEntity struct {
+	_          struct{} `etl:"table=ref_admin.cog_departements,deleted=supprime_le,vintage=millesime"`
+	Code       string   `json:"code_insee_departement" etl:"code_insee_departement,key"`
+	CodeRegion string   `json:"code_insee_region" etl:"code_insee_region"`
+	ChefLieu   string   `json:"code_insee_cheflieu" etl:"code_insee_cheflieu"`
+	TNCC       int      `json:"tncc" etl:"tncc"`
+	NCC        string   `json:"ncc" etl:"ncc"`
+	NCCENR     string   `json:"nccenr" etl:"nccenr"`
+	Libelle    string   `json:"libelle" etl:"libelle"`
+}
+
+// CogRegionEntity represents a région of the COG (v_region).
+type CogRegionEntity struct {
+	_        struct{} `etl:"table=ref_admin.cog_regions,deleted=supprime_le,vintage=millesime"`
+	Code     string   `json:"code_insee_region" etl:"code_insee_region,key"`
+	ChefLieu string   `json:"code_insee_cheflieu" etl:"code_insee_cheflieu"`
+	TNCC     int      `json:"tncc" etl:"tncc"`
+	NCC      string   `json:"ncc" etl:"ncc"`
+	NCCENR   string   `json:"nccenr" etl:"nccenr"`
+	Libelle  string   `json:"libelle" etl:"libelle"`
+}
+
+// CogArrondissementEntity represents an arrondissement (départemental) of the COG (v_arrondissement).
+type CogArrondissementEntity struct {
+	_               struct{} `etl:"table=ref_admin.cog_arrondissements,deleted=supprime_le,vintage=millesime"`
+	Code            string   `json:"code_insee_arrondissement" etl:"code_insee_arrondissement,key"`
+	CodeDepartement string   `json:"code_insee_departement" etl:"code_insee_departement"`
+	CodeRegion      string   `json:"code_insee_region" etl:"code_insee_region"`
+	ChefLieu        string   `json:"code_insee_cheflieu" etl:"code_insee_cheflieu"`
+	TNCC            int      `json:"tncc" etl:"tncc"`
+	NCC             string   `json:"ncc" etl:"ncc"`
+	NCCENR          string   `json:"nccenr" etl:"nccenr"`
+	Libelle         string   `json:"libelle" etl:"libelle"`
+}
+
+// CogCantonEntity represents a canton or pseudo-canton of the COG (v_canton).
+type CogCantonEntity struct {
+	_               struct{} `etl:"table=ref_admin.cog_cantons,deleted=supprime_le,vintage=millesime"`
+	Code            string   `json:"code_insee_canton" etl:"code_insee_canton,key"`
+	CodeDepartement string   `json:"code_insee_departement" etl:"code_insee_departement"`
+	CodeRegion      string   `json:"code_insee_region" etl:"code_insee_region"`
+	Composition     *string  `json:"composition_cantonale" etl:"composition_cantonale"`
+	BureauCentral   *string  `json:"code_insee_bureau_centralisateur" etl:"code_insee_bureau_centralisateur"`
+	TNCC            int      `json:"tncc" etl:"tncc"`
+	NCC             string   `json:"ncc" etl:"ncc"`
+	NCCENR          string   `json:"nccenr" etl:"nccenr"`
+	Libelle         string   `json:"libelle" etl:"libelle"`
+	TypeCanton      string   `json:"type_canton" etl:"type_canton"`
+}
+
+// CogComerEntity represents a commune or zoning of a collectivité d'outre-mer of the COG (v_comer).
+type CogComerEntity struct {
+	_            struct{} `etl:"table=ref_admin.cog_comer,deleted=supprime_le,vintage=millesime"`
+	Code         string   `json:"code_comer" etl:"code_comer,key"`
+	TNCC         int      `json:"tncc" etl:"tncc"`
+	NCC          string   `json:"ncc" etl:"ncc"`
+	NCCENR       string   `json:"nccenr" etl:"nccenr"`
+	Libelle      string   `json:"libelle" etl:"libelle"`
+	NatureZonage string   `json:"nature_zonage" etl:"nature_zonage"`
+	CodeCOM      string   `json:"code_collectivite" etl:"code_collectivite"`
+}
+
+// CogCommuneTypes lists the TYPECOM values of v_commune: commune, commune associée, commune déléguée
+// and arrondissement municipal.
+var CogCommuneTypes = []string{"COM", "COMA", "COMD", "ARM"}
+
+// cogNaming holds the naming variants shared by every COG file.
+type cogNaming struct {
+	tncc    int
+	ncc     string
+	nccenr  string
+	libelle string
+}
+
+// parseCogNaming reads and validates the TNCC, NCC, NCCENR and LIBELLE columns of a COG record.
+func parseCogNaming(record model.CSVRecord) (cogNaming, error) {
+	tncc, err := strconv.Atoi(record["TNCC"])
+	if err != nil || tncc < 0 || tncc > 8 {
+		return cogNaming{}, fmt.Errorf("invalid TNCC: %q, must be 0 to 8", record["TNCC"])
+	}
+	naming := cogNaming{tncc: tncc, ncc: record["NCC"], nccenr: record["NCCENR"], libelle: record["LIBELLE"]}
+	if naming.ncc == "" || naming.nccenr == "" || naming.libelle == "" {
+		return cogNaming{}, fmt.Errorf("missing NCC, NCCENR or LIBELLE")
+	}
+	return naming, nil
+}
+
+// requireCogCode returns the value of a code column, which must be set.
+func requireCogCode(record model.CSVRecord, column string) (string, error) {
+	code := record[column]
+	if code == "" {
+		return "", fmt.Errorf("missing %s code", column)
+	}
+	return code, nil
+}
+
+// optionalCogCode returns the value of a code column, nil when empty (e.g. the canton of a commune déléguée).
+func optionalCogCode(record model.CSVRecord, column string) *string {
+	if code := record[column]; code != "" {
+		return &code
+	}
+	return nil
+}
+
+// CogCommuneMapper maps v_commune records to CogCommuneEntity.
+type CogCommuneMapper struct{}
+
+// NewCogCommuneMapper creates a new mapper for COG communes.
+func NewCogCommuneMapper() *CogCommuneMapper {
+	return &CogCommuneMapper{}
+}
+
+var _ model.Mapper[model.CSVRecord, CogCommuneEntity] = (*CogCommuneMapper)(nil)
+
+// Map converts a v_commune record to a CogCommuneEntity.
+func (m *CogCommuneMapper) Map(record model.CSVRecord) (*CogCommuneEntity, error) {
+	typeCommune := record["TYPECOM"]
+	if !slices.Contains(CogCommuneTypes, typeCommune) {
+		return nil, fmt.Errorf("invalid TYPECOM: %q, must be one of COM, COMA, COMD, ARM", typeCommune)
+	}
+	code := record["COM"]
+	if len(code) != 5 {
+		return nil, fmt.Errorf("invalid COM code: %q, must be 5 characters", code)
+	}
+	naming, err := parseCogNaming(record)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CogCommuneEntity{
+		TypeCommune:        typeCommune,
+		Code:               code,
+		CodeRegion:         optionalCogCode(record, "REG"),
+		CodeDepartement:    optionalCogCode(record, "DEP"),
+		CodeCTCD:           optionalCogCode(record, "CTCD"),
+		CodeArrondissement: optionalCogCode(record, "ARR"),
+		TNCC:               naming.tncc,
+		NCC:                naming.ncc,
+		NCCENR:             naming.nccenr,
+		Libelle:            naming.libelle,
+		CodeCanton:         optionalCogCode(record, "CAN"),
+		CodeParent:         optionalCogCode(record, "COMPARENT"),
+	}, nil
+}
+
+// CogDepartementMapper maps v_departement records to CogDepartementEntity.
+type CogDepartementMapper struct{}
+
+// NewCogDepartementMapper creates a new mapper for COG départements.
+func NewCogDepartementMapper() *CogDepartementMapper {
+	return &CogDepartementMapper{}
+}
+
+var _ model.Mapper[model.CSVRecord, CogDepartementEntity] = (*CogDepartementMapper)(nil)
+
+// Map converts a v_departement record to a CogDepartementEntity.
+func (m *CogDepartementMapper) Map(record model.CSVRecord) (*CogDepartementEntity, error) {
+	entity := &CogDepartementEntity{}
+	var err error
+	if entity.Code, err = requireCogCode(record, "DEP"); err != nil {
+		return nil, err
+	}
+	if entity.CodeRegion, err = requireCogCode(record, "REG"); err != nil {
+		return nil, err
+	}
+	if entity.ChefLieu, err = requireCogCode(record, "CHEFLIEU"); err != nil {
+		return nil, err
+	}
+	naming, err := parseCogNaming(record)
+	if err != nil {
+		return nil, err
+	}
+	entity.TNCC, entity.NCC, entity.NCCENR, entity.Libelle = naming.tncc, naming.ncc, naming.nccenr, naming.libelle
+	return entity, nil
+}
+
+// CogRegionMapper maps v_region records to CogRegionEntity.
+type CogRegionMapper struct{}
+
+// NewCogRegionMapper creates a new mapper for COG régions.
+func NewCogRegionMapper() *CogRegionMapper {
+	return &CogRegionMapper{}
+}
+
+var _ model.Mapper[model.CSVRecord, CogRegionEntity] = (*CogRegionMapper)(nil)
+
+// Map converts a v_region record to a CogRegionEntity.
+func (m *CogRegionMapper) Map(record model.CSVRecord) (*CogRegionEntity, error) {
+	entity := &CogRegionEntity{}
+	var err error
+	if entity.Code, err = requireCogCode(record, "REG"); err != nil {
+		return nil, err
+	}
+	if entity.ChefLieu, err = requireCogCode(record, "CHEFLIEU"); err != nil {
+		return nil, err
+	}
+	naming, err := parseCogNaming(record)
+	if err != nil {
+		return nil, err
+	}
+	entity.TNCC, entity.NCC, entity.NCCENR, entity.Libelle = naming.tncc, naming.ncc, naming.nccenr, naming.libelle
+	return entity, nil
+}
+
+// CogArrondissementMapper maps v_arrondissement records to CogArrondissementEntity.
+type CogArrondissementMapper struct{}
+
+// NewCogArrondissementMapper creates a new mapper for COG arrondissements.
+func NewCogArrondissementMapper() *CogArrondissementMapper {
+	return &CogArrondissementMapper{}
+}
+
+var _ model.Mapper[model.CSVRecord, CogArrondissementEntity] = (*CogArrondissementMapper)(nil)
+
+// Map converts a v_arrondissement record to a CogArrondissementEntity.
+func (m *CogArrondissementMapper) Map(record model.CSVRecord) (*CogArrondissementEntity, error) {
+	entity := &CogArrondissementEntity{}
+	var err error
+	if entity.Code, err = requireCogCode(record, "ARR"); err != nil {
+		return nil, err
+	}
+	if entity.CodeDepartement, err = requireCogCode(record, "DEP"); err != nil {
+		return nil, err
+	}
+	if entity.CodeRegion, err = requireCogCode(record, "REG"); err != nil {
+		return nil, err
+	}
+	if entity.ChefLieu, err = requireCogCode(record, "CHEFLIEU"); err != nil {
+		return nil, err
+	}
+	naming, err := parseCogNaming(record)
+	if err != nil {
+		return nil, err
+	}
+	entity.TNCC, entity.NCC, entity.NCCENR, entity.Libelle = naming.tncc, naming.ncc, naming.nccenr, naming.libelle
+	return entity, nil
+}
+
+// CogCantonMapper maps v_canton records to CogCantonEntity.
+type CogCantonMapper struct{}
+
+// NewCogCantonMapper creates a new mapper for COG cantons.
+func NewCogCantonMapper() *CogCantonMapper {
+	return &CogCantonMapper{}
+}
+
+var _ model.Mapper[model.CSVRecord, CogCantonEntity] = (*CogCantonMapper)(nil)
+
+// Map converts a v_canton record to a CogCantonEntity.
+func (m *CogCantonMapper) Map(record model.CSVRecord) (*CogCantonEntity, error) {
+	entity := &CogCantonEntity{
+		Composition:   optionalCogCode(record, "COMPCT"),
+		BureauCentral: optionalCogCode(record, "BURCENTRAL"),
+	}
+	var err error
+	if entity.Code, err = requireCogCode(record, "CAN"); err != nil {
+		return nil, err
+	}
+	if entity.CodeDepartement, err = requireCogCode(record, "DEP"); err != nil {
+		return nil, err
+	}
+	if entity.CodeRegion, err = requireCogCode(record, "REG"); err != nil {
+		return nil, err
+	}
+	if entity.TypeCanton, err = requireCogCode(record, "TYPECT"); err != nil {
+		return nil, err
+	}
+	naming, err := parseCogNaming(record)
+	if err != nil {
+		return nil, err
+	}
+	entity.TNCC, entity.NCC, entity.NCCENR, entity.Libelle = naming.tncc, naming.ncc, naming.nccenr, naming.libelle
+	return entity, nil
+}
+
+// CogComerMapper maps v_comer records to CogComerEntity.
+type CogComerMapper struct{}
+
+// NewCogComerMapper creates a new mapper for COG communes of the collectivités d'outre-mer.
+func NewCogComerMapper() *CogComerMapper {
+	return &CogComerMapper{}
+}
+
+var _ model.Mapper[model.CSVRecord, CogComerEntity] = (*CogComerMapper)(nil)
+
+// Map converts a v_comer record to a CogComerEntity.
+func (m *CogComerMapper) Map(record model.CSVRecord) (*CogComerEntity, error) {
+	entity := &CogComerEntity{}
+	var err error
+	if entity.Code, err = requireCogCode(record, "COM_COMER"); err != nil {
+		return nil, err
+	}
+	if entity.NatureZonage, err = requireCogCode(record, "NATURE_ZONAGE"); err != nil {
+		return nil, err
+	}
+	if entity.CodeCOM, err = requireCogCode(record, "COMER"); err != nil {
+		return nil, err
+	}
+	naming, err := parseCogNaming(record)
+	if err != nil {
+		return nil, err
+	}
+	entity.TNCC, entity.NCC, entity.NCCENR, entity.Libelle = naming.tncc, naming.ncc, naming.nccenr, naming.libelle
+	return entity, nil
+}
@@ -0,0 +1,312 @@
+package entities
+
+import (
+	"french-admin-etl/internal/model"
+	"reflect"
+	"testing"
+)
+
+// cogRecord returns a COG record with the naming columns of Ain, overridden by the given columns
+func cogRecord(columns model.CSVRecord) model.CSVRecord {
+	record := model.CSVRecord{"TNCC": "5", "NCC": "AIN", "NCCENR": "Ain", "LIBELLE": "Ain"}
+	for column, value := range columns {
+		record[column] = value
+	}
+	return record
+}
+
+func TestParseCogNaming(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   model.CSVRecord
+		want    cogNaming
+		wantErr bool
+	}{
+		{name: "valid", input: cogRecord(nil), want: cogNaming{tncc: 5, ncc: "AIN", nccenr: "Ain", libelle: "Ain"}},
+		{name: "lowest TNCC", input: cogRecord(model.CSVRecord{"TNCC": "0"}), want: cogNaming{tncc: 0, ncc: "AIN", nccenr: "Ain", libelle: "Ain"}},
+		{name: "highest TNCC", input: cogRecord(model.CSVRecord{"TNCC": "8"}), want: cogNaming{tncc: 8, ncc: "AIN", nccenr: "Ain", libelle: "Ain"}},
+		{name: "TNCC out of range", input: cogRecord(model.CSVRecord{"TNCC": "9"}), wantErr: true},
+		{name: "negative TNCC", input: cogRecord(model.CSVRecord{"TNCC": "-1"}), wantErr: true},
+		{name: "non numeric TNCC", input: cogRecord(model.CSVRecord{"TNCC": "A"}), wantErr: true},
+		{name: "empty TNCC", input: cogRecord(model.CSVRecord{"TNCC": ""}), wantErr: true},
+		{name: "empty NCC", input: cogRecord(model.CSVRecord{"NCC": ""}), wantErr: true},
+		{name: "empty NCCENR", input: cogRecord(model.CSVRecord{"NCCENR": ""}), wantErr: true},
+		{name: "empty LIBELLE", input: cogRecord(model.CSVRecord{"LIBELLE": ""}), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			naming, err := parseCogNaming(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %+v", naming)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseCogNaming() error = %v", err)
+			}
+			if naming != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, naming)
+			}
+		})
+	}
+}
+
+func TestCogCommuneMapper_Map(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   model.CSVRecord
+		want    *CogCommuneEntity
+		wantErr bool
+	}{
+		{
+			name: "commune",
+			input: cogRecord(model.CSVRecord{"TYPECOM": "COM", "COM": "01001", "REG": "84", "DEP": "01", "CTCD": "01D", "ARR": "012", "CAN": "0108",
+				"TNCC": "5", "NCC": "ABERGEMENT CLEMENCIAT", "NCCENR": "Abergement-Clémenciat", "LIBELLE": "L'Abergement-Clémenciat"}),
+			want: &CogCommuneEntity{TypeCommune: "COM", Code: "01001", CodeRegion: ptr("84"), CodeDepartement: ptr("01"), CodeCTCD: ptr("01D"),
+				CodeArrondissement: ptr("012"), TNCC: 5, NCC: "ABERGEMENT CLEMENCIAT", NCCENR: "Abergement-Clémenciat", Libelle: "L'Abergement-Clémenciat",
+				CodeCanton: ptr("0108")},
+		},
+		{
+			name: "commune déléguée without parents but its commune",
+			input: cogRecord(model.CSVRecord{"TYPECOM": "COMD", "COM": "01015", "REG": "", "DEP": "", "CTCD": "", "ARR": "", "CAN": "", "COMPARENT": "01015",
+				"TNCC": "1", "NCC": "ARBIGNIEU", "NCCENR": "Arbignieu", "LIBELLE": "Arbignieu"}),
+			want: &CogCommuneEntity{TypeCommune: "COMD", Code: "01015", TNCC: 1, NCC: "ARBIGNIEU", NCCENR: "Arbignieu", Libelle: "Arbignieu", CodeParent: ptr("01015")},
+		},
+		{
+			name:  "arrondissement municipal",
+			input: cogRecord(model.CSVRecord{"TYPECOM": "ARM", "COM": "75101", "COMPARENT": "75056", "TNCC": "0", "NCC": "PARIS 1ER ARRONDISSEMENT", "NCCENR": "Paris 1er Arrondissement", "LIBELLE": "Paris 1er Arrondissement"}),
+			want: &CogCommuneEntity{TypeCommune: "ARM", Code: "75101", TNCC: 0, NCC: "PARIS 1ER ARRONDISSEMENT", NCCENR: "Paris 1er Arrondissement",
+				Libelle: "Paris 1er Arrondissement", CodeParent: ptr("75056")},
+		},
+		{name: "unknown type", input: cogRecord(model.CSVRecord{"TYPECOM": "COMX", "COM": "01001"}), wantErr: true},
+		{name: "empty type", input: cogRecord(model.CSVRecord{"COM": "01001"}), wantErr: true},
+		{name: "short code", input: cogRecord(model.CSVRecord{"TYPECOM": "COM", "COM": "1001"}), wantErr: true},
+		{name: "empty code", input: cogRecord(model.CSVRecord{"TYPECOM": "COM"}), wantErr: true},
+		{name: "invalid naming", input: cogRecord(model.CSVRecord{"TYPECOM": "COM", "COM": "01001", "TNCC": "9"}), wantErr: true},
+	}
+
+	mapper := NewCogCommuneMapper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entity, err := mapper.Map(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %+v", entity)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Map() error = %v", err)
+			}
+			if !reflect.DeepEqual(entity, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, entity)
+			}
+		})
+	}
+}
+
+func TestCogDepartementMapper_Map(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   model.CSVRecord
+		want    *CogDepartementEntity
+		wantErr bool
+	}{
+		{
+			name:  "département",
+			input: cogRecord(model.CSVRecord{"DEP": "01", "REG": "84", "CHEFLIEU": "01053"}),
+			want:  &CogDepartementEntity{Code: "01", CodeRegion: "84", ChefLieu: "01053", TNCC: 5, NCC: "AIN", NCCENR: "Ain", Libelle: "Ain"},
+		},
+		{name: "empty code", input: cogRecord(model.CSVRecord{"DEP": "", "REG": "84", "CHEFLIEU": "01053"}), wantErr: true},
+		{name: "empty région", input: cogRecord(model.CSVRecord{"DEP": "01", "REG": "", "CHEFLIEU": "01053"}), wantErr: true},
+		{name: "empty chef-lieu", input: cogRecord(model.CSVRecord{"DEP": "01", "REG": "84", "CHEFLIEU": ""}), wantErr: true},
+		{name: "invalid naming", input: cogRecord(model.CSVRecord{"DEP": "01", "REG": "84", "CHEFLIEU": "01053", "LIBELLE": ""}), wantErr: true},
+	}
+
+	mapper := NewCogDepartementMapper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entity, err := mapper.Map(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %+v", entity)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Map() error = %v", err)
+			}
+			if !reflect.DeepEqual(entity, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, entity)
+			}
+		})
+	}
+}
+
+func TestCogRegionMapper_Map(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   model.CSVRecord
+		want    *CogRegionEntity
+		wantErr bool
+	}{
+		{
+			name: "région",
+			input: cogRecord(model.CSVRecord{"REG": "84", "CHEFLIEU": "69123",
+				"TNCC": "0", "NCC": "AUVERGNE RHONE ALPES", "NCCENR": "Auvergne-Rhône-Alpes", "LIBELLE": "Auvergne-Rhône-Alpes"}),
+			want: &CogRegionEntity{Code: "84", ChefLieu: "69123", TNCC: 0, NCC: "AUVERGNE RHONE ALPES", NCCENR: "Auvergne-Rhône-Alpes", Libelle: "Auvergne-Rhône-Alpes"},
+		},
+		{name: "empty code", input: cogRecord(model.CSVRecord{"REG": "", "CHEFLIEU": "69123"}), wantErr: true},
+		{name: "empty chef-lieu", input: cogRecord(model.CSVRecord{"REG": "84", "CHEFLIEU": ""}), wantErr: true},
+		{name: "invalid naming", input: cogRecord(model.CSVRecord{"REG": "84", "CHEFLIEU": "69123", "NCC": ""}), wantErr: true},
+	}
+
+	mapper := NewCogRegionMapper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entity, err := mapper.Map(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %+v", entity)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Map() error = %v", err)
+			}
+			if !reflect.DeepEqual(entity, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, entity)
+			}
+		})
+	}
+}
+
+func TestCogArrondissementMapper_Map(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   model.CSVRecord
+		want    *CogArrondissementEntity
+		wantErr bool
+	}{
+		{
+			name: "arrondissement",
+			input: cogRecord(model.CSVRecord{"ARR": "011", "DEP": "01", "REG": "84", "CHEFLIEU": "01053",
+				"TNCC": "2", "NCC": "BOURG EN BRESSE", "NCCENR": "Bourg-en-Bresse", "LIBELLE": "Bourg-en-Bresse"}),
+			want: &CogArrondissementEntity{Code: "011", CodeDepartement: "01", CodeRegion: "84", ChefLieu: "01053",
+				TNCC: 2, NCC: "BOURG EN BRESSE", NCCENR: "Bourg-en-Bresse", Libelle: "Bourg-en-Bresse"},
+		},
+		{name: "empty code", input: cogRecord(model.CSVRecord{"DEP": "01", "REG": "84", "CHEFLIEU": "01053"}), wantErr: true},
+		{name: "empty département", input: cogRecord(model.CSVRecord{"ARR": "011", "REG": "84", "CHEFLIEU": "01053"}), wantErr: true},
+		{name: "empty région", input: cogRecord(model.CSVRecord{"ARR": "011", "DEP": "01", "CHEFLIEU": "01053"}), wantErr: true},
+		{name: "empty chef-lieu", input: cogRecord(model.CSVRecord{"ARR": "011", "DEP": "01", "REG": "84"}), wantErr: true},
+		{name: "invalid naming", input: cogRecord(model.CSVRecord{"ARR": "011", "DEP": "01", "REG": "84", "CHEFLIEU": "01053", "NCCENR": ""}), wantErr: true},
+	}
+
+	mapper := NewCogArrondissementMapper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entity, err := mapper.Map(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %+v", entity)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Map() error = %v", err)
+			}
+			if !reflect.DeepEqual(entity, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, entity)
+			}
+		})
+	}
+}
+
+func TestCogCantonMapper_Map(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   model.CSVRecord
+		want    *CogCantonEntity
+		wantErr bool
+	}{
+		{
+			name: "canton",
+			input: cogRecord(model.CSVRecord{"CAN": "0101", "DEP": "01", "REG": "84", "COMPCT": "1", "BURCENTRAL": "01004", "TYPECT": "C",
+				"TNCC": "1", "NCC": "AMBERIEU EN BUGEY", "NCCENR": "Ambérieu-en-Bugey", "LIBELLE": "Ambérieu-en-Bugey"}),
+			want: &CogCantonEntity{Code: "0101", CodeDepartement: "01", CodeRegion: "84", Composition: ptr("1"), BureauCentral: ptr("01004"),
+				TNCC: 1, NCC: "AMBERIEU EN BUGEY", NCCENR: "Ambérieu-en-Bugey", Libelle: "Ambérieu-en-Bugey", TypeCanton: "C"},
+		},
+		{
+			name: "pseudo-canton without composition nor bureau centralisateur",
+			input: cogRecord(model.CSVRecord{"CAN": "0199", "DEP": "01", "REG": "84", "COMPCT": "", "BURCENTRAL": "", "TYPECT": "N",
+				"TNCC": "0", "NCC": "AIN", "NCCENR": "Ain", "LIBELLE": "Ain"}),
+			want: &CogCantonEntity{Code: "0199", CodeDepartement: "01", CodeRegion: "84", TNCC: 0, NCC: "AIN", NCCENR: "Ain", Libelle: "Ain", TypeCanton: "N"},
+		},
+		{name: "empty code", input: cogRecord(model.CSVRecord{"DEP": "01", "REG": "84", "TYPECT": "C"}), wantErr: true},
+		{name: "empty département", input: cogRecord(model.CSVRecord{"CAN": "0101", "REG": "84", "TYPECT": "C"}), wantErr: true},
+		{name: "empty région", input: cogRecord(model.CSVRecord{"CAN": "0101", "DEP": "01", "TYPECT": "C"}), wantErr: true},
+		{name: "empty type", input: cogRecord(model.CSVRecord{"CAN": "0101", "DEP": "01", "REG": "84"}), wantErr: true},
+		{name: "invalid naming", input: cogRecord(model.CSVRecord{"CAN": "0101", "DEP": "01", "REG": "84", "TYPECT": "C", "TNCC": "x"}), wantErr: true},
+	}
+
+	mapper := NewCogCantonMapper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entity, err := mapper.Map(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %+v", entity)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Map() error = %v", err)
+			}
+			if !reflect.DeepEqual(entity, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, entity)
+			}
+		})
+	}
+}
+
+func TestCogComerMapper_Map(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   model.CSVRecord
+		want    *CogComerEntity
+		wantErr bool
+	}{
+		{
+			name: "commune of a collectivité d'outre-mer",
+			input: cogRecord(model.CSVRecord{"COM_COMER": "97501", "NATURE_ZONAGE": "COM", "COMER": "975",
+				"TNCC": "0", "NCC": "MIQUELON LANGLADE", "NCCENR": "Miquelon-Langlade", "LIBELLE": "Miquelon-Langlade"}),
+			want: &CogComerEntity{Code: "97501", TNCC: 0, NCC: "MIQUELON LANGLADE", NCCENR: "Miquelon-Langlade", Libelle: "Miquelon-Langlade",
+				NatureZonage: "COM", CodeCOM: "975"},
+		},
+		{name: "empty code", input: cogRecord(model.CSVRecord{"NATURE_ZONAGE": "COM", "COMER": "975"}), wantErr: true},
+		{name: "empty nature", input: cogRecord(model.CSVRecord{"COM_COMER": "97501", "COMER": "975"}), wantErr: true},
+		{name: "empty collectivité", input: cogRecord(model.CSVRecord{"COM_COMER": "97501", "NATURE_ZONAGE": "COM"}), wantErr: true},
+		{name: "invalid naming", input: cogRecord(model.CSVRecord{"COM_COMER": "97501", "NATURE_ZONAGE": "COM", "COMER": "975", "LIBELLE": ""}), wantErr: true},
+	}
+
+	mapper := NewCogComerMapper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entity, err := mapper.Map(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %+v", entity)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Map() error = %v", err)
+			}
+			if !reflect.DeepEqual(entity, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, entity)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package entities
+
+import (
+	"fmt"
+	"french-admin-etl/internal/model"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// communeEventTypes lists the event types (MOD) of the COG "mouvements des communes" file (v_mvt_commune):
+// 10 changement de nom, 20 création, 21 rétablissement, 30 suppression, 31 fusion simple,
+// 32 création de commune nouvelle, 33 fusion association, 34 transformation de fusion association en fusion simple,
+// 35 suppression de commune déléguée, 41 changement de code dû à un changement de département,
+// 50 changement de code dû à un transfert de chef-lieu, 70 transformation de commune associée en commune déléguée,
+// 71 rétablissement de commune déléguée.
+var communeEventTypes = []int{10, 20, 21, 30, 31, 32, 33, 34, 35, 41, 50, 70, 71}
+
+// CommuneEventEntity represents an event of the COG "mouvements des communes" file: one row per commune
+// before and after the event (e.g. a commune nouvelle has one row per merged commune).
+type CommuneEventEntity struct {
+	_            struct{}  `etl:"table=ref_admin.commune_events"`
+	Mod          int       `json:"type_evenement" etl:"type_evenement,key"`
+	DateEffet    time.Time `json:"date_effet" etl:"date_effet,key"`
+	TypeAvant    string    `json:"type_commune_avant" etl:"type_commune_avant,key"`
+	CodeAvant    string    `json:"code_insee_commune_avant" etl:"code_insee_commune_avant,key"`
+	TNCCAvant    int       `json:"tncc_avant" etl:"tncc_avant"`
+	NCCAvant     string    `json:"ncc_avant" etl:"ncc_avant"`
+	NCCENRAvant  string    `json:"nccenr_avant" etl:"nccenr_avant"`
+	LibelleAvant string    `json:"libelle_avant" etl:"libelle_avant"`
+	TypeApres    string    `json:"type_commune_apres" etl:"type_commune_apres,key"`
+	CodeApres    string    `json:"code_insee_commune_apres" etl:"code_insee_commune_apres,key"`
+	TNCCApres    int       `json:"tncc_apres" etl:"tncc_apres"`
+	NCCApres     string    `json:"ncc_apres" etl:"ncc_apres"`
+	NCCENRApres  string    `json:"nccenr_apres" etl:"nccenr_apres"`
+	LibelleApres string    `json:"libelle_apres" etl:"libelle_apres"`
+}
+
+// CommuneEventMapper maps v_mvt_commune records to CommuneEventEntity.
+type CommuneEventMapper struct{}
+
+// NewCommuneEventMapper creates a new mapper for the commune events.
+func NewCommuneEventMapper() *CommuneEventMapper {
+	return &CommuneEventMapper{}
+}
+
+var _ model.Mapper[model.CSVRecord, CommuneEventEntity] = (*CommuneEventMapper)(nil)
+
+// Map converts a v_mvt_commune record to a CommuneEventEntity.
+func (m *CommuneEventMapper) Map(record model.CSVRecord) (*CommuneEventEntity, error) {
+	mod, err := strconv.Atoi(record["MOD"])
+	if err != nil || !slices.Contains(communeEventTypes, mod) {
+		return nil, fmt.Errorf("invalid MOD: %q", record["MOD"])
+	}
+	date, err := time.Parse(time.DateOnly, record["DATE_EFF"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid DATE_EFF: %s, %w", record["DATE_EFF"], err)
+	}
+
+	before, err := parseCommuneEventSide(record, "AV")
+	if err != nil {
+		return nil, err
+	}
+	after, err := parseCommuneEventSide(record, "AP")
+	if err != nil {
+		return nil, err
+	}
+
+	return &CommuneEventEntity{
+		Mod:          mod,
+		DateEffet:    date,
+		TypeAvant:    before.typeCommune,
+		CodeAvant:    before.code,
+		TNCCAvant:    before.tncc,
+		NCCAvant:     before.ncc,
+		NCCENRAvant:  before.nccenr,
+		LibelleAvant: before.libelle,
+		TypeApres:    after.typeCommune,
+		CodeApres:    after.code,
+		TNCCApres:    after.tncc,
+		NCCApres:     after.ncc,
+		NCCENRApres:  after.nccenr,
+		LibelleApres: after.libelle,
+	}, nil
+}
+
+// communeEventSide is the commune before or after an event.
+type communeEventSide struct {
+	cogNaming
+	typeCommune string
+	code        string
+}
+
+// parseCommuneEventSide reads the columns of the commune before (suffix AV) or after (suffix AP) an event.
+func parseCommuneEventSide(record model.CSVRecord, suffix string) (communeEventSide, error) {
+	side := communeEventSide{
+		typeCommune: record["TYPECOM_"+suffix],
+		code:        record["COM_"+suffix],
+	}
+	if !slices.Contains(CogCommuneTypes, side.typeCommune) {
+		return side, fmt.Errorf("invalid TYPECOM_%s: %q, must be one of COM, COMA, COMD, ARM", suffix, side.typeCommune)
+	}
+	if len(side.code) != 5 {
+		return side, fmt.Errorf("invalid COM_%s code: %q, must be 5 characters", suffix, side.code)
+	}
+
+	naming, err := parseCogNaming(model.CSVRecord{
+		"TNCC":    record["TNCC_"+suffix],
+		"NCC":     record["NCC_"+suffix],
+		"NCCENR":  record["NCCENR_"+suffix],
+		"LIBELLE": record["LIBELLE_"+suffix],
+	})
+	if err != nil {
+		return side, fmt.Errorf("commune %s: %w", suffix, err)
+	}
+	side.cogNaming = naming
+	return side, nil
+}
@@ -5,40 +5,75 @@ import (
 	"fmt"
 	filters "french-admin-etl/internal/Filters"
 	"french-admin-etl/internal/model"
-	"math"
+	"math/big"
 	"strconv"
 	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// GEO_OBJECT values of the population records kept by CommunePopulationPrincFilter.
+const (
+	GeoObjectCommune                 = "COM"
+	GeoObjectArrondissementMunicipal = "ARM" // Paris, Lyon and Marseille
 )
 
 // CommunePopulationPrincEntity represents commune population data by age and gender.
 type CommunePopulationPrincEntity struct {
-	Age         string // _T, Y15T24, Y20T64, Y25T39, Y40T54, Y55T64, Y65T79, Y_GE65, Y_GE80, Y_LT15, Y_LT20
-	CodeCommune string // 5-character code for communes
+	Age         string // _T, Y15T24, Y20T64, Y25T39, Y40T54, Y55T64, Y65T79, Y_GE65, Y_GE80, Y_LT15, Y_LT20 in DS_RP_POPULATION_PRINC
+	CodeCommune string // 5-character code for communes, or arrondissements municipaux when GeoObject is ARM
+	GeoObject   string // COM or ARM
 	Sexe        string // _T for total, M for men, F for women
 	Annee       int
-	Population  int
+	Population  pgtype.Numeric // weighted estimate, rounded to the nearest integer unless the mapper keeps decimals
+	// CodeCommuneOrigine is the code of the source when CodeCommune was recoded to a later geography
+	// (e.g. a commune merged into a commune nouvelle), empty otherwise.
+	CodeCommuneOrigine string
+}
+
+// CommuneCode returns the code of the commune the figures refer to.
+func (e *CommunePopulationPrincEntity) CommuneCode() string {
+	return e.CodeCommune
+}
+
+// Recode replaces the code of the commune, keeping the code of the source in CodeCommuneOrigine.
+func (e *CommunePopulationPrincEntity) Recode(code string) {
+	if e.CodeCommuneOrigine == "" {
+		e.CodeCommuneOrigine = e.CodeCommune
+	}
+	e.CodeCommune = code
 }
 
 // CommunePopulationPrincFilter is a predefined filter that keeps only commune and arrondissement records.
 var CommunePopulationPrincFilter = filters.NewCsvRecordFilterFromAllowList(map[string][]string{
-	"GEO_OBJECT": {"COM", "ARM"},
+	"GEO_OBJECT": {GeoObjectCommune, GeoObjectArrondissementMunicipal},
 })
 
 // CommunePopulationMapper maps CSV records to CommunePopulationPrincEntity.
-type CommunePopulationMapper struct{}
+type CommunePopulationMapper struct {
+	decimals bool // keep the decimals of OBS_VALUE instead of rounding it
+}
 
-// NewCommunePopulationMapper creates a new mapper for commune population data.
+// NewCommunePopulationMapper creates a new mapper for commune population data, rounding the figures to
+// the nearest integer.
 func NewCommunePopulationMapper() *CommunePopulationMapper {
 	return &CommunePopulationMapper{}
 }
 
+// NewCommunePopulationMapperWithDecimals creates a new mapper for commune population data, keeping the
+// decimals of the weighted estimates, so that the age groups add up to the totals.
+func NewCommunePopulationMapperWithDecimals() *CommunePopulationMapper {
+	return &CommunePopulationMapper{decimals: true}
+}
+
 var _ model.Mapper[model.CSVRecord, CommunePopulationPrincEntity] = (*CommunePopulationMapper)(nil)
 
 // Map converts a CSV record to a CommunePopulationPrincEntity, with validation and error handling.
 func (m *CommunePopulationMapper) Map(record model.CSVRecord) (*CommunePopulationPrincEntity, error) {
+	// Age groups and sexes are checked by the pivot of the repository, which reports the unknown ones
 	age := record["AGE"]
-	if age != "_T" && age != "Y15T24" && age != "Y20T64" && age != "Y25T39" && age != "Y40T54" && age != "Y55T64" && age != "Y65T79" && age != "Y_GE65" && age != "Y_GE80" && age != "Y_LT15" && age != "Y_LT20" {
-		return nil, fmt.Errorf("invalid AGE, must be one of _T, Y15T24, Y20T64, Y25T39, Y40T54, Y55T64, Y65T79, Y_GE65, Y_GE80, Y_LT15, Y_LT20")
+	if age == "" {
+		return nil, fmt.Errorf("missing AGE")
 	}
 
 	codeCommune := record["GEO"]
@@ -46,16 +81,18 @@ func (m *CommunePopulationMapper) Map(record model.CSVRecord) (*CommunePopulatio
 		return nil, fmt.Errorf("invalid GEO code, must be 5 characters for communes")
 	}
 
-	// geoObject := record["GEO_OBJECT"] // COM
-	// if geoObject != "COM" && geoObject != "ARM" {
-	//     slog.Warn("Skip record, ignored GEO_OBJECT", "record", record)
-	// 	return nil, nil // Only keep code commune and arrondissment for population_princ
-	// }
+	// Records without GEO_OBJECT are communes
+	geoObject := record["GEO_OBJECT"]
+	if geoObject == "" {
+		geoObject = GeoObjectCommune
+	}
+	if geoObject != GeoObjectCommune && geoObject != GeoObjectArrondissementMunicipal {
+		return nil, fmt.Errorf("invalid GEO_OBJECT, must be COM or ARM")
+	}
 
 	sexe := record["SEX"]
-	// Validate sexe: must be _T (total), M (hommes), or F (femmes)
-	if sexe != "_T" && sexe != "M" && sexe != "F" {
-		return nil, fmt.Errorf("invalid SEX, must be _T, M, or F") // Skip invalid sexe
+	if sexe == "" {
+		return nil, fmt.Errorf("missing SEX")
 	}
 
 	annee, err := strconv.Atoi(record["TIME_PERIOD"])
@@ -63,20 +100,47 @@ func (m *CommunePopulationMapper) Map(record model.CSVRecord) (*CommunePopulatio
 		return nil, fmt.Errorf("invalid TIME_PERIOD: %s, %w", record["TIME_PERIOD"], err)
 	}
 
-	// Parse population: handle both decimal separators ("." and ",")
-	populationStr := strings.ReplaceAll(record["OBS_VALUE"], ",", ".")
-	populationFloat, err := strconv.ParseFloat(populationStr, 64)
+	population, err := parsePopulation(record["OBS_VALUE"], m.decimals)
 	if err != nil {
 		return nil, fmt.Errorf("invalid OBS_VALUE: %s, %w", record["OBS_VALUE"], err)
 	}
-	// Round to nearest integer
-	population := int(math.Round(populationFloat))
 
 	return &CommunePopulationPrincEntity{
 		Age:         age,
 		CodeCommune: codeCommune,
+		GeoObject:   geoObject,
 		Sexe:        sexe,
 		Annee:       annee,
 		Population:  population,
 	}, nil
 }
+
+// parsePopulation parses a population figure, rounded to the nearest integer unless decimals is set: the
+// census figures are weighted estimates. Both decimal separators ("." and ",") are accepted. The figure is
+// kept as an exact decimal, to be stored in the numeric columns without binary rounding.
+func parsePopulation(value string, decimals bool) (pgtype.Numeric, error) {
+	var population pgtype.Numeric
+	if err := population.Scan(strings.ReplaceAll(value, ",", ".")); err != nil {
+		return pgtype.Numeric{}, err
+	}
+	if population.NaN || population.InfinityModifier != pgtype.Finite {
+		return pgtype.Numeric{}, fmt.Errorf("%s is not a number", value)
+	}
+	if decimals {
+		return population, nil
+	}
+	return roundPopulation(population), nil
+}
+
+// roundPopulation rounds a figure to the nearest integer, halves away from zero as math.Round does.
+func roundPopulation(population pgtype.Numeric) pgtype.Numeric {
+	if population.Exp >= 0 {
+		return population
+	}
+	divisor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-population.Exp)), nil)
+	quotient, remainder := new(big.Int).QuoRem(population.Int, divisor, new(big.Int))
+	if new(big.Int).Lsh(remainder.Abs(remainder), 1).Cmp(divisor) >= 0 {
+		quotient.Add(quotient, big.NewInt(int64(population.Int.Sign())))
+	}
+	return pgtype.Numeric{Int: quotient, Valid: true}
+}
@@ -16,33 +16,57 @@ type GeoJSONCommuneFeature = model.GeoJSONFeature[CommuneProperties]
 
 // CommuneEntity represents the commune entity to be stored in the database.
 type CommuneEntity struct {
-	Code            string `json:"code_insee_commune"`
-	Nom             string `json:"nom_commune"`
-	CodeEPCI        string `json:"code_insee_epci"`
-	CodeDepartement string `json:"code_insee_departement"`
-	CodeRegion      string `json:"code_insee_region"`
+	_               struct{} `etl:"table=ref_admin.communes,geometry=geom,deleted=supprime_le,vintage=millesime"`
+	Code            string   `json:"code_insee_commune" etl:"code_insee_commune,key"`
+	Nom             string   `json:"nom_commune" etl:"nom_commune"`
+	CodeEPCI        string   `json:"code_insee_epci" etl:"code_insee_epci,ref=ref_admin.epci(code_insee_epci)"`
+	CodeDepartement string   `json:"code_insee_departement" etl:"code_insee_departement"`
+	CodeRegion      string   `json:"code_insee_region" etl:"code_insee_region"`
+	// The arrondissement and canton come from the COG (see NewCommuneMapperWithCog), nil without it
+	CodeArrondissement *string `json:"code_insee_arrondissement" etl:"code_insee_arrondissement,ref=ref_admin.arrondissements(code_insee_arrondissement)"`
+	CodeCanton         *string `json:"code_insee_canton" etl:"code_insee_canton,ref=ref_admin.cantons(code_insee_canton)"`
 }
 
 // CommuneWithGeometry combines the commune entity with its GeoJSON geometry for database insertion.
 type CommuneWithGeometry = model.EntityWithGeoJSONGeometry[CommuneEntity]
 
+// CogCommuneParents are the arrondissement and canton of a commune in the COG (v_commune), which the
+// Etalab contours lack.
+type CogCommuneParents struct {
+	Arrondissement *string
+	Canton         *string
+}
+
 // CommuneMapper is responsible for mapping CommuneProperties to CommuneEntity.
-type CommuneMapper struct{}
+type CommuneMapper struct {
+	parents map[string]CogCommuneParents // nil when the communes are mapped without the COG
+}
 
 // NewCommuneMapper creates a new mapper for commune data.
 func NewCommuneMapper() *CommuneMapper {
 	return &CommuneMapper{}
 }
 
+// NewCommuneMapperWithCog creates a new mapper for commune data, setting the arrondissement and canton of
+// each commune from the parents of the COG, by commune code.
+func NewCommuneMapperWithCog(parents map[string]CogCommuneParents) *CommuneMapper {
+	return &CommuneMapper{parents: parents}
+}
+
 var _ model.Mapper[CommuneProperties, CommuneEntity] = (*CommuneMapper)(nil)
 
 // Map converts CommuneProperties to a CommuneEntity for database insertion.
 func (m *CommuneMapper) Map(input CommuneProperties) (*CommuneEntity, error) {
-	return &CommuneEntity{
+	entity := &CommuneEntity{
 		Code:            input.Code,
 		Nom:             input.Nom,
 		CodeEPCI:        input.EPCI,
 		CodeDepartement: input.Departement,
 		CodeRegion:      input.Region,
-	}, nil
+	}
+	if parents, ok := m.parents[input.Code]; ok {
+		entity.CodeArrondissement = parents.Arrondissement
+		entity.CodeCanton = parents.Canton
+	}
+	return entity, nil
 }
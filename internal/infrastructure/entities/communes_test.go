@@ -0,0 +1,34 @@
+package entities
+
+import "testing"
+
+func TestCommuneMapper_Map(t *testing.T) {
+	input := CommuneProperties{Code: "01001", Nom: "L'Abergement-Clémenciat", EPCI: "200069193", Departement: "01", Region: "84"}
+
+	entity, err := NewCommuneMapper().Map(input)
+	if err != nil {
+		t.Fatalf("Map() error = %v", err)
+	}
+	if entity.Code != "01001" || entity.CodeEPCI != "200069193" || entity.CodeArrondissement != nil || entity.CodeCanton != nil {
+		t.Errorf("Expected a commune without arrondissement nor canton, got %+v", entity)
+	}
+
+	mapper := NewCommuneMapperWithCog(map[string]CogCommuneParents{
+		"01001": {Arrondissement: ptr("012"), Canton: ptr("0108")},
+	})
+	entity, err = mapper.Map(input)
+	if err != nil {
+		t.Fatalf("Map() error = %v", err)
+	}
+	if entity.CodeArrondissement == nil || *entity.CodeArrondissement != "012" || entity.CodeCanton == nil || *entity.CodeCanton != "0108" {
+		t.Errorf("Expected arrondissement 012 and canton 0108 from the COG, got %v, %v", entity.CodeArrondissement, entity.CodeCanton)
+	}
+
+	entity, err = mapper.Map(CommuneProperties{Code: "01002", Nom: "L'Abergement-de-Varey", Departement: "01", Region: "84"})
+	if err != nil {
+		t.Fatalf("Map() error = %v", err)
+	}
+	if entity.CodeArrondissement != nil || entity.CodeCanton != nil {
+		t.Errorf("Expected no arrondissement nor canton for a commune absent from the COG, got %+v", entity)
+	}
+}
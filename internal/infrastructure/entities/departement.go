@@ -14,9 +14,10 @@ type GeoJSONDepartementFeature = model.GeoJSONFeature[DepartementProperties]
 
 // DepartementEntity represents the department entity to be stored in the database
 type DepartementEntity struct {
-	Code       string `json:"code_insee_departement"`
-	Nom        string `json:"nom_departement"`
-	CodeRegion string `json:"code_insee_region"`
+	_          struct{} `etl:"table=ref_admin.departements,geometry=geom,deleted=supprime_le,vintage=millesime"`
+	Code       string   `json:"code_insee_departement" etl:"code_insee_departement,key"`
+	Nom        string   `json:"nom_departement" etl:"nom_departement"`
+	CodeRegion string   `json:"code_insee_region" etl:"code_insee_region"`
 }
 
 // DepartementWithGeometry combines the department entity with its GeoJSON geometry for database insertion
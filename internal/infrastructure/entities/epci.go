@@ -13,8 +13,9 @@ type GeoJSONEpciFeature = model.GeoJSONFeature[EPCIProperties]
 
 // EPCIEntity represents the EPCI entity to be stored in the database.
 type EPCIEntity struct {
-	Code string `json:"code_insee_epci"`
-	Nom  string `json:"nom_epci"`
+	_    struct{} `etl:"table=ref_admin.epci,geometry=geom,deleted=supprime_le,vintage=millesime"`
+	Code string   `json:"code_insee_epci" etl:"code_insee_epci,key"`
+	Nom  string   `json:"nom_epci" etl:"nom_epci"`
 }
 
 // EPCIWithGeometry combines the EPCI entity with its GeoJSON geometry for database insertion.
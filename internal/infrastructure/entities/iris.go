@@ -0,0 +1,75 @@
+package entities
+
+import (
+	"fmt"
+	"french-admin-etl/internal/model"
+)
+
+// IRIS types (TYP_IRIS).
+const (
+	IrisTypeHabitat  = "H" // IRIS d'habitat
+	IrisTypeActivite = "A" // IRIS d'activité
+	IrisTypeDivers   = "D" // IRIS divers (parcs, zones portuaires, ...)
+	IrisTypeCommune  = "Z" // commune not divided into IRIS
+)
+
+// IrisProperties represents the properties of an IRIS in the GeoJSON file converted from the IGN
+// CONTOURS-IRIS (see make download-iris).
+type IrisProperties struct {
+	Code    string `json:"code"`
+	Nom     string `json:"nom"`
+	Type    string `json:"type"`
+	Commune string `json:"commune"` // commune, or arrondissement municipal in Paris, Lyon and Marseille
+}
+
+// GeoJSONIrisFeature is a type alias for a GeoJSON feature with IrisProperties.
+type GeoJSONIrisFeature = model.GeoJSONFeature[IrisProperties]
+
+// IrisEntity represents the IRIS (Ilots Regroupés pour l'Information Statistique) entity to be stored in the database.
+type IrisEntity struct {
+	_                           struct{} `etl:"table=ref_admin.iris,geometry=geom,deleted=supprime_le,vintage=millesime"`
+	Code                        string   `json:"code_iris" etl:"code_iris,key"`
+	Nom                         string   `json:"nom_iris" etl:"nom_iris"`
+	Type                        string   `json:"type_iris" etl:"type_iris"`
+	CodeCommune                 string   `json:"code_insee_commune" etl:"code_insee_commune,ref=ref_admin.communes(code_insee_commune)"`
+	CodeArrondissementMunicipal *string  `json:"code_insee_arm" etl:"code_insee_arm,ref=ref_admin.arrondissements_municipaux(code_insee_arm)"`
+}
+
+// IrisWithGeometry combines the IRIS entity with its GeoJSON geometry for database insertion.
+type IrisWithGeometry = model.EntityWithGeoJSONGeometry[IrisEntity]
+
+// IrisMapper is responsible for mapping IrisProperties to IrisEntity.
+type IrisMapper struct{}
+
+// NewIrisMapper creates a new mapper for IRIS data.
+func NewIrisMapper() *IrisMapper {
+	return &IrisMapper{}
+}
+
+var _ model.Mapper[IrisProperties, IrisEntity] = (*IrisMapper)(nil)
+
+// Map converts IrisProperties to an IrisEntity for database insertion. The IRIS of Paris, Lyon and
+// Marseille are attached to their arrondissement municipal and to its commune.
+func (m *IrisMapper) Map(input IrisProperties) (*IrisEntity, error) {
+	if len(input.Code) != 9 {
+		return nil, fmt.Errorf("invalid IRIS code %q, must be 9 characters", input.Code)
+	}
+	switch input.Type {
+	case IrisTypeHabitat, IrisTypeActivite, IrisTypeDivers, IrisTypeCommune:
+	default:
+		return nil, fmt.Errorf("invalid IRIS type %q, must be one of H, A, D, Z", input.Type)
+	}
+
+	entity := &IrisEntity{
+		Code:        input.Code,
+		Nom:         input.Nom,
+		Type:        input.Type,
+		CodeCommune: input.Commune,
+	}
+	if commune, ok := ArrondissementMunicipalCommune(input.Commune); ok {
+		arrondissement := input.Commune
+		entity.CodeArrondissementMunicipal = &arrondissement
+		entity.CodeCommune = commune
+	}
+	return entity, nil
+}
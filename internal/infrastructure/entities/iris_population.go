@@ -0,0 +1,110 @@
+package entities
+
+import (
+	"fmt"
+	"french-admin-etl/internal/model"
+	"regexp"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// PopulationFigure is the population of an age group and sex, with the codes of the INSEE population
+// datasets (see CommunePopulationPrincEntity).
+type PopulationFigure struct {
+	Age        string
+	Sexe       string
+	Population pgtype.Numeric
+}
+
+// IrisPopulationEntity represents the population of an IRIS by age and gender, for a census year.
+type IrisPopulationEntity struct {
+	CodeIris string // 9-character IRIS code
+	Annee    int
+	Figures  []PopulationFigure
+}
+
+// irisPopulationColumns maps the columns of the INSEE IRIS population file (base-ic-evol-struct-pop),
+// without their P<yy>_ prefix, to the age groups and sexes of the population tables. Some age groups are
+// only published for both sexes.
+var irisPopulationColumns = map[string]struct{ age, sexe string }{
+	"POP":     {"_T", "_T"},
+	"POPH":    {"_T", "M"},
+	"POPF":    {"_T", "F"},
+	"POP0014": {"Y_LT15", "_T"},
+	"H0014":   {"Y_LT15", "M"},
+	"F0014":   {"Y_LT15", "F"},
+	"POP0019": {"Y_LT20", "_T"},
+	"H0019":   {"Y_LT20", "M"},
+	"F0019":   {"Y_LT20", "F"},
+	"POP2064": {"Y20T64", "_T"},
+	"H2064":   {"Y20T64", "M"},
+	"F2064":   {"Y20T64", "F"},
+	"POP2539": {"Y25T39", "_T"},
+	"POP4054": {"Y40T54", "_T"},
+	"POP5564": {"Y55T64", "_T"},
+	"POP6579": {"Y65T79", "_T"},
+	"POP65P":  {"Y_GE65", "_T"},
+	"H65P":    {"Y_GE65", "M"},
+	"F65P":    {"Y_GE65", "F"},
+	"POP80P":  {"Y_GE80", "_T"},
+}
+
+// irisPopulationYear matches the total population column, giving the census year of the file (P21_POP).
+var irisPopulationYear = regexp.MustCompile(`^P(\d{2})_POP$`)
+
+// IrisPopulationMapper maps the rows of the INSEE IRIS population file to IrisPopulationEntity.
+type IrisPopulationMapper struct {
+	decimals bool // keep the decimals of the figures instead of rounding them
+}
+
+// NewIrisPopulationMapper creates a new mapper for IRIS population data, rounding the figures to the
+// nearest integer.
+func NewIrisPopulationMapper() *IrisPopulationMapper {
+	return &IrisPopulationMapper{}
+}
+
+// NewIrisPopulationMapperWithDecimals creates a new mapper for IRIS population data, keeping the decimals
+// of the weighted estimates.
+func NewIrisPopulationMapperWithDecimals() *IrisPopulationMapper {
+	return &IrisPopulationMapper{decimals: true}
+}
+
+var _ model.Mapper[model.CSVRecord, IrisPopulationEntity] = (*IrisPopulationMapper)(nil)
+
+// Map converts a CSV record to an IrisPopulationEntity. Empty cells (secret statistique) are skipped.
+func (m *IrisPopulationMapper) Map(record model.CSVRecord) (*IrisPopulationEntity, error) {
+	codeIris := record["IRIS"]
+	if len(codeIris) != 9 {
+		return nil, fmt.Errorf("invalid IRIS code, must be 9 characters")
+	}
+
+	prefix := ""
+	for column := range record {
+		if match := irisPopulationYear.FindStringSubmatch(column); match != nil {
+			prefix = match[1]
+			break
+		}
+	}
+	if prefix == "" {
+		return nil, fmt.Errorf("missing P<yy>_POP column")
+	}
+	year, err := strconv.Atoi(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("invalid year %q: %w", prefix, err)
+	}
+
+	entity := &IrisPopulationEntity{CodeIris: codeIris, Annee: 2000 + year}
+	for column, group := range irisPopulationColumns {
+		value := record["P"+prefix+"_"+column]
+		if value == "" {
+			continue
+		}
+		population, err := parsePopulation(value, m.decimals)
+		if err != nil {
+			return nil, fmt.Errorf("invalid P%s_%s: %s, %w", prefix, column, value, err)
+		}
+		entity.Figures = append(entity.Figures, PopulationFigure{Age: group.age, Sexe: group.sexe, Population: population})
+	}
+	return entity, nil
+}
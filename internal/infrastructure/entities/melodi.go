@@ -0,0 +1,25 @@
+package entities
+
+// MelodiObservationEntity represents an observation of an INSEE Melodi dataset (cube DS_*), stored in long
+// format: one row per territory, period and combination of the modalities of the dimensions of the dataset.
+type MelodiObservationEntity struct {
+	_          struct{} `etl:"table=demography.melodi_observations,vintage=millesime"`
+	Dataset    string   `json:"dataset" etl:"dataset,key"`
+	GeoObject  string   `json:"objet_geo" etl:"objet_geo,key"`
+	Geo        string   `json:"code_geo" etl:"code_geo,key"`
+	Periode    string   `json:"periode" etl:"periode,key"`
+	Dimensions string   `json:"dimensions" etl:"dimensions,key"` // JSON object of the modalities by dimension, keys sorted
+	Valeur     *float64 `json:"valeur" etl:"valeur"`             // nil when the value is not available (secret, missing)
+	Statut     *string  `json:"statut" etl:"statut"`
+}
+
+// MelodiModalityEntity represents a modality of a dimension of an INSEE Melodi dataset, with the labels
+// of the metadata file of the dataset.
+type MelodiModalityEntity struct {
+	_               struct{} `etl:"table=demography.melodi_modalites"`
+	Dataset         string   `json:"dataset" etl:"dataset,key"`
+	Variable        string   `json:"variable" etl:"variable,key"`
+	LibelleVariable string   `json:"libelle_variable" etl:"libelle_variable"`
+	Modalite        string   `json:"modalite" etl:"modalite,key"`
+	LibelleModalite string   `json:"libelle_modalite" etl:"libelle_modalite"`
+}
@@ -13,8 +13,9 @@ type GeoJSONRegionFeature = model.GeoJSONFeature[RegionProperties]
 
 // RegionEntity represents the region entity to be stored in the database.
 type RegionEntity struct {
-	Code string `json:"code_insee_region"`
-	Nom  string `json:"nom_region"`
+	_    struct{} `etl:"table=ref_admin.regions,geometry=geom,deleted=supprime_le,vintage=millesime"`
+	Code string   `json:"code_insee_region" etl:"code_insee_region,key"`
+	Nom  string   `json:"nom_region" etl:"nom_region"`
 }
 
 // RegionWithGeometry combines the region entity with its GeoJSON geometry for database insertion.
@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"french-admin-etl/internal/model"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// AdjacencyLevel is a level of the adjacency graph ref_admin.communes_voisines.
+type AdjacencyLevel string
+
+const (
+	// AdjacencyCommune is the adjacency between communes.
+	AdjacencyCommune AdjacencyLevel = "commune"
+	// AdjacencyDepartement is the adjacency between départements.
+	AdjacencyDepartement AdjacencyLevel = "departement"
+)
+
+// adjacencyTable is the table of the adjacency graph, both directions of each pair being stored.
+const adjacencyTable = "ref_admin.communes_voisines"
+
+// adjacencyFingerprintsTable holds the fingerprint of the geometry of each row the graph was computed from,
+// to only recompute the neighbours of the rows whose geometry changed.
+const adjacencyFingerprintsTable = "ref_admin.etl_adjacency_fingerprints"
+
+// adjacencyChangedTable is the temporary table receiving the codes whose geometry changed since the last update.
+const adjacencyChangedTable = "etl_adjacency_changed"
+
+// adjacencySource is the ref_admin table a level of the graph is computed from.
+type adjacencySource struct {
+	level      AdjacencyLevel
+	table      string
+	codeColumn string
+}
+
+// adjacencySources maps the tables of the levels of the graph to their level.
+var adjacencySources = map[string]adjacencySource{
+	"ref_admin.communes":     {level: AdjacencyCommune, table: "ref_admin.communes", codeColumn: "code_insee_commune"},
+	"ref_admin.departements": {level: AdjacencyDepartement, table: "ref_admin.departements", codeColumn: "code_insee_departement"},
+}
+
+// adjacencySourceOf returns the source of the level.
+func adjacencySourceOf(level AdjacencyLevel) (adjacencySource, error) {
+	for _, source := range adjacencySources {
+		if source.level == level {
+			return source, nil
+		}
+	}
+	return adjacencySource{}, fmt.Errorf("invalid adjacency level %q, must be commune or departement", level)
+}
+
+// Neighbour is a commune or département sharing a border with another one.
+type Neighbour struct {
+	Code string
+	// BorderKm is the length of the shared border, in km
+	BorderKm float64
+}
+
+// createChangedSQL is the statement creating the temporary table of the changed codes, dropped with the transaction.
+var createChangedSQL = fmt.Sprintf(`CREATE TEMPORARY TABLE %s (code text PRIMARY KEY, present boolean NOT NULL) ON COMMIT DROP`, adjacencyChangedTable)
+
+// changedSQL generates the statement collecting into the temporary table the codes of the rows of the vintage
+// $2, in the geometry column, whose fingerprint differs from the one the graph of the level $1 was computed
+// from: new, modified, and removed or soft-deleted rows.
+func (s adjacencySource) changedSQL(column string) string {
+	return fmt.Sprintf(`
+		INSERT INTO %[1]s (code, present)
+		SELECT coalesce(c.code, f.code) AS code, c.code IS NOT NULL AS present
+		FROM (
+			SELECT t.%[3]s AS code, md5(ST_AsBinary(t.%[4]s)) AS fingerprint
+			FROM %[2]s t
+			WHERE t.millesime = $2 AND t.supprime_le IS NULL AND t.%[4]s IS NOT NULL
+		) c
+		FULL JOIN (
+			SELECT code, fingerprint FROM %[5]s WHERE niveau = $1 AND millesime = $2
+		) f ON f.code = c.code
+		WHERE c.fingerprint IS DISTINCT FROM f.fingerprint
+	`, adjacencyChangedTable, s.table, s.codeColumn, column, adjacencyFingerprintsTable)
+}
+
+// clearSQL is the statement removing from the graph of the level $1 and vintage $2 the pairs of the changed rows.
+var clearSQL = fmt.Sprintf(`
+	DELETE FROM %s v
+	WHERE v.niveau = $1 AND v.millesime = $2
+		AND (v.code IN (SELECT code FROM %[2]s) OR v.code_voisin IN (SELECT code FROM %[2]s))
+`, adjacencyTable, adjacencyChangedTable)
+
+// neighboursSQL generates the statement inserting into the graph of the level $1 and vintage $2 the pairs of
+// each changed row with the rows sharing a border with it, in both directions. The candidates are found with
+// the spatial index of the column, the shared border being the common part of their boundaries, so that
+// rows touching at a point only are not neighbours. A pair of two changed rows is computed once.
+func (s adjacencySource) neighboursSQL(column string) string {
+	return fmt.Sprintf(`
+		WITH pairs AS (
+			SELECT code, code_voisin, frontiere_km
+			FROM (
+				SELECT a.%[3]s AS code, b.%[3]s AS code_voisin,
+					ST_Length(ST_CollectionExtract(ST_Intersection(ST_Boundary(a.%[4]s::geometry), ST_Boundary(b.%[4]s::geometry)), 2)::geography) / 1e3 AS frontiere_km
+				FROM %[2]s a
+				JOIN %[1]s changed ON changed.code = a.%[3]s AND changed.present
+				JOIN %[2]s b ON b.millesime = a.millesime AND b.%[3]s <> a.%[3]s AND b.supprime_le IS NULL
+					AND ST_Intersects(a.%[4]s, b.%[4]s)
+				WHERE a.millesime = $2 AND a.supprime_le IS NULL
+					AND (b.%[3]s > a.%[3]s OR NOT EXISTS (SELECT 1 FROM %[1]s o WHERE o.code = b.%[3]s))
+			) p
+			WHERE frontiere_km > 0
+		)
+		INSERT INTO %[5]s (niveau, millesime, code, code_voisin, frontiere_km)
+		SELECT $1::text, $2::smallint, code, code_voisin, frontiere_km FROM pairs
+		UNION ALL
+		SELECT $1::text, $2::smallint, code_voisin, code, frontiere_km FROM pairs
+	`, adjacencyChangedTable, s.table, s.codeColumn, column, adjacencyTable)
+}
+
+// fingerprintsSQL generates the statement recording the fingerprints of the changed rows, those of the other
+// rows being unchanged. The fingerprints of the removed rows are deleted beforehand.
+func (s adjacencySource) fingerprintsSQL(column string) string {
+	return fmt.Sprintf(`
+		INSERT INTO %[1]s (niveau, millesime, code, fingerprint)
+		SELECT $1, $2, t.%[3]s, md5(ST_AsBinary(t.%[4]s))
+		FROM %[2]s t
+		JOIN %[5]s changed ON changed.code = t.%[3]s AND changed.present
+		WHERE t.millesime = $2 AND t.supprime_le IS NULL
+	`, adjacencyFingerprintsTable, s.table, s.codeColumn, column, adjacencyChangedTable)
+}
+
+// clearFingerprintsSQL is the statement removing the fingerprints of the changed rows of the level $1 and vintage $2.
+var clearFingerprintsSQL = fmt.Sprintf(`
+	DELETE FROM %s WHERE niveau = $1 AND millesime = $2 AND code IN (SELECT code FROM %s)
+`, adjacencyFingerprintsTable, adjacencyChangedTable)
+
+// UpdateAdjacency updates the adjacency graph of the level for the vintage from the geometries of the column
+// of the resolution, once loaded. Only the neighbours of the rows whose geometry changed since the last update
+// are recomputed, so that a run modifying a few communes doesn't recompute the whole graph. The update runs
+// in a single transaction.
+func UpdateAdjacency(ctx context.Context, dm *DatabaseManager, level AdjacencyLevel, vintage model.Vintage, resolution model.Resolution) error {
+	source, err := adjacencySourceOf(level)
+	if err != nil {
+		return err
+	}
+	if dm.dryRun {
+		slog.Info("Adjacency update skipped in dry run", "level", level)
+		return nil
+	}
+	column := resolution.GeometryColumn("geom")
+
+	tx, err := dm.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, createChangedSQL); err != nil {
+		return err
+	}
+	changed, err := tx.Exec(ctx, source.changedSQL(column), string(level), int(vintage))
+	if err != nil {
+		return fmt.Errorf("error finding the changed geometries of %s: %w", source.table, err)
+	}
+	if changed.RowsAffected() == 0 {
+		slog.Info("Adjacency up to date", "level", level, "millesime", int(vintage))
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, clearSQL, string(level), int(vintage)); err != nil {
+		return fmt.Errorf("error clearing the neighbours of the changed %s: %w", source.table, err)
+	}
+	pairs, err := tx.Exec(ctx, source.neighboursSQL(column), string(level), int(vintage))
+	if err != nil {
+		return fmt.Errorf("error computing the neighbours of the changed %s: %w", source.table, err)
+	}
+	if _, err := tx.Exec(ctx, clearFingerprintsSQL, string(level), int(vintage)); err != nil {
+		return fmt.Errorf("error clearing the fingerprints of %s: %w", source.table, err)
+	}
+	if _, err := tx.Exec(ctx, source.fingerprintsSQL(column), string(level), int(vintage)); err != nil {
+		return fmt.Errorf("error recording the fingerprints of %s: %w", source.table, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	slog.Info("Adjacency updated", "level", level, "millesime", int(vintage), "changed", changed.RowsAffected(), "pairs", pairs.RowsAffected())
+	return nil
+}
+
+// Neighbours returns the communes or départements of the vintage sharing a border with the one of the code,
+// by decreasing length of the shared border. It is empty when the code is unknown or the graph not computed.
+func Neighbours(ctx context.Context, dm *DatabaseManager, level AdjacencyLevel, code string, vintage model.Vintage) ([]Neighbour, error) {
+	if _, err := adjacencySourceOf(level); err != nil {
+		return nil, err
+	}
+	if dm.dryRun {
+		return nil, nil
+	}
+
+	rows, err := dm.pool.Query(ctx, fmt.Sprintf(`
+		SELECT code_voisin, frontiere_km
+		FROM %s
+		WHERE niveau = $1 AND millesime = $2 AND code = $3
+		ORDER BY frontiere_km DESC, code_voisin`, adjacencyTable), string(level), int(vintage), code)
+	if err != nil {
+		return nil, fmt.Errorf("error querying the neighbours of %s: %w", code, err)
+	}
+	neighbours, err := pgx.CollectRows(rows, pgx.RowToStructByPos[Neighbour])
+	if err != nil {
+		return nil, fmt.Errorf("error reading the neighbours of %s: %w", code, err)
+	}
+	return neighbours, nil
+}
+
+// updateAdjacency updates, once the run is loaded, the adjacency graph of the level of the table from the
+// column of the resolution of the run.
+func (t *entityTable) updateAdjacency(ctx context.Context) error {
+	vintage, err := t.runVintage(ctx)
+	if err != nil {
+		return err
+	}
+	return UpdateAdjacency(ctx, t.databaseManager, t.adjacency.level, vintage, model.ResolutionFromContext(ctx))
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"french-admin-etl/internal/infrastructure/entities"
+	"french-admin-etl/internal/model"
+)
+
+// TestAdjacencySourceOf tests the tables of the levels of the adjacency graph
+func TestAdjacencySourceOf(t *testing.T) {
+	source, err := adjacencySourceOf(AdjacencyDepartement)
+	if err != nil || source.table != "ref_admin.departements" || source.codeColumn != "code_insee_departement" {
+		t.Errorf("adjacencySourceOf() = %+v, %v", source, err)
+	}
+	if _, err := adjacencySourceOf("region"); err == nil {
+		t.Error("Expected error for unknown level")
+	}
+}
+
+// TestAdjacencySQL tests the statements of the incremental update of the adjacency graph
+func TestAdjacencySQL(t *testing.T) {
+	source := adjacencySources["ref_admin.communes"]
+
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{
+			name: "changed",
+			sql:  source.changedSQL("geom_5m"),
+			want: []string{
+				"md5(ST_AsBinary(t.geom_5m))",
+				"t.supprime_le IS NULL AND t.geom_5m IS NOT NULL",
+				"FULL JOIN",
+				"WHERE c.fingerprint IS DISTINCT FROM f.fingerprint",
+			},
+		},
+		{
+			name: "neighbours",
+			sql:  source.neighboursSQL("geom_5m"),
+			want: []string{
+				"ST_Intersects(a.geom_5m, b.geom_5m)",
+				"ST_Boundary(a.geom_5m::geometry), ST_Boundary(b.geom_5m::geometry)",
+				"WHERE frontiere_km > 0",
+				"b.code_insee_commune > a.code_insee_commune OR NOT EXISTS",
+				"SELECT $1::text, $2::smallint, code_voisin, code, frontiere_km FROM pairs",
+			},
+		},
+		{
+			name: "fingerprints",
+			sql:  source.fingerprintsSQL("geom"),
+			want: []string{"INSERT INTO ref_admin.etl_adjacency_fingerprints", "md5(ST_AsBinary(t.geom))", "changed.present"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, want := range tt.want {
+				if !strings.Contains(tt.sql, want) {
+					t.Errorf("missing %q in %s", want, tt.sql)
+				}
+			}
+		})
+	}
+}
+
+// TestNewGeoRepository_WithAdjacency tests that the adjacency graph is only updated by communes and départements
+func TestNewGeoRepository_WithAdjacency(t *testing.T) {
+	repository := NewGeoRepository[entities.CommuneEntity](nil, WithAdjacency())
+	if repository.adjacency == nil || repository.adjacency.level != AdjacencyCommune {
+		t.Errorf("Expected the commune adjacency, got %+v", repository.adjacency)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for adjacency of the régions")
+		}
+	}()
+	NewGeoRepository[entities.RegionEntity](nil, WithAdjacency())
+}
+
+// TestAdjacency_DryRun tests that a dry run neither updates nor queries the graph
+func TestAdjacency_DryRun(t *testing.T) {
+	ctx := context.Background()
+	dm := NewDryRunDatabaseManager()
+
+	if err := UpdateAdjacency(ctx, dm, AdjacencyCommune, 2024, model.Resolution5m); err != nil {
+		t.Errorf("UpdateAdjacency() error = %v", err)
+	}
+	neighbours, err := Neighbours(ctx, dm, AdjacencyCommune, "75056", 2024)
+	if err != nil || len(neighbours) != 0 {
+		t.Errorf("Neighbours() = %v, %v", neighbours, err)
+	}
+	if _, err := Neighbours(ctx, dm, "region", "11", 2024); err == nil {
+		t.Error("Expected error for unknown level")
+	}
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// LoadStrategy selects how the rows of a batch are sent to the database.
+type LoadStrategy string
+
+const (
+	// SavepointStrategy isolates each row in its own savepoint: SAVEPOINT, INSERT and RELEASE cost 3 round trips per row.
+	SavepointStrategy LoadStrategy = "savepoint"
+	// PipelineStrategy sends the whole batch in a single round trip with pgx.Batch.
+	// When a row fails, the batch is rolled back and bisected until the bad rows are isolated.
+	PipelineStrategy LoadStrategy = "pipeline"
+)
+
+// ParseLoadStrategy converts a string to a LoadStrategy, returning an error for unknown values.
+func ParseLoadStrategy(s string) (LoadStrategy, error) {
+	switch strategy := LoadStrategy(s); strategy {
+	case SavepointStrategy, PipelineStrategy:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("invalid load strategy %q, must be savepoint or pipeline", s)
+	}
+}
+
+// statement is a parameterised SQL statement loading one row of a batch.
+type statement struct {
+	sql  string
+	args []any
+	// check validates the row without the database. It is only run in dry-run mode, the database
+	// reporting the same errors in a real run. Optional.
+	check func() error
+}
+
+// loadBatch executes the statements of a batch in a single transaction and returns the error of each row, nil when the row was loaded.
+// A dry-run DatabaseManager runs the checks of the statements instead and never touches the database.
+func (dm *DatabaseManager) loadBatch(ctx context.Context, stmts []statement) ([]error, error) {
+	if dm.dryRun {
+		return checkStatements(stmts), nil
+	}
+
+	// batch transaction
+	tx, err := dm.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	rowErrors, err := execStatements(ctx, tx, stmts, dm.loadStrategy)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return rowErrors, nil
+}
+
+// checkStatements runs the checks of the statements, in place of their execution.
+func checkStatements(stmts []statement) []error {
+	rowErrors := make([]error, len(stmts))
+	for i, stmt := range stmts {
+		if stmt.check != nil {
+			rowErrors[i] = stmt.check()
+		}
+	}
+	return rowErrors
+}
+
+// execStatements executes the statements in tx with the given strategy and returns the error of each row, nil when the row was loaded.
+// The returned error is only set when the transaction can't be used anymore.
+func execStatements(ctx context.Context, tx pgx.Tx, stmts []statement, strategy LoadStrategy) ([]error, error) {
+	rowErrors := make([]error, len(stmts))
+	if len(stmts) == 0 {
+		return rowErrors, nil
+	}
+
+	if strategy == SavepointStrategy {
+		execWithSavepoints(ctx, tx, stmts, rowErrors)
+		return rowErrors, nil
+	}
+
+	if err := execPipeline(ctx, tx, stmts, rowErrors); err != nil {
+		return nil, err
+	}
+	return rowErrors, nil
+}
+
+// execWithSavepoints executes each statement in its own savepoint to allow rollback on error.
+func execWithSavepoints(ctx context.Context, tx pgx.Tx, stmts []statement, rowErrors []error) {
+	for i, stmt := range stmts {
+		// Create savepoint before each insert to allow rollback on error
+		savepoint := fmt.Sprintf("sp_%d", i)
+		if _, err := tx.Exec(ctx, fmt.Sprintf("SAVEPOINT %s", savepoint)); err != nil {
+			slog.Error("Error creating savepoint", "error", err)
+			rowErrors[i] = err
+			continue
+		}
+
+		if _, err := tx.Exec(ctx, stmt.sql, stmt.args...); err != nil {
+			rowErrors[i] = err
+			// Rollback to savepoint to continue with other inserts
+			if _, rbErr := tx.Exec(ctx, fmt.Sprintf("ROLLBACK TO SAVEPOINT %s", savepoint)); rbErr != nil {
+				slog.Error("Rollback to savepoint", "error", rbErr)
+			}
+			continue
+		}
+
+		// Release savepoint on success
+		if _, err := tx.Exec(ctx, fmt.Sprintf("RELEASE SAVEPOINT %s", savepoint)); err != nil {
+			slog.Warn("Release savepoint", "error", err)
+		}
+	}
+}
+
+// pipelineExecutor runs the statements of a batch atomically, either all of them or none.
+type pipelineExecutor interface {
+	// exec runs the statements, undoing them all when one fails. failed is the error of the statements, err is
+	// only set when they couldn't be undone and the transaction can't be used anymore.
+	exec(ctx context.Context, stmts []statement) (failed error, err error)
+}
+
+// txPipeline runs the statements in one round trip, wrapped in a savepoint of the transaction.
+type txPipeline struct {
+	tx pgx.Tx
+}
+
+func (p txPipeline) exec(ctx context.Context, stmts []statement) (error, error) {
+	const savepoint = "sp_pipeline"
+
+	batch := &pgx.Batch{}
+	batch.Queue(fmt.Sprintf("SAVEPOINT %s", savepoint))
+	for _, stmt := range stmts {
+		batch.Queue(stmt.sql, stmt.args...)
+	}
+	batch.Queue(fmt.Sprintf("RELEASE SAVEPOINT %s", savepoint))
+
+	failed := p.tx.SendBatch(ctx, batch).Close()
+	if failed == nil {
+		return nil, nil
+	}
+
+	// Rollback to savepoint to continue with the rows that are not at fault
+	if _, err := p.tx.Exec(ctx, fmt.Sprintf("ROLLBACK TO SAVEPOINT %s; RELEASE SAVEPOINT %s", savepoint, savepoint)); err != nil {
+		return failed, fmt.Errorf("rollback to savepoint after %v: %w", failed, err)
+	}
+	return failed, nil
+}
+
+// execPipeline sends the statements in one round trip, wrapped in a savepoint, bisecting the batch on failure.
+func execPipeline(ctx context.Context, tx pgx.Tx, stmts []statement, rowErrors []error) error {
+	return bisect(ctx, txPipeline{tx: tx}, stmts, rowErrors)
+}
+
+// bisect runs the statements with the executor. On failure both halves are retried, down to single rows
+// whose error is recorded in rowErrors.
+func bisect(ctx context.Context, executor pipelineExecutor, stmts []statement, rowErrors []error) error {
+	failed, err := executor.exec(ctx, stmts)
+	if err != nil || failed == nil {
+		return err
+	}
+
+	if len(stmts) == 1 {
+		rowErrors[0] = failed
+		return nil
+	}
+
+	middle := len(stmts) / 2
+	if err := bisect(ctx, executor, stmts[:middle], rowErrors[:middle]); err != nil {
+		return err
+	}
+	return bisect(ctx, executor, stmts[middle:], rowErrors[middle:])
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+
+	"french-admin-etl/internal/infrastructure/config"
+)
+
+// benchEntity is a small entity with a foreign key lookup, close to the shape of the administrative layers
+type benchEntity struct {
+	_      struct{} `etl:"table=etl_bench.entities"`
+	Code   string   `etl:"code,key"`
+	Nom    string   `etl:"nom"`
+	Parent string   `etl:"code_parent,ref=etl_bench.parents(code)"`
+}
+
+const benchBatchSize = 1000
+
+// openBenchDatabase connects to the database of the environment configuration and creates the benchmark schema.
+// Benchmarks are skipped unless ETL_BENCH_DATABASE=true, as they need a running PostgreSQL.
+func openBenchDatabase(b *testing.B) *DatabaseManager {
+	b.Helper()
+	if os.Getenv("ETL_BENCH_DATABASE") != "true" {
+		b.Skip("set ETL_BENCH_DATABASE=true to run the benchmarks against the configured database")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		b.Fatalf("config.Load() error = %v", err)
+	}
+	dm, err := NewDatabaseManager(cfg)
+	if err != nil {
+		b.Fatalf("NewDatabaseManager() error = %v", err)
+	}
+
+	ctx := context.Background()
+	setup := `
+		DROP SCHEMA IF EXISTS etl_bench CASCADE;
+		CREATE SCHEMA etl_bench;
+		CREATE TABLE etl_bench.parents (code TEXT PRIMARY KEY);
+		INSERT INTO etl_bench.parents VALUES ('01'), ('02');
+		CREATE TABLE etl_bench.entities (
+			code TEXT PRIMARY KEY,
+			nom TEXT NOT NULL CHECK (nom <> ''),
+			code_parent TEXT REFERENCES etl_bench.parents(code)
+		);
+	`
+	if _, err := dm.pool.Exec(ctx, setup); err != nil {
+		b.Fatalf("Failed to create benchmark schema: %v", err)
+	}
+
+	b.Cleanup(func() {
+		if _, err := dm.pool.Exec(ctx, "DROP SCHEMA IF EXISTS etl_bench CASCADE"); err != nil {
+			b.Errorf("Failed to drop benchmark schema: %v", err)
+		}
+		_ = dm.Close()
+	})
+	return dm
+}
+
+// benchStatements builds a batch of upserts, one row out of badEvery violating the CHECK constraint (none if 0)
+func benchStatements(b *testing.B, badEvery int) []statement {
+	b.Helper()
+	metadata, err := parseEntityMetadata(reflect.TypeFor[benchEntity]())
+	if err != nil {
+		b.Fatalf("parseEntityMetadata() error = %v", err)
+	}
+	sql := metadata.upsertSQL(metadata.table, "")
+
+	stmts := make([]statement, benchBatchSize)
+	for i := range stmts {
+		entity := benchEntity{Code: fmt.Sprintf("%05d", i), Nom: fmt.Sprintf("Entity %d", i), Parent: "01"}
+		if badEvery > 0 && i%badEvery == 0 {
+			entity.Nom = ""
+		}
+		stmts[i] = statement{sql: sql, args: metadata.values(entity)}
+	}
+	return stmts
+}
+
+func benchmarkLoad(b *testing.B, strategy LoadStrategy, badEvery int) {
+	dm := openBenchDatabase(b)
+	stmts := benchStatements(b, badEvery)
+	ctx := context.Background()
+
+	for b.Loop() {
+		tx, err := dm.pool.Begin(ctx)
+		if err != nil {
+			b.Fatalf("Begin() error = %v", err)
+		}
+		rowErrors, err := execStatements(ctx, tx, stmts, strategy)
+		if err != nil {
+			b.Fatalf("execStatements() error = %v", err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			b.Fatalf("Commit() error = %v", err)
+		}
+
+		failed := 0
+		for _, rowErr := range rowErrors {
+			if rowErr != nil {
+				failed++
+			}
+		}
+		if badEvery > 0 && failed != (benchBatchSize+badEvery-1)/badEvery {
+			b.Fatalf("Expected %d failed rows, got %d", (benchBatchSize+badEvery-1)/badEvery, failed)
+		}
+	}
+}
+
+func BenchmarkLoad_Savepoint(b *testing.B) { benchmarkLoad(b, SavepointStrategy, 0) }
+
+func BenchmarkLoad_Pipeline(b *testing.B) { benchmarkLoad(b, PipelineStrategy, 0) }
+
+func BenchmarkLoad_SavepointWithBadRows(b *testing.B) { benchmarkLoad(b, SavepointStrategy, 100) }
+
+func BenchmarkLoad_PipelineWithBadRows(b *testing.B) { benchmarkLoad(b, PipelineStrategy, 100) }
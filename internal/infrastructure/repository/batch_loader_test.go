@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// TestParseLoadStrategy tests the parsing of the ETL_LOAD_STRATEGY values
+func TestParseLoadStrategy(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected LoadStrategy
+		wantErr  bool
+	}{
+		{input: "savepoint", expected: SavepointStrategy},
+		{input: "pipeline", expected: PipelineStrategy},
+		{input: "", wantErr: true},
+		{input: "copy", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseLoadStrategy(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLoadStrategy(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("ParseLoadStrategy(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestWithLoadStrategy tests that invalid strategies are rejected by the option
+func TestWithLoadStrategy(t *testing.T) {
+	dm := &DatabaseManager{}
+	if err := WithLoadStrategy(SavepointStrategy)(dm); err != nil {
+		t.Fatalf("WithLoadStrategy() error = %v", err)
+	}
+	if dm.loadStrategy != SavepointStrategy {
+		t.Errorf("Expected savepoint strategy, got %q", dm.loadStrategy)
+	}
+	if err := WithLoadStrategy("copy")(dm); err == nil {
+		t.Error("Expected error for unknown strategy, got nil")
+	}
+}
+
+// TestExecStatements_Empty tests that an empty batch doesn't use the transaction
+func TestExecStatements_Empty(t *testing.T) {
+	for _, strategy := range []LoadStrategy{SavepointStrategy, PipelineStrategy} {
+		rowErrors, err := execStatements(context.Background(), nil, nil, strategy)
+		if err != nil {
+			t.Fatalf("execStatements(%s) error = %v", strategy, err)
+		}
+		if len(rowErrors) != 0 {
+			t.Errorf("Expected no row errors, got %d", len(rowErrors))
+		}
+	}
+}
+
+// fakePipeline fails the batches holding a bad statement, and counts its round trips
+type fakePipeline struct {
+	bad      map[string]bool
+	rollback error
+	calls    int
+}
+
+func (p *fakePipeline) exec(_ context.Context, stmts []statement) (error, error) {
+	p.calls++
+	for _, stmt := range stmts {
+		if p.bad[stmt.sql] {
+			return fmt.Errorf("row %s refused", stmt.sql), p.rollback
+		}
+	}
+	return nil, nil
+}
+
+// statements returns n statements named after their index
+func statements(n int) []statement {
+	stmts := make([]statement, n)
+	for i := range stmts {
+		stmts[i] = statement{sql: fmt.Sprint(i)}
+	}
+	return stmts
+}
+
+// TestBisect tests that the bisection isolates the bad rows of a batch, wherever they are
+func TestBisect(t *testing.T) {
+	tests := []struct {
+		name  string
+		rows  int
+		bad   []string
+		calls int
+	}{
+		{name: "no bad row", rows: 4, calls: 1},
+		{name: "only row", rows: 1, bad: []string{"0"}, calls: 1},
+		{name: "first row", rows: 4, bad: []string{"0"}, calls: 5},
+		{name: "last row", rows: 4, bad: []string{"3"}, calls: 5},
+		{name: "odd batch", rows: 5, bad: []string{"4"}, calls: 7},
+		{name: "several rows", rows: 4, bad: []string{"0", "3"}, calls: 7},
+		{name: "every row", rows: 2, bad: []string{"0", "1"}, calls: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := &fakePipeline{bad: make(map[string]bool)}
+			for _, sql := range tt.bad {
+				executor.bad[sql] = true
+			}
+
+			rowErrors := make([]error, tt.rows)
+			if err := bisect(context.Background(), executor, statements(tt.rows), rowErrors); err != nil {
+				t.Fatalf("bisect() error = %v", err)
+			}
+			for i, rowErr := range rowErrors {
+				if bad := executor.bad[fmt.Sprint(i)]; bad != (rowErr != nil) {
+					t.Errorf("Row %d: bad %v, got error %v", i, bad, rowErr)
+				}
+			}
+			if executor.calls != tt.calls {
+				t.Errorf("Expected %d round trips, got %d", tt.calls, executor.calls)
+			}
+		})
+	}
+}
+
+// TestBisect_RollbackError tests that the bisection stops when the failed statements can't be undone
+func TestBisect_RollbackError(t *testing.T) {
+	rollback := errors.New("connection lost")
+	executor := &fakePipeline{bad: map[string]bool{"2": true}, rollback: rollback}
+
+	rowErrors := make([]error, 4)
+	if err := bisect(context.Background(), executor, statements(4), rowErrors); !errors.Is(err, rollback) {
+		t.Fatalf("Expected the rollback error, got %v", err)
+	}
+	if executor.calls != 1 {
+		t.Errorf("Expected no retry after the rollback error, got %d round trips", executor.calls)
+	}
+}
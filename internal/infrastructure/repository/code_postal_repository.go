@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"french-admin-etl/internal/infrastructure/entities"
+	"french-admin-etl/internal/model"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// CodePostalRepository loads the La Poste postal codes into ref_admin.codes_postaux. Lines whose commune
+// is missing from ref_admin.communes in the vintage of the run are rejected, and reported by Finalize.
+type CodePostalRepository struct {
+	*TableRepository[entities.CodePostalEntity]
+
+	mu      sync.Mutex
+	missing map[string][]string // postal codes of the rejected lines, by commune code
+}
+
+var _ model.EntityLoader[entities.CodePostalEntity] = (*CodePostalRepository)(nil)
+var _ model.LoadInitializer = (*CodePostalRepository)(nil)
+var _ model.LoadFinalizer = (*CodePostalRepository)(nil)
+
+// NewCodePostalRepository creates a new repository for loading postal codes.
+func NewCodePostalRepository(dbManager *DatabaseManager, opts ...RepositoryOption) *CodePostalRepository {
+	return &CodePostalRepository{
+		TableRepository: NewTableRepository[entities.CodePostalEntity](dbManager, opts...),
+		missing:         make(map[string][]string),
+	}
+}
+
+// Initialize forgets the rejected lines of a previous run, then initializes the table.
+func (r *CodePostalRepository) Initialize(ctx context.Context) error {
+	r.mu.Lock()
+	r.missing = make(map[string][]string)
+	r.mu.Unlock()
+	return r.TableRepository.Initialize(ctx)
+}
+
+// Load upserts the lines of a batch whose commune exists. In dry run, communes can't be checked and every
+// line is validated.
+func (r *CodePostalRepository) Load(ctx context.Context, batch []entities.CodePostalEntity) (int, error) {
+	if r.databaseManager.dryRun {
+		return r.TableRepository.Load(ctx, batch)
+	}
+
+	vintage, err := r.runVintage(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	codes := make([]string, 0, len(batch))
+	for _, entity := range batch {
+		codes = append(codes, entity.CodeCommune)
+	}
+	existing, err := r.existingCommunes(ctx, codes, vintage)
+	if err != nil {
+		return 0, err
+	}
+
+	found := make([]entities.CodePostalEntity, 0, len(batch))
+	r.mu.Lock()
+	for _, entity := range batch {
+		if existing[entity.CodeCommune] {
+			found = append(found, entity)
+			continue
+		}
+		r.missing[entity.CodeCommune] = append(r.missing[entity.CodeCommune], entity.CodePostal)
+	}
+	r.mu.Unlock()
+
+	return r.TableRepository.Load(ctx, found)
+}
+
+// existingCommunes returns the codes among codes of the communes of the vintage.
+func (r *CodePostalRepository) existingCommunes(ctx context.Context, codes []string, vintage model.Vintage) (map[string]bool, error) {
+	rows, err := r.databaseManager.pool.Query(ctx,
+		"SELECT code_insee_commune FROM ref_admin.communes WHERE millesime = $1 AND code_insee_commune = ANY($2)",
+		int(vintage), codes)
+	if err != nil {
+		return nil, fmt.Errorf("error checking the communes of the postal codes: %w", err)
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool, len(codes))
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, fmt.Errorf("error checking the communes of the postal codes: %w", err)
+		}
+		existing[code] = true
+	}
+	return existing, rows.Err()
+}
+
+// Missing returns the postal codes of the lines rejected during the run, by commune code.
+func (r *CodePostalRepository) Missing() map[string][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	missing := make(map[string][]string, len(r.missing))
+	for commune, codes := range r.missing {
+		codes = slices.Clone(codes)
+		slices.Sort(codes)
+		missing[commune] = slices.Compact(codes)
+	}
+	return missing
+}
+
+// Finalize reports the communes missing from ref_admin.communes, then finalizes the table.
+func (r *CodePostalRepository) Finalize(ctx context.Context) error {
+	missing := r.Missing()
+	communes := slices.Sorted(maps.Keys(missing))
+	for _, commune := range communes {
+		slog.Warn("Postal codes of a missing commune", "commune", commune, "codes_postaux", missing[commune])
+	}
+	if len(communes) > 0 {
+		slog.Warn("Postal codes rejected, their commune is missing from ref_admin.communes", "communes", len(communes))
+	}
+
+	return r.TableRepository.Finalize(ctx)
+}
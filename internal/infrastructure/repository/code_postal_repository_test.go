@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"french-admin-etl/internal/infrastructure/entities"
+	"french-admin-etl/internal/model"
+)
+
+// TestCodePostalRepository_Load_DryRun tests that a dry run validates the keys, an empty ligne 5 included
+func TestCodePostalRepository_Load_DryRun(t *testing.T) {
+	repository := NewCodePostalRepository(NewDryRunDatabaseManager())
+	rows := []entities.CodePostalEntity{
+		{CodeCommune: "01001", CodePostal: "01400", NomCommune: "L ABERGEMENT CLEMENCIAT", LibelleAcheminement: "L ABERGEMENT CLEMENCIAT"},
+		{CodeCommune: "01033", CodePostal: "01200", Ligne5: "VANCHY", NomCommune: "VALSERHONE", LibelleAcheminement: "VALSERHONE"},
+		{CodeCommune: "01033", Ligne5: "MISSING CODE POSTAL"},
+	}
+
+	ctx := model.WithVintage(context.Background(), 2024)
+	if err := repository.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	count, err := repository.Load(ctx, rows)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 loaded rows, got %d", count)
+	}
+	if err := repository.Finalize(ctx); err != nil {
+		t.Fatalf("Finalize() error = %v", err)
+	}
+}
+
+// TestCodePostalRepository_Missing tests that the rejected postal codes are reported once per commune
+func TestCodePostalRepository_Missing(t *testing.T) {
+	repository := NewCodePostalRepository(NewDryRunDatabaseManager())
+	repository.missing["97501"] = []string{"97500", "97500", "97133"}
+
+	expected := map[string][]string{"97501": {"97133", "97500"}}
+	if got := repository.Missing(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
@@ -5,326 +5,636 @@ import (
 	"fmt"
 	"french-admin-etl/internal/infrastructure/entities"
 	"french-admin-etl/internal/model"
+	"french-admin-etl/internal/validation"
 	"log/slog"
 	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+// populationCommuneTable is the table of the commune population data.
+const populationCommuneTable = "demography.population_commune"
+
+// populationArmTable is the table of the arrondissement municipal population data, rolled up into
+// populationCommuneTable for the parent commune.
+const populationArmTable = "demography.population_arrondissement_municipal"
+
+// populationVintageColumn is the column holding the vintage of the geography the population data refers to.
+const populationVintageColumn = "millesime"
+
 type communePopulationRepository struct {
 	databaseManager *DatabaseManager
+	pivot           *PivotSpec
+	validator       *validation.Validator // nil when the figures are not checked
+	shadow          *shadowTable          // nil when the run is loaded into the live table
+	armShadow       *shadowTable          // shadow of populationArmTable, nil when the run is loaded into the live table
+
+	// Entities recoded to a later geography (see lineage.Mapper) are summed into the figures of their
+	// commune once every batch is loaded, as the other communes merged into it may be in other batches.
+	mu      sync.Mutex
+	recoded []entities.CommunePopulationPrincEntity
+	loaded  map[string]bool // keys (see populationKey) of the records loaded from entities not recoded
+	// armLoaded is set once arrondissement municipal records are loaded, to roll them up in Finalize.
+	armLoaded bool
+	rejects   populationRejects
+	// figures and armFigures hold, when the figures are checked, the figures loaded for each commune/year
+	// and arrondissement/year, which the file spreads over several batches, checked once in Finalize.
+	figures    map[string]*populationRecord
+	armFigures map[string]*populationRecord
+	// counts holds the entities of the run set aside by Load, recoded or rejected by the pivot, kept once
+	// the run is finalized for the results of the run.
+	counts map[string]int
 }
 
 var _ model.EntityLoader[entities.CommunePopulationPrincEntity] = (*communePopulationRepository)(nil)
+var _ model.LoadInitializer = (*communePopulationRepository)(nil)
+var _ model.LoadFinalizer = (*communePopulationRepository)(nil)
+var _ model.LoadCounter = (*communePopulationRepository)(nil)
 
 // NewCommunePopulationRepository creates a new repository for loading commune population data.
-func NewCommunePopulationRepository(dbManager *DatabaseManager) model.EntityLoader[entities.CommunePopulationPrincEntity] {
-	return &communePopulationRepository{
+// Only the WithShadowTable, WithPivot and WithValidation options are supported.
+func NewCommunePopulationRepository(dbManager *DatabaseManager, opts ...RepositoryOption) model.EntityLoader[entities.CommunePopulationPrincEntity] {
+	options := newRepositoryOptions(opts)
+	if options.syncMode != NoSync {
+		panic("sync is not supported for commune population data")
+	}
+
+	repository := &communePopulationRepository{
 		databaseManager: dbManager,
+		pivot:           options.populationPivot(),
+		validator:       options.validator,
+		loaded:          make(map[string]bool),
+		figures:         make(map[string]*populationRecord),
+		armFigures:      make(map[string]*populationRecord),
+	}
+	if options.shadowTable {
+		repository.shadow = newShadowTable(populationCommuneTable, populationVintageColumn, options.maxRemovalRatio)
+		repository.armShadow = newShadowTable(populationArmTable, populationVintageColumn, options.maxRemovalRatio)
+	}
+	return repository
+}
+
+// Initialize forgets the counts of a previous run, then creates, in shadow table mode, the shadow tables
+// receiving the rows of the run.
+func (l *communePopulationRepository) Initialize(ctx context.Context) error {
+	l.reset()
+	l.mu.Lock()
+	l.counts = nil
+	l.mu.Unlock()
+	if l.shadow == nil {
+		return nil
+	}
+	if err := l.shadow.create(ctx, l.databaseManager); err != nil {
+		return err
+	}
+	return l.armShadow.create(ctx, l.databaseManager)
+}
+
+// Finalize loads the recoded entities, summed into the figures of their commune, rolls the arrondissement
+// municipal figures up to their commune, swaps, in shadow table mode, the shadow tables in, then aggregates
+// the commune figures to the EPCI, départements and régions. The figures rejected by the pivot are reported.
+// The figures of each commune/year and arrondissement/year are checked against the consistency rules once
+// every batch is loaded, the run failing before the swap when the violations block it.
+func (l *communePopulationRepository) Finalize(ctx context.Context) error {
+	defer l.reset()
+	l.rejects.report("commune")
+	if err := l.loadRecoded(ctx); err != nil {
+		return err
+	}
+	if err := l.rollUpArrondissements(ctx); err != nil {
+		return err
+	}
+	if l.validator != nil {
+		l.validate()
+		if err := l.validator.Finish("commune"); err != nil {
+			return err
+		}
+	}
+	if l.shadow != nil {
+		// The arrondissements are swapped first, the commune figures being derived from them
+		if err := l.armShadow.validateAndSwap(ctx, l.databaseManager); err != nil {
+			return err
+		}
+		if err := l.shadow.validateAndSwap(ctx, l.databaseManager); err != nil {
+			return err
+		}
+	}
+	return aggregatePopulation(ctx, l.databaseManager, l.pivot, populationCommuneTable)
+}
+
+// Counts returns the entities of the run set aside by Load: recoded to a later geography (loaded by
+// Finalize), and rejected, their age/sex having no column in the pivot.
+func (l *communePopulationRepository) Counts() map[string]int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return map[string]int{"entities_recoded": l.counts["entities_recoded"], "entities_rejected": l.counts["entities_rejected"]}
+}
+
+// reset forgets the recoded entities and loaded keys, so that the repository can be used for another run.
+func (l *communePopulationRepository) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.recoded = nil
+	l.loaded = make(map[string]bool)
+	l.armLoaded = false
+	l.figures = make(map[string]*populationRecord)
+	l.armFigures = make(map[string]*populationRecord)
+	l.rejects.reset()
+	if l.validator != nil {
+		l.validator.Reset()
+	}
+}
+
+// loadRecoded sums the figures of the recoded entities by commune/year, then adds them to the figures
+// loaded during the run for the same commune/year, or replaces the stored ones when there are none
+// (e.g. a commune nouvelle whose merged communes are all recoded). The recoded entities being left out
+// of the counts of Load, it reports those loaded and failed.
+func (l *communePopulationRepository) loadRecoded(ctx context.Context) error {
+	l.mu.Lock()
+	recoded, loaded := l.recoded, l.loaded
+	l.mu.Unlock()
+	if len(recoded) == 0 {
+		return nil
+	}
+
+	vintage, ok := model.VintageFromContext(ctx)
+	if !ok {
+		return fmt.Errorf("table %s is vintaged, the run must have a vintage", populationCommuneTable)
+	}
+
+	// Combinations without column were rejected before the entities were recorded
+	records := sortPopulationRecords(sumPopulationData(l.pivot, recoded))
+	table := l.targetTable()
+	stmts := make([]statement, len(records))
+	for i, record := range records {
+		stmts[i] = statement{
+			sql:   populationUpsertSQL(l.pivot, table, "code_insee_commune", loaded[populationKey(record.code, record.annee)]),
+			args:  record.args(vintage),
+			check: record.check,
+		}
+	}
+
+	rowErrors, err := l.databaseManager.loadBatch(ctx, stmts)
+	if err != nil {
+		return fmt.Errorf("error loading recoded population data: %w", err)
+	}
+
+	count := 0
+	failed := 0
+	for i, rowErr := range rowErrors {
+		if rowErr != nil {
+			slog.Error("Insert error", "entity", "population", "commune", records[i].code, "year", records[i].annee, "error", rowErr)
+			failed++
+			continue
+		}
+		count += records[i].entityCount
+		l.keep(l.figures, records[i], loaded[populationKey(records[i].code, records[i].annee)])
+	}
+	slog.Info("Recoded population data loaded",
+		"input_entities", len(recoded),
+		"records_inserted", len(records)-failed,
+		"records_failed", failed,
+		"entities_loaded", count,
+		"entities_failed", len(recoded)-count)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d recoded population records failed to load", failed, len(records))
+	}
+	return nil
+}
+
+// rollUpArrondissements sums the figures of the arrondissements municipaux loaded during the run into
+// the figures of their commune (Paris, Lyon, Marseille). Figures loaded for the commune itself are kept,
+// the sums only fill the missing ones.
+func (l *communePopulationRepository) rollUpArrondissements(ctx context.Context) error {
+	l.mu.Lock()
+	armLoaded := l.armLoaded
+	l.mu.Unlock()
+	if !armLoaded {
+		return nil
+	}
+	if l.databaseManager.dryRun {
+		slog.Info("Arrondissement municipal roll-up skipped in dry run")
+		return nil
+	}
+
+	vintage, ok := model.VintageFromContext(ctx)
+	if !ok {
+		return fmt.Errorf("table %s is vintaged, the run must have a vintage", populationArmTable)
 	}
+
+	tag, err := l.databaseManager.pool.Exec(ctx, populationRollUpSQL(l.pivot, l.targetTable(), l.armTargetTable()), int(vintage))
+	if err != nil {
+		return fmt.Errorf("error rolling up arrondissement municipal population data: %w", err)
+	}
+	slog.Info("Arrondissement municipal population data rolled up", "records", tag.RowsAffected())
+	return nil
 }
 
-// populationRecord aggregates all population data for a single commune/year
+// keep records, when the repository has a validator, the figures of a record loaded into those of its
+// commune/year (or arrondissement/year) in figures, as the upsert stores them: they replace the figures of
+// the previous batches, or are added to them when merge is set (recoded entities, see loadRecoded).
+func (l *communePopulationRepository) keep(figures map[string]*populationRecord, record *populationRecord, merge bool) {
+	if l.validator == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := populationKey(record.code, record.annee)
+	kept, exists := figures[key]
+	if !exists {
+		kept = newPopulationRecord(l.pivot, record.code, record.annee)
+		figures[key] = kept
+	}
+	for i, values := range record.populations {
+		switch {
+		case values == nil:
+		case merge:
+			kept.populations[i] = append(kept.populations[i], values...)
+		default:
+			kept.populations[i] = values
+		}
+	}
+}
+
+// validate checks the figures of each commune/year and arrondissement/year loaded during the run, once
+// every batch is loaded: the file isn't grouped by commune, so that a batch only holds part of them.
+func (l *communePopulationRepository) validate() {
+	l.mu.Lock()
+	communes, arrondissements := sortPopulationRecords(l.figures), sortPopulationRecords(l.armFigures)
+	l.mu.Unlock()
+	for _, record := range append(communes, arrondissements...) {
+		l.validator.Check(record.code, record.annee, record.figures(l.pivot))
+	}
+}
+
+// targetTable returns the table receiving the commune rows of the run.
+func (l *communePopulationRepository) targetTable() string {
+	if l.shadow != nil {
+		return l.shadow.name()
+	}
+	return populationCommuneTable
+}
+
+// armTargetTable returns the table receiving the arrondissement municipal rows of the run.
+func (l *communePopulationRepository) armTargetTable() string {
+	if l.armShadow != nil {
+		return l.armShadow.name()
+	}
+	return populationArmTable
+}
+
+// populationUpsertSQL generates the statement loading a populationRecord into table, with the columns of
+// the pivot, whose rows are identified by codeColumn. The figures replace the stored ones, or are added to
+// them when merge is set. Population columns left NULL keep the stored figures.
+func populationUpsertSQL(pivot *PivotSpec, table, codeColumn string, merge bool) string {
+	columns := pivot.columnNames()
+	placeholders := make([]string, len(columns))
+	updates := make([]string, len(columns))
+	for i, column := range columns {
+		// Summed in SQL, so that the figures of recoded communes add up exactly
+		placeholders[i] = fmt.Sprintf("(SELECT sum(v) FROM unnest($%d::numeric[]) AS v)", i+3)
+		if merge {
+			updates[i] = fmt.Sprintf("%s = COALESCE(target.%s + EXCLUDED.%s, EXCLUDED.%s, target.%s)", column, column, column, column, column)
+		} else {
+			updates[i] = fmt.Sprintf("%s = COALESCE(EXCLUDED.%s, target.%s)", column, column, column)
+		}
+	}
+
+	// The alias keeps the ON CONFLICT clause valid when loading into the shadow table
+	return fmt.Sprintf(`
+		INSERT INTO %s AS target(%s, annee, %s, millesime)
+		VALUES ($1, $2, %s, $%d)
+		ON CONFLICT (%s, annee, millesime) DO UPDATE SET
+			%s
+	`, table, codeColumn, strings.Join(columns, ", "), strings.Join(placeholders, ", "), len(columns)+3,
+		codeColumn, strings.Join(updates, ",\n\t\t\t"))
+}
+
+// populationRollUpSQL generates the statement summing the figures of the columns of the pivot of armTable
+// into communeTable, by parent commune (see ref_admin.arrondissements_municipaux) and year, for the vintage
+// $1. A sum is NULL as soon as one arrondissement lacks the figure. Figures stored for the commune are kept.
+func populationRollUpSQL(pivot *PivotSpec, communeTable, armTable string) string {
+	columns := pivot.columnNames()
+	sums := make([]string, len(columns))
+	updates := make([]string, len(columns))
+	for i, column := range columns {
+		sums[i] = fmt.Sprintf("CASE WHEN count(p.%s) = count(*) THEN sum(p.%s) END", column, column)
+		updates[i] = fmt.Sprintf("%s = COALESCE(target.%s, EXCLUDED.%s)", column, column, column)
+	}
+
+	return fmt.Sprintf(`
+		INSERT INTO %s AS target(code_insee_commune, annee, %s, millesime)
+		SELECT a.code_insee_commune, p.annee, %s, p.millesime
+		FROM %s p
+		JOIN ref_admin.arrondissements_municipaux a ON a.code_insee_arm = p.code_insee_arm AND a.millesime = p.millesime
+		WHERE p.millesime = $1 AND a.code_insee_commune IS NOT NULL
+		GROUP BY a.code_insee_commune, p.annee, p.millesime
+		ON CONFLICT (code_insee_commune, annee, millesime) DO UPDATE SET
+			%s
+	`, communeTable, strings.Join(columns, ", "), strings.Join(sums, ", "), armTable, strings.Join(updates, ",\n\t\t\t"))
+}
+
+// populationRecord aggregates all population data for a single commune/year (or arrondissement municipal, IRIS)
 type populationRecord struct {
-	codeCommune string
+	code        string // code of the commune, arrondissement municipal or IRIS
 	annee       int
 	entityCount int // Number of original entities that contributed to this record
-	// Total population
-	pop  *int
-	popH *int
-	popF *int
-	// Population by age groups
-	popLT15   *int
-	popLT15H  *int
-	popLT15F  *int
-	popLT20   *int
-	popLT20H  *int
-	popLT20F  *int
-	pop15T24  *int
-	pop15T24H *int
-	pop15T24F *int
-	pop20T64  *int
-	pop20T64H *int
-	pop20T64F *int
-	pop25T39  *int
-	pop25T39H *int
-	pop25T39F *int
-	pop40T54  *int
-	pop40T54H *int
-	pop40T54F *int
-	pop55T64  *int
-	pop55T64H *int
-	pop55T64F *int
-	pop65T79  *int
-	pop65T79H *int
-	pop65T79F *int
-	popGE65   *int
-	popGE65H  *int
-	popGE65F  *int
-	popGE80   *int
-	popGE80H  *int
-	popGE80F  *int
-}
-
-// aggregatePopulationData groups entities by commune/year and aggregates by age/sex
-func aggregatePopulationData(entities []entities.CommunePopulationPrincEntity) map[string]*populationRecord {
+	// populations holds the figures in the column order of the pivot, nil when absent from the source. The
+	// figures of a column are summed by the insert statement, as exact decimals (see populationUpsertSQL).
+	populations [][]pgtype.Numeric
+}
+
+// newPopulationRecord creates an empty record with the columns of the pivot
+func newPopulationRecord(pivot *PivotSpec, code string, annee int) *populationRecord {
+	return &populationRecord{code: code, annee: annee, populations: make([][]pgtype.Numeric, len(pivot.columns))}
+}
+
+// set assigns population to the column i, or adds it to the figures of the column when sum is set
+func (r *populationRecord) set(i int, population pgtype.Numeric, sum bool) {
+	if sum {
+		r.populations[i] = append(r.populations[i], population)
+		return
+	}
+	r.populations[i] = []pgtype.Numeric{population}
+}
+
+// figure returns the sum of the figures of the column i as a float, for the consistency rules, nil when absent
+func (r *populationRecord) figure(i int) *float64 {
+	if r.populations[i] == nil {
+		return nil
+	}
+	sum := 0.0
+	for _, population := range r.populations[i] {
+		value, err := population.Float64Value()
+		if err != nil {
+			return nil
+		}
+		sum += value.Float64
+	}
+	return &sum
+}
+
+// populationKey identifies the record of a commune/year (or arrondissement municipal, IRIS)
+func populationKey(code string, annee int) string {
+	return fmt.Sprintf("%s_%d", code, annee)
+}
+
+// populationModalities returns the modalities of a population figure, by dimension of PopulationDimensions
+func populationModalities(age, sexe string) map[string]string {
+	return map[string]string{"AGE": age, "SEX": sexe}
+}
+
+// populationRejects counts the population figures whose combination of modalities has no column in the
+// pivot, by combination. It is safe for concurrent use.
+type populationRejects struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// add records the rejected figures of a batch.
+func (r *populationRejects) add(counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.counts == nil {
+		r.counts = make(map[string]int)
+	}
+	for combination, count := range counts {
+		r.counts[combination] += count
+	}
+}
+
+// report logs the rejected figures of the run, one warning by combination.
+func (r *populationRejects) report(entity string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	combinations := make([]string, 0, len(r.counts))
+	for combination := range r.counts {
+		combinations = append(combinations, combination)
+	}
+	sort.Strings(combinations)
+	for _, combination := range combinations {
+		slog.Warn("Population figures rejected, their combination has no column in the pivot", "entity", entity, "combination", combination, "figures", r.counts[combination])
+	}
+}
+
+// reset forgets the rejected figures.
+func (r *populationRejects) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counts = nil
+}
+
+// aggregatePopulationData groups entities by commune/year and aggregates by age/sex. Entities whose age/sex
+// has no column in the pivot are left out: Load rejects them beforehand.
+func aggregatePopulationData(pivot *PivotSpec, entities []entities.CommunePopulationPrincEntity) map[string]*populationRecord {
+	return groupPopulationData(pivot, entities, false)
+}
+
+// sumPopulationData groups entities by commune/year and sums the figures of each age/sex, for
+// communes merged into the same commune
+func sumPopulationData(pivot *PivotSpec, entities []entities.CommunePopulationPrincEntity) map[string]*populationRecord {
+	return groupPopulationData(pivot, entities, true)
+}
+
+func groupPopulationData(pivot *PivotSpec, entities []entities.CommunePopulationPrincEntity, sum bool) map[string]*populationRecord {
 	records := make(map[string]*populationRecord)
 
 	for _, entity := range entities {
-		key := fmt.Sprintf("%s_%d", entity.CodeCommune, entity.Annee)
+		column, ok := pivot.column(populationModalities(entity.Age, entity.Sexe))
+		if !ok {
+			continue
+		}
 
+		key := populationKey(entity.CodeCommune, entity.Annee)
 		record, exists := records[key]
 		if !exists {
-			record = &populationRecord{
-				codeCommune: entity.CodeCommune,
-				annee:       entity.Annee,
-				entityCount: 0,
-			}
+			record = newPopulationRecord(pivot, entity.CodeCommune, entity.Annee)
 			records[key] = record
 		}
 
 		// Increment entity count for this record
 		record.entityCount++
-
-		// Map age and sex to appropriate field
-		setPopulationValue(record, entity.Age, entity.Sexe, entity.Population)
+		record.set(column, entity.Population, sum)
 	}
 
 	return records
 }
 
-// fieldSelector returns a pointer to the appropriate field based on age and sex
-type fieldSelector func(*populationRecord) **int
-
-// populationFieldMap maps (age, sex) combinations to record fields
-var populationFieldMap = map[string]map[string]fieldSelector{
-	"Y_LT15": {
-		"_T": func(r *populationRecord) **int { return &r.popLT15 },
-		"M":  func(r *populationRecord) **int { return &r.popLT15H },
-		"F":  func(r *populationRecord) **int { return &r.popLT15F },
-	},
-	"Y_LT20": {
-		"_T": func(r *populationRecord) **int { return &r.popLT20 },
-		"M":  func(r *populationRecord) **int { return &r.popLT20H },
-		"F":  func(r *populationRecord) **int { return &r.popLT20F },
-	},
-	"Y15T24": {
-		"_T": func(r *populationRecord) **int { return &r.pop15T24 },
-		"M":  func(r *populationRecord) **int { return &r.pop15T24H },
-		"F":  func(r *populationRecord) **int { return &r.pop15T24F },
-	},
-	"Y20T64": {
-		"_T": func(r *populationRecord) **int { return &r.pop20T64 },
-		"M":  func(r *populationRecord) **int { return &r.pop20T64H },
-		"F":  func(r *populationRecord) **int { return &r.pop20T64F },
-	},
-	"Y25T39": {
-		"_T": func(r *populationRecord) **int { return &r.pop25T39 },
-		"M":  func(r *populationRecord) **int { return &r.pop25T39H },
-		"F":  func(r *populationRecord) **int { return &r.pop25T39F },
-	},
-	"Y40T54": {
-		"_T": func(r *populationRecord) **int { return &r.pop40T54 },
-		"M":  func(r *populationRecord) **int { return &r.pop40T54H },
-		"F":  func(r *populationRecord) **int { return &r.pop40T54F },
-	},
-	"Y55T64": {
-		"_T": func(r *populationRecord) **int { return &r.pop55T64 },
-		"M":  func(r *populationRecord) **int { return &r.pop55T64H },
-		"F":  func(r *populationRecord) **int { return &r.pop55T64F },
-	},
-	"Y65T79": {
-		"_T": func(r *populationRecord) **int { return &r.pop65T79 },
-		"M":  func(r *populationRecord) **int { return &r.pop65T79H },
-		"F":  func(r *populationRecord) **int { return &r.pop65T79F },
-	},
-	"Y_GE65": {
-		"_T": func(r *populationRecord) **int { return &r.popGE65 },
-		"M":  func(r *populationRecord) **int { return &r.popGE65H },
-		"F":  func(r *populationRecord) **int { return &r.popGE65F },
-	},
-	"Y_GE80": {
-		"_T": func(r *populationRecord) **int { return &r.popGE80 },
-		"M":  func(r *populationRecord) **int { return &r.popGE80H },
-		"F":  func(r *populationRecord) **int { return &r.popGE80F },
-	},
-	"_T": {
-		"_T": func(r *populationRecord) **int { return &r.pop },
-		"M":  func(r *populationRecord) **int { return &r.popH },
-		"F":  func(r *populationRecord) **int { return &r.popF },
-	},
-}
-
-// setPopulationValue assigns population to the correct field based on age and sex
-func setPopulationValue(record *populationRecord, age, sex string, population int) {
-	ageMap, ageExists := populationFieldMap[age]
-	if !ageExists {
-		panic(fmt.Sprintf("invalid age group %q for record: %v", age, *record))
-	}
-
-	selector, sexExists := ageMap[sex]
-	if !sexExists {
-		panic(fmt.Sprintf("invalid sex %q for age %q in record: %v", sex, age, *record))
-	}
-
-	field := selector(record)
-	*field = &population
+// sortPopulationRecords returns the records sorted by (code_commune, annee), for a deterministic lock
+// acquisition order that prevents deadlocks when multiple workers access the same keys
+func sortPopulationRecords(records map[string]*populationRecord) []*populationRecord {
+	sorted := make([]*populationRecord, 0, len(records))
+	for _, record := range records {
+		sorted = append(sorted, record)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].code != sorted[j].code {
+			return sorted[i].code < sorted[j].code
+		}
+		return sorted[i].annee < sorted[j].annee
+	})
+	return sorted
+}
+
+// check validates the record as the constraints of the population tables would
+func (r *populationRecord) check() error {
+	if r.code == "" {
+		return fmt.Errorf("missing code")
+	}
+	if r.annee < 1900 || r.annee > 2100 {
+		return fmt.Errorf("year %d out of range [1900, 2100]", r.annee)
+	}
+	for _, values := range r.populations {
+		for _, value := range values {
+			if value.Int != nil && value.Int.Sign() < 0 {
+				population, _ := value.Value()
+				return fmt.Errorf("negative population %v", population)
+			}
+		}
+	}
+	return nil
+}
+
+// figures returns the figures of the record by name of their column in the pivot
+func (r *populationRecord) figures(pivot *PivotSpec) map[string]*float64 {
+	figures := make(map[string]*float64, len(r.populations))
+	for i, column := range pivot.columns {
+		figures[column.Name] = r.figure(i)
+	}
+	return figures
+}
+
+// args returns the parameters of the insert statement, the figures of each column as a numeric array
+func (r *populationRecord) args(vintage model.Vintage) []any {
+	args := []any{r.code, r.annee}
+	for _, values := range r.populations {
+		if values == nil {
+			args = append(args, nil)
+			continue
+		}
+		args = append(args, values)
+	}
+	return append(args, int(vintage))
 }
 
 func (l *communePopulationRepository) Load(
 	ctx context.Context,
-	entities []entities.CommunePopulationPrincEntity) (int, error) {
+	batch []entities.CommunePopulationPrincEntity) (int, error) {
+
+	// Population figures are joined to the communes of the vintage of the run
+	vintage, ok := model.VintageFromContext(ctx)
+	if !ok {
+		return 0, fmt.Errorf("table %s is vintaged, the run must have a vintage", populationCommuneTable)
+	}
 
-	// Aggregate entities by commune/year
-	records := aggregatePopulationData(entities)
+	// Recoded entities are loaded by Finalize (see loadRecoded)
+	direct := make([]entities.CommunePopulationPrincEntity, 0, len(batch))
+	var arrondissements []entities.CommunePopulationPrincEntity
+	recoded := 0
+	rejects := make(map[string]int)
+	rejected := 0
+	for _, entity := range batch {
+		modalities := populationModalities(entity.Age, entity.Sexe)
+		if _, ok := l.pivot.column(modalities); !ok {
+			rejects[l.pivot.combination(modalities)]++
+			rejected++
+			continue
+		}
 
-	// Convert map to sorted slice to avoid deadlocks
-	// Sort by (code_commune, annee) to ensure deterministic lock acquisition order
-	sortedRecords := make([]*populationRecord, 0, len(records))
-	for _, record := range records {
-		sortedRecords = append(sortedRecords, record)
+		switch {
+		case entity.GeoObject == entities.GeoObjectArrondissementMunicipal:
+			arrondissements = append(arrondissements, entity)
+		case entity.CodeCommuneOrigine == "":
+			direct = append(direct, entity)
+		default:
+			l.mu.Lock()
+			l.recoded = append(l.recoded, entity)
+			l.mu.Unlock()
+			recoded++
+		}
 	}
-	// Sort by code_commune first, then by annee
-	sort.Slice(sortedRecords, func(i, j int) bool {
-		if sortedRecords[i].codeCommune != sortedRecords[j].codeCommune {
-			return sortedRecords[i].codeCommune < sortedRecords[j].codeCommune
+
+	l.rejects.add(rejects)
+	l.mu.Lock()
+	if l.counts == nil {
+		l.counts = make(map[string]int)
+	}
+	l.counts["entities_recoded"] += recoded
+	l.counts["entities_rejected"] += rejected
+	l.mu.Unlock()
+
+	// Aggregate entities by commune/year, then by arrondissement/year
+	communeRecords := sortPopulationRecords(aggregatePopulationData(l.pivot, direct))
+	armRecords := sortPopulationRecords(aggregatePopulationData(l.pivot, arrondissements))
+	sortedRecords := append(communeRecords, armRecords...)
+	communeStmt := populationUpsertSQL(l.pivot, l.targetTable(), "code_insee_commune", false)
+	armStmt := populationUpsertSQL(l.pivot, l.armTargetTable(), "code_insee_arm", false)
+
+	stmts := make([]statement, len(sortedRecords))
+	for i, record := range sortedRecords {
+		stmt := communeStmt
+		if i >= len(communeRecords) {
+			stmt = armStmt
 		}
-		return sortedRecords[i].annee < sortedRecords[j].annee
-	})
+		stmts[i] = statement{
+			sql:   stmt,
+			args:  record.args(vintage),
+			check: record.check,
+		}
+	}
 
-	// Begin transaction
-	tx, err := l.databaseManager.pool.Begin(ctx)
+	// Insert records in sorted order
+	// Sorting prevents deadlocks when multiple workers access same keys
+	rowErrors, err := l.databaseManager.loadBatch(ctx, stmts)
 	if err != nil {
 		return 0, err
 	}
-	defer func() { _ = tx.Rollback(ctx) }()
-
-	// Prepare statement with all columns
-	stmt := `
-		INSERT INTO demography.population_commune(
-			code_insee_commune, annee,
-			pop, pop_h, pop_f,
-			pop_LT15, pop_LT15_h, pop_LT15_f,
-			pop_LT20, pop_LT20_h, pop_LT20_f,
-			pop_15T24, pop_15T24_h, pop_15T24_f,
-			pop_20T64, pop_20T64_h, pop_20T64_f,
-			pop_25T39, pop_25T39_h, pop_25T39_f,
-			pop_40T54, pop_40T54_h, pop_40T54_f,
-			pop_55T64, pop_55T64_h, pop_55T64_f,
-			pop_65T79, pop_65T79_h, pop_65T79_f,
-			pop_GE65, pop_GE65_h, pop_GE65_f,
-			pop_GE80, pop_GE80_h, pop_GE80_f
-		)
-		VALUES (
-			$1, $2,
-			$3, $4, $5,
-			$6, $7, $8,
-			$9, $10, $11,
-			$12, $13, $14,
-			$15, $16, $17,
-			$18, $19, $20,
-			$21, $22, $23,
-			$24, $25, $26,
-			$27, $28, $29,
-			$30, $31, $32,
-			$33, $34, $35
-		)
-		ON CONFLICT (code_insee_commune, annee) DO UPDATE SET
-			pop = COALESCE(EXCLUDED.pop, population_commune.pop),
-			pop_h = COALESCE(EXCLUDED.pop_h, population_commune.pop_h),
-			pop_f = COALESCE(EXCLUDED.pop_f, population_commune.pop_f),
-			pop_LT15 = COALESCE(EXCLUDED.pop_LT15, population_commune.pop_LT15),
-			pop_LT15_h = COALESCE(EXCLUDED.pop_LT15_h, population_commune.pop_LT15_h),
-			pop_LT15_f = COALESCE(EXCLUDED.pop_LT15_f, population_commune.pop_LT15_f),
-			pop_LT20 = COALESCE(EXCLUDED.pop_LT20, population_commune.pop_LT20),
-			pop_LT20_h = COALESCE(EXCLUDED.pop_LT20_h, population_commune.pop_LT20_h),
-			pop_LT20_f = COALESCE(EXCLUDED.pop_LT20_f, population_commune.pop_LT20_f),
-			pop_15T24 = COALESCE(EXCLUDED.pop_15T24, population_commune.pop_15T24),
-			pop_15T24_h = COALESCE(EXCLUDED.pop_15T24_h, population_commune.pop_15T24_h),
-			pop_15T24_f = COALESCE(EXCLUDED.pop_15T24_f, population_commune.pop_15T24_f),
-			pop_20T64 = COALESCE(EXCLUDED.pop_20T64, population_commune.pop_20T64),
-			pop_20T64_h = COALESCE(EXCLUDED.pop_20T64_h, population_commune.pop_20T64_h),
-			pop_20T64_f = COALESCE(EXCLUDED.pop_20T64_f, population_commune.pop_20T64_f),
-			pop_25T39 = COALESCE(EXCLUDED.pop_25T39, population_commune.pop_25T39),
-			pop_25T39_h = COALESCE(EXCLUDED.pop_25T39_h, population_commune.pop_25T39_h),
-			pop_25T39_f = COALESCE(EXCLUDED.pop_25T39_f, population_commune.pop_25T39_f),
-			pop_40T54 = COALESCE(EXCLUDED.pop_40T54, population_commune.pop_40T54),
-			pop_40T54_h = COALESCE(EXCLUDED.pop_40T54_h, population_commune.pop_40T54_h),
-			pop_40T54_f = COALESCE(EXCLUDED.pop_40T54_f, population_commune.pop_40T54_f),
-			pop_55T64 = COALESCE(EXCLUDED.pop_55T64, population_commune.pop_55T64),
-			pop_55T64_h = COALESCE(EXCLUDED.pop_55T64_h, population_commune.pop_55T64_h),
-			pop_55T64_f = COALESCE(EXCLUDED.pop_55T64_f, population_commune.pop_55T64_f),
-			pop_65T79 = COALESCE(EXCLUDED.pop_65T79, population_commune.pop_65T79),
-			pop_65T79_h = COALESCE(EXCLUDED.pop_65T79_h, population_commune.pop_65T79_h),
-			pop_65T79_f = COALESCE(EXCLUDED.pop_65T79_f, population_commune.pop_65T79_f),
-			pop_GE65 = COALESCE(EXCLUDED.pop_GE65, population_commune.pop_GE65),
-			pop_GE65_h = COALESCE(EXCLUDED.pop_GE65_h, population_commune.pop_GE65_h),
-			pop_GE65_f = COALESCE(EXCLUDED.pop_GE65_f, population_commune.pop_GE65_f),
-			pop_GE80 = COALESCE(EXCLUDED.pop_GE80, population_commune.pop_GE80),
-			pop_GE80_h = COALESCE(EXCLUDED.pop_GE80_h, population_commune.pop_GE80_h),
-			pop_GE80_f = COALESCE(EXCLUDED.pop_GE80_f, population_commune.pop_GE80_f)
-	`
 
 	count := 0
 	failed := 0
 	failedEntityCount := 0
-
-	// Insert records in sorted order with savepoints
-	// Sorting prevents deadlocks when multiple workers access same keys
-	for i, record := range sortedRecords {
-		// Create savepoint before each insert to isolate errors
-		savepoint := fmt.Sprintf("sp_%d", i)
-		if _, err := tx.Exec(ctx, fmt.Sprintf("SAVEPOINT %s", savepoint)); err != nil {
-			slog.Error("Error creating savepoint", "error", err)
+	for i, rowErr := range rowErrors {
+		record := sortedRecords[i]
+		if rowErr != nil {
+			slog.Error("Insert error", "entity", "population", "commune", record.code, "year", record.annee, "error", rowErr)
 			failed++
 			failedEntityCount += record.entityCount
 			continue
 		}
 
-		// Insert record
-		_, err = tx.Exec(ctx, stmt,
-			record.codeCommune, record.annee,
-			record.pop, record.popH, record.popF,
-			record.popLT15, record.popLT15H, record.popLT15F,
-			record.popLT20, record.popLT20H, record.popLT20F,
-			record.pop15T24, record.pop15T24H, record.pop15T24F,
-			record.pop20T64, record.pop20T64H, record.pop20T64F,
-			record.pop25T39, record.pop25T39H, record.pop25T39F,
-			record.pop40T54, record.pop40T54H, record.pop40T54F,
-			record.pop55T64, record.pop55T64H, record.pop55T64F,
-			record.pop65T79, record.pop65T79H, record.pop65T79F,
-			record.popGE65, record.popGE65H, record.popGE65F,
-			record.popGE80, record.popGE80H, record.popGE80F,
-		)
-		if err != nil {
-			slog.Error("Insert error", "entity", "population", "commune", record.codeCommune, "year", record.annee, "error", err)
-			// Rollback to savepoint to continue with other inserts
-			if _, rbErr := tx.Exec(ctx, fmt.Sprintf("ROLLBACK TO SAVEPOINT %s", savepoint)); rbErr != nil {
-				slog.Error("Rollback to savepoint failed", "error", rbErr)
-			}
-			failed++
-			failedEntityCount += record.entityCount
-			continue
-		}
-
-		// Release savepoint on success
-		if _, err := tx.Exec(ctx, fmt.Sprintf("RELEASE SAVEPOINT %s", savepoint)); err != nil {
-			slog.Warn("Release savepoint warning", "error", err)
-		}
-
 		// Count all entities that contributed to this successfully inserted record
 		count += record.entityCount
-	}
-
-	if err := tx.Commit(ctx); err != nil {
-		return 0, err
+		if i < len(communeRecords) {
+			l.keep(l.figures, record, false)
+		} else {
+			l.keep(l.armFigures, record, false)
+		}
+		l.mu.Lock()
+		if i < len(communeRecords) {
+			l.loaded[populationKey(record.code, record.annee)] = true
+		} else {
+			l.armLoaded = true
+		}
+		l.mu.Unlock()
 	}
 
 	slog.Debug("Population data loaded",
-		"input_entities", len(entities),
-		"aggregated_records", len(records),
-		"records_inserted", len(records)-failed,
+		"input_entities", len(batch),
+		"recoded_entities", recoded,
+		"rejected_entities", rejected,
+		"arrondissement_entities", len(arrondissements),
+		"aggregated_records", len(sortedRecords),
+		"records_inserted", len(sortedRecords)-failed,
 		"records_failed", failed,
 		"entities_loaded", count,
 		"entities_failed", failedEntityCount)
 
-	return count, nil
+	// The entities set aside are not failures: they are counted apart, see Counts
+	return count + recoded + rejected, nil
 }
@@ -0,0 +1,349 @@
+package repository
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"french-admin-etl/internal/infrastructure/entities"
+	"french-admin-etl/internal/model"
+	"french-admin-etl/internal/validation"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// TestCommunePopulationRepository_Load_DryRun tests that a dry run counts the entities of every valid aggregated record
+func TestCommunePopulationRepository_Load_DryRun(t *testing.T) {
+	repository := NewCommunePopulationRepository(NewDryRunDatabaseManager())
+
+	rows := []entities.CommunePopulationPrincEntity{
+		{CodeCommune: "33063", Annee: 2022, Age: "_T", Sexe: "_T", Population: numeric("261804")},
+		{CodeCommune: "33063", Annee: 2022, Age: "_T", Sexe: "M", Population: numeric("123456")},
+		{CodeCommune: "33063", Annee: 2022, Age: "Y_LT15", Sexe: "_T", Population: numeric("35000")},
+		{CodeCommune: "", Annee: 2022, Age: "_T", Sexe: "_T", Population: numeric("10")},
+		{CodeCommune: "75056", Annee: 1850, Age: "_T", Sexe: "_T", Population: numeric("10")},
+		{CodeCommune: "69123", Annee: 2022, Age: "Y_GE80", Sexe: "F", Population: numeric("-1")},
+	}
+
+	count, err := repository.Load(model.WithVintage(context.Background(), 2024), rows)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 loaded entities, got %d", count)
+	}
+}
+
+// TestCommunePopulationRepository_Recoded_DryRun tests that recoded entities are handled by Load without
+// failing, counted apart as they are loaded by Finalize
+func TestCommunePopulationRepository_Recoded_DryRun(t *testing.T) {
+	repository := NewCommunePopulationRepository(NewDryRunDatabaseManager())
+	ctx := model.WithVintage(context.Background(), 2024)
+
+	rows := []entities.CommunePopulationPrincEntity{
+		{CodeCommune: "01001", Annee: 2016, Age: "_T", Sexe: "_T", Population: numeric("100")},
+		{CodeCommune: "01001", Annee: 2016, Age: "_T", Sexe: "_T", Population: numeric("50"), CodeCommuneOrigine: "01002"},
+	}
+
+	count, err := repository.Load(ctx, rows)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 handled entities, the recoded one being deferred, got %d", count)
+	}
+	if err := repository.(model.LoadFinalizer).Finalize(ctx); err != nil {
+		t.Fatalf("Finalize() error = %v", err)
+	}
+	expected := map[string]int{"entities_recoded": 1, "entities_rejected": 0}
+	if counts := repository.(model.LoadCounter).Counts(); !reflect.DeepEqual(counts, expected) {
+		t.Errorf("Expected counts %v, got %v", expected, counts)
+	}
+}
+
+// TestCommunePopulationRepository_Arrondissements_DryRun tests that arrondissement municipal entities are
+// loaded apart from the communes, the roll-up being skipped by a dry run
+func TestCommunePopulationRepository_Arrondissements_DryRun(t *testing.T) {
+	repository := NewCommunePopulationRepository(NewDryRunDatabaseManager())
+	ctx := model.WithVintage(context.Background(), 2024)
+
+	rows := []entities.CommunePopulationPrincEntity{
+		{CodeCommune: "75056", GeoObject: entities.GeoObjectCommune, Annee: 2022, Age: "_T", Sexe: "_T", Population: numeric("2113705")},
+		{CodeCommune: "75101", GeoObject: entities.GeoObjectArrondissementMunicipal, Annee: 2022, Age: "_T", Sexe: "_T", Population: numeric("15917")},
+		{CodeCommune: "75102", GeoObject: entities.GeoObjectArrondissementMunicipal, Annee: 2022, Age: "_T", Sexe: "_T", Population: numeric("20900")},
+	}
+
+	count, err := repository.Load(ctx, rows)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 loaded entities, got %d", count)
+	}
+
+	population := repository.(*communePopulationRepository)
+	if !population.armLoaded || len(population.loaded) != 1 {
+		t.Errorf("Expected the arrondissements apart from the commune, got armLoaded=%v, loaded=%v", population.armLoaded, population.loaded)
+	}
+	if err := population.Finalize(ctx); err != nil {
+		t.Fatalf("Finalize() error = %v", err)
+	}
+}
+
+// TestCommunePopulationRepository_Rejects_DryRun tests that figures whose age/sex has no column in the
+// pivot are rejected and reported, not loaded
+func TestCommunePopulationRepository_Rejects_DryRun(t *testing.T) {
+	repository := NewCommunePopulationRepository(NewDryRunDatabaseManager())
+	ctx := model.WithVintage(context.Background(), 2024)
+
+	rows := []entities.CommunePopulationPrincEntity{
+		{CodeCommune: "33063", Annee: 2022, Age: "_T", Sexe: "_T", Population: numeric("261804")},
+		{CodeCommune: "33063", Annee: 2022, Age: "Y_LT05", Sexe: "_T", Population: numeric("12000")},
+		{CodeCommune: "33064", Annee: 2022, Age: "Y_LT05", Sexe: "_T", Population: numeric("100")},
+		{CodeCommune: "33063", Annee: 2022, Age: "_T", Sexe: "X", Population: numeric("1")},
+	}
+
+	count, err := repository.Load(ctx, rows)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if count != 4 {
+		t.Errorf("Expected 4 handled entities, the rejected ones not failing, got %d", count)
+	}
+
+	population := repository.(*communePopulationRepository)
+	if counts := population.Counts(); counts["entities_rejected"] != 3 {
+		t.Errorf("Expected 3 rejected entities, got %v", counts)
+	}
+	expected := map[string]int{"AGE=Y_LT05,SEX=_T": 2, "AGE=_T,SEX=X": 1}
+	if !reflect.DeepEqual(population.rejects.counts, expected) {
+		t.Errorf("Expected rejects %v, got %v", expected, population.rejects.counts)
+	}
+	if err := population.Finalize(ctx); err != nil {
+		t.Fatalf("Finalize() error = %v", err)
+	}
+	if population.rejects.counts != nil {
+		t.Errorf("Expected the rejects to be reset by Finalize, got %v", population.rejects.counts)
+	}
+}
+
+// TestCommunePopulationRepository_WithValidation_DryRun tests that the records loaded are checked by Finalize,
+// which fails when the violations exceed the threshold of the validator
+func TestCommunePopulationRepository_WithValidation_DryRun(t *testing.T) {
+	report := filepath.Join(t.TempDir(), "violations.csv")
+	validator := validation.NewValidator(validation.PopulationRules(DefaultPopulationPivot().PopulationColumns()), validation.DefaultTolerance,
+		validation.WithMaxViolationRatio(0.4), validation.WithReport(report))
+	repository := NewCommunePopulationRepository(NewDryRunDatabaseManager(), WithValidation(validator))
+	ctx := model.WithVintage(context.Background(), 2024)
+
+	rows := []entities.CommunePopulationPrincEntity{
+		{CodeCommune: "01001", Annee: 2022, Age: "_T", Sexe: "_T", Population: numeric("1000")},
+		{CodeCommune: "01001", Annee: 2022, Age: "_T", Sexe: "M", Population: numeric("490")},
+		{CodeCommune: "01001", Annee: 2022, Age: "_T", Sexe: "F", Population: numeric("510")},
+		{CodeCommune: "01002", Annee: 2022, Age: "_T", Sexe: "_T", Population: numeric("1000")},
+		{CodeCommune: "01002", Annee: 2022, Age: "_T", Sexe: "M", Population: numeric("400")},
+		{CodeCommune: "01002", Annee: 2022, Age: "_T", Sexe: "F", Population: numeric("510")},
+	}
+
+	if _, err := repository.Load(ctx, rows); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if violations := validator.Violations(); len(violations) != 0 {
+		t.Errorf("Expected no violation before Finalize, got %+v", violations)
+	}
+	err := repository.(model.LoadFinalizer).Finalize(ctx)
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 commune records") {
+		t.Errorf("Expected Finalize() to fail above the threshold with 1 of 2 records, got %v", err)
+	}
+	content, readErr := os.ReadFile(report)
+	if readErr != nil {
+		t.Fatalf("ReadFile() error = %v", readErr)
+	}
+	if !strings.Contains(string(content), "01002") || !strings.Contains(string(content), "pop_h + pop_f = pop") || strings.Contains(string(content), "01001") {
+		t.Errorf("Expected a violation of pop_h + pop_f = pop by 01002 only, got %s", content)
+	}
+	if len(validator.Violations()) != 0 {
+		t.Error("Expected the validator to be reset by Finalize")
+	}
+}
+
+// TestCommunePopulationRepository_WithValidation_SeveralBatches tests that the figures of a commune spread
+// over several batches are checked once, all together
+func TestCommunePopulationRepository_WithValidation_SeveralBatches(t *testing.T) {
+	report := filepath.Join(t.TempDir(), "violations.csv")
+	validator := validation.NewValidator(validation.PopulationRules(DefaultPopulationPivot().PopulationColumns()), validation.DefaultTolerance,
+		validation.WithMaxViolationRatio(0.4), validation.WithReport(report))
+	repository := NewCommunePopulationRepository(NewDryRunDatabaseManager(), WithValidation(validator))
+	ctx := model.WithVintage(context.Background(), 2024)
+
+	batches := [][]entities.CommunePopulationPrincEntity{
+		{
+			{CodeCommune: "01001", Annee: 2022, Age: "_T", Sexe: "_T", Population: numeric("1000")},
+			{CodeCommune: "01002", Annee: 2022, Age: "_T", Sexe: "_T", Population: numeric("300")},
+			{CodeCommune: "01002", Annee: 2022, Age: "_T", Sexe: "M", Population: numeric("150")},
+		},
+		{
+			{CodeCommune: "01001", Annee: 2022, Age: "_T", Sexe: "M", Population: numeric("400")},
+			{CodeCommune: "01002", Annee: 2022, Age: "_T", Sexe: "F", Population: numeric("150")},
+		},
+		{
+			{CodeCommune: "01001", Annee: 2022, Age: "_T", Sexe: "F", Population: numeric("510")},
+		},
+	}
+	for _, batch := range batches {
+		if _, err := repository.Load(ctx, batch); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+	}
+
+	// Checked by fragment, 01001 would be skipped, each batch lacking one of the figures of the rule, and
+	// counted five times
+	err := repository.(model.LoadFinalizer).Finalize(ctx)
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 commune records") {
+		t.Errorf("Expected Finalize() to fail with 1 of 2 records, got %v", err)
+	}
+	content, readErr := os.ReadFile(report)
+	if readErr != nil {
+		t.Fatalf("ReadFile() error = %v", readErr)
+	}
+	if !strings.Contains(string(content), "01001") || strings.Contains(string(content), "01002") {
+		t.Errorf("Expected a violation by 01001 only, got %s", content)
+	}
+}
+
+// TestCommunePopulationRepository_WithPivot tests that the statements follow the columns of the pivot
+func TestCommunePopulationRepository_WithPivot(t *testing.T) {
+	pivot, err := NewPivotSpec(PopulationDimensions, []PivotColumn{
+		{Name: "pop", Modalities: map[string]string{"AGE": "_T", "SEX": "_T"}},
+		{Name: "pop_LT05", Modalities: map[string]string{"AGE": "Y_LT05", "SEX": "_T"}},
+	})
+	if err != nil {
+		t.Fatalf("NewPivotSpec() error = %v", err)
+	}
+
+	repository := NewCommunePopulationRepository(NewDryRunDatabaseManager(), WithPivot(pivot))
+	rows := []entities.CommunePopulationPrincEntity{
+		{CodeCommune: "33063", Annee: 2022, Age: "_T", Sexe: "_T", Population: numeric("261804")},
+		{CodeCommune: "33063", Annee: 2022, Age: "Y_LT05", Sexe: "_T", Population: numeric("12000")},
+		{CodeCommune: "33063", Annee: 2022, Age: "Y_LT15", Sexe: "_T", Population: numeric("35000")},
+	}
+	count, err := repository.Load(model.WithVintage(context.Background(), 2024), rows)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 handled entities, got %d", count)
+	}
+	if counts := repository.(model.LoadCounter).Counts(); counts["entities_rejected"] != 1 {
+		t.Errorf("Expected the Y_LT15 entity to be rejected, got %v", counts)
+	}
+
+	upsert := populationUpsertSQL(pivot, populationCommuneTable, "code_insee_commune", false)
+	if !strings.Contains(upsert, "(code_insee_commune, annee, pop, pop_LT05, millesime)") || !strings.Contains(upsert, "unnest($4::numeric[]) AS v), $5)") {
+		t.Errorf("Unexpected upsert SQL for the pivot:\n%s", upsert)
+	}
+}
+
+// TestSumPopulationData tests that the figures of communes merged into the same commune are summed
+func TestSumPopulationData(t *testing.T) {
+	pivot := DefaultPopulationPivot()
+	records := sumPopulationData(pivot, []entities.CommunePopulationPrincEntity{
+		{CodeCommune: "01001", Annee: 2016, Age: "_T", Sexe: "_T", Population: numeric("100"), CodeCommuneOrigine: "01002"},
+		{CodeCommune: "01001", Annee: 2016, Age: "_T", Sexe: "_T", Population: numeric("50"), CodeCommuneOrigine: "01003"},
+		{CodeCommune: "01001", Annee: 2016, Age: "_T", Sexe: "F", Population: numeric("30"), CodeCommuneOrigine: "01003"},
+	})
+
+	record := records[populationKey("01001", 2016)]
+	if pop := figure(pivot, record, "pop"); pop == nil || *pop != 150 {
+		t.Fatalf("Expected a total population of 150, got %+v", record)
+	}
+	if popF := figure(pivot, record, "pop_f"); popF == nil || *popF != 30 {
+		t.Errorf("Expected a female population of 30, got %v", popF)
+	}
+	if popH := figure(pivot, record, "pop_h"); popH != nil {
+		t.Errorf("Expected no male population, got %g", *popH)
+	}
+}
+
+// TestSumPopulationData_Decimals tests that the decimals of the weighted estimates are kept by the sums
+func TestSumPopulationData_Decimals(t *testing.T) {
+	pivot := DefaultPopulationPivot()
+	records := sumPopulationData(pivot, []entities.CommunePopulationPrincEntity{
+		{CodeCommune: "01001", Annee: 2016, Age: "Y_GE80", Sexe: "_T", Population: numeric("34.25"), CodeCommuneOrigine: "01002"},
+		{CodeCommune: "01001", Annee: 2016, Age: "Y_GE80", Sexe: "_T", Population: numeric("8.5"), CodeCommuneOrigine: "01003"},
+	})
+
+	if popGE80 := figure(pivot, records[populationKey("01001", 2016)], "pop_GE80"); popGE80 == nil || *popGE80 != 42.75 {
+		t.Errorf("Expected a population of 42.75, got %v", popGE80)
+	}
+}
+
+// TestSumPopulationData_Exact tests that the figures of merged communes reach the database as exact decimals,
+// summed by the insert statement: 0.1 + 0.2 is not 0.3 in floating point
+func TestSumPopulationData_Exact(t *testing.T) {
+	pivot := DefaultPopulationPivot()
+	records := sumPopulationData(pivot, []entities.CommunePopulationPrincEntity{
+		{CodeCommune: "01001", Annee: 2016, Age: "_T", Sexe: "_T", Population: numeric("0.1"), CodeCommuneOrigine: "01002"},
+		{CodeCommune: "01001", Annee: 2016, Age: "_T", Sexe: "_T", Population: numeric("0.2"), CodeCommuneOrigine: "01003"},
+	})
+
+	args := records[populationKey("01001", 2016)].args(2024)
+	values, ok := args[2].([]pgtype.Numeric)
+	if !ok || len(values) != 2 {
+		t.Fatalf("Expected the two figures of pop as a numeric array, got %#v", args[2])
+	}
+	var sum big.Rat
+	for _, value := range values {
+		text, err := value.Value()
+		if err != nil {
+			t.Fatalf("Value() error = %v", err)
+		}
+		figure, _ := new(big.Rat).SetString(text.(string))
+		sum.Add(&sum, figure)
+	}
+	if want := big.NewRat(3, 10); sum.Cmp(want) != 0 {
+		t.Errorf("Expected an exact sum of 0.3, got %s", sum.FloatString(20))
+	}
+	if args[3] != nil {
+		t.Errorf("Expected NULL for the absent pop_h, got %#v", args[3])
+	}
+}
+
+// TestPopulationUpsertSQL tests that merged figures are added to the stored ones
+func TestPopulationUpsertSQL(t *testing.T) {
+	replace := populationUpsertSQL(DefaultPopulationPivot(), populationCommuneTable, "code_insee_commune", false)
+	if !strings.Contains(replace, "pop_GE80_f = COALESCE(EXCLUDED.pop_GE80_f, target.pop_GE80_f)") {
+		t.Errorf("Unexpected replace SQL:\n%s", replace)
+	}
+	if !strings.Contains(replace, "VALUES ($1, $2, (SELECT sum(v) FROM unnest($3::numeric[]) AS v)") || !strings.Contains(replace, "$35::numeric[]) AS v), $36)") {
+		t.Errorf("Expected the millésime as last parameter, got:\n%s", replace)
+	}
+
+	merge := populationUpsertSQL(DefaultPopulationPivot(), populationCommuneTable, "code_insee_commune", true)
+	if !strings.Contains(merge, "pop = COALESCE(target.pop + EXCLUDED.pop, EXCLUDED.pop, target.pop)") {
+		t.Errorf("Unexpected merge SQL:\n%s", merge)
+	}
+
+	arrondissement := populationUpsertSQL(DefaultPopulationPivot(), populationArmTable, "code_insee_arm", false)
+	if !strings.Contains(arrondissement, "ON CONFLICT (code_insee_arm, annee, millesime)") {
+		t.Errorf("Expected the arrondissement code as key, got:\n%s", arrondissement)
+	}
+}
+
+// TestPopulationRollUpSQL tests that the arrondissement figures only fill the missing commune figures
+func TestPopulationRollUpSQL(t *testing.T) {
+	rollUp := populationRollUpSQL(DefaultPopulationPivot(), populationCommuneTable, populationArmTable)
+	for _, expected := range []string{
+		"CASE WHEN count(p.pop_h) = count(*) THEN sum(p.pop_h) END",
+		"GROUP BY a.code_insee_commune, p.annee, p.millesime",
+		"pop_h = COALESCE(target.pop_h, EXCLUDED.pop_h)",
+	} {
+		if !strings.Contains(rollUp, expected) {
+			t.Errorf("Expected %q in roll-up SQL:\n%s", expected, rollUp)
+		}
+	}
+}
@@ -14,9 +14,11 @@ import (
 
 // DatabaseManager manage connections to the database and provides utility methods for health checks and stats
 type DatabaseManager struct {
-	db     *sql.DB
-	config *config.PostgresDatabase
-	pool   *pgxpool.Pool
+	db           *sql.DB
+	config       *config.PostgresDatabase
+	pool         *pgxpool.Pool
+	loadStrategy LoadStrategy
+	dryRun       bool
 }
 
 // DatabaseManagerOption is a configuration function
@@ -29,6 +31,17 @@ func WithMigrations(migrationsPath string) DatabaseManagerOption {
 	}
 }
 
+// WithLoadStrategy is an option to select how repositories send the rows of a batch (pipeline by default)
+func WithLoadStrategy(strategy LoadStrategy) DatabaseManagerOption {
+	return func(dm *DatabaseManager) error {
+		if _, err := ParseLoadStrategy(string(strategy)); err != nil {
+			return err
+		}
+		dm.loadStrategy = strategy
+		return nil
+	}
+}
+
 // NewDatabaseManager creates a new database manager
 func NewDatabaseManager(config *config.Config, opts ...DatabaseManagerOption) (*DatabaseManager, error) {
 	if config == nil {
@@ -86,9 +99,10 @@ func NewDatabaseManager(config *config.Config, opts ...DatabaseManagerOption) (*
 	}
 
 	dm := &DatabaseManager{
-		db:     db,
-		config: &config.PostgresDatabase,
-		pool:   pool,
+		db:           db,
+		config:       &config.PostgresDatabase,
+		pool:         pool,
+		loadStrategy: PipelineStrategy,
 	}
 
 	// Apply options
@@ -102,6 +116,20 @@ func NewDatabaseManager(config *config.Config, opts ...DatabaseManagerOption) (*
 	return dm, nil
 }
 
+// NewDryRunDatabaseManager creates a database manager that doesn't connect to any database.
+// Repositories using it validate the rows of each batch and report them as a real run would, without writing anything.
+func NewDryRunDatabaseManager() *DatabaseManager {
+	return &DatabaseManager{
+		loadStrategy: PipelineStrategy,
+		dryRun:       true,
+	}
+}
+
+// DryRun reports whether the database manager is a dry-run one.
+func (dm *DatabaseManager) DryRun() bool {
+	return dm.dryRun
+}
+
 // GetDB retourne l'instance *sql.DB
 func (dm *DatabaseManager) GetDB() *sql.DB {
 	return dm.db
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"french-admin-etl/internal/model"
+)
+
+// DissolveLevel is a level above the communes whose geometries can be derived from those of its communes.
+type DissolveLevel string
+
+const (
+	// DissolveRegion derives the régions from the code_insee_region of their communes.
+	DissolveRegion DissolveLevel = "region"
+	// DissolveDepartement derives the départements from the code_insee_departement of their communes.
+	DissolveDepartement DissolveLevel = "departement"
+	// DissolveEPCI derives the EPCI from the code_insee_epci of their communes.
+	DissolveEPCI DissolveLevel = "epci"
+)
+
+// dissolveSource describes how the features of a level are built from ref_admin.communes.
+type dissolveSource struct {
+	table      string // table of the level, whose rows name the features
+	codeColumn string // code of the level, in the table and in ref_admin.communes
+	nameColumn string
+	cogTable   string // COG table naming the features absent from the table, empty when none
+	withRegion bool   // whether the features have the région of the level, as the départements
+}
+
+// dissolveSources maps the levels to their source.
+var dissolveSources = map[DissolveLevel]dissolveSource{
+	DissolveRegion:      {table: "ref_admin.regions", codeColumn: "code_insee_region", nameColumn: "nom_region", cogTable: "ref_admin.cog_regions"},
+	DissolveDepartement: {table: "ref_admin.departements", codeColumn: "code_insee_departement", nameColumn: "nom_departement", cogTable: "ref_admin.cog_departements", withRegion: true},
+	DissolveEPCI:        {table: "ref_admin.epci", codeColumn: "code_insee_epci", nameColumn: "nom_epci"},
+}
+
+// dissolveSQL generates the query dissolving, for the vintage $1, the geometries of the communes in the column
+// by code of the level. It returns the code, the name of the level, from its table or else from the COG, and
+// the GeoJSON feature with the properties of the files of the level (code, nom and, for the départements,
+// region), so that it is loaded by the same mapper and repository.
+func (s dissolveSource) dissolveSQL(column string) string {
+	name := "p." + s.nameColumn
+	joins := fmt.Sprintf("LEFT JOIN %s p ON p.%[2]s = d.code AND p.millesime = $1 AND p.supprime_le IS NULL", s.table, s.codeColumn)
+	if s.cogTable != "" {
+		name = fmt.Sprintf("coalesce(%s, cog.libelle)", name)
+		joins += fmt.Sprintf("\n\t\tLEFT JOIN %s cog ON cog.%[2]s = d.code AND cog.millesime = $1 AND cog.supprime_le IS NULL", s.cogTable, s.codeColumn)
+	}
+	properties := fmt.Sprintf("'code', d.code, 'nom', %s", name)
+	if s.withRegion {
+		region := "coalesce(p.code_insee_region, d.region)"
+		if s.cogTable != "" {
+			region = "coalesce(p.code_insee_region, cog.code_insee_region, d.region)"
+		}
+		properties += ", 'region', " + region
+	}
+
+	return fmt.Sprintf(`
+		WITH dissolved AS (
+			SELECT c.%[1]s AS code, min(c.code_insee_region) AS region,
+				ST_AsGeoJSON(ST_Multi(ST_CollectionExtract(ST_Union(c.%[2]s::geometry), 3))) AS geometry
+			FROM ref_admin.communes c
+			WHERE c.millesime = $1 AND c.supprime_le IS NULL AND c.%[2]s IS NOT NULL AND c.%[1]s IS NOT NULL
+			GROUP BY c.%[1]s
+		)
+		SELECT d.code, %[3]s,
+			json_build_object('type', 'Feature', 'properties', json_build_object(%[4]s), 'geometry', d.geometry::json)
+		FROM dissolved d
+		%[5]s
+		ORDER BY d.code`, s.codeColumn, column, name, properties, joins)
+}
+
+// DissolveCommunes writes to w, as a GeoJSON FeatureCollection, the geometries of the level for the vintage
+// derived by dissolving the commune geometries of the column of the resolution by their code of the level.
+// Loaded with the processor, mapper and repository of the level, they nest the hierarchy exactly in its
+// communes. Codes without name, neither in the table of the level nor in the COG, are skipped. It returns
+// the number of features written, none in dry run.
+func DissolveCommunes(ctx context.Context, dm *DatabaseManager, level DissolveLevel, vintage model.Vintage, resolution model.Resolution, w io.Writer) (int, error) {
+	source, ok := dissolveSources[level]
+	if !ok {
+		return 0, fmt.Errorf("invalid dissolve level %q, must be region, departement or epci", level)
+	}
+
+	if _, err := io.WriteString(w, `{"type":"FeatureCollection","features":[`); err != nil {
+		return 0, err
+	}
+	written := 0
+	if dm.dryRun {
+		slog.Info("Dissolve skipped in dry run", "level", level)
+	} else {
+		rows, err := dm.pool.Query(ctx, source.dissolveSQL(resolution.GeometryColumn("geom")), int(vintage))
+		if err != nil {
+			return 0, fmt.Errorf("error dissolving the communes into %s: %w", source.table, err)
+		}
+		defer rows.Close()
+		if written, err = writeFeatures(w, rows, source.table); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := io.WriteString(w, "]}\n"); err != nil {
+		return 0, err
+	}
+
+	slog.Info("Communes dissolved", "table", source.table, "millesime", int(vintage), "features", written)
+	return written, nil
+}
+
+// featureRows is the part of pgx.Rows read by writeFeatures.
+type featureRows interface {
+	Next() bool
+	Scan(dest ...any) error
+	Err() error
+}
+
+// writeFeatures writes to w the features of the rows of dissolveSQL, separated by commas, skipping those
+// without name. It returns the number of features written.
+func writeFeatures(w io.Writer, rows featureRows, table string) (int, error) {
+	written := 0
+	for rows.Next() {
+		var code string
+		var name *string
+		var feature []byte
+		if err := rows.Scan(&code, &name, &feature); err != nil {
+			return 0, fmt.Errorf("error reading the dissolved %s: %w", table, err)
+		}
+		if name == nil {
+			slog.Warn("Dissolved geometry without name skipped", "table", table, "code", code)
+			continue
+		}
+		if written > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return 0, err
+			}
+		}
+		if _, err := w.Write(feature); err != nil {
+			return 0, err
+		}
+		written++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error dissolving the communes into %s: %w", table, err)
+	}
+	return written, nil
+}
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"french-admin-etl/internal/model"
+)
+
+// TestDissolveSQL tests the queries dissolving the communes by code of each level
+func TestDissolveSQL(t *testing.T) {
+	tests := []struct {
+		level   DissolveLevel
+		want    []string
+		notWant []string
+	}{
+		{
+			level: DissolveRegion,
+			want: []string{
+				"SELECT c.code_insee_region AS code",
+				"ST_Union(c.geom_5m::geometry)",
+				"'code', d.code, 'nom', coalesce(p.nom_region, cog.libelle)",
+				"LEFT JOIN ref_admin.cog_regions cog ON cog.code_insee_region = d.code",
+			},
+			notWant: []string{"'region'"},
+		},
+		{
+			level: DissolveDepartement,
+			want: []string{
+				"GROUP BY c.code_insee_departement",
+				"'region', coalesce(p.code_insee_region, cog.code_insee_region, d.region)",
+			},
+		},
+		{
+			level:   DissolveEPCI,
+			want:    []string{"LEFT JOIN ref_admin.epci p ON p.code_insee_epci = d.code", "'nom', p.nom_epci"},
+			notWant: []string{"cog"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.level), func(t *testing.T) {
+			sql := dissolveSources[tt.level].dissolveSQL("geom_5m")
+			for _, want := range tt.want {
+				if !strings.Contains(sql, want) {
+					t.Errorf("dissolveSQL() missing %q in %s", want, sql)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(sql, notWant) {
+					t.Errorf("dissolveSQL() unexpected %q in %s", notWant, sql)
+				}
+			}
+		})
+	}
+}
+
+// TestDissolveCommunes_DryRun tests that a dry run writes an empty FeatureCollection
+func TestDissolveCommunes_DryRun(t *testing.T) {
+	var buf bytes.Buffer
+	written, err := DissolveCommunes(context.Background(), NewDryRunDatabaseManager(), DissolveDepartement, 2024, model.DefaultResolution, &buf)
+	if err != nil || written != 0 {
+		t.Fatalf("DissolveCommunes() = %d, %v", written, err)
+	}
+
+	var collection struct {
+		Type     string            `json:"type"`
+		Features []json.RawMessage `json:"features"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &collection); err != nil {
+		t.Fatalf("Invalid GeoJSON %q: %v", buf.String(), err)
+	}
+	if collection.Type != "FeatureCollection" || len(collection.Features) != 0 {
+		t.Errorf("Expected an empty FeatureCollection, got %s", buf.String())
+	}
+
+	if _, err := DissolveCommunes(context.Background(), NewDryRunDatabaseManager(), "canton", 2024, model.DefaultResolution, &buf); err == nil {
+		t.Error("Expected error for unknown level")
+	}
+}
+
+// fakeFeatureRows returns the code, name and feature of each of its rows.
+type fakeFeatureRows struct {
+	rows [][3]any
+	next int
+	err  error
+}
+
+func (r *fakeFeatureRows) Next() bool {
+	r.next++
+	return r.next <= len(r.rows)
+}
+
+func (r *fakeFeatureRows) Scan(dest ...any) error {
+	row := r.rows[r.next-1]
+	*dest[0].(*string) = row[0].(string)
+	*dest[1].(**string) = row[1].(*string)
+	*dest[2].(*[]byte) = row[2].([]byte)
+	return nil
+}
+
+func (r *fakeFeatureRows) Err() error {
+	return r.err
+}
+
+// TestWriteFeatures tests that the features are separated by commas, those without name being skipped
+func TestWriteFeatures(t *testing.T) {
+	name := func(s string) *string { return &s }
+	feature := func(code string) []byte {
+		return []byte(`{"type":"Feature","properties":{"code":"` + code + `"},"geometry":null}`)
+	}
+	tests := []struct {
+		name string
+		rows [][3]any
+		want []string
+	}{
+		{name: "empty"},
+		{name: "single", rows: [][3]any{{"01", name("Ain"), feature("01")}}, want: []string{"01"}},
+		{
+			name: "nameless first",
+			rows: [][3]any{{"00", (*string)(nil), feature("00")}, {"01", name("Ain"), feature("01")}, {"02", name("Aisne"), feature("02")}},
+			want: []string{"01", "02"},
+		},
+		{
+			name: "nameless between",
+			rows: [][3]any{{"01", name("Ain"), feature("01")}, {"00", (*string)(nil), feature("00")}, {"02", name("Aisne"), feature("02")}},
+			want: []string{"01", "02"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			buf.WriteString(`{"type":"FeatureCollection","features":[`)
+			written, err := writeFeatures(&buf, &fakeFeatureRows{rows: tt.rows}, "ref_admin.departements")
+			if err != nil {
+				t.Fatalf("writeFeatures() error = %v", err)
+			}
+			buf.WriteString("]}")
+			if written != len(tt.want) {
+				t.Errorf("Expected %d features written, got %d", len(tt.want), written)
+			}
+
+			var collection struct {
+				Features []struct {
+					Properties struct {
+						Code string `json:"code"`
+					} `json:"properties"`
+				} `json:"features"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &collection); err != nil {
+				t.Fatalf("Invalid GeoJSON %q: %v", buf.String(), err)
+			}
+			codes := make([]string, 0, len(collection.Features))
+			for _, f := range collection.Features {
+				codes = append(codes, f.Properties.Code)
+			}
+			if strings.Join(codes, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Expected features %v, got %v", tt.want, codes)
+			}
+		})
+	}
+
+	if _, err := writeFeatures(&bytes.Buffer{}, &fakeFeatureRows{err: errors.New("connection lost")}, "ref_admin.departements"); err == nil {
+		t.Error("Expected the error of the rows, got nil")
+	}
+}
@@ -0,0 +1,323 @@
+package repository
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"french-admin-etl/internal/infrastructure/entities"
+)
+
+// entityTag is the struct tag describing how an entity is stored.
+//
+// The table is declared on a blank field, the columns on the exported fields:
+//
+//	type RegionEntity struct {
+//		_    struct{} `etl:"table=ref_admin.regions,geometry=geom"`
+//		Code string   `etl:"code_insee_region,key"`
+//		Nom  string   `etl:"nom_region"`
+//	}
+//
+// Table options:
+//   - table: the table, with its schema (required)
+//   - geometry: the base geometry column, for entities with geometry
+//   - deleted: the timestamp column set by a soft-delete sync, reset when the entity is loaded again
+//   - vintage: the column holding the vintage (millésime) of the run, part of the key. References to
+//     other tables are resolved within the same vintage, through the vintage column of the referenced table
+//
+// Column options:
+//   - key: the column belongs to the conflict target of the upsert
+//   - empty: the key column may be empty, when other key columns identify the row (e.g. an optional
+//     line of an address)
+//   - ref=schema.table(column): the value is replaced by NULL when no row of the referenced table matches it,
+//     or the row is rejected, depending on the ReferencePolicy of the repository. Soft-deleted rows of the
+//     referenced table don't match, as read from the deleted and vintage options of its entity (see
+//     referencedEntities)
+const entityTag = "etl"
+
+// reference is a column referencing another table.
+type reference struct {
+	table   string
+	column  string
+	deleted string // soft-delete column of the referenced table, empty if it doesn't support soft-delete
+	vintage string // vintage column of the referenced table, empty if it holds a single vintage
+}
+
+// referencedEntities are the entities whose table is referenced by a ref= column option. The deleted and
+// vintage options of their table tag tell how a reference is resolved; a table of another entity is taken
+// as having neither.
+var referencedEntities = []reflect.Type{
+	reflect.TypeFor[entities.RegionEntity](),
+	reflect.TypeFor[entities.DepartementEntity](),
+	reflect.TypeFor[entities.ArrondissementEntity](),
+	reflect.TypeFor[entities.CantonEntity](),
+	reflect.TypeFor[entities.EPCIEntity](),
+	reflect.TypeFor[entities.CommuneEntity](),
+	reflect.TypeFor[entities.ArrondissementMunicipalEntity](),
+}
+
+// referencedTables returns the table options of the referencedEntities by table, read once.
+var referencedTables = sync.OnceValue(func() map[string]*entityMetadata {
+	tables := make(map[string]*entityMetadata, len(referencedEntities))
+	for _, t := range referencedEntities {
+		metadata := &entityMetadata{}
+		for i := 0; i < t.NumField(); i++ {
+			if field := t.Field(i); field.Name == "_" {
+				if err := metadata.parseTableTag(field.Tag.Get(entityTag)); err != nil {
+					panic(fmt.Sprintf("entity %s: %v", t, err))
+				}
+			}
+		}
+		tables[metadata.table] = metadata
+	}
+	return tables
+})
+
+// condition returns the condition on the rows of the referenced table resolving a reference: the value
+// placeholder in column, not soft-deleted, within the vintage placeholder when both tables are vintaged
+// (vintage is empty otherwise).
+func (r *reference) condition(placeholder, vintage string) string {
+	condition := fmt.Sprintf("%s = %s", r.column, placeholder)
+	if r.deleted != "" {
+		condition += fmt.Sprintf(" AND %s IS NULL", r.deleted)
+	}
+	if r.vintage != "" && vintage != "" {
+		condition += fmt.Sprintf(" AND %s = %s", r.vintage, vintage)
+	}
+	return condition
+}
+
+// columnMetadata describes a column mapped to an entity field.
+type columnMetadata struct {
+	name  string
+	index []int
+	key   bool
+	empty bool // empty values are accepted for the key column
+	ref   *reference
+}
+
+// entityMetadata describes how an entity type is stored in the database.
+type entityMetadata struct {
+	table    string
+	geometry string // base geometry column, empty for entities without geometry
+	deleted  string // soft-delete column, empty if the table doesn't support soft-delete
+	vintage  string // vintage column, empty if the table holds a single vintage
+	columns  []columnMetadata
+}
+
+// parseEntityMetadata reads the etl struct tags of an entity type.
+func parseEntityMetadata(t reflect.Type) (*entityMetadata, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("entity %s must be a struct", t)
+	}
+
+	metadata := &entityMetadata{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, ok := field.Tag.Lookup(entityTag)
+		if !ok {
+			continue
+		}
+
+		if field.Name == "_" {
+			if err := metadata.parseTableTag(tag); err != nil {
+				return nil, fmt.Errorf("entity %s: %w", t, err)
+			}
+			continue
+		}
+
+		if !field.IsExported() {
+			return nil, fmt.Errorf("entity %s: field %s must be exported", t, field.Name)
+		}
+		column, err := parseColumnTag(tag)
+		if err != nil {
+			return nil, fmt.Errorf("entity %s, field %s: %w", t, field.Name, err)
+		}
+		column.index = field.Index
+		metadata.columns = append(metadata.columns, column)
+	}
+
+	if metadata.table == "" {
+		return nil, fmt.Errorf("entity %s: missing table tag", t)
+	}
+	if !slices.ContainsFunc(metadata.columns, func(c columnMetadata) bool { return c.key }) {
+		return nil, fmt.Errorf("entity %s: at least one key column is required", t)
+	}
+	return metadata, nil
+}
+
+func (m *entityMetadata) parseTableTag(tag string) error {
+	for _, option := range strings.Split(tag, ",") {
+		name, value, _ := strings.Cut(option, "=")
+		switch name {
+		case "table":
+			m.table = value
+		case "geometry":
+			m.geometry = value
+		case "deleted":
+			m.deleted = value
+		case "vintage":
+			m.vintage = value
+		default:
+			return fmt.Errorf("unknown table option %q", name)
+		}
+	}
+	return nil
+}
+
+func parseColumnTag(tag string) (columnMetadata, error) {
+	options := strings.Split(tag, ",")
+	column := columnMetadata{name: options[0]}
+	if column.name == "" {
+		return column, fmt.Errorf("missing column name")
+	}
+
+	for _, option := range options[1:] {
+		name, value, _ := strings.Cut(option, "=")
+		switch name {
+		case "key":
+			column.key = true
+		case "empty":
+			column.empty = true
+		case "ref":
+			table, refColumn, ok := strings.Cut(strings.TrimSuffix(value, ")"), "(")
+			if !ok || table == "" || refColumn == "" {
+				return column, fmt.Errorf("invalid reference %q, expected schema.table(column)", value)
+			}
+			column.ref = &reference{table: table, column: refColumn}
+			if target, ok := referencedTables()[table]; ok {
+				column.ref.deleted, column.ref.vintage = target.deleted, target.vintage
+			}
+		default:
+			return column, fmt.Errorf("unknown column option %q", name)
+		}
+	}
+	if column.empty && !column.key {
+		return column, fmt.Errorf("option empty only applies to key columns")
+	}
+	return column, nil
+}
+
+// keyColumns returns the columns of the conflict target, the vintage column last.
+func (m *entityMetadata) keyColumns() []string {
+	keys := make([]string, 0, 2)
+	for _, column := range m.columns {
+		if column.key {
+			keys = append(keys, column.name)
+		}
+	}
+	if m.vintage != "" {
+		keys = append(keys, m.vintage)
+	}
+	return keys
+}
+
+// hasReferences reports whether a column references another table.
+func (m *entityMetadata) hasReferences() bool {
+	return slices.ContainsFunc(m.columns, func(c columnMetadata) bool { return c.ref != nil })
+}
+
+// columnIndex returns the index of the column named name, -1 if the entity has no such column.
+func (m *entityMetadata) columnIndex(name string) int {
+	return slices.IndexFunc(m.columns, func(c columnMetadata) bool { return c.name == name })
+}
+
+// values returns the column values of an entity, in column order.
+func (m *entityMetadata) values(entity any) []any {
+	v := reflect.ValueOf(entity)
+	values := make([]any, len(m.columns))
+	for i, column := range m.columns {
+		values[i] = v.FieldByIndex(column.index).Interface()
+	}
+	return values
+}
+
+// keyValues returns the key column values of an entity, for logging.
+func (m *entityMetadata) keyValues(entity any) []any {
+	v := reflect.ValueOf(entity)
+	values := make([]any, 0, 1)
+	for _, column := range m.columns {
+		if column.key {
+			values = append(values, v.FieldByIndex(column.index).Interface())
+		}
+	}
+	return values
+}
+
+// checkKeys validates the column values returned by values: every key column must be set, as NULL or
+// empty keys are refused or would collide in the database, unless declared empty.
+func (m *entityMetadata) checkKeys(values []any) error {
+	for i, column := range m.columns {
+		if !column.key {
+			continue
+		}
+		if values[i] == nil {
+			return fmt.Errorf("missing key column %s", column.name)
+		}
+		value := reflect.ValueOf(values[i])
+		if value.IsZero() && (!column.empty || value.Kind() == reflect.Pointer) {
+			return fmt.Errorf("missing key column %s", column.name)
+		}
+	}
+	return nil
+}
+
+// upsertSQL generates the INSERT ... ON CONFLICT statement of the entity into table, the table of the
+// entity or a copy of it. Parameters follow the column order, then the vintage when the table has a vintage
+// column, then the GeoJSON geometry when geometryColumn is not empty.
+func (m *entityMetadata) upsertSQL(table, geometryColumn string) string {
+	names := make([]string, 0, len(m.columns)+2)
+	placeholders := make([]string, 0, len(m.columns)+2)
+	updates := make([]string, 0, len(m.columns)+1)
+
+	vintagePlaceholder := fmt.Sprintf("$%d", len(m.columns)+1)
+	for i, column := range m.columns {
+		placeholder := fmt.Sprintf("$%d", i+1)
+		if column.ref != nil {
+			vintage := ""
+			if m.vintage != "" {
+				vintage = vintagePlaceholder
+			}
+			condition := column.ref.condition(placeholder, vintage)
+			// Insert NULL if the referenced row doesn't exist (avoids FK constraint violation). Repositories resolve
+			// the references beforehand, this only covers a referenced row removed in the meantime
+			placeholder = fmt.Sprintf("CASE WHEN EXISTS(SELECT 1 FROM %s WHERE %s) THEN %s ELSE NULL END",
+				column.ref.table, condition, placeholder)
+		}
+		names = append(names, column.name)
+		placeholders = append(placeholders, placeholder)
+		if !column.key {
+			updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", column.name, column.name))
+		}
+	}
+
+	next := len(m.columns) + 1
+	if m.vintage != "" {
+		names = append(names, m.vintage)
+		placeholders = append(placeholders, vintagePlaceholder)
+		next++
+	}
+
+	if geometryColumn != "" {
+		names = append(names, geometryColumn)
+		placeholders = append(placeholders, fmt.Sprintf("ST_SetSRID(ST_GeomFromGeoJSON($%d), 4326)", next))
+		updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", geometryColumn, geometryColumn))
+	}
+	if m.deleted != "" {
+		// A soft-deleted entity present in the source again is restored
+		updates = append(updates, fmt.Sprintf("%s = NULL", m.deleted))
+	}
+
+	conflict := "DO NOTHING"
+	if len(updates) > 0 {
+		conflict = "DO UPDATE SET\n\t\t\t" + strings.Join(updates, ",\n\t\t\t")
+	}
+
+	return fmt.Sprintf(`
+		INSERT INTO %s (%s)
+		VALUES (%s)
+		ON CONFLICT (%s) %s
+	`, table, strings.Join(names, ", "), strings.Join(placeholders, ", "), strings.Join(m.keyColumns(), ", "), conflict)
+}
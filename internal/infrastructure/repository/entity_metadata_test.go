@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"french-admin-etl/internal/infrastructure/entities"
+)
+
+// TestParseEntityMetadata_Entities tests that the tags of every entity with geometry are valid
+func TestParseEntityMetadata_Entities(t *testing.T) {
+	tests := []struct {
+		name       string
+		entityType reflect.Type
+		table      string
+		keys       []string
+		columns    int
+	}{
+		{name: "régions", entityType: reflect.TypeFor[entities.RegionEntity](), table: "ref_admin.regions", keys: []string{"code_insee_region", "millesime"}, columns: 2},
+		{name: "départements", entityType: reflect.TypeFor[entities.DepartementEntity](), table: "ref_admin.departements", keys: []string{"code_insee_departement", "millesime"}, columns: 3},
+		{name: "EPCI", entityType: reflect.TypeFor[entities.EPCIEntity](), table: "ref_admin.epci", keys: []string{"code_insee_epci", "millesime"}, columns: 2},
+		{name: "communes", entityType: reflect.TypeFor[entities.CommuneEntity](), table: "ref_admin.communes", keys: []string{"code_insee_commune", "millesime"}, columns: 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata, err := parseEntityMetadata(tt.entityType)
+			if err != nil {
+				t.Fatalf("parseEntityMetadata() error = %v", err)
+			}
+			if metadata.table != tt.table {
+				t.Errorf("Expected table %q, got %q", tt.table, metadata.table)
+			}
+			if metadata.geometry != "geom" {
+				t.Errorf("Expected geometry column 'geom', got %q", metadata.geometry)
+			}
+			if !reflect.DeepEqual(metadata.keyColumns(), tt.keys) {
+				t.Errorf("Expected keys %v, got %v", tt.keys, metadata.keyColumns())
+			}
+			if metadata.vintage != "millesime" {
+				t.Errorf("Expected vintage column 'millesime', got %q", metadata.vintage)
+			}
+			if len(metadata.columns) != tt.columns {
+				t.Errorf("Expected %d columns, got %d", tt.columns, len(metadata.columns))
+			}
+		})
+	}
+}
+
+// TestParseEntityMetadata_Invalid tests that invalid tags are rejected
+func TestParseEntityMetadata_Invalid(t *testing.T) {
+	type noTable struct {
+		Code string `etl:"code,key"`
+	}
+	type noKey struct {
+		_    struct{} `etl:"table=t"`
+		Code string   `etl:"code"`
+	}
+	type unknownOption struct {
+		_    struct{} `etl:"table=t"`
+		Code string   `etl:"code,key,unique"`
+	}
+	type emptyNotKey struct {
+		_    struct{} `etl:"table=t"`
+		Code string   `etl:"code,key"`
+		Line string   `etl:"line,empty"`
+	}
+	type invalidRef struct {
+		_    struct{} `etl:"table=t"`
+		Code string   `etl:"code,key"`
+		Ref  string   `etl:"ref,ref=other"`
+	}
+
+	tests := []struct {
+		name       string
+		entityType reflect.Type
+	}{
+		{name: "not a struct", entityType: reflect.TypeFor[string]()},
+		{name: "missing table", entityType: reflect.TypeFor[noTable]()},
+		{name: "missing key", entityType: reflect.TypeFor[noKey]()},
+		{name: "unknown option", entityType: reflect.TypeFor[unknownOption]()},
+		{name: "invalid reference", entityType: reflect.TypeFor[invalidRef]()},
+		{name: "empty on a column outside the key", entityType: reflect.TypeFor[emptyNotKey]()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseEntityMetadata(tt.entityType); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+// TestEntityMetadata_UpsertSQL tests the generated upsert statement
+func TestEntityMetadata_UpsertSQL(t *testing.T) {
+	metadata, err := parseEntityMetadata(reflect.TypeFor[entities.CommuneEntity]())
+	if err != nil {
+		t.Fatalf("parseEntityMetadata() error = %v", err)
+	}
+
+	sql := metadata.upsertSQL(metadata.table, "geom_100m")
+
+	expected := []string{
+		"INSERT INTO ref_admin.communes (code_insee_commune, nom_commune, code_insee_epci, code_insee_departement, code_insee_region, code_insee_arrondissement, code_insee_canton, millesime, geom_100m)",
+		"CASE WHEN EXISTS(SELECT 1 FROM ref_admin.epci WHERE code_insee_epci = $3 AND supprime_le IS NULL AND millesime = $8) THEN $3 ELSE NULL END",
+		"CASE WHEN EXISTS(SELECT 1 FROM ref_admin.cantons WHERE code_insee_canton = $7 AND supprime_le IS NULL AND millesime = $8) THEN $7 ELSE NULL END",
+		"$8, ST_SetSRID(ST_GeomFromGeoJSON($9), 4326)",
+		"ON CONFLICT (code_insee_commune, millesime) DO UPDATE SET",
+		"nom_commune = EXCLUDED.nom_commune",
+		"geom_100m = EXCLUDED.geom_100m",
+	}
+	for _, e := range expected {
+		if !strings.Contains(sql, e) {
+			t.Errorf("Expected SQL to contain %q, got:\n%s", e, sql)
+		}
+	}
+	if strings.Contains(sql, "code_insee_commune = EXCLUDED") || strings.Contains(sql, "millesime = EXCLUDED") {
+		t.Errorf("Key column must not be updated, got:\n%s", sql)
+	}
+}
+
+// TestEntityMetadata_UpsertSQL_KeysOnly tests the statement of an entity made of key columns only
+func TestEntityMetadata_UpsertSQL_KeysOnly(t *testing.T) {
+	type keysOnly struct {
+		_    struct{} `etl:"table=t"`
+		Code string   `etl:"code,key"`
+	}
+
+	metadata, err := parseEntityMetadata(reflect.TypeFor[keysOnly]())
+	if err != nil {
+		t.Fatalf("parseEntityMetadata() error = %v", err)
+	}
+
+	if sql := metadata.upsertSQL(metadata.table, ""); !strings.Contains(sql, "ON CONFLICT (code) DO NOTHING") {
+		t.Errorf("Expected DO NOTHING conflict clause, got:\n%s", sql)
+	}
+}
+
+// TestEntityMetadata_Values tests the extraction of column values
+func TestEntityMetadata_Values(t *testing.T) {
+	metadata, err := parseEntityMetadata(reflect.TypeFor[entities.DepartementEntity]())
+	if err != nil {
+		t.Fatalf("parseEntityMetadata() error = %v", err)
+	}
+
+	entity := entities.DepartementEntity{Code: "33", Nom: "Gironde", CodeRegion: "75"}
+
+	if got := metadata.values(entity); !reflect.DeepEqual(got, []any{"33", "Gironde", "75"}) {
+		t.Errorf("Unexpected values %v", got)
+	}
+	if got := metadata.keyValues(entity); !reflect.DeepEqual(got, []any{"33"}) {
+		t.Errorf("Unexpected key values %v", got)
+	}
+}
+
+// TestEntityMetadata_CheckKeys tests that key columns must be set, unless declared empty
+func TestEntityMetadata_CheckKeys(t *testing.T) {
+	type withEmptyKey struct {
+		_    struct{} `etl:"table=t"`
+		Code string   `etl:"code,key"`
+		Line string   `etl:"line,key,empty"`
+	}
+
+	metadata, err := parseEntityMetadata(reflect.TypeFor[withEmptyKey]())
+	if err != nil {
+		t.Fatalf("parseEntityMetadata() error = %v", err)
+	}
+
+	if err := metadata.checkKeys(metadata.values(withEmptyKey{Code: "01001"})); err != nil {
+		t.Errorf("Expected the empty key column to be accepted, got %v", err)
+	}
+	if err := metadata.checkKeys(metadata.values(withEmptyKey{Line: "LIEU-DIT"})); err == nil {
+		t.Error("Expected error for a missing key column, got nil")
+	}
+}
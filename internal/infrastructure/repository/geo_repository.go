@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"french-admin-etl/internal/infrastructure/entities"
+	"french-admin-etl/internal/model"
+	"log/slog"
+	"reflect"
+
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+)
+
+// GeoRepository loads entities with geometry into the table described by the etl struct tags of E.
+type GeoRepository[E any] struct {
+	*entityTable
+}
+
+var _ model.EntityWithGeoJSONGeometryLoader[entities.RegionEntity] = (*GeoRepository[entities.RegionEntity])(nil)
+var _ model.LoadInitializer = (*GeoRepository[entities.RegionEntity])(nil)
+var _ model.LoadFinalizer = (*GeoRepository[entities.RegionEntity])(nil)
+var _ model.LoadCounter = (*GeoRepository[entities.RegionEntity])(nil)
+
+// NewGeoRepository creates a new repository for the entity type E with the provided DatabaseManager and options.
+// It panics if the etl struct tags of E are invalid, which is a programming error.
+func NewGeoRepository[E any](dbManager *DatabaseManager, opts ...RepositoryOption) *GeoRepository[E] {
+	table := newEntityTable(dbManager, reflect.TypeFor[E](), opts)
+	if table.metadata.geometry == "" {
+		panic(fmt.Sprintf("invalid entity metadata: entity %s has no geometry column", reflect.TypeFor[E]()))
+	}
+	return &GeoRepository[E]{entityTable: table}
+}
+
+// NewRegionRepository creates a new repository for loading régions.
+func NewRegionRepository(dbManager *DatabaseManager, opts ...RepositoryOption) model.EntityWithGeoJSONGeometryLoader[entities.RegionEntity] {
+	return NewGeoRepository[entities.RegionEntity](dbManager, opts...)
+}
+
+// NewDepartementRepository creates a new repository for loading départements.
+func NewDepartementRepository(dbManager *DatabaseManager, opts ...RepositoryOption) model.EntityWithGeoJSONGeometryLoader[entities.DepartementEntity] {
+	return NewGeoRepository[entities.DepartementEntity](dbManager, opts...)
+}
+
+// NewArrondissementRepository creates a new repository for loading arrondissements (départementaux).
+func NewArrondissementRepository(dbManager *DatabaseManager, opts ...RepositoryOption) model.EntityWithGeoJSONGeometryLoader[entities.ArrondissementEntity] {
+	return NewGeoRepository[entities.ArrondissementEntity](dbManager, opts...)
+}
+
+// NewCantonRepository creates a new repository for loading cantons.
+func NewCantonRepository(dbManager *DatabaseManager, opts ...RepositoryOption) model.EntityWithGeoJSONGeometryLoader[entities.CantonEntity] {
+	return NewGeoRepository[entities.CantonEntity](dbManager, opts...)
+}
+
+// NewEPCIRepository creates a new repository for loading EPCI.
+func NewEPCIRepository(dbManager *DatabaseManager, opts ...RepositoryOption) model.EntityWithGeoJSONGeometryLoader[entities.EPCIEntity] {
+	return NewGeoRepository[entities.EPCIEntity](dbManager, opts...)
+}
+
+// NewCommuneRepository creates a new repository for loading communes.
+func NewCommuneRepository(dbManager *DatabaseManager, opts ...RepositoryOption) model.EntityWithGeoJSONGeometryLoader[entities.CommuneEntity] {
+	return NewGeoRepository[entities.CommuneEntity](dbManager, opts...)
+}
+
+// NewArrondissementMunicipalRepository creates a new repository for loading arrondissements municipaux.
+func NewArrondissementMunicipalRepository(dbManager *DatabaseManager, opts ...RepositoryOption) model.EntityWithGeoJSONGeometryLoader[entities.ArrondissementMunicipalEntity] {
+	return NewGeoRepository[entities.ArrondissementMunicipalEntity](dbManager, opts...)
+}
+
+// NewIrisRepository creates a new repository for loading IRIS.
+func NewIrisRepository(dbManager *DatabaseManager, opts ...RepositoryOption) model.EntityWithGeoJSONGeometryLoader[entities.IrisEntity] {
+	return NewGeoRepository[entities.IrisEntity](dbManager, opts...)
+}
+
+// Load upserts a batch of entities in a single transaction. A failing row doesn't abort the batch: it is isolated according to the load strategy of the DatabaseManager.
+func (r *GeoRepository[E]) Load(ctx context.Context, entities []model.EntityWithGeoJSONGeometry[E]) (int, error) {
+	// Rows of a vintaged table are stored in the vintage of the run
+	vintage, err := r.runVintage(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// Geometries are stored in the column of the resolution populated by the run
+	geomColumn := model.ResolutionFromContext(ctx).GeometryColumn(r.metadata.geometry)
+	sql := r.metadata.upsertSQL(r.targetTable(), geomColumn)
+
+	rows := make([][]any, 0, len(entities))
+	rowKeys := make([][]any, 0, len(entities))
+	geometries := make([]string, 0, len(entities))
+	for _, entity := range entities {
+		r.see(entity.Data, vintage)
+
+		// Retrieve geometry
+		if entity.GeoJSONGeometry == "" {
+			slog.Warn("Missing geometry", "table", r.metadata.table, "key", r.metadata.keyValues(entity.Data))
+			continue
+		}
+
+		rows = append(rows, r.metadata.values(entity.Data))
+		rowKeys = append(rowKeys, r.metadata.keyValues(entity.Data))
+		geometries = append(geometries, entity.GeoJSONGeometry)
+	}
+	rows, rowUnresolved, err := r.resolveReferences(ctx, rows, rowKeys, vintage)
+	if err != nil {
+		return 0, err
+	}
+
+	stmts := make([]statement, 0, len(rows))
+	keys := make([][]any, 0, len(rows))
+	unresolved := make([][]UnresolvedReference, 0, len(rows))
+	for i, values := range rows {
+		// Rejected by the reference policy
+		if values == nil {
+			continue
+		}
+		geometry := geometries[i]
+		stmts = append(stmts, statement{
+			sql:  sql,
+			args: append(r.args(values, vintage), geometry),
+			check: func() error {
+				if err := r.metadata.checkKeys(values); err != nil {
+					return err
+				}
+				return checkGeoJSONGeometry(geometry)
+			},
+		})
+		keys = append(keys, rowKeys[i])
+		unresolved = append(unresolved, rowUnresolved[i])
+	}
+
+	return r.load(ctx, stmts, keys, unresolved, vintage)
+}
+
+// checkGeoJSONGeometry validates a geometry as PostGIS does when storing it in a geography(multipolygon, 4326) column.
+func checkGeoJSONGeometry(geometry string) error {
+	var g geom.T
+	if err := geojson.Unmarshal([]byte(geometry), &g); err != nil {
+		return fmt.Errorf("invalid GeoJSON geometry: %w", err)
+	}
+
+	multiPolygon, ok := g.(*geom.MultiPolygon)
+	if !ok {
+		return fmt.Errorf("geometry type (%T) does not match column type (MultiPolygon)", g)
+	}
+	if multiPolygon.Layout() != geom.XY {
+		return fmt.Errorf("geometry layout %v does not match column layout XY", multiPolygon.Layout())
+	}
+
+	flatCoords := multiPolygon.FlatCoords()
+	for i := 0; i+1 < len(flatCoords); i += 2 {
+		lon, lat := flatCoords[i], flatCoords[i+1]
+		if lon < -180 || lon > 180 || lat < -90 || lat > 90 {
+			return fmt.Errorf("coordinate values are out of range [-180 -90, 180 90] for geography: %v %v", lon, lat)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"french-admin-etl/internal/infrastructure/entities"
+	"french-admin-etl/internal/model"
+)
+
+// TestNewGeoRepository tests that repositories are created for every entity with geometry
+func TestNewGeoRepository(t *testing.T) {
+	if NewRegionRepository(nil) == nil {
+		t.Error("NewRegionRepository() returned nil")
+	}
+	if NewDepartementRepository(nil) == nil {
+		t.Error("NewDepartementRepository() returned nil")
+	}
+	if NewArrondissementRepository(nil) == nil {
+		t.Error("NewArrondissementRepository() returned nil")
+	}
+	if NewCantonRepository(nil) == nil {
+		t.Error("NewCantonRepository() returned nil")
+	}
+	if NewEPCIRepository(nil) == nil {
+		t.Error("NewEPCIRepository() returned nil")
+	}
+	if NewCommuneRepository(nil) == nil {
+		t.Error("NewCommuneRepository() returned nil")
+	}
+	if NewArrondissementMunicipalRepository(nil) == nil {
+		t.Error("NewArrondissementMunicipalRepository() returned nil")
+	}
+	if NewIrisRepository(nil) == nil {
+		t.Error("NewIrisRepository() returned nil")
+	}
+}
+
+// TestNewGeoRepository_WithoutGeometry tests that entities without geometry column are rejected
+func TestNewGeoRepository_WithoutGeometry(t *testing.T) {
+	type withoutGeometry struct {
+		_    struct{} `etl:"table=t"`
+		Code string   `etl:"code,key"`
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for entity without geometry column")
+		}
+	}()
+	NewGeoRepository[withoutGeometry](nil)
+}
+
+// TestNewGeoRepository_SoftDeleteWithoutDeletedColumn tests that soft-delete requires a deleted column
+func TestNewGeoRepository_SoftDeleteWithoutDeletedColumn(t *testing.T) {
+	type withoutDeleted struct {
+		_    struct{} `etl:"table=t,geometry=geom"`
+		Code string   `etl:"code,key"`
+	}
+
+	if NewGeoRepository[withoutDeleted](nil, WithSync(SyncDelete, 0.05)) == nil {
+		t.Fatal("NewGeoRepository() returned nil")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for soft-delete without deleted column")
+		}
+	}()
+	NewGeoRepository[withoutDeleted](nil, WithSync(SyncSoftDelete, 0.05))
+}
+
+// TestGeoRepository_Finalize_DryRun tests that a dry run tracks the keys without removing anything
+func TestGeoRepository_Finalize_DryRun(t *testing.T) {
+	repository := NewGeoRepository[entities.RegionEntity](NewDryRunDatabaseManager(), WithSync(SyncDelete, 0.05))
+	rows := []model.EntityWithGeoJSONGeometry[entities.RegionEntity]{
+		{Data: entities.RegionEntity{Code: "01", Nom: "Without geometry"}},
+	}
+
+	ctx := model.WithVintage(context.Background(), 2024)
+	if _, err := repository.Load(ctx, rows); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(repository.sync.seen) != 1 {
+		t.Errorf("Expected the key of the entity without geometry to be seen, got %v", repository.sync.seen)
+	}
+	if err := repository.Finalize(ctx); err != nil {
+		t.Fatalf("Finalize() error = %v", err)
+	}
+}
+
+// TestNewGeoRepository_InvalidTags tests that invalid tags are rejected
+func TestNewGeoRepository_InvalidTags(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for entity without tags")
+		}
+	}()
+	NewGeoRepository[entities.CommunePopulationPrincEntity](nil)
+}
+
+// TestGeoRepository_Load_DryRun tests that a dry run validates the rows and counts them as a real run would
+func TestGeoRepository_Load_DryRun(t *testing.T) {
+	const square = `{"type":"MultiPolygon","coordinates":[[[[0,0],[1,0],[1,1],[0,1],[0,0]]]]}`
+
+	repository := NewRegionRepository(NewDryRunDatabaseManager())
+	rows := []model.EntityWithGeoJSONGeometry[entities.RegionEntity]{
+		{Data: entities.RegionEntity{Code: "01", Nom: "Valid"}, GeoJSONGeometry: square},
+		{Data: entities.RegionEntity{Code: "02", Nom: "Missing geometry"}},
+		{Data: entities.RegionEntity{Nom: "Missing key"}, GeoJSONGeometry: square},
+		{Data: entities.RegionEntity{Code: "04", Nom: "Polygon"}, GeoJSONGeometry: `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}`},
+		{Data: entities.RegionEntity{Code: "05", Nom: "Out of range"}, GeoJSONGeometry: `{"type":"MultiPolygon","coordinates":[[[[0,0],[200,0],[1,1],[0,0]]]]}`},
+		{Data: entities.RegionEntity{Code: "06", Nom: "Invalid"}, GeoJSONGeometry: `{"type":"MultiPolygon","coordinates":"none"}`},
+	}
+
+	count, err := repository.Load(model.WithVintage(context.Background(), 2024), rows)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 loaded row, got %d", count)
+	}
+}
+
+// TestGeoRepository_Load_WithoutVintage tests that a vintaged table cannot be loaded without a vintage
+func TestGeoRepository_Load_WithoutVintage(t *testing.T) {
+	repository := NewRegionRepository(NewDryRunDatabaseManager())
+	rows := []model.EntityWithGeoJSONGeometry[entities.RegionEntity]{
+		{Data: entities.RegionEntity{Code: "01", Nom: "Valid"}},
+	}
+
+	if _, err := repository.Load(context.Background(), rows); err == nil {
+		t.Error("Expected error for a run without vintage, got nil")
+	}
+}
+
+// TestCheckGeoJSONGeometry tests the validation of geometries in dry-run mode
+func TestCheckGeoJSONGeometry(t *testing.T) {
+	tests := []struct {
+		name     string
+		geometry string
+		wantErr  bool
+	}{
+		{name: "multipolygon", geometry: `{"type":"MultiPolygon","coordinates":[[[[2.3,48.8],[2.4,48.8],[2.4,48.9],[2.3,48.8]]]]}`},
+		{name: "polygon", geometry: `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}`, wantErr: true},
+		{name: "3D", geometry: `{"type":"MultiPolygon","coordinates":[[[[0,0,1],[1,0,1],[1,1,1],[0,0,1]]]]}`, wantErr: true},
+		{name: "latitude out of range", geometry: `{"type":"MultiPolygon","coordinates":[[[[0,0],[1,95],[1,1],[0,0]]]]}`, wantErr: true},
+		{name: "not GeoJSON", geometry: `not json`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkGeoJSONGeometry(tt.geometry); (err != nil) != tt.wantErr {
+				t.Errorf("checkGeoJSONGeometry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestGeometryAttributesSQL tests the statement deriving the attributes of the geometries
+func TestGeometryAttributesSQL(t *testing.T) {
+	metadata, err := parseEntityMetadata(reflect.TypeOf(entities.CommuneEntity{}))
+	if err != nil {
+		t.Fatalf("parseEntityMetadata() error = %v", err)
+	}
+
+	sql := metadata.geometryAttributesSQL("ref_admin.communes_shadow")
+	for _, want := range []string{
+		"UPDATE ref_admin.communes_shadow t SET",
+		"coalesce(t.geom_5m, t.geom_100m, t.geom, t.geom_1000m)",
+		"surface_km2 = ST_Area(",
+		"point_label = ST_PointOnSurface(",
+		"WHERE t.millesime = $1",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("geometryAttributesSQL() missing %q in %s", want, sql)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"french-admin-etl/internal/model"
+)
+
+// geometryAttributesResolutions lists the geometry resolutions, most precise first: the attributes derived
+// from the geometry of a row are computed on its most precise geometry.
+var geometryAttributesResolutions = []model.Resolution{model.Resolution5m, model.Resolution100m, model.DefaultResolution, model.Resolution1000m}
+
+// geometryAttributesSQL generates the statement computing the attributes derived from the geometry of the
+// rows of table, of the vintage $1 when the entity is vintaged: area in km², perimeter in km, a label point
+// guaranteed to lie inside the geometry, centroid and bounding box.
+func (m *entityMetadata) geometryAttributesSQL(table string) string {
+	columns := make([]string, len(geometryAttributesResolutions))
+	for i, resolution := range geometryAttributesResolutions {
+		columns[i] = "t." + resolution.GeometryColumn(m.geometry)
+	}
+	geometry := "coalesce(" + columns[0]
+	for _, column := range columns[1:] {
+		geometry += ", " + column
+	}
+	geometry += ")"
+
+	where := ""
+	if m.vintage != "" {
+		where = fmt.Sprintf("\n\t\tWHERE t.%s = $1", m.vintage)
+	}
+
+	return fmt.Sprintf(`
+		UPDATE %[1]s t SET
+			surface_km2 = ST_Area(%[2]s) / 1e6,
+			perimetre_km = ST_Perimeter(%[2]s) / 1e3,
+			point_label = ST_PointOnSurface(%[2]s::geometry)::geography,
+			centroide = ST_Centroid(%[2]s),
+			bbox = ST_Envelope(%[2]s::geometry)%[3]s
+	`, table, geometry, where)
+}
+
+// deriveGeometryAttributes computes the attributes derived from the geometries of the rows of the vintage
+// of the run in table, the table of the entity or its shadow table, once every batch is loaded.
+func (t *entityTable) deriveGeometryAttributes(ctx context.Context, table string) error {
+	if t.databaseManager.dryRun {
+		slog.Info("Geometry attributes skipped in dry run", "table", t.metadata.table)
+		return nil
+	}
+
+	vintage, err := t.runVintage(ctx)
+	if err != nil {
+		return err
+	}
+	var args []any
+	if t.metadata.vintage != "" {
+		args = append(args, int(vintage))
+	}
+
+	tag, err := t.databaseManager.pool.Exec(ctx, t.metadata.geometryAttributesSQL(table), args...)
+	if err != nil {
+		return fmt.Errorf("error deriving the geometry attributes of %s: %w", t.metadata.table, err)
+	}
+	slog.Info("Geometry attributes derived", "table", t.metadata.table, "rows", tag.RowsAffected())
+	return nil
+}
@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"french-admin-etl/internal/model"
+)
+
+// IntegritySeverity tells whether an integrity issue fails the check.
+type IntegritySeverity string
+
+const (
+	// IntegrityError is a broken reference of the hierarchy, failing the check.
+	IntegrityError IntegritySeverity = "error"
+	// IntegrityWarning is a suspicious row that may be legitimate, e.g. one of the few communes without EPCI.
+	IntegrityWarning IntegritySeverity = "warning"
+)
+
+// integrityCheck is a query listing the rows of a ref_admin table breaking the hierarchy, for the vintage $1.
+// Soft-deleted rows are ignored, as if they had been deleted.
+type integrityCheck struct {
+	name     string
+	severity IntegritySeverity
+	table    string
+	column   string // column holding the reference, empty when the row lacks children
+	sql      string // returns the code of the row and its reference, empty when NULL
+}
+
+// integrityChecks lists the checks of the hierarchy régions > départements > communes, and EPCI > communes.
+var integrityChecks = []integrityCheck{
+	{
+		name:     "commune_departement_inconnu",
+		severity: IntegrityError,
+		table:    "ref_admin.communes",
+		column:   "code_insee_departement",
+		sql:      missingParentSQL("ref_admin.communes", "code_insee_commune", "code_insee_departement", "ref_admin.departements"),
+	},
+	{
+		name:     "commune_region_inconnue",
+		severity: IntegrityError,
+		table:    "ref_admin.communes",
+		column:   "code_insee_region",
+		sql:      missingParentSQL("ref_admin.communes", "code_insee_commune", "code_insee_region", "ref_admin.regions"),
+	},
+	{
+		name:     "commune_region_incoherente",
+		severity: IntegrityError,
+		table:    "ref_admin.communes",
+		column:   "code_insee_region",
+		sql: `
+		SELECT c.code_insee_commune, coalesce(c.code_insee_region, '')
+		FROM ref_admin.communes c
+		JOIN ref_admin.departements d ON d.code_insee_departement = c.code_insee_departement AND d.millesime = c.millesime AND d.supprime_le IS NULL
+		WHERE c.millesime = $1 AND c.supprime_le IS NULL AND c.code_insee_region IS DISTINCT FROM d.code_insee_region
+		ORDER BY 1`,
+	},
+	{
+		// The communes whose EPCI was nulled or deferred at load time are reported by commune_epci_non_resolu
+		name:     "commune_sans_epci",
+		severity: IntegrityWarning,
+		table:    "ref_admin.communes",
+		column:   "code_insee_epci",
+		sql: fmt.Sprintf(`
+		SELECT c.code_insee_commune, ''
+		FROM ref_admin.communes c
+		WHERE c.millesime = $1 AND c.supprime_le IS NULL AND c.code_insee_epci IS NULL
+			AND NOT EXISTS (SELECT 1 FROM %s p WHERE %s)
+		ORDER BY 1`, pendingReferencesTable, pendingEPCIJoin),
+	},
+	{
+		// The EPCI code of a commune is replaced by NULL when the EPCI is unknown at load time, see ReferencePolicy
+		name:     "commune_epci_non_resolu",
+		severity: IntegrityError,
+		table:    "ref_admin.communes",
+		column:   "code_insee_epci",
+		sql: fmt.Sprintf(`
+		SELECT c.code_insee_commune, p.reference
+		FROM ref_admin.communes c
+		JOIN %s p ON %s
+		WHERE c.millesime = $1 AND c.supprime_le IS NULL AND c.code_insee_epci IS NULL
+		ORDER BY 1`, pendingReferencesTable, pendingEPCIJoin),
+	},
+	{
+		name:     "commune_epci_inconnu",
+		severity: IntegrityError,
+		table:    "ref_admin.communes",
+		column:   "code_insee_epci",
+		sql: `
+		SELECT c.code_insee_commune, c.code_insee_epci
+		FROM ref_admin.communes c
+		WHERE c.millesime = $1 AND c.supprime_le IS NULL AND c.code_insee_epci IS NOT NULL
+			AND NOT EXISTS (SELECT 1 FROM ref_admin.epci e WHERE e.code_insee_epci = c.code_insee_epci AND e.millesime = c.millesime AND e.supprime_le IS NULL)
+		ORDER BY 1`,
+	},
+	{
+		name:     "epci_sans_commune",
+		severity: IntegrityError,
+		table:    "ref_admin.epci",
+		sql: `
+		SELECT e.code_insee_epci, ''
+		FROM ref_admin.epci e
+		WHERE e.millesime = $1 AND e.supprime_le IS NULL
+			AND NOT EXISTS (SELECT 1 FROM ref_admin.communes c WHERE c.code_insee_epci = e.code_insee_epci AND c.millesime = e.millesime AND c.supprime_le IS NULL)
+		ORDER BY 1`,
+	},
+	{
+		name:     "departement_region_inconnue",
+		severity: IntegrityError,
+		table:    "ref_admin.departements",
+		column:   "code_insee_region",
+		sql:      missingParentSQL("ref_admin.departements", "code_insee_departement", "code_insee_region", "ref_admin.regions"),
+	},
+}
+
+// pendingEPCIJoin matches the commune c with its EPCI reference nulled or deferred at load time p.
+const pendingEPCIJoin = `p.table_name = 'ref_admin.communes' AND p.column_name = 'code_insee_epci'
+			AND p.key_values[1] = c.code_insee_commune AND p.millesime = c.millesime`
+
+// missingParentSQL generates the query listing the rows of table whose parent code, NULL or not, has no
+// row in parentTable of the same vintage.
+func missingParentSQL(table, codeColumn, parentColumn, parentTable string) string {
+	return fmt.Sprintf(`
+		SELECT t.%s, coalesce(t.%s, '')
+		FROM %s t
+		WHERE t.millesime = $1 AND t.supprime_le IS NULL
+			AND NOT EXISTS (SELECT 1 FROM %s p WHERE p.%s = t.%s AND p.millesime = t.millesime AND p.supprime_le IS NULL)
+		ORDER BY 1`, codeColumn, parentColumn, table, parentTable, parentColumn, parentColumn)
+}
+
+// IntegrityIssue is a row of a ref_admin table breaking the hierarchy.
+type IntegrityIssue struct {
+	Check     string            `json:"check"`
+	Severity  IntegritySeverity `json:"severity"`
+	Table     string            `json:"table"`
+	Code      string            `json:"code"`
+	Column    string            `json:"column,omitempty"`
+	Reference string            `json:"reference,omitempty"` // the code referenced by Column, empty when NULL
+}
+
+// IntegrityReport is the outcome of CheckIntegrity, counting the issues by check.
+type IntegrityReport struct {
+	Millesime int              `json:"millesime"`
+	Errors    int              `json:"errors"`
+	Warnings  int              `json:"warnings"`
+	Checks    map[string]int   `json:"checks"`
+	Issues    []IntegrityIssue `json:"issues"`
+}
+
+// Failed reports whether the report has errors, warnings being informative.
+func (r *IntegrityReport) Failed() bool {
+	return r.Errors > 0
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *IntegrityReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// add records an issue of the check.
+func (r *IntegrityReport) add(check integrityCheck, code, reference string) {
+	r.Issues = append(r.Issues, IntegrityIssue{
+		Check:     check.name,
+		Severity:  check.severity,
+		Table:     check.table,
+		Code:      code,
+		Column:    check.column,
+		Reference: reference,
+	})
+	r.Checks[check.name]++
+	if check.severity == IntegrityError {
+		r.Errors++
+	} else {
+		r.Warnings++
+	}
+}
+
+// CheckIntegrity cross-checks the references between the régions, départements, EPCI and communes of the
+// vintage, once loaded: parent codes missing from their table, EPCI without communes, communes whose EPCI
+// was replaced by NULL at load time because it was unknown, and the other communes without EPCI.
+func CheckIntegrity(ctx context.Context, dm *DatabaseManager, vintage model.Vintage) (*IntegrityReport, error) {
+	report := &IntegrityReport{Millesime: int(vintage), Checks: make(map[string]int, len(integrityChecks)), Issues: []IntegrityIssue{}}
+	if dm.dryRun {
+		slog.Info("Integrity check skipped in dry run")
+		return report, nil
+	}
+
+	for _, check := range integrityChecks {
+		report.Checks[check.name] = 0
+		rows, err := dm.pool.Query(ctx, check.sql, int(vintage))
+		if err != nil {
+			return nil, fmt.Errorf("error running integrity check %s: %w", check.name, err)
+		}
+		for rows.Next() {
+			var code, reference string
+			if err := rows.Scan(&code, &reference); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("error reading integrity check %s: %w", check.name, err)
+			}
+			report.add(check, code, reference)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("error running integrity check %s: %w", check.name, err)
+		}
+
+		if count := report.Checks[check.name]; count > 0 {
+			slog.Warn("Integrity check failed", "check", check.name, "severity", check.severity, "rows", count)
+		}
+	}
+
+	slog.Info("Integrity checked", "millesime", int(vintage), "errors", report.Errors, "warnings", report.Warnings)
+	return report, nil
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestMissingParentSQL tests that parent codes are looked up within the vintage, ignoring soft-deleted rows
+func TestMissingParentSQL(t *testing.T) {
+	sql := missingParentSQL("ref_admin.departements", "code_insee_departement", "code_insee_region", "ref_admin.regions")
+	for _, expected := range []string{
+		"SELECT t.code_insee_departement, coalesce(t.code_insee_region, '')",
+		"WHERE t.millesime = $1 AND t.supprime_le IS NULL",
+		"NOT EXISTS (SELECT 1 FROM ref_admin.regions p WHERE p.code_insee_region = t.code_insee_region AND p.millesime = t.millesime AND p.supprime_le IS NULL)",
+	} {
+		if !strings.Contains(sql, expected) {
+			t.Errorf("Expected %q in missing parent SQL:\n%s", expected, sql)
+		}
+	}
+}
+
+// TestIntegrityChecks_NulledEPCI tests that the communes whose EPCI was nulled or deferred at load time fail
+// the check, apart from the communes without EPCI
+func TestIntegrityChecks_NulledEPCI(t *testing.T) {
+	checks := make(map[string]integrityCheck, len(integrityChecks))
+	for _, check := range integrityChecks {
+		checks[check.name] = check
+	}
+
+	join := "p.table_name = 'ref_admin.communes' AND p.column_name = 'code_insee_epci'"
+	withoutEPCI := checks["commune_sans_epci"]
+	if !strings.Contains(withoutEPCI.sql, "NOT EXISTS (SELECT 1 FROM ref_admin.etl_pending_references p WHERE "+join) {
+		t.Errorf("Expected the communes without EPCI to exclude the unresolved references:\n%s", withoutEPCI.sql)
+	}
+
+	unresolved, ok := checks["commune_epci_non_resolu"]
+	if !ok {
+		t.Fatal("Expected a check of the communes whose EPCI was not resolved")
+	}
+	if unresolved.severity != IntegrityError {
+		t.Errorf("Expected the unresolved EPCI to be an error, got %s", unresolved.severity)
+	}
+	for _, expected := range []string{
+		"SELECT c.code_insee_commune, p.reference",
+		"JOIN ref_admin.etl_pending_references p ON " + join,
+		"p.key_values[1] = c.code_insee_commune AND p.millesime = c.millesime",
+	} {
+		if !strings.Contains(unresolved.sql, expected) {
+			t.Errorf("Expected %q in unresolved EPCI SQL:\n%s", expected, unresolved.sql)
+		}
+	}
+}
+
+// TestIntegrityReport tests that warnings are reported without failing the check
+func TestIntegrityReport(t *testing.T) {
+	checks := make(map[string]integrityCheck, len(integrityChecks))
+	for _, check := range integrityChecks {
+		checks[check.name] = check
+	}
+
+	report := &IntegrityReport{Millesime: 2024, Checks: map[string]int{}, Issues: []IntegrityIssue{}}
+	report.add(checks["commune_sans_epci"], "29155", "")
+	if report.Failed() {
+		t.Errorf("Expected a commune without EPCI not to fail the check")
+	}
+
+	report.add(checks["departement_region_inconnue"], "976", "06")
+	if !report.Failed() || report.Errors != 1 || report.Warnings != 1 {
+		t.Errorf("Expected 1 error and 1 warning, got %d errors and %d warnings", report.Errors, report.Warnings)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	var decoded IntegrityReport
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON report: %v", err)
+	}
+	expected := IntegrityIssue{Check: "departement_region_inconnue", Severity: IntegrityError, Table: "ref_admin.departements", Code: "976", Column: "code_insee_region", Reference: "06"}
+	if len(decoded.Issues) != 2 || decoded.Issues[1] != expected {
+		t.Errorf("Expected issue %+v, got %+v", expected, decoded.Issues)
+	}
+	if decoded.Checks["commune_sans_epci"] != 1 {
+		t.Errorf("Expected 1 commune without EPCI, got %v", decoded.Checks)
+	}
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"french-admin-etl/internal/infrastructure/entities"
+	"french-admin-etl/internal/model"
+	"french-admin-etl/internal/validation"
+	"log/slog"
+)
+
+// populationIrisTable is the table of the IRIS population data.
+const populationIrisTable = "demography.population_iris"
+
+type irisPopulationRepository struct {
+	databaseManager *DatabaseManager
+	pivot           *PivotSpec
+	validator       *validation.Validator // nil when the figures are not checked
+	shadow          *shadowTable          // nil when the run is loaded into the live table
+	rejects         populationRejects
+}
+
+var _ model.EntityLoader[entities.IrisPopulationEntity] = (*irisPopulationRepository)(nil)
+var _ model.LoadInitializer = (*irisPopulationRepository)(nil)
+var _ model.LoadFinalizer = (*irisPopulationRepository)(nil)
+
+// NewIrisPopulationRepository creates a new repository for loading IRIS population data.
+// Only the WithShadowTable, WithPivot and WithValidation options are supported.
+func NewIrisPopulationRepository(dbManager *DatabaseManager, opts ...RepositoryOption) model.EntityLoader[entities.IrisPopulationEntity] {
+	options := newRepositoryOptions(opts)
+	if options.syncMode != NoSync {
+		panic("sync is not supported for IRIS population data")
+	}
+
+	repository := &irisPopulationRepository{
+		databaseManager: dbManager,
+		pivot:           options.populationPivot(),
+		validator:       options.validator,
+	}
+	if options.shadowTable {
+		repository.shadow = newShadowTable(populationIrisTable, populationVintageColumn, options.maxRemovalRatio)
+	}
+	return repository
+}
+
+// Initialize creates, in shadow table mode, the shadow table receiving the rows of the run.
+func (l *irisPopulationRepository) Initialize(ctx context.Context) error {
+	l.rejects.reset()
+	if l.validator != nil {
+		l.validator.Reset()
+	}
+	if l.shadow == nil {
+		return nil
+	}
+	return l.shadow.create(ctx, l.databaseManager)
+}
+
+// Finalize reports the figures rejected by the pivot and the violations of the consistency rules, then
+// swaps, in shadow table mode, the shadow table in, unless the violations block the run.
+func (l *irisPopulationRepository) Finalize(ctx context.Context) error {
+	l.rejects.report("iris")
+	l.rejects.reset()
+	if l.validator != nil {
+		err := l.validator.Finish("iris")
+		l.validator.Reset()
+		if err != nil {
+			return err
+		}
+	}
+	if l.shadow == nil {
+		return nil
+	}
+	return l.shadow.validateAndSwap(ctx, l.databaseManager)
+}
+
+// targetTable returns the table receiving the rows of the run.
+func (l *irisPopulationRepository) targetTable() string {
+	if l.shadow != nil {
+		return l.shadow.name()
+	}
+	return populationIrisTable
+}
+
+// aggregateIrisPopulationData groups the figures of the entities by IRIS/year, as aggregatePopulationData
+// does for communes. Figures whose age/sex has no column in the pivot are counted in rejects, by combination.
+func aggregateIrisPopulationData(pivot *PivotSpec, batch []entities.IrisPopulationEntity, rejects map[string]int) map[string]*populationRecord {
+	records := make(map[string]*populationRecord)
+	for _, entity := range batch {
+		key := populationKey(entity.CodeIris, entity.Annee)
+		record, exists := records[key]
+		if !exists {
+			record = newPopulationRecord(pivot, entity.CodeIris, entity.Annee)
+			records[key] = record
+		}
+
+		record.entityCount++
+		for _, figure := range entity.Figures {
+			modalities := populationModalities(figure.Age, figure.Sexe)
+			column, ok := pivot.column(modalities)
+			if !ok {
+				rejects[pivot.combination(modalities)]++
+				continue
+			}
+			record.set(column, figure.Population, false)
+		}
+	}
+	return records
+}
+
+func (l *irisPopulationRepository) Load(ctx context.Context, batch []entities.IrisPopulationEntity) (int, error) {
+	// Population figures are joined to the IRIS of the vintage of the run
+	vintage, ok := model.VintageFromContext(ctx)
+	if !ok {
+		return 0, fmt.Errorf("table %s is vintaged, the run must have a vintage", populationIrisTable)
+	}
+
+	// Sorting prevents deadlocks when multiple workers access same keys
+	rejects := make(map[string]int)
+	records := sortPopulationRecords(aggregateIrisPopulationData(l.pivot, batch, rejects))
+	l.rejects.add(rejects)
+	stmt := populationUpsertSQL(l.pivot, l.targetTable(), "code_iris", false)
+
+	stmts := make([]statement, len(records))
+	for i, record := range records {
+		stmts[i] = statement{
+			sql:   stmt,
+			args:  record.args(vintage),
+			check: record.check,
+		}
+	}
+
+	rowErrors, err := l.databaseManager.loadBatch(ctx, stmts)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	failed := 0
+	for i, rowErr := range rowErrors {
+		record := records[i]
+		if rowErr != nil {
+			slog.Error("Insert error", "entity", "population", "iris", record.code, "year", record.annee, "error", rowErr)
+			failed++
+			continue
+		}
+		count += record.entityCount
+		if l.validator != nil {
+			l.validator.Check(record.code, record.annee, record.figures(l.pivot))
+		}
+	}
+
+	slog.Debug("IRIS population data loaded",
+		"input_entities", len(batch),
+		"aggregated_records", len(records),
+		"records_inserted", len(records)-failed,
+		"records_failed", failed)
+
+	return count, nil
+}
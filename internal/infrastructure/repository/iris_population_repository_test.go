@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"french-admin-etl/internal/infrastructure/entities"
+	"french-admin-etl/internal/model"
+)
+
+// TestIrisPopulationRepository_Load_DryRun tests that a dry run counts the entities of every valid record
+func TestIrisPopulationRepository_Load_DryRun(t *testing.T) {
+	repository := NewIrisPopulationRepository(NewDryRunDatabaseManager())
+
+	rows := []entities.IrisPopulationEntity{
+		{CodeIris: "751010101", Annee: 2021, Figures: []entities.PopulationFigure{
+			{Age: "_T", Sexe: "_T", Population: numeric("1800")},
+			{Age: "Y_GE80", Sexe: "_T", Population: numeric("150")},
+		}},
+		{CodeIris: "010010000", Annee: 2021, Figures: []entities.PopulationFigure{
+			{Age: "_T", Sexe: "F", Population: numeric("-1")},
+		}},
+	}
+
+	count, err := repository.Load(model.WithVintage(context.Background(), 2024), rows)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 loaded entity, got %d", count)
+	}
+}
+
+// TestAggregateIrisPopulationData tests that the figures of an IRIS are set on its record
+func TestAggregateIrisPopulationData(t *testing.T) {
+	pivot := DefaultPopulationPivot()
+	rejects := make(map[string]int)
+	records := aggregateIrisPopulationData(pivot, []entities.IrisPopulationEntity{
+		{CodeIris: "751010101", Annee: 2021, Figures: []entities.PopulationFigure{
+			{Age: "_T", Sexe: "_T", Population: numeric("1800")},
+			{Age: "Y_LT15", Sexe: "M", Population: numeric("90")},
+			{Age: "Y_LT05", Sexe: "_T", Population: numeric("40")},
+		}},
+	}, rejects)
+
+	record := records[populationKey("751010101", 2021)]
+	if pop := figure(pivot, record, "pop"); pop == nil || *pop != 1800 {
+		t.Fatalf("Expected a total population of 1800, got %+v", record)
+	}
+	if popLT15H := figure(pivot, record, "pop_LT15_h"); popLT15H == nil || *popLT15H != 90 {
+		t.Errorf("Expected 90 men under 15, got %v", popLT15H)
+	}
+	if rejects["AGE=Y_LT05,SEX=_T"] != 1 {
+		t.Errorf("Expected the figure under 5 to be rejected, got %v", rejects)
+	}
+}
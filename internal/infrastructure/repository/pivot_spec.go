@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"french-admin-etl/internal/validation"
+)
+
+// PopulationDimensions are the dimensions of the population observations, with the codes of the INSEE
+// population datasets: age group (AGE) and sex (SEX).
+var PopulationDimensions = []string{"AGE", "SEX"}
+
+// columnName matches the column names accepted in a pivot spec, inserted as is in the SQL statements.
+var columnName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// PivotColumn is a column of a pivot table, receiving the observations of a combination of modalities.
+type PivotColumn struct {
+	Name       string            `json:"column"`
+	Modalities map[string]string `json:"modalities"` // modality by dimension, e.g. {"AGE": "Y_LT15", "SEX": "F"}
+}
+
+// PivotSpec maps the combinations of modalities of the dimensions of the observations to the columns of
+// a table holding one row per territory and year. The repositories generate their column list, upsert and
+// aggregation statements from it.
+type PivotSpec struct {
+	dimensions []string
+	columns    []PivotColumn
+	index      map[string]int // column index by combination, see combination
+}
+
+// NewPivotSpec validates and indexes a pivot spec: every column gives a modality for each dimension, and
+// column names and combinations are unique.
+func NewPivotSpec(dimensions []string, columns []PivotColumn) (*PivotSpec, error) {
+	if len(dimensions) == 0 || len(columns) == 0 {
+		return nil, fmt.Errorf("pivot spec must have dimensions and columns")
+	}
+
+	spec := &PivotSpec{
+		dimensions: slices.Clone(dimensions),
+		columns:    slices.Clone(columns),
+		index:      make(map[string]int, len(columns)),
+	}
+	names := make(map[string]bool, len(columns))
+	for i, column := range columns {
+		if !columnName.MatchString(column.Name) {
+			return nil, fmt.Errorf("invalid pivot column name %q", column.Name)
+		}
+		// Unquoted identifiers are case-insensitive
+		name := strings.ToLower(column.Name)
+		if names[name] {
+			return nil, fmt.Errorf("duplicate pivot column %s", column.Name)
+		}
+		names[name] = true
+
+		if len(column.Modalities) != len(dimensions) {
+			return nil, fmt.Errorf("pivot column %s must have a modality for each of the dimensions %v", column.Name, dimensions)
+		}
+		for _, dimension := range dimensions {
+			if column.Modalities[dimension] == "" {
+				return nil, fmt.Errorf("pivot column %s has no modality for dimension %s", column.Name, dimension)
+			}
+		}
+		combination := spec.combination(column.Modalities)
+		if other, ok := spec.index[combination]; ok {
+			return nil, fmt.Errorf("pivot columns %s and %s share the combination %s", columns[other].Name, column.Name, combination)
+		}
+		spec.index[combination] = i
+	}
+	return spec, nil
+}
+
+// LoadPivotSpec reads a pivot spec from a JSON file, {"dimensions": [...], "columns": [{"column": ...,
+// "modalities": {...}}]}. Its dimensions must be the given ones, those of the observations it applies to.
+func LoadPivotSpec(filePath string, dimensions []string) (*PivotSpec, error) {
+	// #nosec G304 -- filePath is controlled by the application, not user input
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Dimensions []string      `json:"dimensions"`
+		Columns    []PivotColumn `json:"columns"`
+	}
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("error reading pivot spec %s: %w", filePath, err)
+	}
+	if len(file.Dimensions) != len(dimensions) || !slices.Equal(slices.Sorted(slices.Values(file.Dimensions)), slices.Sorted(slices.Values(dimensions))) {
+		return nil, fmt.Errorf("pivot spec %s has dimensions %v, must be %v", filePath, file.Dimensions, dimensions)
+	}
+
+	spec, err := NewPivotSpec(file.Dimensions, file.Columns)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pivot spec %s: %w", filePath, err)
+	}
+	return spec, nil
+}
+
+// DefaultPopulationPivot returns the pivot of the population tables (demography.population_commune and
+// alike): the total and 10 age groups of DS_RP_POPULATION_PRINC, each for both sexes, men and women.
+func DefaultPopulationPivot() *PivotSpec {
+	ages := []struct{ code, suffix string }{
+		{"_T", ""}, {"Y_LT15", "_LT15"}, {"Y_LT20", "_LT20"}, {"Y15T24", "_15T24"}, {"Y20T64", "_20T64"},
+		{"Y25T39", "_25T39"}, {"Y40T54", "_40T54"}, {"Y55T64", "_55T64"}, {"Y65T79", "_65T79"},
+		{"Y_GE65", "_GE65"}, {"Y_GE80", "_GE80"},
+	}
+	sexes := []struct{ code, suffix string }{{"_T", ""}, {"M", "_h"}, {"F", "_f"}}
+
+	columns := make([]PivotColumn, 0, len(ages)*len(sexes))
+	for _, age := range ages {
+		for _, sex := range sexes {
+			columns = append(columns, PivotColumn{
+				Name:       "pop" + age.suffix + sex.suffix,
+				Modalities: map[string]string{"AGE": age.code, "SEX": sex.code},
+			})
+		}
+	}
+
+	spec, err := NewPivotSpec(PopulationDimensions, columns)
+	if err != nil {
+		panic(fmt.Sprintf("invalid default population pivot: %v", err))
+	}
+	return spec
+}
+
+// combination identifies a combination of modalities, e.g. AGE=Y_LT15,SEX=F. Dimensions missing from
+// modalities are left empty.
+func (p *PivotSpec) combination(modalities map[string]string) string {
+	parts := make([]string, len(p.dimensions))
+	for i, dimension := range p.dimensions {
+		parts[i] = dimension + "=" + modalities[dimension]
+	}
+	return strings.Join(parts, ",")
+}
+
+// column returns the index of the column of a combination of modalities, ok being false when the
+// combination has no column.
+func (p *PivotSpec) column(modalities map[string]string) (index int, ok bool) {
+	index, ok = p.index[p.combination(modalities)]
+	return index, ok
+}
+
+// columnNames returns the names of the columns, in the order of the spec.
+func (p *PivotSpec) columnNames() []string {
+	names := make([]string, len(p.columns))
+	for i, column := range p.columns {
+		names[i] = column.Name
+	}
+	return names
+}
+
+// PopulationColumns returns the columns of a pivot of PopulationDimensions with their age group and sex, from
+// which validation.PopulationRules derives the identities of their figures.
+func (p *PivotSpec) PopulationColumns() []validation.PopulationColumn {
+	columns := make([]validation.PopulationColumn, len(p.columns))
+	for i, column := range p.columns {
+		columns[i] = validation.PopulationColumn{Name: column.Name, Age: column.Modalities["AGE"], Sex: column.Modalities["SEX"]}
+	}
+	return columns
+}
+
+// populationTables returns the tables loaded with the columns of the population pivot: the commune,
+// arrondissement municipal and IRIS tables, and the tables of the levels the communes are aggregated to.
+func populationTables() []string {
+	tables := []string{populationCommuneTable, populationArmTable, populationIrisTable}
+	for _, level := range populationLevels {
+		tables = append(tables, level.table)
+	}
+	return tables
+}
+
+// CheckPivotColumns checks that the population tables have the columns of the pivot, so that a pivot naming
+// a column no migration creates fails before the run rather than on every insert. Nothing is checked in dry
+// run, without database.
+func CheckPivotColumns(ctx context.Context, dm *DatabaseManager, pivot *PivotSpec) error {
+	if dm.dryRun {
+		return nil
+	}
+
+	for _, table := range populationTables() {
+		schema, name := splitTableName(table)
+		rows, err := dm.pool.Query(ctx, "SELECT column_name FROM information_schema.columns WHERE table_schema = $1 AND table_name = $2", schema, name)
+		if err != nil {
+			return fmt.Errorf("error listing the columns of %s: %w", table, err)
+		}
+		columns := make(map[string]bool)
+		for rows.Next() {
+			var column string
+			if err := rows.Scan(&column); err != nil {
+				rows.Close()
+				return fmt.Errorf("error listing the columns of %s: %w", table, err)
+			}
+			columns[column] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error listing the columns of %s: %w", table, err)
+		}
+
+		if missing := pivot.missingColumns(columns); len(missing) > 0 {
+			return fmt.Errorf("table %s has no column %s of the pivot", table, strings.Join(missing, ", "))
+		}
+	}
+	return nil
+}
+
+// missingColumns returns the columns of the pivot absent from the columns of a table, compared as unquoted
+// identifiers, lower case.
+func (p *PivotSpec) missingColumns(columns map[string]bool) []string {
+	var missing []string
+	for _, column := range p.columns {
+		if !columns[strings.ToLower(column.Name)] {
+			missing = append(missing, column.Name)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"french-admin-etl/internal/infrastructure/entities"
+	"french-admin-etl/internal/validation"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// figure returns the figure of a record for a column of the pivot
+func figure(pivot *PivotSpec, record *populationRecord, column string) *float64 {
+	i := slices.Index(pivot.columnNames(), column)
+	if record == nil || i < 0 {
+		return nil
+	}
+	return record.figure(i)
+}
+
+// numeric parses an exact decimal figure
+func numeric(value string) pgtype.Numeric {
+	var n pgtype.Numeric
+	if err := n.Scan(value); err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// TestDefaultPopulationPivot tests that the default pivot has the columns of the population tables
+func TestDefaultPopulationPivot(t *testing.T) {
+	pivot := DefaultPopulationPivot()
+
+	columns := pivot.columnNames()
+	if len(columns) != 33 || columns[0] != "pop" || columns[1] != "pop_h" || columns[32] != "pop_GE80_f" {
+		t.Errorf("Unexpected columns %v", columns)
+	}
+	if i, ok := pivot.column(map[string]string{"AGE": "Y15T24", "SEX": "M"}); !ok || columns[i] != "pop_15T24_h" {
+		t.Errorf("Expected Y15T24/M in pop_15T24_h, got %d, %v", i, ok)
+	}
+	if _, ok := pivot.column(map[string]string{"AGE": "Y_LT05", "SEX": "M"}); ok {
+		t.Error("Expected no column for an unknown age group")
+	}
+}
+
+// TestNewPivotSpec_Invalid tests that inconsistent pivot specs are refused
+func TestNewPivotSpec_Invalid(t *testing.T) {
+	modalities := func(age, sex string) map[string]string { return map[string]string{"AGE": age, "SEX": sex} }
+
+	tests := []struct {
+		name    string
+		columns []PivotColumn
+	}{
+		{name: "no column", columns: nil},
+		{name: "invalid name", columns: []PivotColumn{{Name: "pop; DROP TABLE x", Modalities: modalities("_T", "_T")}}},
+		{name: "duplicate name", columns: []PivotColumn{{Name: "pop", Modalities: modalities("_T", "_T")}, {Name: "POP", Modalities: modalities("_T", "M")}}},
+		{name: "duplicate combination", columns: []PivotColumn{{Name: "pop", Modalities: modalities("_T", "_T")}, {Name: "pop_t", Modalities: modalities("_T", "_T")}}},
+		{name: "missing dimension", columns: []PivotColumn{{Name: "pop", Modalities: map[string]string{"AGE": "_T"}}}},
+		{name: "other dimension", columns: []PivotColumn{{Name: "pop", Modalities: map[string]string{"AGE": "_T", "PCS": "1"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewPivotSpec(PopulationDimensions, tt.columns); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+// TestLoadPivotSpec tests that a pivot spec is read from a JSON file with the dimensions of the observations
+func TestLoadPivotSpec(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "pivot.json")
+	content := `{
+		"dimensions": ["SEX", "AGE"],
+		"columns": [
+			{"column": "pop", "modalities": {"AGE": "_T", "SEX": "_T"}},
+			{"column": "pop_LT05", "modalities": {"AGE": "Y_LT05", "SEX": "_T"}}
+		]
+	}`
+	if err := os.WriteFile(filePath, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	pivot, err := LoadPivotSpec(filePath, PopulationDimensions)
+	if err != nil {
+		t.Fatalf("LoadPivotSpec() error = %v", err)
+	}
+	if i, ok := pivot.column(map[string]string{"AGE": "Y_LT05", "SEX": "_T"}); !ok || i != 1 {
+		t.Errorf("Expected Y_LT05/_T in the second column, got %d, %v", i, ok)
+	}
+
+	if _, err := LoadPivotSpec(filePath, []string{"AGE"}); err == nil {
+		t.Error("Expected error for other dimensions, got nil")
+	}
+	if _, err := LoadPivotSpec(filepath.Join(t.TempDir(), "missing.json"), PopulationDimensions); err == nil {
+		t.Error("Expected error for a missing file, got nil")
+	}
+}
+
+// TestPivotSpec_PopulationColumns tests that the columns of the pivot carry their age group and sex
+func TestPivotSpec_PopulationColumns(t *testing.T) {
+	columns := DefaultPopulationPivot().PopulationColumns()
+
+	if len(columns) != 33 {
+		t.Fatalf("Expected 33 columns, got %d", len(columns))
+	}
+	expected := validation.PopulationColumn{Name: "pop_15T24_h", Age: "Y15T24", Sex: "M"}
+	if !slices.Contains(columns, expected) {
+		t.Errorf("Expected %+v in %+v", expected, columns)
+	}
+}
+
+// TestPivotSpec_MissingColumns tests that the columns of the pivot are looked up as unquoted identifiers
+func TestPivotSpec_MissingColumns(t *testing.T) {
+	pivot, err := NewPivotSpec(PopulationDimensions, []PivotColumn{
+		{Name: "pop", Modalities: map[string]string{"AGE": "_T", "SEX": "_T"}},
+		{Name: "pop_LT05", Modalities: map[string]string{"AGE": "Y_LT05", "SEX": "_T"}},
+		{Name: "pop_LT05_h", Modalities: map[string]string{"AGE": "Y_LT05", "SEX": "M"}},
+	})
+	if err != nil {
+		t.Fatalf("NewPivotSpec() error = %v", err)
+	}
+
+	missing := pivot.missingColumns(map[string]bool{"code_commune": true, "pop": true, "pop_lt05": true})
+	if !slices.Equal(missing, []string{"pop_LT05_h"}) {
+		t.Errorf("Expected pop_LT05_h missing, got %v", missing)
+	}
+}
+
+// TestNewTableRepository_WithPivot tests that the pivot option is refused by the tag-driven repositories
+func TestNewTableRepository_WithPivot(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for a pivot option")
+		}
+	}()
+	NewTableRepository[entities.CogRegionEntity](nil, WithPivot(DefaultPopulationPivot()))
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"french-admin-etl/internal/model"
+)
+
+// populationLevel is a level above the communes the population is aggregated to.
+type populationLevel struct {
+	table      string // population table of the level
+	codeColumn string // code of the level, in the table and in ref_admin.communes
+}
+
+// populationLevels lists the levels the commune population is aggregated to, once loaded.
+var populationLevels = []populationLevel{
+	{table: "demography.population_epci", codeColumn: "code_insee_epci"},
+	{table: "demography.population_departement", codeColumn: "code_insee_departement"},
+	{table: "demography.population_region", codeColumn: "code_insee_region"},
+}
+
+// populationReconciliation counts the commune rows of a vintage by outcome of their aggregation to a level.
+type populationReconciliation struct {
+	communeRows int64 // rows of the commune table
+	unknown     int64 // rows of communes absent from ref_admin.communes (or soft-deleted)
+	orphans     int64 // rows of communes without code for the level (e.g. communes without EPCI)
+	aggregated  int64 // rows summed into the level table (sum of nb_communes)
+}
+
+// check verifies that every commune row was aggregated, or reported as unknown or orphan.
+func (r populationReconciliation) check(level populationLevel) error {
+	if expected := r.communeRows - r.unknown - r.orphans; r.aggregated != expected {
+		return fmt.Errorf("population of %s doesn't reconcile: %d commune rows aggregated instead of %d (%d rows, %d unknown, %d without %s)",
+			level.table, r.aggregated, expected, r.communeRows, r.unknown, r.orphans, level.codeColumn)
+	}
+	return nil
+}
+
+// populationAggregateSQL generates the statement summing the figures of the columns of the pivot of
+// communeTable into the table of level, by code of the level of the commune in ref_admin.communes and year,
+// for the vintage $1. As for the arrondissements municipaux, a sum is NULL as soon as one commune lacks the figure.
+func populationAggregateSQL(pivot *PivotSpec, level populationLevel, communeTable string) string {
+	columns := pivot.columnNames()
+	sums := make([]string, len(columns))
+	for i, column := range columns {
+		sums[i] = fmt.Sprintf("CASE WHEN count(p.%s) = count(*) THEN sum(p.%s) END", column, column)
+	}
+
+	return fmt.Sprintf(`
+		INSERT INTO %s (%s, annee, %s, nb_communes, millesime)
+		SELECT c.%s, p.annee, %s, count(*), p.millesime
+		FROM %s p
+		JOIN ref_admin.communes c ON c.code_insee_commune = p.code_insee_commune AND c.millesime = p.millesime
+		WHERE p.millesime = $1 AND c.supprime_le IS NULL AND c.%s IS NOT NULL
+		GROUP BY c.%s, p.annee, p.millesime
+	`, level.table, level.codeColumn, strings.Join(columns, ", "), level.codeColumn, strings.Join(sums, ", "),
+		communeTable, level.codeColumn, level.codeColumn)
+}
+
+// populationReconciliationSQL generates the query counting, for the vintage $1, the rows of communeTable,
+// those of communes unknown to ref_admin.communes, those without code for the level, and the rows aggregated
+// into the table of the level.
+func populationReconciliationSQL(level populationLevel, communeTable string) string {
+	return fmt.Sprintf(`
+		SELECT count(*),
+			count(*) FILTER (WHERE c.code_insee_commune IS NULL),
+			count(*) FILTER (WHERE c.code_insee_commune IS NOT NULL AND c.%s IS NULL),
+			(SELECT coalesce(sum(nb_communes), 0) FROM %s WHERE millesime = $1)
+		FROM %s p
+		LEFT JOIN ref_admin.communes c ON c.code_insee_commune = p.code_insee_commune AND c.millesime = p.millesime AND c.supprime_le IS NULL
+		WHERE p.millesime = $1
+	`, level.codeColumn, level.table, communeTable)
+}
+
+// aggregatePopulation replaces the population of the EPCI, départements and régions of the vintage of the
+// run by the sums of the figures of their communes in communeTable, arrondissements municipaux being
+// already rolled up into their commune. Each level is replaced in a single transaction, rolled back when
+// its row counts don't reconcile with the commune table.
+func aggregatePopulation(ctx context.Context, dm *DatabaseManager, pivot *PivotSpec, communeTable string) error {
+	if dm.dryRun {
+		slog.Info("Population aggregation skipped in dry run")
+		return nil
+	}
+
+	vintage, ok := model.VintageFromContext(ctx)
+	if !ok {
+		return fmt.Errorf("table %s is vintaged, the run must have a vintage", communeTable)
+	}
+
+	for _, level := range populationLevels {
+		if err := aggregatePopulationLevel(ctx, dm, pivot, level, communeTable, vintage); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// aggregatePopulationLevel replaces the population of a level for the vintage, see aggregatePopulation.
+func aggregatePopulationLevel(ctx context.Context, dm *DatabaseManager, pivot *PivotSpec, level populationLevel, communeTable string, vintage model.Vintage) error {
+	tx, err := dm.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE millesime = $1", level.table), int(vintage)); err != nil {
+		return fmt.Errorf("error clearing %s: %w", level.table, err)
+	}
+	tag, err := tx.Exec(ctx, populationAggregateSQL(pivot, level, communeTable), int(vintage))
+	if err != nil {
+		return fmt.Errorf("error aggregating population into %s: %w", level.table, err)
+	}
+
+	var reconciliation populationReconciliation
+	err = tx.QueryRow(ctx, populationReconciliationSQL(level, communeTable), int(vintage)).Scan(
+		&reconciliation.communeRows, &reconciliation.unknown, &reconciliation.orphans, &reconciliation.aggregated)
+	if err != nil {
+		return fmt.Errorf("error reconciling %s: %w", level.table, err)
+	}
+	if err := reconciliation.check(level); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	if reconciliation.unknown > 0 {
+		slog.Warn("Population of communes unknown to ref_admin.communes not aggregated", "table", level.table, "rows", reconciliation.unknown)
+	}
+	if reconciliation.orphans > 0 {
+		slog.Warn("Population of communes without parent not aggregated", "table", level.table, "column", level.codeColumn, "rows", reconciliation.orphans)
+	}
+	slog.Info("Population aggregated", "table", level.table, "records", tag.RowsAffected(), "communeRows", reconciliation.aggregated)
+	return nil
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestPopulationAggregateSQL tests that the figures are summed by code of the level of the communes
+func TestPopulationAggregateSQL(t *testing.T) {
+	aggregate := populationAggregateSQL(DefaultPopulationPivot(), populationLevels[0], populationCommuneTable)
+	for _, expected := range []string{
+		"INSERT INTO demography.population_epci (code_insee_epci, annee, pop, pop_h",
+		"CASE WHEN count(p.pop_GE80_f) = count(*) THEN sum(p.pop_GE80_f) END, count(*), p.millesime",
+		"c.supprime_le IS NULL AND c.code_insee_epci IS NOT NULL",
+		"GROUP BY c.code_insee_epci, p.annee, p.millesime",
+	} {
+		if !strings.Contains(aggregate, expected) {
+			t.Errorf("Expected %q in aggregation SQL:\n%s", expected, aggregate)
+		}
+	}
+}
+
+// TestPopulationReconciliation_Check tests that commune rows neither aggregated nor reported fail the reconciliation
+func TestPopulationReconciliation_Check(t *testing.T) {
+	level := populationLevels[0]
+
+	tests := []struct {
+		name           string
+		reconciliation populationReconciliation
+		expectError    bool
+	}{
+		{name: "every row aggregated", reconciliation: populationReconciliation{communeRows: 100, aggregated: 100}},
+		{name: "communes without EPCI", reconciliation: populationReconciliation{communeRows: 100, orphans: 4, aggregated: 96}},
+		{name: "unknown communes", reconciliation: populationReconciliation{communeRows: 100, unknown: 2, orphans: 4, aggregated: 94}},
+		{name: "missing rows", reconciliation: populationReconciliation{communeRows: 100, orphans: 4, aggregated: 90}, expectError: true},
+		{name: "rows counted twice", reconciliation: populationReconciliation{communeRows: 100, aggregated: 101}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.reconciliation.check(level)
+			if (err != nil) != tt.expectError {
+				t.Errorf("check() error = %v, expectError %v", err, tt.expectError)
+			}
+		})
+	}
+}
@@ -0,0 +1,467 @@
+package repository
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"french-admin-etl/internal/model"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ReferencePolicy selects what happens to a row whose reference (ref= column option of the etl tag) has no
+// row in the referenced table, e.g. a commune whose EPCI is missing from ref_admin.epci.
+type ReferencePolicy string
+
+const (
+	// ReferenceNull loads the row with a NULL reference, reported at the end of the run and recorded in
+	// pendingReferencesTable, without being back-filled.
+	ReferenceNull ReferencePolicy = "null"
+	// ReferenceReject doesn't load the row, reported at the end of the run.
+	ReferenceReject ReferencePolicy = "reject"
+	// ReferenceDefer loads the row with a NULL reference, and records the reference in pendingReferencesTable.
+	// It is back-filled once a run loads the referenced row, e.g. when the EPCI are loaded after the communes.
+	ReferenceDefer ReferencePolicy = "defer"
+)
+
+// pendingReferencesTable holds the references nulled by ReferenceNull, and those deferred by ReferenceDefer
+// until their row is loaded.
+const pendingReferencesTable = "ref_admin.etl_pending_references"
+
+// ParseReferencePolicy converts a string to a ReferencePolicy, ReferenceNull when empty, returning an error
+// for unknown values.
+func ParseReferencePolicy(s string) (ReferencePolicy, error) {
+	switch policy := ReferencePolicy(s); policy {
+	case "":
+		return ReferenceNull, nil
+	case ReferenceNull, ReferenceReject, ReferenceDefer:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid reference policy %q, must be null, reject or defer", s)
+	}
+}
+
+// UnresolvedReference is a reference of a row of the run that has no row in the referenced table.
+type UnresolvedReference struct {
+	Table     string
+	Key       []any
+	Column    string
+	Reference string
+	Policy    ReferencePolicy // what happened to the row
+}
+
+// referenceState resolves the references of the rows of a run against the referenced tables, and collects
+// the unresolved ones.
+type referenceState struct {
+	policy    ReferencePolicy
+	reportDir string // empty when no report is written
+
+	mu         sync.Mutex
+	unresolved []UnresolvedReference
+}
+
+func newReferenceState(policy ReferencePolicy, reportDir string) *referenceState {
+	if policy == "" {
+		policy = ReferenceNull
+	}
+	return &referenceState{policy: policy, reportDir: reportDir}
+}
+
+// add records the unresolved references of a row.
+func (s *referenceState) add(references []UnresolvedReference) {
+	if len(references) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unresolved = append(s.unresolved, references...)
+}
+
+// references returns the unresolved references of the run, sorted by column and key.
+func (s *referenceState) references() []UnresolvedReference {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	references := append([]UnresolvedReference(nil), s.unresolved...)
+	sort.SliceStable(references, func(i, j int) bool {
+		if references[i].Column != references[j].Column {
+			return references[i].Column < references[j].Column
+		}
+		return fmt.Sprint(references[i].Key...) < fmt.Sprint(references[j].Key...)
+	})
+	return references
+}
+
+// reset forgets the unresolved references, so that the repository can be used for another run.
+func (s *referenceState) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unresolved = nil
+}
+
+// referenceCounts names the count of the unresolved references of a run in its results, by policy.
+var referenceCounts = map[ReferencePolicy]string{
+	ReferenceNull:   "references_nulled",
+	ReferenceReject: "references_rejected",
+	ReferenceDefer:  "references_deferred",
+}
+
+// counts returns the number of unresolved references of the run, named after the policy applied to them.
+func (s *referenceState) counts() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return map[string]int{referenceCounts[s.policy]: len(s.unresolved)}
+}
+
+// referenceValue returns the reference held by the value of a ref= column, false when the row has none
+// (empty string or nil pointer).
+func referenceValue(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, v != ""
+	case *string:
+		if v == nil || *v == "" {
+			return "", false
+		}
+		return *v, true
+	default:
+		return "", false
+	}
+}
+
+// existingReferencesSQL generates the query returning, among the references $1 of the ref= column, those
+// having a row in the referenced table, within the vintage $2 when both tables are vintaged. A soft-deleted
+// row doesn't resolve a reference.
+func (m *entityMetadata) existingReferencesSQL(column columnMetadata) string {
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s", column.ref.column, column.ref.table, column.ref.condition("ANY($1)", m.referenceVintage(column)))
+}
+
+// referenceVintage returns the placeholder of the vintage of the run in existingReferencesSQL, empty when
+// the references of the column are not resolved within a vintage.
+func (m *entityMetadata) referenceVintage(column columnMetadata) string {
+	if m.vintage == "" || column.ref.vintage == "" {
+		return ""
+	}
+	return "$2"
+}
+
+// existingReferences returns, by column index, the references among the values of the ref= columns of
+// rows that have a row in their referenced table, within the vintage of the run and not soft-deleted.
+func (t *entityTable) existingReferences(ctx context.Context, rows [][]any, vintage model.Vintage) (map[int]map[string]bool, error) {
+	existing := make(map[int]map[string]bool)
+	for i, column := range t.metadata.columns {
+		if column.ref == nil {
+			continue
+		}
+
+		references := make([]string, 0, len(rows))
+		for _, values := range rows {
+			if reference, ok := referenceValue(values[i]); ok {
+				references = append(references, reference)
+			}
+		}
+
+		found := make(map[string]bool, len(references))
+		existing[i] = found
+		if len(references) == 0 {
+			continue
+		}
+
+		args := []any{references}
+		if t.metadata.referenceVintage(column) != "" {
+			args = append(args, int(vintage))
+		}
+		dbRows, err := t.databaseManager.pool.Query(ctx, t.metadata.existingReferencesSQL(column), args...)
+		if err != nil {
+			return nil, fmt.Errorf("error checking the references of %s to %s: %w", column.name, column.ref.table, err)
+		}
+		for dbRows.Next() {
+			var reference string
+			if err := dbRows.Scan(&reference); err != nil {
+				dbRows.Close()
+				return nil, fmt.Errorf("error checking the references of %s to %s: %w", column.name, column.ref.table, err)
+			}
+			found[reference] = true
+		}
+		dbRows.Close()
+		if err := dbRows.Err(); err != nil {
+			return nil, fmt.Errorf("error checking the references of %s to %s: %w", column.name, column.ref.table, err)
+		}
+	}
+	return existing, nil
+}
+
+// resolveReferences applies the reference policy to the column values of the rows of a batch: unresolved
+// references are replaced by NULL in a copy of the values, and returned by row. A rejected row is nil in
+// the returned values, its references being recorded at once. In dry run, references can't be checked and
+// are left unchanged.
+func (t *entityTable) resolveReferences(ctx context.Context, rows [][]any, keys [][]any, vintage model.Vintage) ([][]any, [][]UnresolvedReference, error) {
+	unresolved := make([][]UnresolvedReference, len(rows))
+	if t.databaseManager.dryRun || !t.metadata.hasReferences() {
+		return rows, unresolved, nil
+	}
+
+	existing, err := t.existingReferences(ctx, rows, vintage)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resolved := make([][]any, len(rows))
+	for r, values := range rows {
+		resolved[r] = values
+		for i, column := range t.metadata.columns {
+			found, ok := existing[i]
+			if !ok {
+				continue
+			}
+			if reference, ok := referenceValue(values[i]); ok && !found[reference] {
+				if len(unresolved[r]) == 0 {
+					resolved[r] = slices.Clone(values)
+				}
+				resolved[r][i] = nil
+				unresolved[r] = append(unresolved[r], UnresolvedReference{
+					Table:     t.metadata.table,
+					Key:       keys[r],
+					Column:    column.name,
+					Reference: reference,
+					Policy:    t.references.policy,
+				})
+			}
+		}
+
+		if len(unresolved[r]) > 0 && t.references.policy == ReferenceReject {
+			t.references.add(unresolved[r])
+			resolved[r] = nil
+			unresolved[r] = nil
+		}
+	}
+	return resolved, unresolved, nil
+}
+
+// loaded records the unresolved references of the rows loaded by a batch, and the nulled or deferred ones
+// in pendingReferencesTable.
+func (t *entityTable) loaded(ctx context.Context, unresolved [][]UnresolvedReference, rowErrors []error, vintage model.Vintage) error {
+	var references []UnresolvedReference
+	for i, rowErr := range rowErrors {
+		if rowErr == nil {
+			references = append(references, unresolved[i]...)
+		}
+	}
+	if len(references) == 0 {
+		return nil
+	}
+	t.references.add(references)
+
+	if t.references.policy == ReferenceReject || t.databaseManager.dryRun {
+		return nil
+	}
+	return t.recordReferences(ctx, references, vintage)
+}
+
+// recordReferences records the references in pendingReferencesTable, the deferred ones to be back-filled by
+// backfillReferences.
+func (t *entityTable) recordReferences(ctx context.Context, references []UnresolvedReference, vintage model.Vintage) error {
+	keyColumns := t.metadata.keyColumns()
+	var millesime *int
+	if t.metadata.vintage != "" {
+		millesime = new(int)
+		*millesime = int(vintage)
+	}
+
+	batch := &pgx.Batch{}
+	for _, reference := range references {
+		ref := t.metadata.columns[t.metadata.columnIndex(reference.Column)].ref
+		keyValues := make([]string, 0, len(keyColumns))
+		for _, value := range reference.Key {
+			keyValues = append(keyValues, fmt.Sprint(value))
+		}
+		if millesime != nil {
+			keyValues = append(keyValues, fmt.Sprint(*millesime))
+		}
+		batch.Queue(recordReferenceSQL, t.metadata.table, keyColumns, keyValues, reference.Column, ref.table, ref.column, reference.Reference, millesime, string(reference.Policy))
+	}
+	if err := t.databaseManager.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("error recording the unresolved references of %s: %w", t.metadata.table, err)
+	}
+	return nil
+}
+
+// recordReferenceSQL records a nulled or deferred reference, replacing the one of a previous run.
+var recordReferenceSQL = fmt.Sprintf(`
+		INSERT INTO %s (table_name, key_columns, key_values, column_name, ref_table, ref_column, reference, millesime, policy)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (table_name, column_name, key_values) DO UPDATE SET
+			ref_table = EXCLUDED.ref_table,
+			ref_column = EXCLUDED.ref_column,
+			reference = EXCLUDED.reference,
+			policy = EXCLUDED.policy,
+			deferred_at = now()
+	`, pendingReferencesTable)
+
+// clearDeferredReferences forgets the references of the table nulled or deferred by a previous run of the
+// vintage, the run loading its rows again.
+func (t *entityTable) clearDeferredReferences(ctx context.Context) error {
+	if t.databaseManager.dryRun || !t.metadata.hasReferences() {
+		return nil
+	}
+	vintage, err := t.runVintage(ctx)
+	if err != nil {
+		return err
+	}
+
+	sql := fmt.Sprintf("DELETE FROM %s WHERE table_name = $1", pendingReferencesTable)
+	args := []any{t.metadata.table}
+	if t.metadata.vintage != "" {
+		sql += " AND millesime = $2"
+		args = append(args, int(vintage))
+	}
+	if _, err := t.databaseManager.pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("error clearing the deferred references of %s: %w", t.metadata.table, err)
+	}
+	return nil
+}
+
+// pendingGroup is a set of deferred references to the table, of the same referencing table and column.
+type pendingGroup struct {
+	table      string
+	column     string
+	refColumn  string
+	keyColumns []string
+}
+
+// backfillSQL generates the statement setting the deferred references of the group whose row now exists in
+// the referenced table refTable, and removing them from pendingReferencesTable. Nulled references are left
+// as they are. Key values are compared as text, as they are stored.
+func (g pendingGroup) backfillSQL(refTable, refVintage string) string {
+	joins := make([]string, len(g.keyColumns))
+	for i, column := range g.keyColumns {
+		joins[i] = fmt.Sprintf("t.%s::text = resolved.key_values[%d]", pgx.Identifier{column}.Sanitize(), i+1)
+	}
+	exists := fmt.Sprintf("r.%s = p.reference", pgx.Identifier{g.refColumn}.Sanitize())
+	if refVintage != "" {
+		exists += fmt.Sprintf(" AND r.%s = p.millesime", refVintage)
+	}
+
+	return fmt.Sprintf(`
+		WITH resolved AS (
+			DELETE FROM %s p
+			WHERE p.ref_table = $1 AND p.table_name = $2 AND p.column_name = $3 AND p.policy = 'defer'
+				AND EXISTS (SELECT 1 FROM %s r WHERE %s)
+			RETURNING p.key_values, p.reference
+		)
+		UPDATE %s t SET %s = resolved.reference
+		FROM resolved
+		WHERE %s
+	`, pendingReferencesTable, refTable, exists, sanitizeTableName(g.table), pgx.Identifier{g.column}.Sanitize(), strings.Join(joins, " AND "))
+}
+
+// sanitizeTableName quotes the schema and name of a schema-qualified table.
+func sanitizeTableName(table string) string {
+	schema, name := splitTableName(table)
+	return pgx.Identifier{schema, name}.Sanitize()
+}
+
+// backfillReferences sets the references to the table deferred by the runs of other tables, whose row
+// was loaded by the run, in a single transaction.
+func (t *entityTable) backfillReferences(ctx context.Context) error {
+	if t.databaseManager.dryRun {
+		return nil
+	}
+
+	tx, err := t.databaseManager.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	rows, err := tx.Query(ctx, fmt.Sprintf(`
+		SELECT DISTINCT table_name, column_name, ref_column, key_columns
+		FROM %s
+		WHERE ref_table = $1 AND policy = 'defer'
+	`, pendingReferencesTable), t.metadata.table)
+	if err != nil {
+		return fmt.Errorf("error listing the references deferred to %s: %w", t.metadata.table, err)
+	}
+	groups, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (pendingGroup, error) {
+		var g pendingGroup
+		err := row.Scan(&g.table, &g.column, &g.refColumn, &g.keyColumns)
+		return g, err
+	})
+	if err != nil {
+		return fmt.Errorf("error listing the references deferred to %s: %w", t.metadata.table, err)
+	}
+
+	for _, group := range groups {
+		tag, err := tx.Exec(ctx, group.backfillSQL(t.metadata.table, t.metadata.vintage), t.metadata.table, group.table, group.column)
+		if err != nil {
+			return fmt.Errorf("error back-filling the references of %s.%s: %w", group.table, group.column, err)
+		}
+		if tag.RowsAffected() > 0 {
+			slog.Info("Deferred references back-filled", "table", group.table, "column", group.column, "rows", tag.RowsAffected())
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// report logs the unresolved references of the run by column and writes them to
+// <reportDir>/<table>_references.csv.
+func (s *referenceState) report(table string) error {
+	references := s.references()
+
+	counts := make(map[string]int)
+	for _, reference := range references {
+		counts[reference.Column]++
+	}
+	for _, column := range slices.Sorted(maps.Keys(counts)) {
+		slog.Warn("References not resolved", "table", table, "column", column, "references", counts[column], "policy", s.policy)
+	}
+
+	if s.reportDir == "" {
+		return nil
+	}
+	filePath := filepath.Join(s.reportDir, table+"_references.csv")
+	if err := writeReferenceReport(filePath, references); err != nil {
+		return fmt.Errorf("error writing reference report: %w", err)
+	}
+	slog.Info("Reference report written", "file", filePath, "references", len(references))
+	return nil
+}
+
+// writeReferenceReport writes the unresolved references to a CSV file separated by semicolons.
+func writeReferenceReport(filePath string, references []UnresolvedReference) error {
+	// #nosec G304 -- filePath is controlled by the application, not user input
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	writer := csv.NewWriter(file)
+	writer.Comma = ';'
+	if err := writer.Write([]string{"table", "cle", "colonne", "reference", "politique"}); err != nil {
+		return err
+	}
+	for _, reference := range references {
+		key := make([]string, len(reference.Key))
+		for i, value := range reference.Key {
+			key[i] = fmt.Sprint(value)
+		}
+		err := writer.Write([]string{reference.Table, strings.Join(key, ","), reference.Column, reference.Reference, string(reference.Policy)})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return file.Close()
+}
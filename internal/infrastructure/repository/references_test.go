@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"french-admin-etl/internal/infrastructure/entities"
+	"french-admin-etl/internal/model"
+)
+
+// TestParseReferencePolicy tests the parsing of the reference policies
+func TestParseReferencePolicy(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected ReferencePolicy
+		wantErr  bool
+	}{
+		{input: "", expected: ReferenceNull},
+		{input: "null", expected: ReferenceNull},
+		{input: "reject", expected: ReferenceReject},
+		{input: "defer", expected: ReferenceDefer},
+		{input: "ignore", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseReferencePolicy(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseReferencePolicy(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("ParseReferencePolicy(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestReferenceValue tests that empty strings and nil pointers are not references
+func TestReferenceValue(t *testing.T) {
+	arm := "75101"
+	empty := ""
+	tests := []struct {
+		name     string
+		value    any
+		expected string
+		ok       bool
+	}{
+		{name: "string", value: "200054781", expected: "200054781", ok: true},
+		{name: "empty string", value: ""},
+		{name: "pointer", value: &arm, expected: "75101", ok: true},
+		{name: "nil pointer", value: (*string)(nil)},
+		{name: "pointer to empty string", value: &empty},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := referenceValue(tt.value)
+			if got != tt.expected || ok != tt.ok {
+				t.Errorf("referenceValue(%v) = %q, %v, want %q, %v", tt.value, got, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+// TestPendingGroup_BackfillSQL tests that deferred references are set on the rows of their key once the
+// referenced row exists in the same vintage
+func TestPendingGroup_BackfillSQL(t *testing.T) {
+	group := pendingGroup{
+		table:      "ref_admin.communes",
+		column:     "code_insee_epci",
+		refColumn:  "code_insee_epci",
+		keyColumns: []string{"code_insee_commune", "millesime"},
+	}
+
+	sql := group.backfillSQL("ref_admin.epci", "millesime")
+	for _, expected := range []string{
+		"DELETE FROM ref_admin.etl_pending_references p",
+		"p.column_name = $3 AND p.policy = 'defer'",
+		`EXISTS (SELECT 1 FROM ref_admin.epci r WHERE r."code_insee_epci" = p.reference AND r.millesime = p.millesime)`,
+		`UPDATE "ref_admin"."communes" t SET "code_insee_epci" = resolved.reference`,
+		`WHERE t."code_insee_commune"::text = resolved.key_values[1] AND t."millesime"::text = resolved.key_values[2]`,
+	} {
+		if !strings.Contains(sql, expected) {
+			t.Errorf("Expected %q in backfill SQL:\n%s", expected, sql)
+		}
+	}
+}
+
+// TestReferenceState_Report tests that the unresolved references are written sorted by column and key
+func TestReferenceState_Report(t *testing.T) {
+	dir := t.TempDir()
+	state := newReferenceState(ReferenceDefer, dir)
+	state.add([]UnresolvedReference{
+		{Table: "ref_admin.communes", Key: []any{"85113"}, Column: "code_insee_epci", Reference: "200000001", Policy: ReferenceDefer},
+		{Table: "ref_admin.communes", Key: []any{"29155"}, Column: "code_insee_epci", Reference: "200000002", Policy: ReferenceDefer},
+	})
+
+	if err := state.report("ref_admin.communes"); err != nil {
+		t.Fatalf("report() error = %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "ref_admin.communes_references.csv"))
+	if err != nil {
+		t.Fatalf("Failed to read report: %v", err)
+	}
+	expected := "table;cle;colonne;reference;politique\n" +
+		"ref_admin.communes;29155;code_insee_epci;200000002;defer\n" +
+		"ref_admin.communes;85113;code_insee_epci;200000001;defer\n"
+	if string(content) != expected {
+		t.Errorf("Expected report:\n%s\ngot:\n%s", expected, content)
+	}
+
+	state.reset()
+	if references := state.references(); len(references) != 0 {
+		t.Errorf("Expected references to be reset, got %v", references)
+	}
+}
+
+// TestEntityMetadata_ExistingReferencesSQL tests that soft-deleted rows of the vintage don't resolve references
+func TestEntityMetadata_ExistingReferencesSQL(t *testing.T) {
+	metadata, err := parseEntityMetadata(reflect.TypeFor[entities.CommuneEntity]())
+	if err != nil {
+		t.Fatalf("parseEntityMetadata() error = %v", err)
+	}
+
+	sql := metadata.existingReferencesSQL(metadata.columns[metadata.columnIndex("code_insee_epci")])
+	expected := "SELECT code_insee_epci FROM ref_admin.epci WHERE code_insee_epci = ANY($1) AND supprime_le IS NULL AND millesime = $2"
+	if sql != expected {
+		t.Errorf("Expected %q, got %q", expected, sql)
+	}
+}
+
+// TestReference_Condition tests that the condition resolving a reference follows the deleted and vintage
+// options of the referenced table, and skips them when it has none
+func TestReference_Condition(t *testing.T) {
+	tests := []struct {
+		name      string
+		reference reference
+		vintage   string
+		expected  string
+	}{
+		{name: "soft-deleted and vintaged", reference: reference{column: "code", deleted: "supprime_le", vintage: "millesime"}, vintage: "$2", expected: "code = ANY($1) AND supprime_le IS NULL AND millesime = $2"},
+		{name: "other column names", reference: reference{column: "code", deleted: "deleted_at", vintage: "annee_geo"}, vintage: "$2", expected: "code = ANY($1) AND deleted_at IS NULL AND annee_geo = $2"},
+		{name: "without soft-delete", reference: reference{column: "code", vintage: "millesime"}, vintage: "$2", expected: "code = ANY($1) AND millesime = $2"},
+		{name: "without vintage", reference: reference{column: "code", deleted: "supprime_le"}, vintage: "$2", expected: "code = ANY($1) AND supprime_le IS NULL"},
+		{name: "referencing table without vintage", reference: reference{column: "code", vintage: "millesime"}, expected: "code = ANY($1)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.reference.condition("ANY($1)", tt.vintage); got != tt.expected {
+				t.Errorf("condition() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestParseEntityMetadata_ReferencedTable tests that the options of a reference are read from the entity of
+// the referenced table, a table of no referenced entity having neither soft-delete nor vintage
+func TestParseEntityMetadata_ReferencedTable(t *testing.T) {
+	type entity struct {
+		_       struct{} `etl:"table=ref_admin.test,vintage=millesime"`
+		Code    string   `etl:"code,key"`
+		Commune string   `etl:"code_insee_commune,ref=ref_admin.communes(code_insee_commune)"`
+		Parent  string   `etl:"code_parent,ref=ref_admin.parents(code)"`
+	}
+
+	metadata, err := parseEntityMetadata(reflect.TypeFor[entity]())
+	if err != nil {
+		t.Fatalf("parseEntityMetadata() error = %v", err)
+	}
+	expected := reference{table: "ref_admin.communes", column: "code_insee_commune", deleted: "supprime_le", vintage: "millesime"}
+	if got := *metadata.columns[1].ref; got != expected {
+		t.Errorf("Expected reference %+v, got %+v", expected, got)
+	}
+	if sql := metadata.existingReferencesSQL(metadata.columns[2]); sql != "SELECT code FROM ref_admin.parents WHERE code = ANY($1)" {
+		t.Errorf("Unexpected SQL for a table without soft-delete nor vintage: %q", sql)
+	}
+}
+
+// TestReferenceState_Counts tests that the unresolved references are counted under the name of the policy
+func TestReferenceState_Counts(t *testing.T) {
+	tests := []struct {
+		policy ReferencePolicy
+		name   string
+	}{
+		{policy: ReferenceNull, name: "references_nulled"},
+		{policy: ReferenceReject, name: "references_rejected"},
+		{policy: ReferenceDefer, name: "references_deferred"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			state := newReferenceState(tt.policy, "")
+			if counts := state.counts(); counts[tt.name] != 0 || len(counts) != 1 {
+				t.Errorf("Expected no %s, got %v", tt.name, counts)
+			}
+			state.add([]UnresolvedReference{
+				{Table: "ref_admin.communes", Key: []any{"85113"}, Column: "code_insee_epci", Reference: "200000001", Policy: tt.policy},
+				{Table: "ref_admin.communes", Key: []any{"29155"}, Column: "code_insee_epci", Reference: "200000002", Policy: tt.policy},
+			})
+			if counts := state.counts(); counts[tt.name] != 2 || len(counts) != 1 {
+				t.Errorf("Expected 2 %s, got %v", tt.name, counts)
+			}
+		})
+	}
+
+	if counts := NewRegionRepository(NewDryRunDatabaseManager()).(model.LoadCounter).Counts(); counts != nil {
+		t.Errorf("Expected no counts for a table without references, got %v", counts)
+	}
+}
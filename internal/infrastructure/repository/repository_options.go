@@ -0,0 +1,87 @@
+package repository
+
+import "french-admin-etl/internal/validation"
+
+// RepositoryOption configures optional behaviour of a repository.
+type RepositoryOption func(*repositoryOptions)
+
+type repositoryOptions struct {
+	syncMode        SyncMode
+	shadowTable     bool
+	maxRemovalRatio float64
+	pivot           *PivotSpec            // nil for the default pivot of the population repositories
+	validator       *validation.Validator // nil when the population figures are not checked
+	referencePolicy ReferencePolicy       // empty for ReferenceNull
+	referenceReport string                // directory of the reference reports, empty when none is written
+	adjacency       bool                  // updates the adjacency graph of the table, see WithAdjacency
+}
+
+func newRepositoryOptions(opts []RepositoryOption) repositoryOptions {
+	options := repositoryOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// populationPivot returns the pivot of the population repositories.
+func (o repositoryOptions) populationPivot() *PivotSpec {
+	if o.pivot != nil {
+		return o.pivot
+	}
+	return DefaultPopulationPivot()
+}
+
+// WithSync is an option to remove, when the run is finalized, the rows whose key was absent from the source.
+// The sync is aborted if more than maxRemovalRatio (0 to 1) of the rows would be removed.
+func WithSync(mode SyncMode, maxRemovalRatio float64) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.syncMode = mode
+		o.maxRemovalRatio = maxRemovalRatio
+	}
+}
+
+// WithShadowTable is an option to load the run into a shadow copy of the table (<table>__shadow), swapped in
+// when the run is finalized. The swap is aborted if the shadow table has more than maxRemovalRatio (0 to 1)
+// fewer rows than the live table. The swapped-out table is kept as <table>__previous, see RollbackSwap.
+func WithShadowTable(maxRemovalRatio float64) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.shadowTable = true
+		o.maxRemovalRatio = maxRemovalRatio
+	}
+}
+
+// WithPivot is an option of the population repositories, setting the pivot of the age/sex figures to the
+// columns of their tables, instead of DefaultPopulationPivot.
+func WithPivot(spec *PivotSpec) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.pivot = spec
+	}
+}
+
+// WithValidation is an option of the population repositories, checking the figures of the records loaded
+// against the rules of validator, by the names of the columns of the pivot. The run fails before the shadow
+// tables are swapped in when the validator blocks it.
+func WithValidation(validator *validation.Validator) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.validator = validator
+	}
+}
+
+// WithReferencePolicy is an option selecting what happens to the rows whose reference to another table
+// (ref= column option of the etl tag) doesn't resolve, instead of ReferenceNull. The unresolved references of
+// the run are written to <reportDir>/<table>_references.csv when reportDir is not empty.
+func WithReferencePolicy(policy ReferencePolicy, reportDir string) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.referencePolicy = policy
+		o.referenceReport = reportDir
+	}
+}
+
+// WithAdjacency is an option of the commune and département repositories, updating the adjacency graph
+// ref_admin.communes_voisines of their level from the geometries of the run once it is loaded, see UpdateAdjacency.
+func WithAdjacency() RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.adjacency = true
+	}
+}
@@ -0,0 +1,370 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"french-admin-etl/internal/model"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// shadowSuffix names the table receiving the rows of a run before it is swapped in.
+	shadowSuffix = "__shadow"
+	// previousSuffix names the table swapped out by the last swap, kept for rollback.
+	previousSuffix = "__previous"
+	// swapSuffix names the live table while it is being swapped out.
+	swapSuffix = "__swap"
+)
+
+// shadowTable loads a run into a copy of a table, then swaps it in with renames in a single transaction,
+// so that readers never see a half-updated table. The swapped-out table is kept as <table>__previous.
+type shadowTable struct {
+	table           string // live table, with its schema
+	vintage         string // vintage column, empty if the table holds a single vintage
+	maxRemovalRatio float64
+}
+
+func newShadowTable(table, vintage string, maxRemovalRatio float64) *shadowTable {
+	return &shadowTable{table: table, vintage: vintage, maxRemovalRatio: maxRemovalRatio}
+}
+
+// name returns the name of the shadow table, with its schema.
+func (s *shadowTable) name() string {
+	return s.table + shadowSuffix
+}
+
+// splitTableName splits a schema-qualified table name.
+func splitTableName(table string) (schema, name string) {
+	schema, name, ok := strings.Cut(table, ".")
+	if !ok {
+		return "public", table
+	}
+	return schema, name
+}
+
+// create (re)creates the shadow table with the columns, defaults, constraints and indexes of the live table.
+// Foreign keys are not copied by LIKE, they are added from the definitions of the live table.
+// For a vintaged table, the rows of the other vintages are copied: only the vintage of the run is replaced.
+func (s *shadowTable) create(ctx context.Context, dm *DatabaseManager) error {
+	vintage, hasVintage := model.VintageFromContext(ctx)
+	if s.vintage != "" && !hasVintage {
+		return fmt.Errorf("table %s is vintaged, the run must have a vintage", s.table)
+	}
+	if dm.dryRun {
+		slog.Info("Shadow table skipped in dry run", "table", s.table)
+		return nil
+	}
+
+	tx, err := dm.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", s.name())); err != nil {
+		return fmt.Errorf("error dropping shadow table: %w", err)
+	}
+	if _, err := tx.Exec(ctx, fmt.Sprintf("CREATE TABLE %s (LIKE %s INCLUDING ALL)", s.name(), s.table)); err != nil {
+		return fmt.Errorf("error creating shadow table: %w", err)
+	}
+
+	if s.vintage != "" {
+		copyOthers := fmt.Sprintf("INSERT INTO %s SELECT * FROM %s WHERE %s <> $1", s.name(), s.table, s.vintage)
+		if _, err := tx.Exec(ctx, copyOthers, int(vintage)); err != nil {
+			return fmt.Errorf("error copying the other vintages to the shadow table: %w", err)
+		}
+	}
+
+	outgoing, err := foreignKeyDefinitions(ctx, tx, "conrelid", s.table)
+	if err != nil {
+		return fmt.Errorf("error listing foreign keys of %s: %w", s.table, err)
+	}
+	for _, fk := range outgoing {
+		if _, err := tx.Exec(ctx, fk.addSQL(s.name(), false)); err != nil {
+			return fmt.Errorf("error copying foreign key %s: %w", fk.name, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	slog.Info("Shadow table created", "table", s.name())
+	return nil
+}
+
+// checkShadowCount aborts the swap when the shadow table is empty or lost too many rows compared to the live table.
+func checkShadowCount(table string, shadow, live int64, maxRemovalRatio float64) error {
+	if shadow == 0 {
+		return fmt.Errorf("swap of %s aborted: the shadow table is empty", table)
+	}
+	if shadow >= live {
+		return nil
+	}
+	if ratio := float64(live-shadow) / float64(live); ratio > maxRemovalRatio {
+		return fmt.Errorf("swap of %s aborted: %d rows instead of %d (%.1f%% removed), above the %.1f%% threshold",
+			table, shadow, live, ratio*100, maxRemovalRatio*100)
+	}
+	return nil
+}
+
+// countSQL returns the query counting the rows of the shadow and live tables. For a vintaged table, only the
+// rows of the vintage $1 are counted, the other vintages being copied to the shadow table as they are.
+func (s *shadowTable) countSQL() string {
+	where := ""
+	if s.vintage != "" {
+		where = fmt.Sprintf(" WHERE %s = $1", s.vintage)
+	}
+	return fmt.Sprintf("SELECT (SELECT count(*) FROM %s%s), (SELECT count(*) FROM %s%s)", s.name(), where, s.table, where)
+}
+
+// validateAndSwap checks the row count of the shadow table, within the vintage of the run, and swaps it in.
+func (s *shadowTable) validateAndSwap(ctx context.Context, dm *DatabaseManager) error {
+	vintage, hasVintage := model.VintageFromContext(ctx)
+	if s.vintage != "" && !hasVintage {
+		return fmt.Errorf("table %s is vintaged, the run must have a vintage", s.table)
+	}
+	if dm.dryRun {
+		slog.Info("Swap skipped in dry run", "table", s.table)
+		return nil
+	}
+
+	var args []any
+	if s.vintage != "" {
+		args = append(args, int(vintage))
+	}
+	var shadow, live int64
+	if err := dm.pool.QueryRow(ctx, s.countSQL(), args...).Scan(&shadow, &live); err != nil {
+		return fmt.Errorf("error counting rows of the shadow table: %w", err)
+	}
+	if err := checkShadowCount(s.table, shadow, live, s.maxRemovalRatio); err != nil {
+		return err
+	}
+
+	if err := swapTable(ctx, dm, s.table, s.name()); err != nil {
+		return err
+	}
+
+	slog.Info("Shadow table swapped in", "table", s.table, "vintage", int(vintage), "rows", shadow, "previousRows", live, "previous", s.table+previousSuffix)
+	return nil
+}
+
+// RollbackSwap swaps back the previous version of a table (<table>__previous) kept by the last swap.
+// The current version becomes the previous one, so that a rollback can itself be rolled back.
+func RollbackSwap(ctx context.Context, dm *DatabaseManager, table string) error {
+	if dm.dryRun {
+		slog.Info("Rollback skipped in dry run", "table", table)
+		return nil
+	}
+
+	if err := swapTable(ctx, dm, table, table+previousSuffix); err != nil {
+		return err
+	}
+
+	slog.Info("Swap rolled back", "table", table)
+	return nil
+}
+
+// swapTable replaces the live table by the replacement table in a single transaction. The live table becomes
+// <table>__previous, replacing the former one. Foreign keys referencing the live table and the sequences it
+// owns are moved to the replacement.
+func swapTable(ctx context.Context, dm *DatabaseManager, table, replacement string) error {
+	schema, name := splitTableName(table)
+	_, replacementName := splitTableName(replacement)
+	previous := table + previousSuffix
+
+	tx, err := dm.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Readers wait for the end of the swap rather than seeing a missing table
+	if _, err := tx.Exec(ctx, fmt.Sprintf("LOCK TABLE %s, %s IN ACCESS EXCLUSIVE MODE", table, replacement)); err != nil {
+		return fmt.Errorf("error locking %s: %w", table, err)
+	}
+
+	incoming, err := foreignKeyDefinitions(ctx, tx, "confrelid", table)
+	if err != nil {
+		return fmt.Errorf("error listing foreign keys referencing %s: %w", table, err)
+	}
+	outgoing, err := foreignKeyDefinitions(ctx, tx, "conrelid", table)
+	if err != nil {
+		return fmt.Errorf("error listing foreign keys of %s: %w", table, err)
+	}
+	sequences, err := ownedSequences(ctx, tx, table)
+	if err != nil {
+		return fmt.Errorf("error listing sequences of %s: %w", table, err)
+	}
+
+	for _, fk := range incoming {
+		if _, err := tx.Exec(ctx, fk.dropSQL()); err != nil {
+			return fmt.Errorf("error dropping foreign key %s: %w", fk.name, err)
+		}
+	}
+
+	swapped := table + swapSuffix
+	statements := []string{
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", table, pgx.Identifier{name + swapSuffix}.Sanitize()),
+		fmt.Sprintf("ALTER TABLE %s.%s RENAME TO %s", schema, pgx.Identifier{replacementName}.Sanitize(), pgx.Identifier{name}.Sanitize()),
+		fmt.Sprintf("DROP TABLE IF EXISTS %s", previous),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", swapped, pgx.Identifier{name + previousSuffix}.Sanitize()),
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("error swapping %s: %w", table, err)
+		}
+	}
+
+	// The previous version is a snapshot: it doesn't keep references to other tables
+	for _, fk := range outgoing {
+		if _, err := tx.Exec(ctx, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s", previous, pgx.Identifier{fk.name}.Sanitize())); err != nil {
+			return fmt.Errorf("error dropping foreign key %s of %s: %w", fk.name, previous, err)
+		}
+		if _, err := tx.Exec(ctx, fk.addIfMissingSQL(table)); err != nil {
+			return fmt.Errorf("error adding foreign key %s to %s: %w", fk.name, table, err)
+		}
+	}
+
+	// Sequences (serial columns) must not be dropped with the previous version
+	for _, seq := range sequences {
+		if _, err := tx.Exec(ctx, fmt.Sprintf("ALTER SEQUENCE %s OWNED BY %s.%s", seq.sequence, table, pgx.Identifier{seq.column}.Sanitize())); err != nil {
+			return fmt.Errorf("error moving sequence %s: %w", seq.sequence, err)
+		}
+	}
+
+	// Rows referencing entities absent from the new version are kept: the foreign key is then not validated
+	for _, fk := range incoming {
+		var orphans int64
+		if err := tx.QueryRow(ctx, fk.orphansSQL(table)).Scan(&orphans); err != nil {
+			return fmt.Errorf("error counting rows of %s referencing removed entities: %w", fk.table, err)
+		}
+		if orphans > 0 {
+			slog.Warn("Rows referencing entities absent from the new version, foreign key not validated",
+				"table", fk.table, "constraint", fk.name, "rows", orphans)
+		}
+		if _, err := tx.Exec(ctx, fk.addSQL(fk.table, orphans > 0)); err != nil {
+			return fmt.Errorf("error restoring foreign key %s: %w", fk.name, err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// foreignKeyDefinition is a foreign key constraint with its definition.
+type foreignKeyDefinition struct {
+	table      string // table holding the constraint
+	name       string
+	definition string // as returned by pg_get_constraintdef, e.g. FOREIGN KEY (a) REFERENCES t(b)
+	columns    []string
+	refTable   string
+	refColumns []string
+}
+
+// foreignKeyDefinitions lists the foreign keys held by (side = conrelid) or referencing (side = confrelid) the table.
+func foreignKeyDefinitions(ctx context.Context, tx pgx.Tx, side, table string) ([]foreignKeyDefinition, error) {
+	if side != "conrelid" && side != "confrelid" {
+		return nil, fmt.Errorf("invalid foreign key side %q", side)
+	}
+
+	// #nosec G201 -- side is one of two constants
+	rows, err := tx.Query(ctx, fmt.Sprintf(`
+		SELECT c.conrelid::regclass::text, c.conname, pg_get_constraintdef(c.oid), c.confrelid::regclass::text,
+			ARRAY(SELECT a.attname FROM unnest(c.conkey) WITH ORDINALITY k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum ORDER BY k.ord)::text[],
+			ARRAY(SELECT a.attname FROM unnest(c.confkey) WITH ORDINALITY k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = c.confrelid AND a.attnum = k.attnum ORDER BY k.ord)::text[]
+		FROM pg_constraint c
+		WHERE c.contype = 'f' AND c.%s = $1::regclass AND c.conrelid <> c.confrelid
+		ORDER BY 1, 2
+	`, side), table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var definitions []foreignKeyDefinition
+	for rows.Next() {
+		var fk foreignKeyDefinition
+		if err := rows.Scan(&fk.table, &fk.name, &fk.definition, &fk.refTable, &fk.columns, &fk.refColumns); err != nil {
+			return nil, err
+		}
+		definitions = append(definitions, fk)
+	}
+	return definitions, rows.Err()
+}
+
+func (fk foreignKeyDefinition) dropSQL() string {
+	return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", fk.table, pgx.Identifier{fk.name}.Sanitize())
+}
+
+// addSQL returns the statement adding the foreign key to the table, not validating existing rows if notValid.
+func (fk foreignKeyDefinition) addSQL(table string, notValid bool) string {
+	sql := fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s", table, pgx.Identifier{fk.name}.Sanitize(), fk.definition)
+	if notValid {
+		sql += " NOT VALID"
+	}
+	return sql
+}
+
+// addIfMissingSQL returns the statement adding the foreign key to the table unless it already has a constraint of that name,
+// without validating existing rows.
+func (fk foreignKeyDefinition) addIfMissingSQL(table string) string {
+	return fmt.Sprintf(`DO $$ BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conrelid = %s::regclass AND conname = %s) THEN
+			%s;
+		END IF;
+	END $$`, quoteLiteral(table), quoteLiteral(fk.name), fk.addSQL(table, true))
+}
+
+// orphansSQL returns the query counting the rows of the referencing table whose reference is missing from the referenced table.
+func (fk foreignKeyDefinition) orphansSQL(refTable string) string {
+	columns := make([]string, len(fk.columns))
+	joins := make([]string, len(fk.columns))
+	for i, column := range fk.columns {
+		columns[i] = "d." + pgx.Identifier{column}.Sanitize() + " IS NOT NULL"
+		joins[i] = fmt.Sprintf("r.%s = d.%s", pgx.Identifier{fk.refColumns[i]}.Sanitize(), pgx.Identifier{column}.Sanitize())
+	}
+	return fmt.Sprintf("SELECT count(*) FROM %s d WHERE %s AND NOT EXISTS (SELECT 1 FROM %s r WHERE %s)",
+		fk.table, strings.Join(columns, " AND "), refTable, strings.Join(joins, " AND "))
+}
+
+// quoteLiteral quotes a string as an SQL literal.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// ownedSequence is a sequence owned by a column of a table (serial column).
+type ownedSequence struct {
+	column   string
+	sequence string
+}
+
+// ownedSequences lists the sequences owned by the columns of the table.
+func ownedSequences(ctx context.Context, tx pgx.Tx, table string) ([]ownedSequence, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT a.attname, pg_get_serial_sequence($1, a.attname)
+		FROM pg_attribute a
+		WHERE a.attrelid = $1::regclass AND a.attnum > 0 AND NOT a.attisdropped
+			AND pg_get_serial_sequence($1, a.attname) IS NOT NULL
+		ORDER BY a.attnum
+	`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sequences []ownedSequence
+	for rows.Next() {
+		var seq ownedSequence
+		if err := rows.Scan(&seq.column, &seq.sequence); err != nil {
+			return nil, err
+		}
+		sequences = append(sequences, seq)
+	}
+	return sequences, rows.Err()
+}
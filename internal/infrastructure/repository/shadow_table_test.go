@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+
+	"french-admin-etl/internal/infrastructure/entities"
+)
+
+// TestSplitTableName tests the split of schema-qualified table names
+func TestSplitTableName(t *testing.T) {
+	if schema, name := splitTableName("ref_admin.communes"); schema != "ref_admin" || name != "communes" {
+		t.Errorf("splitTableName() = %q, %q", schema, name)
+	}
+	if schema, name := splitTableName("communes"); schema != "public" || name != "communes" {
+		t.Errorf("splitTableName() = %q, %q", schema, name)
+	}
+}
+
+// TestCheckShadowCount tests the validation of the shadow table before the swap
+func TestCheckShadowCount(t *testing.T) {
+	tests := []struct {
+		name    string
+		shadow  int64
+		live    int64
+		wantErr bool
+	}{
+		{name: "first load", shadow: 100, live: 0},
+		{name: "more rows", shadow: 110, live: 100},
+		{name: "fewer rows below threshold", shadow: 95, live: 100},
+		{name: "fewer rows above threshold", shadow: 94, live: 100, wantErr: true},
+		{name: "empty shadow table", shadow: 0, live: 0, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkShadowCount("ref_admin.communes", tt.shadow, tt.live, 0.05); (err != nil) != tt.wantErr {
+				t.Errorf("checkShadowCount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestShadowTable_CountSQL tests that the rows of a vintaged table are only counted within the vintage of
+// the run, so that the other vintages copied to the shadow table don't dilute the removed rows
+func TestShadowTable_CountSQL(t *testing.T) {
+	vintaged := newShadowTable("ref_admin.communes", "millesime", 0.05)
+	expected := "SELECT (SELECT count(*) FROM ref_admin.communes__shadow WHERE millesime = $1), (SELECT count(*) FROM ref_admin.communes WHERE millesime = $1)"
+	if sql := vintaged.countSQL(); sql != expected {
+		t.Errorf("countSQL() = %q, want %q", sql, expected)
+	}
+
+	single := newShadowTable("ref_admin.commune_events", "", 0.05)
+	expected = "SELECT (SELECT count(*) FROM ref_admin.commune_events__shadow), (SELECT count(*) FROM ref_admin.commune_events)"
+	if sql := single.countSQL(); sql != expected {
+		t.Errorf("countSQL() = %q, want %q", sql, expected)
+	}
+}
+
+// TestForeignKeyDefinition_SQL tests the statements moving foreign keys during a swap
+func TestForeignKeyDefinition_SQL(t *testing.T) {
+	fk := foreignKeyDefinition{
+		table:      "demography.population_commune",
+		name:       "population_commune_code_insee_commune_fkey",
+		definition: "FOREIGN KEY (code_insee_commune) REFERENCES ref_admin.communes(code_insee_commune)",
+		columns:    []string{"code_insee_commune"},
+		refTable:   "ref_admin.communes",
+		refColumns: []string{"code_insee_commune"},
+	}
+
+	if got, want := fk.dropSQL(), `ALTER TABLE demography.population_commune DROP CONSTRAINT "population_commune_code_insee_commune_fkey"`; got != want {
+		t.Errorf("dropSQL() = %q, want %q", got, want)
+	}
+	if got := fk.addSQL(fk.table, true); !strings.HasSuffix(got, "REFERENCES ref_admin.communes(code_insee_commune) NOT VALID") {
+		t.Errorf("addSQL() = %q", got)
+	}
+	if got := fk.addSQL("demography.population_commune__shadow", false); strings.Contains(got, "NOT VALID") || !strings.HasPrefix(got, "ALTER TABLE demography.population_commune__shadow ADD CONSTRAINT") {
+		t.Errorf("addSQL() = %q", got)
+	}
+	if got := fk.addIfMissingSQL("demography.population_commune"); !strings.Contains(got, "conrelid = 'demography.population_commune'::regclass AND conname = 'population_commune_code_insee_commune_fkey'") {
+		t.Errorf("addIfMissingSQL() = %q", got)
+	}
+
+	expected := `SELECT count(*) FROM demography.population_commune d WHERE d."code_insee_commune" IS NOT NULL AND NOT EXISTS (SELECT 1 FROM ref_admin.communes r WHERE r."code_insee_commune" = d."code_insee_commune")`
+	if got := fk.orphansSQL("ref_admin.communes"); got != expected {
+		t.Errorf("orphansSQL() = %q, want %q", got, expected)
+	}
+}
+
+// TestGeoRepository_TargetTable tests that rows are loaded into the shadow table in shadow table mode
+func TestGeoRepository_TargetTable(t *testing.T) {
+	if got := NewGeoRepository[entities.CommuneEntity](nil).targetTable(); got != "ref_admin.communes" {
+		t.Errorf("targetTable() = %q, want ref_admin.communes", got)
+	}
+	if got := NewGeoRepository[entities.CommuneEntity](nil, WithShadowTable(0.05)).targetTable(); got != "ref_admin.communes__shadow" {
+		t.Errorf("targetTable() = %q, want ref_admin.communes__shadow", got)
+	}
+}
+
+// TestNewGeoRepository_SyncWithShadowTable tests that sync and shadow table options are exclusive
+func TestNewGeoRepository_SyncWithShadowTable(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for sync with shadow table")
+		}
+	}()
+	NewGeoRepository[entities.CommuneEntity](nil, WithSync(SyncDelete, 0.05), WithShadowTable(0.05))
+}
@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"french-admin-etl/internal/model"
+)
+
+// SpatialTolerance is the deviation accepted by the spatial consistency checks, simplified geometries
+// never matching exactly.
+type SpatialTolerance struct {
+	// GapRatio is the area by which the union of the communes of a département may differ from its
+	// contour (gaps plus overflows), as a ratio of the area of the département.
+	GapRatio float64 `json:"gap_ratio"`
+	// SliverArea is the area, in m², by which two communes may overlap.
+	SliverArea float64 `json:"sliver_m2"`
+}
+
+// DefaultSpatialTolerance accepts 0.1% of the area of a département, and overlaps below 1,000 m².
+var DefaultSpatialTolerance = SpatialTolerance{GapRatio: 0.001, SliverArea: 1000}
+
+// Names of the spatial consistency checks.
+const (
+	SpatialDepartementContour = "departement_contour"
+	SpatialCommuneOverlap     = "commune_chevauchement"
+	SpatialCommuneDepartement = "commune_hors_departement"
+	SpatialCommuneRegion      = "commune_hors_region"
+)
+
+// spatialChecks lists the spatial consistency checks, in their order of execution.
+var spatialChecks = []string{SpatialDepartementContour, SpatialCommuneOverlap, SpatialCommuneDepartement, SpatialCommuneRegion}
+
+// SpatialIssue is a geometry of a ref_admin table inconsistent with another one.
+type SpatialIssue struct {
+	Check string `json:"check"`
+	Code  string `json:"code"`
+	// Other is the other commune of an overlap, or the declared département or région of a commune
+	Other string `json:"other,omitempty"`
+	// GapM2 is the area of the département not covered by its communes
+	GapM2 float64 `json:"gap_m2,omitempty"`
+	// OverlapM2 is the area of the communes of a département outside of it, or the overlap of two communes
+	OverlapM2 float64 `json:"overlap_m2,omitempty"`
+}
+
+// SpatialReport is the outcome of CheckSpatialConsistency, counting the issues by check.
+type SpatialReport struct {
+	Millesime int              `json:"millesime"`
+	Column    string           `json:"column"`
+	Tolerance SpatialTolerance `json:"tolerance"`
+	Checks    map[string]int   `json:"checks"`
+	Issues    []SpatialIssue   `json:"issues"`
+}
+
+// Failed reports whether the report has issues.
+func (r *SpatialReport) Failed() bool {
+	return len(r.Issues) > 0
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *SpatialReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// add records an issue.
+func (r *SpatialReport) add(issue SpatialIssue) {
+	r.Issues = append(r.Issues, issue)
+	r.Checks[issue.Check]++
+}
+
+// exceedsGapRatio reports whether the gaps and overflows of the communes of a département exceed the
+// tolerance, a département without communes being entirely a gap.
+func (t SpatialTolerance) exceedsGapRatio(gap, overflow, area float64) bool {
+	return gap+overflow > t.GapRatio*area
+}
+
+// departementContourSQL generates the query comparing, for the vintage $1, the union of the communes of
+// each département with its contour, in the geometry column: the area of the département not covered by
+// its communes, the area of its communes outside of it, and its area, in m².
+func departementContourSQL(column string) string {
+	return fmt.Sprintf(`
+		WITH communes AS (
+			SELECT c.code_insee_departement, ST_Union(c.%[1]s::geometry) AS geom
+			FROM ref_admin.communes c
+			WHERE c.millesime = $1 AND c.supprime_le IS NULL AND c.%[1]s IS NOT NULL
+			GROUP BY c.code_insee_departement
+		)
+		SELECT d.code_insee_departement,
+			coalesce(ST_Area(ST_Difference(d.%[1]s::geometry, c.geom)::geography), ST_Area(d.%[1]s)),
+			coalesce(ST_Area(ST_Difference(c.geom, d.%[1]s::geometry)::geography), 0),
+			ST_Area(d.%[1]s)
+		FROM ref_admin.departements d
+		LEFT JOIN communes c ON c.code_insee_departement = d.code_insee_departement
+		WHERE d.millesime = $1 AND d.supprime_le IS NULL AND d.%[1]s IS NOT NULL
+		ORDER BY 1`, column)
+}
+
+// communeOverlapSQL generates the query listing, for the vintage $1, the pairs of communes whose geometries
+// overlap by more than $2 m², with the area of the overlap. Pairs are found with the spatial index of the
+// column, neighbouring communes merely touching each other.
+func communeOverlapSQL(column string) string {
+	return fmt.Sprintf(`
+		SELECT code, other, overlap
+		FROM (
+			SELECT a.code_insee_commune AS code, b.code_insee_commune AS other,
+				ST_Area(ST_Intersection(a.%[1]s::geometry, b.%[1]s::geometry)::geography) AS overlap
+			FROM ref_admin.communes a
+			JOIN ref_admin.communes b ON b.millesime = a.millesime AND b.code_insee_commune > a.code_insee_commune
+				AND ST_Intersects(a.%[1]s, b.%[1]s)
+			WHERE a.millesime = $1 AND a.supprime_le IS NULL AND b.supprime_le IS NULL
+		) o
+		WHERE overlap > $2
+		ORDER BY 1, 2`, column)
+}
+
+// communeOutsideSQL generates the query listing, for the vintage $1, the communes whose point on surface
+// falls outside the geometry of their declared parent (département or région), with the parent code.
+// The point on surface is the centroid of the commune moved inside it when it is concave.
+func communeOutsideSQL(column, parentTable, parentColumn string) string {
+	return fmt.Sprintf(`
+		SELECT c.code_insee_commune, c.%[3]s
+		FROM ref_admin.communes c
+		JOIN %[2]s p ON p.%[3]s = c.%[3]s AND p.millesime = c.millesime AND p.supprime_le IS NULL AND p.%[1]s IS NOT NULL
+		WHERE c.millesime = $1 AND c.supprime_le IS NULL AND c.%[1]s IS NOT NULL
+			AND NOT ST_Covers(p.%[1]s::geometry, ST_PointOnSurface(c.%[1]s::geometry))
+		ORDER BY 1`, column, parentTable, parentColumn)
+}
+
+// CheckSpatialConsistency checks, with PostGIS, the commune geometries of the vintage in the column of the
+// resolution against each other and their parents: the union of the communes of each département must
+// match its contour, communes must not overlap, and every commune must lie within its declared
+// département and région.
+func CheckSpatialConsistency(ctx context.Context, dm *DatabaseManager, vintage model.Vintage, resolution model.Resolution, tolerance SpatialTolerance) (*SpatialReport, error) {
+	column := resolution.GeometryColumn("geom")
+	report := &SpatialReport{Millesime: int(vintage), Column: column, Tolerance: tolerance, Checks: make(map[string]int), Issues: []SpatialIssue{}}
+	for _, check := range spatialChecks {
+		report.Checks[check] = 0
+	}
+	if dm.dryRun {
+		slog.Info("Spatial consistency check skipped in dry run")
+		return report, nil
+	}
+
+	// Contours of the départements
+	rows, err := dm.pool.Query(ctx, departementContourSQL(column), int(vintage))
+	if err != nil {
+		return nil, fmt.Errorf("error checking the contours of the départements: %w", err)
+	}
+	for rows.Next() {
+		var code string
+		var gap, overflow, area float64
+		if err := rows.Scan(&code, &gap, &overflow, &area); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error checking the contours of the départements: %w", err)
+		}
+		if tolerance.exceedsGapRatio(gap, overflow, area) {
+			report.add(SpatialIssue{Check: SpatialDepartementContour, Code: code, GapM2: gap, OverlapM2: overflow})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error checking the contours of the départements: %w", err)
+	}
+
+	// Overlaps between communes
+	rows, err = dm.pool.Query(ctx, communeOverlapSQL(column), int(vintage), tolerance.SliverArea)
+	if err != nil {
+		return nil, fmt.Errorf("error checking the overlaps of the communes: %w", err)
+	}
+	for rows.Next() {
+		issue := SpatialIssue{Check: SpatialCommuneOverlap}
+		if err := rows.Scan(&issue.Code, &issue.Other, &issue.OverlapM2); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error checking the overlaps of the communes: %w", err)
+		}
+		report.add(issue)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error checking the overlaps of the communes: %w", err)
+	}
+
+	// Communes within their parents
+	parents := []struct {
+		check, table, column string
+	}{
+		{check: SpatialCommuneDepartement, table: "ref_admin.departements", column: "code_insee_departement"},
+		{check: SpatialCommuneRegion, table: "ref_admin.regions", column: "code_insee_region"},
+	}
+	for _, parent := range parents {
+		rows, err := dm.pool.Query(ctx, communeOutsideSQL(column, parent.table, parent.column), int(vintage))
+		if err != nil {
+			return nil, fmt.Errorf("error checking the communes within %s: %w", parent.table, err)
+		}
+		for rows.Next() {
+			issue := SpatialIssue{Check: parent.check}
+			if err := rows.Scan(&issue.Code, &issue.Other); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("error checking the communes within %s: %w", parent.table, err)
+			}
+			report.add(issue)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("error checking the communes within %s: %w", parent.table, err)
+		}
+	}
+
+	for _, check := range spatialChecks {
+		if count := report.Checks[check]; count > 0 {
+			slog.Warn("Spatial consistency check failed", "check", check, "rows", count)
+		}
+	}
+	slog.Info("Spatial consistency checked", "millesime", int(vintage), "column", column, "issues", len(report.Issues))
+	return report, nil
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"french-admin-etl/internal/model"
+)
+
+// TestSpatialTolerance_ExceedsGapRatio tests that gaps and overflows add up against the area of the département
+func TestSpatialTolerance_ExceedsGapRatio(t *testing.T) {
+	tolerance := SpatialTolerance{GapRatio: 0.001, SliverArea: 1000}
+
+	tests := []struct {
+		name     string
+		gap      float64
+		overflow float64
+		area     float64
+		expected bool
+	}{
+		{name: "matching contour", area: 6e9},
+		{name: "gap within tolerance", gap: 4e6, area: 6e9},
+		{name: "gap and overflow above tolerance", gap: 4e6, overflow: 3e6, area: 6e9, expected: true},
+		{name: "département without communes", gap: 6e9, area: 6e9, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tolerance.exceedsGapRatio(tt.gap, tt.overflow, tt.area); got != tt.expected {
+				t.Errorf("exceedsGapRatio(%v, %v, %v) = %v, want %v", tt.gap, tt.overflow, tt.area, got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestSpatialConsistencySQL tests that the checks read the geometry column of the resolution
+func TestSpatialConsistencySQL(t *testing.T) {
+	column := model.Resolution5m.GeometryColumn("geom")
+
+	tests := []struct {
+		name     string
+		sql      string
+		expected []string
+	}{
+		{
+			name: "département contours",
+			sql:  departementContourSQL(column),
+			expected: []string{
+				"ST_Union(c.geom_5m::geometry) AS geom",
+				"GROUP BY c.code_insee_departement",
+				"LEFT JOIN communes c ON c.code_insee_departement = d.code_insee_departement",
+			},
+		},
+		{
+			name: "overlaps",
+			sql:  communeOverlapSQL(column),
+			expected: []string{
+				"b.code_insee_commune > a.code_insee_commune",
+				"ST_Intersects(a.geom_5m, b.geom_5m)",
+				"WHERE overlap > $2",
+			},
+		},
+		{
+			name: "communes within their région",
+			sql:  communeOutsideSQL(column, "ref_admin.regions", "code_insee_region"),
+			expected: []string{
+				"JOIN ref_admin.regions p ON p.code_insee_region = c.code_insee_region AND p.millesime = c.millesime",
+				"NOT ST_Covers(p.geom_5m::geometry, ST_PointOnSurface(c.geom_5m::geometry))",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, expected := range tt.expected {
+				if !strings.Contains(tt.sql, expected) {
+					t.Errorf("Expected %q in SQL:\n%s", expected, tt.sql)
+				}
+			}
+		})
+	}
+}
+
+// TestCheckSpatialConsistency_DryRun tests that a dry run returns an empty report without a database
+func TestCheckSpatialConsistency_DryRun(t *testing.T) {
+	report, err := CheckSpatialConsistency(context.Background(), NewDryRunDatabaseManager(), 2024, model.DefaultResolution, DefaultSpatialTolerance)
+	if err != nil {
+		t.Fatalf("CheckSpatialConsistency() error = %v", err)
+	}
+	if report.Failed() || report.Column != "geom" || len(report.Checks) != len(spatialChecks) {
+		t.Errorf("Expected an empty report of the geom column, got %+v", report)
+	}
+}
@@ -0,0 +1,304 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"french-admin-etl/internal/model"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SyncMode selects what happens to the rows absent from the source at the end of a run.
+type SyncMode string
+
+const (
+	// NoSync only upserts the entities of the source, absent rows are kept.
+	NoSync SyncMode = ""
+	// SyncDelete deletes the absent rows. Rows of other tables referencing them are unlinked when
+	// the referencing columns are nullable, deleted otherwise, along with their own dependents.
+	SyncDelete SyncMode = "delete"
+	// SyncSoftDelete sets the deleted column (see the etl table tag) of the absent rows.
+	SyncSoftDelete SyncMode = "soft-delete"
+)
+
+// syncKeysTable is the temporary table receiving the keys seen during the run.
+const syncKeysTable = "etl_sync_keys"
+
+// ParseSyncMode converts a string to a SyncMode, returning an error for unknown values.
+func ParseSyncMode(s string) (SyncMode, error) {
+	switch mode := SyncMode(s); mode {
+	case NoSync, SyncDelete, SyncSoftDelete:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid sync mode %q, must be delete or soft-delete", s)
+	}
+}
+
+// syncState tracks the keys seen during a run and removes the other rows of the table when the run is finalized.
+type syncState struct {
+	mode            SyncMode
+	maxRemovalRatio float64
+
+	mu   sync.Mutex
+	seen map[string][]any
+}
+
+func newSyncState(mode SyncMode, maxRemovalRatio float64) *syncState {
+	return &syncState{
+		mode:            mode,
+		maxRemovalRatio: maxRemovalRatio,
+		seen:            make(map[string][]any),
+	}
+}
+
+// see records the key of an entity of the source, whether it is loaded or not.
+func (s *syncState) see(key []any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seen[fmt.Sprintf("%#v", key)] = key
+}
+
+// keys returns the keys seen so far and resets them, so that the repository can be used for another run.
+func (s *syncState) keys() [][]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([][]any, 0, len(s.seen))
+	for _, key := range s.seen {
+		keys = append(keys, key)
+	}
+	s.seen = make(map[string][]any)
+	return keys
+}
+
+// checkRemovalRatio aborts the sync when too many rows would be removed, which usually means a truncated or wrong source file.
+func checkRemovalRatio(table string, stale, total int64, maxRemovalRatio float64) error {
+	if total == 0 || stale == 0 {
+		return nil
+	}
+	if ratio := float64(stale) / float64(total); ratio > maxRemovalRatio {
+		return fmt.Errorf("sync of %s aborted: %d of %d rows would be removed (%.1f%%), above the %.1f%% threshold",
+			table, stale, total, ratio*100, maxRemovalRatio*100)
+	}
+	return nil
+}
+
+// scopeCondition returns the condition on the rows of the table aliased t that the sync may remove:
+// the rows of the vintage of the run, not already soft-deleted.
+func scopeCondition(metadata *entityMetadata, mode SyncMode, vintage model.Vintage) string {
+	conditions := []string{"TRUE"}
+	if metadata.vintage != "" {
+		conditions = append(conditions, fmt.Sprintf("t.%s = %d", metadata.vintage, vintage))
+	}
+	if mode == SyncSoftDelete {
+		conditions = append(conditions, fmt.Sprintf("t.%s IS NULL", metadata.deleted))
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+// staleCondition returns the condition on the rows of the table aliased t, within the scope of the sync, whose key was not seen during the run.
+func staleCondition(metadata *entityMetadata, mode SyncMode, vintage model.Vintage) string {
+	keys := metadata.keyColumns()
+	joins := make([]string, len(keys))
+	for i, key := range keys {
+		joins[i] = fmt.Sprintf("k.%s = t.%s", key, key)
+	}
+
+	return fmt.Sprintf("%s AND NOT EXISTS (SELECT 1 FROM %s k WHERE %s)",
+		scopeCondition(metadata, mode, vintage), syncKeysTable, strings.Join(joins, " AND "))
+}
+
+// foreignKey is a foreign key of another table referencing the synced table.
+type foreignKey struct {
+	table      string
+	columns    []string
+	refColumns []string
+	nullable   bool // all the referencing columns are nullable
+}
+
+// selection returns the condition on the rows of the referencing table that point to the rows of table
+// matching where, the rows of table being aliased t in where.
+func (fk foreignKey) selection(table, where string) string {
+	columns := make([]string, len(fk.columns))
+	for i, column := range fk.columns {
+		columns[i] = pgx.Identifier{column}.Sanitize()
+	}
+	refColumns := make([]string, len(fk.refColumns))
+	for i, column := range fk.refColumns {
+		refColumns[i] = "t." + pgx.Identifier{column}.Sanitize()
+	}
+	return fmt.Sprintf("(%s) IN (SELECT %s FROM %s t WHERE %s)",
+		strings.Join(columns, ", "), strings.Join(refColumns, ", "), table, where)
+}
+
+// dependentSQL returns the statement unlinking or deleting the rows of a referencing table that point to the
+// rows of table matching where.
+func (fk foreignKey) dependentSQL(table, where string) string {
+	selection := fk.selection(table, where)
+	if !fk.nullable {
+		return fmt.Sprintf("DELETE FROM %s WHERE %s", fk.table, selection)
+	}
+
+	sets := make([]string, len(fk.columns))
+	for i, column := range fk.columns {
+		sets[i] = pgx.Identifier{column}.Sanitize() + " = NULL"
+	}
+	return fmt.Sprintf("UPDATE %s SET %s WHERE %s", fk.table, strings.Join(sets, ", "), selection)
+}
+
+// dependentStatement unlinks or deletes the rows of the table of a foreign key pointing to removed rows.
+type dependentStatement struct {
+	fk  foreignKey
+	sql string
+}
+
+// dependentStatements returns the statements removing the references to the rows of table matching where,
+// walking down the foreign keys of the deleted rows so that the deepest rows go first (e.g. the population of
+// the arrondissements municipaux of a removed commune, before its arrondissements municipaux). Unlinked rows
+// are kept, so their own dependents are left alone. Tables already being deleted from are a cycle, refused.
+func dependentStatements(foreignKeys func(table string) ([]foreignKey, error), table, where string, deleting []string) ([]dependentStatement, error) {
+	fks, err := foreignKeys(table)
+	if err != nil {
+		return nil, fmt.Errorf("error listing foreign keys referencing %s: %w", table, err)
+	}
+
+	var statements []dependentStatement
+	for _, fk := range fks {
+		if !fk.nullable {
+			if slices.Contains(deleting, fk.table) {
+				return nil, fmt.Errorf("cannot delete from %s: cyclic foreign keys through %s", deleting[0], fk.table)
+			}
+			nested, err := dependentStatements(foreignKeys, fk.table, fk.selection(table, where), append(slices.Clone(deleting), fk.table))
+			if err != nil {
+				return nil, err
+			}
+			statements = append(statements, nested...)
+		}
+		statements = append(statements, dependentStatement{fk: fk, sql: fk.dependentSQL(table, where)})
+	}
+	return statements, nil
+}
+
+// dependentForeignKeys lists the foreign keys of other tables referencing the table.
+func dependentForeignKeys(ctx context.Context, tx pgx.Tx, table string) ([]foreignKey, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT c.conrelid::regclass::text, c.conname, a.attname, ra.attname, NOT a.attnotnull
+		FROM pg_constraint c
+		CROSS JOIN LATERAL unnest(c.conkey, c.confkey) WITH ORDINALITY AS k(attnum, refattnum, ord)
+		JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+		JOIN pg_attribute ra ON ra.attrelid = c.confrelid AND ra.attnum = k.refattnum
+		WHERE c.contype = 'f' AND c.confrelid = $1::regclass AND c.conrelid <> c.confrelid
+		ORDER BY 1, 2, k.ord
+	`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var foreignKeys []foreignKey
+	var previous string
+	for rows.Next() {
+		var dependentTable, constraint, column, refColumn string
+		var nullable bool
+		if err := rows.Scan(&dependentTable, &constraint, &column, &refColumn, &nullable); err != nil {
+			return nil, err
+		}
+
+		if name := dependentTable + "." + constraint; name != previous {
+			foreignKeys = append(foreignKeys, foreignKey{table: dependentTable, nullable: true})
+			previous = name
+		}
+		fk := &foreignKeys[len(foreignKeys)-1]
+		fk.columns = append(fk.columns, column)
+		fk.refColumns = append(fk.refColumns, refColumn)
+		fk.nullable = fk.nullable && nullable
+	}
+	return foreignKeys, rows.Err()
+}
+
+// run removes the rows of the table whose key was not seen during the run, in a single transaction.
+func (s *syncState) run(ctx context.Context, dm *DatabaseManager, metadata *entityMetadata) error {
+	keys := s.keys()
+	vintage, hasVintage := model.VintageFromContext(ctx)
+	if metadata.vintage != "" && !hasVintage {
+		return fmt.Errorf("table %s is vintaged, the run must have a vintage", metadata.table)
+	}
+	if dm.dryRun {
+		slog.Info("Sync skipped in dry run", "table", metadata.table, "mode", s.mode, "vintage", vintage, "keys", len(keys))
+		return nil
+	}
+
+	tx, err := dm.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Keys seen during the run
+	keyColumns := metadata.keyColumns()
+	createKeys := fmt.Sprintf("CREATE TEMP TABLE %s ON COMMIT DROP AS SELECT %s FROM %s WITH NO DATA",
+		syncKeysTable, strings.Join(keyColumns, ", "), metadata.table)
+	if _, err := tx.Exec(ctx, createKeys); err != nil {
+		return fmt.Errorf("error creating sync keys table: %w", err)
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{syncKeysTable}, keyColumns, pgx.CopyFromRows(keys)); err != nil {
+		return fmt.Errorf("error copying sync keys: %w", err)
+	}
+	if _, err := tx.Exec(ctx, fmt.Sprintf("ANALYZE %s", syncKeysTable)); err != nil {
+		return fmt.Errorf("error analyzing sync keys table: %w", err)
+	}
+
+	stale := staleCondition(metadata, s.mode, vintage)
+	active := scopeCondition(metadata, s.mode, vintage)
+
+	var total, staleCount int64
+	countSQL := fmt.Sprintf("SELECT count(*) FILTER (WHERE %s), count(*) FILTER (WHERE %s) FROM %s t", active, stale, metadata.table)
+	if err := tx.QueryRow(ctx, countSQL).Scan(&total, &staleCount); err != nil {
+		return fmt.Errorf("error counting stale rows: %w", err)
+	}
+	if err := checkRemovalRatio(metadata.table, staleCount, total, s.maxRemovalRatio); err != nil {
+		return err
+	}
+	if staleCount == 0 {
+		slog.Info("Sync completed, no row to remove", "table", metadata.table, "mode", s.mode, "vintage", vintage, "rows", total)
+		return tx.Commit(ctx)
+	}
+
+	if s.mode == SyncSoftDelete {
+		softDelete := fmt.Sprintf("UPDATE %s t SET %s = now() WHERE %s", metadata.table, metadata.deleted, stale)
+		if _, err := tx.Exec(ctx, softDelete); err != nil {
+			return fmt.Errorf("error soft-deleting stale rows: %w", err)
+		}
+	} else {
+		// Rows of other tables referencing the stale rows (e.g. demography.population_commune) go first
+		statements, err := dependentStatements(func(table string) ([]foreignKey, error) {
+			return dependentForeignKeys(ctx, tx, table)
+		}, metadata.table, stale, []string{metadata.table})
+		if err != nil {
+			return err
+		}
+		for _, statement := range statements {
+			tag, err := tx.Exec(ctx, statement.sql)
+			if err != nil {
+				return fmt.Errorf("error removing references of %s from %s: %w", metadata.table, statement.fk.table, err)
+			}
+			slog.Info("Sync dependent rows", "table", statement.fk.table, "columns", statement.fk.columns, "unlinked", statement.fk.nullable, "rows", tag.RowsAffected())
+		}
+
+		if _, err := tx.Exec(ctx, fmt.Sprintf("DELETE FROM %s t WHERE %s", metadata.table, stale)); err != nil {
+			return fmt.Errorf("error deleting stale rows: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	slog.Info("Sync completed", "table", metadata.table, "mode", s.mode, "vintage", vintage, "rows", total, "removed", staleCount)
+	return nil
+}
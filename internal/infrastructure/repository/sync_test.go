@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"french-admin-etl/internal/infrastructure/entities"
+)
+
+// TestParseSyncMode tests the parsing of the sync modes
+func TestParseSyncMode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected SyncMode
+		wantErr  bool
+	}{
+		{input: "", expected: NoSync},
+		{input: "delete", expected: SyncDelete},
+		{input: "soft-delete", expected: SyncSoftDelete},
+		{input: "truncate", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseSyncMode(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSyncMode(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("ParseSyncMode(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestSyncState_Keys tests that keys are deduplicated and reset once read
+func TestSyncState_Keys(t *testing.T) {
+	state := newSyncState(SyncDelete, 0.05)
+	state.see([]any{"33063"})
+	state.see([]any{"33063"})
+	state.see([]any{"75056"})
+
+	if keys := state.keys(); len(keys) != 2 {
+		t.Errorf("Expected 2 keys, got %v", keys)
+	}
+	if keys := state.keys(); len(keys) != 0 {
+		t.Errorf("Expected keys to be reset, got %v", keys)
+	}
+}
+
+// TestCheckRemovalRatio tests the safety threshold of the sync
+func TestCheckRemovalRatio(t *testing.T) {
+	tests := []struct {
+		name    string
+		stale   int64
+		total   int64
+		wantErr bool
+	}{
+		{name: "empty table", stale: 0, total: 0},
+		{name: "nothing to remove", stale: 0, total: 100},
+		{name: "below threshold", stale: 5, total: 100},
+		{name: "above threshold", stale: 6, total: 100, wantErr: true},
+		{name: "empty source", stale: 100, total: 100, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkRemovalRatio("ref_admin.communes", tt.stale, tt.total, 0.05); (err != nil) != tt.wantErr {
+				t.Errorf("checkRemovalRatio() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestStaleCondition tests the condition selecting the rows absent from the source
+func TestStaleCondition(t *testing.T) {
+	metadata, err := parseEntityMetadata(reflect.TypeFor[entities.CommuneEntity]())
+	if err != nil {
+		t.Fatalf("parseEntityMetadata() error = %v", err)
+	}
+
+	notSeen := " AND NOT EXISTS (SELECT 1 FROM etl_sync_keys k WHERE k.code_insee_commune = t.code_insee_commune AND k.millesime = t.millesime)"
+	if got, want := staleCondition(metadata, SyncDelete, 2024), "TRUE AND t.millesime = 2024"+notSeen; got != want {
+		t.Errorf("staleCondition(delete) = %q, want %q", got, want)
+	}
+	if got, want := staleCondition(metadata, SyncSoftDelete, 2024), "TRUE AND t.millesime = 2024 AND t.supprime_le IS NULL"+notSeen; got != want {
+		t.Errorf("staleCondition(soft-delete) = %q, want %q", got, want)
+	}
+}
+
+// TestScopeCondition tests that the sync only considers the rows of the vintage of the run
+func TestScopeCondition(t *testing.T) {
+	metadata, err := parseEntityMetadata(reflect.TypeFor[entities.RegionEntity]())
+	if err != nil {
+		t.Fatalf("parseEntityMetadata() error = %v", err)
+	}
+	if got, want := scopeCondition(metadata, SyncDelete, 2025), "TRUE AND t.millesime = 2025"; got != want {
+		t.Errorf("scopeCondition() = %q, want %q", got, want)
+	}
+
+	metadata.vintage = ""
+	if got, want := scopeCondition(metadata, SyncDelete, 0), "TRUE"; got != want {
+		t.Errorf("scopeCondition() without vintage = %q, want %q", got, want)
+	}
+}
+
+// TestForeignKey_DependentSQL tests the statements removing the references to stale rows
+func TestForeignKey_DependentSQL(t *testing.T) {
+	metadata, err := parseEntityMetadata(reflect.TypeFor[entities.EPCIEntity]())
+	if err != nil {
+		t.Fatalf("parseEntityMetadata() error = %v", err)
+	}
+
+	nullable := foreignKey{table: "ref_admin.communes", columns: []string{"code_insee_epci"}, refColumns: []string{"code_insee_epci"}, nullable: true}
+	sql := nullable.dependentSQL(metadata.table, "STALE")
+	if !strings.HasPrefix(sql, `UPDATE ref_admin.communes SET "code_insee_epci" = NULL WHERE ("code_insee_epci") IN (SELECT t."code_insee_epci" FROM ref_admin.epci t WHERE STALE)`) {
+		t.Errorf("Unexpected SQL for nullable foreign key: %s", sql)
+	}
+
+	notNull := foreignKey{table: "demography.population_commune", columns: []string{"code_insee_commune"}, refColumns: []string{"code_insee_commune"}}
+	if sql := notNull.dependentSQL(metadata.table, "STALE"); !strings.HasPrefix(sql, "DELETE FROM demography.population_commune WHERE") {
+		t.Errorf("Unexpected SQL for not null foreign key: %s", sql)
+	}
+}
+
+// TestDependentStatements tests that the rows referencing the removed rows through a chain of foreign keys
+// are deleted deepest first, and that cyclic foreign keys are refused
+func TestDependentStatements(t *testing.T) {
+	graph := map[string][]foreignKey{
+		"ref_admin.communes": {
+			{table: "ref_admin.arrondissements_municipaux", columns: []string{"code_insee_commune", "millesime"}, refColumns: []string{"code_insee_commune", "millesime"}},
+		},
+		"ref_admin.arrondissements_municipaux": {
+			{table: "demography.population_arrondissement_municipal", columns: []string{"code_insee_arm", "millesime"}, refColumns: []string{"code_insee_arm", "millesime"}},
+			{table: "ref_admin.codes_postaux", columns: []string{"code_insee_arm"}, refColumns: []string{"code_insee_arm"}, nullable: true},
+		},
+	}
+	foreignKeys := func(table string) ([]foreignKey, error) {
+		return graph[table], nil
+	}
+
+	statements, err := dependentStatements(foreignKeys, "ref_admin.communes", "STALE", []string{"ref_admin.communes"})
+	if err != nil {
+		t.Fatalf("dependentStatements() error = %v", err)
+	}
+	armOfStale := `("code_insee_commune", "millesime") IN (SELECT t."code_insee_commune", t."millesime" FROM ref_admin.communes t WHERE STALE)`
+	expected := []string{
+		`DELETE FROM demography.population_arrondissement_municipal WHERE ("code_insee_arm", "millesime") IN (SELECT t."code_insee_arm", t."millesime" FROM ref_admin.arrondissements_municipaux t WHERE ` + armOfStale + `)`,
+		`UPDATE ref_admin.codes_postaux SET "code_insee_arm" = NULL WHERE ("code_insee_arm") IN (SELECT t."code_insee_arm" FROM ref_admin.arrondissements_municipaux t WHERE ` + armOfStale + `)`,
+		"DELETE FROM ref_admin.arrondissements_municipaux WHERE " + armOfStale,
+	}
+	got := make([]string, len(statements))
+	for i, statement := range statements {
+		got[i] = statement.sql
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected statements:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	graph["demography.population_arrondissement_municipal"] = []foreignKey{
+		{table: "ref_admin.communes", columns: []string{"code_insee_commune"}, refColumns: []string{"code_insee_commune"}},
+	}
+	if _, err := dependentStatements(foreignKeys, "ref_admin.communes", "STALE", []string{"ref_admin.communes"}); err == nil {
+		t.Error("Expected cyclic foreign keys to be refused")
+	}
+}
@@ -0,0 +1,242 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"french-admin-etl/internal/infrastructure/entities"
+	"french-admin-etl/internal/model"
+	"log/slog"
+	"reflect"
+	"slices"
+)
+
+// entityTable holds what the repositories of tag-driven entities share: the metadata of the entity, the
+// sync or shadow table mode of the run and the resolution of its references.
+type entityTable struct {
+	databaseManager *DatabaseManager
+	metadata        *entityMetadata
+	sync            *syncState   // nil when absent rows are kept
+	shadow          *shadowTable // nil when the run is loaded into the live table
+	references      *referenceState
+	adjacency       *adjacencySource // nil when the table has no adjacency graph to update
+}
+
+// newEntityTable reads the etl struct tags of t and applies the options.
+// It panics if the tags are invalid or the options don't fit the table, which is a programming error.
+func newEntityTable(dbManager *DatabaseManager, t reflect.Type, opts []RepositoryOption) *entityTable {
+	metadata, err := parseEntityMetadata(t)
+	if err != nil {
+		panic(fmt.Sprintf("invalid entity metadata: %v", err))
+	}
+
+	options := newRepositoryOptions(opts)
+	if options.shadowTable && options.syncMode != NoSync {
+		panic("sync and shadow table options are exclusive: a shadow table only holds the rows of the run")
+	}
+	if options.pivot != nil {
+		panic("pivot is only supported for population data")
+	}
+	if options.validator != nil {
+		panic("validation is only supported for population data")
+	}
+
+	table := &entityTable{
+		databaseManager: dbManager,
+		metadata:        metadata,
+		references:      newReferenceState(options.referencePolicy, options.referenceReport),
+	}
+	if options.shadowTable {
+		table.shadow = newShadowTable(metadata.table, metadata.vintage, options.maxRemovalRatio)
+	}
+	if options.adjacency {
+		source, ok := adjacencySources[metadata.table]
+		if !ok {
+			panic(fmt.Sprintf("adjacency is only supported for %s and %s", AdjacencyCommune, AdjacencyDepartement))
+		}
+		table.adjacency = &source
+	}
+	if options.syncMode != NoSync {
+		if options.syncMode == SyncSoftDelete && metadata.deleted == "" {
+			panic(fmt.Sprintf("invalid entity metadata: entity %s has no deleted column for soft-delete", t))
+		}
+		table.sync = newSyncState(options.syncMode, options.maxRemovalRatio)
+	}
+	return table
+}
+
+// runVintage returns the vintage of the run, required when the table is vintaged.
+func (t *entityTable) runVintage(ctx context.Context) (model.Vintage, error) {
+	vintage, hasVintage := model.VintageFromContext(ctx)
+	if t.metadata.vintage != "" && !hasVintage {
+		return 0, fmt.Errorf("table %s is vintaged, the run must have a vintage", t.metadata.table)
+	}
+	return vintage, nil
+}
+
+// see records, in sync mode, the key of an entity of the source. Entities of the source are kept by
+// the sync even if they fail to load.
+func (t *entityTable) see(entity any, vintage model.Vintage) {
+	if t.sync == nil {
+		return
+	}
+	key := t.metadata.keyValues(entity)
+	if t.metadata.vintage != "" {
+		key = append(key, int(vintage))
+	}
+	t.sync.see(key)
+}
+
+// args returns the parameters of the upsert statement for the column values of an entity, before the geometry.
+func (t *entityTable) args(values []any, vintage model.Vintage) []any {
+	args := slices.Clone(values)
+	if t.metadata.vintage != "" {
+		args = append(args, int(vintage))
+	}
+	return args
+}
+
+// load runs the statements of a batch and counts the loaded rows, logging the failing ones with their key.
+// The unresolved references of the loaded rows, by statement, are recorded.
+func (t *entityTable) load(ctx context.Context, stmts []statement, keys [][]any, unresolved [][]UnresolvedReference, vintage model.Vintage) (int, error) {
+	rowErrors, err := t.databaseManager.loadBatch(ctx, stmts)
+	if err != nil {
+		return 0, err
+	}
+	if err := t.loaded(ctx, unresolved, rowErrors, vintage); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i, rowErr := range rowErrors {
+		if rowErr != nil {
+			slog.Error("Insert error", "table", t.metadata.table, "key", keys[i], "error", rowErr)
+			continue
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// targetTable returns the table receiving the rows of the run.
+func (t *entityTable) targetTable() string {
+	if t.shadow != nil {
+		return t.shadow.name()
+	}
+	return t.metadata.table
+}
+
+// Initialize forgets the unresolved references of a previous run and those it deferred, then creates, in
+// shadow table mode, the shadow table receiving the rows of the run.
+func (t *entityTable) Initialize(ctx context.Context) error {
+	t.references.reset()
+	if err := t.clearDeferredReferences(ctx); err != nil {
+		return err
+	}
+
+	if t.shadow == nil {
+		return nil
+	}
+	return t.shadow.create(ctx, t.databaseManager)
+}
+
+// Finalize reports the unresolved references of the run and derives the attributes of the geometries, then
+// removes, in sync mode, the rows whose key was not seen during the run. In shadow table mode, it swaps the
+// shadow table in. The references to the table deferred by other runs are then back-filled, and the adjacency
+// graph of the table updated.
+func (t *entityTable) Finalize(ctx context.Context) error {
+	if err := t.references.report(t.metadata.table); err != nil {
+		return err
+	}
+	if t.metadata.geometry != "" {
+		if err := t.deriveGeometryAttributes(ctx, t.targetTable()); err != nil {
+			return err
+		}
+	}
+
+	if t.shadow != nil {
+		if err := t.shadow.validateAndSwap(ctx, t.databaseManager); err != nil {
+			return err
+		}
+	} else if t.sync != nil {
+		if err := t.sync.run(ctx, t.databaseManager, t.metadata); err != nil {
+			return err
+		}
+	}
+	if err := t.backfillReferences(ctx); err != nil {
+		return err
+	}
+	if t.adjacency != nil {
+		return t.updateAdjacency(ctx)
+	}
+	return nil
+}
+
+// Counts returns the number of references of the run left unresolved by the reference policy: replaced by
+// NULL, rejected with their row, or deferred. Only tables with references have counts.
+func (t *entityTable) Counts() map[string]int {
+	if !t.metadata.hasReferences() {
+		return nil
+	}
+	return t.references.counts()
+}
+
+// TableRepository loads entities without geometry into the table described by the etl struct tags of E.
+type TableRepository[E any] struct {
+	*entityTable
+}
+
+var _ model.EntityLoader[entities.CogCommuneEntity] = (*TableRepository[entities.CogCommuneEntity])(nil)
+var _ model.LoadInitializer = (*TableRepository[entities.CogCommuneEntity])(nil)
+var _ model.LoadFinalizer = (*TableRepository[entities.CogCommuneEntity])(nil)
+var _ model.LoadCounter = (*TableRepository[entities.CogCommuneEntity])(nil)
+
+// NewTableRepository creates a new repository for the entity type E with the provided DatabaseManager and options.
+// It panics if the etl struct tags of E are invalid or declare a geometry column, which is a programming error.
+func NewTableRepository[E any](dbManager *DatabaseManager, opts ...RepositoryOption) *TableRepository[E] {
+	table := newEntityTable(dbManager, reflect.TypeFor[E](), opts)
+	if table.metadata.geometry != "" {
+		panic(fmt.Sprintf("invalid entity metadata: entity %s has a geometry column, use NewGeoRepository", reflect.TypeFor[E]()))
+	}
+	return &TableRepository[E]{entityTable: table}
+}
+
+// Load upserts a batch of entities in a single transaction. A failing row doesn't abort the batch: it is isolated according to the load strategy of the DatabaseManager.
+func (r *TableRepository[E]) Load(ctx context.Context, entities []E) (int, error) {
+	vintage, err := r.runVintage(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sql := r.metadata.upsertSQL(r.targetTable(), "")
+
+	rows := make([][]any, len(entities))
+	rowKeys := make([][]any, len(entities))
+	for i, entity := range entities {
+		r.see(entity, vintage)
+		rows[i] = r.metadata.values(entity)
+		rowKeys[i] = r.metadata.keyValues(entity)
+	}
+	rows, rowUnresolved, err := r.resolveReferences(ctx, rows, rowKeys, vintage)
+	if err != nil {
+		return 0, err
+	}
+
+	stmts := make([]statement, 0, len(entities))
+	keys := make([][]any, 0, len(entities))
+	unresolved := make([][]UnresolvedReference, 0, len(entities))
+	for i, values := range rows {
+		// Rejected by the reference policy
+		if values == nil {
+			continue
+		}
+		stmts = append(stmts, statement{
+			sql:   sql,
+			args:  r.args(values, vintage),
+			check: func() error { return r.metadata.checkKeys(values) },
+		})
+		keys = append(keys, rowKeys[i])
+		unresolved = append(unresolved, rowUnresolved[i])
+	}
+
+	return r.load(ctx, stmts, keys, unresolved, vintage)
+}
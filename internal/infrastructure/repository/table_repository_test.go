@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"french-admin-etl/internal/infrastructure/entities"
+	"french-admin-etl/internal/model"
+)
+
+// TestNewTableRepository tests that repositories are created for every COG entity
+func TestNewTableRepository(t *testing.T) {
+	if NewTableRepository[entities.CogRegionEntity](nil) == nil {
+		t.Error("NewTableRepository(CogRegionEntity) returned nil")
+	}
+	if NewTableRepository[entities.CogDepartementEntity](nil) == nil {
+		t.Error("NewTableRepository(CogDepartementEntity) returned nil")
+	}
+	if NewTableRepository[entities.CogArrondissementEntity](nil) == nil {
+		t.Error("NewTableRepository(CogArrondissementEntity) returned nil")
+	}
+	if NewTableRepository[entities.CogCantonEntity](nil) == nil {
+		t.Error("NewTableRepository(CogCantonEntity) returned nil")
+	}
+	if NewTableRepository[entities.CogCommuneEntity](nil, WithSync(SyncSoftDelete, 0.05)) == nil {
+		t.Error("NewTableRepository(CogCommuneEntity) returned nil")
+	}
+	if NewTableRepository[entities.CogComerEntity](nil, WithShadowTable(0.05)) == nil {
+		t.Error("NewTableRepository(CogComerEntity) returned nil")
+	}
+	if NewTableRepository[entities.MelodiObservationEntity](nil) == nil {
+		t.Error("NewTableRepository(MelodiObservationEntity) returned nil")
+	}
+	if NewTableRepository[entities.MelodiModalityEntity](nil) == nil {
+		t.Error("NewTableRepository(MelodiModalityEntity) returned nil")
+	}
+}
+
+// TestNewTableRepository_WithGeometry tests that entities with geometry column are rejected
+func TestNewTableRepository_WithGeometry(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for entity with geometry column")
+		}
+	}()
+	NewTableRepository[entities.RegionEntity](nil)
+}
+
+// TestTableRepository_Load_DryRun tests that a dry run validates the keys of the rows and tracks them in sync mode
+func TestTableRepository_Load_DryRun(t *testing.T) {
+	repository := NewTableRepository[entities.CogCommuneEntity](NewDryRunDatabaseManager(), WithSync(SyncDelete, 0.05))
+	rows := []entities.CogCommuneEntity{
+		{TypeCommune: "COM", Code: "01001", TNCC: 5, NCC: "ABERGEMENT CLEMENCIAT", NCCENR: "Abergement-Clémenciat", Libelle: "L'Abergement-Clémenciat"},
+		{TypeCommune: "COMD", Code: "01015", TNCC: 1, NCC: "ARBIGNIEU", NCCENR: "Arbignieu", Libelle: "Arbignieu"},
+		{TypeCommune: "COM", NCC: "MISSING KEY"},
+	}
+
+	ctx := model.WithVintage(context.Background(), 2024)
+	count, err := repository.Load(ctx, rows)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 loaded rows, got %d", count)
+	}
+	if len(repository.sync.seen) != 3 {
+		t.Errorf("Expected the keys of every entity to be seen, got %v", repository.sync.seen)
+	}
+	if err := repository.Finalize(ctx); err != nil {
+		t.Fatalf("Finalize() error = %v", err)
+	}
+}
+
+// TestTableRepository_Load_WithoutVintage tests that a vintaged table cannot be loaded without a vintage
+func TestTableRepository_Load_WithoutVintage(t *testing.T) {
+	repository := NewTableRepository[entities.CogRegionEntity](NewDryRunDatabaseManager())
+	rows := []entities.CogRegionEntity{{Code: "84", ChefLieu: "69123"}}
+
+	if _, err := repository.Load(context.Background(), rows); err == nil {
+		t.Error("Expected error for a run without vintage, got nil")
+	}
+}
+
+// TestTableRepository_Load_MelodiObservations tests that observations without value are loaded, and
+// observations without dimensions refused
+func TestTableRepository_Load_MelodiObservations(t *testing.T) {
+	repository := NewTableRepository[entities.MelodiObservationEntity](NewDryRunDatabaseManager())
+	value := 412.0
+	rows := []entities.MelodiObservationEntity{
+		{Dataset: "DS_RP_POPULATION_PRINC", GeoObject: "COM", Geo: "01001", Periode: "2022", Dimensions: `{"AGE":"_T","SEX":"_T"}`, Valeur: &value},
+		{Dataset: "DS_RP_POPULATION_PRINC", GeoObject: "COM", Geo: "01002", Periode: "2022", Dimensions: `{"AGE":"_T","SEX":"_T"}`},
+		{Dataset: "DS_RP_POPULATION_PRINC", GeoObject: "COM", Geo: "01003", Periode: "2022", Valeur: &value},
+	}
+
+	count, err := repository.Load(model.WithVintage(context.Background(), 2024), rows)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 loaded rows, got %d", count)
+	}
+}
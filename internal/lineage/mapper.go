@@ -0,0 +1,54 @@
+package lineage
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"french-admin-etl/internal/model"
+)
+
+// Recodable is implemented by the pointer to entities referencing a commune by its code.
+type Recodable interface {
+	CommuneCode() string
+	Recode(code string)
+}
+
+// Mapper is a mapper step recoding the commune of the entities returned by another mapper, from the
+// geography of the source to a later one.
+type Mapper[I any, E any] struct {
+	mapper   model.Mapper[I, E]
+	resolver *Resolver
+	from     time.Time
+	to       time.Time
+}
+
+// NewMapper wraps mapper so that the commune codes of the geography at date from are replaced by their
+// codes in the geography at date to. Entities of a commune split in between are rejected, as their
+// figures can't be shared out between the resulting communes.
+// It panics if *E doesn't implement Recodable, which is a programming error.
+func NewMapper[I any, E any](mapper model.Mapper[I, E], resolver *Resolver, from, to time.Time) *Mapper[I, E] {
+	if _, ok := any(new(E)).(Recodable); !ok {
+		panic(fmt.Sprintf("entity %s doesn't implement lineage.Recodable", reflect.TypeFor[E]()))
+	}
+	return &Mapper[I, E]{mapper: mapper, resolver: resolver, from: from, to: to}
+}
+
+// Map maps the input with the wrapped mapper, then recodes the commune of the entity.
+func (m *Mapper[I, E]) Map(input I) (*E, error) {
+	entity, err := m.mapper.Map(input)
+	if err != nil || entity == nil {
+		return entity, err
+	}
+
+	recodable := any(entity).(Recodable)
+	code := recodable.CommuneCode()
+	codes := m.resolver.Resolve(code, m.from, m.to)
+	if len(codes) != 1 {
+		return nil, fmt.Errorf("commune %s was split into %v between %s and %s", code, codes, m.from.Format(time.DateOnly), m.to.Format(time.DateOnly))
+	}
+	if codes[0] != code {
+		recodable.Recode(codes[0])
+	}
+	return entity, nil
+}
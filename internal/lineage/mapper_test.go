@@ -0,0 +1,57 @@
+package lineage
+
+import (
+	"testing"
+
+	"french-admin-etl/internal/infrastructure/entities"
+	"french-admin-etl/internal/model"
+)
+
+func populationRecord(code string) model.CSVRecord {
+	return model.CSVRecord{"GEO": code, "AGE": "_T", "SEX": "_T", "TIME_PERIOD": "2016", "OBS_VALUE": "100"}
+}
+
+func TestMapper_Map(t *testing.T) {
+	mapper := NewMapper(entities.NewCommunePopulationMapper(), NewResolver(testEvents), date("2018-01-01"), date("2024-01-01"))
+
+	merged, err := mapper.Map(populationRecord("01002"))
+	if err != nil {
+		t.Fatalf("Map() error = %v", err)
+	}
+	if merged.CodeCommune != "01001" || merged.CodeCommuneOrigine != "01002" {
+		t.Errorf("Expected 01002 recoded to 01001, got %s (origin %q)", merged.CodeCommune, merged.CodeCommuneOrigine)
+	}
+
+	unchanged, err := mapper.Map(populationRecord("01001"))
+	if err != nil {
+		t.Fatalf("Map() error = %v", err)
+	}
+	if unchanged.CodeCommune != "01001" || unchanged.CodeCommuneOrigine != "" {
+		t.Errorf("Expected 01001 unchanged, got %s (origin %q)", unchanged.CodeCommune, unchanged.CodeCommuneOrigine)
+	}
+
+	if _, err := mapper.Map(populationRecord("01010")); err == nil {
+		t.Error("Expected error for a split commune, got nil")
+	}
+
+	if _, err := mapper.Map(populationRecord("1")); err == nil {
+		t.Error("Expected error of the wrapped mapper, got nil")
+	}
+}
+
+type notRecodable struct{}
+
+type notRecodableMapper struct{}
+
+func (m notRecodableMapper) Map(model.CSVRecord) (*notRecodable, error) {
+	return &notRecodable{}, nil
+}
+
+func TestNewMapper_NotRecodable(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for an entity without commune code")
+		}
+	}()
+	NewMapper(notRecodableMapper{}, NewResolver(nil), date("2018-01-01"), date("2024-01-01"))
+}
@@ -0,0 +1,114 @@
+// Package lineage resolves historical commune codes to the codes of a later geography, from the events
+// of the COG "mouvements des communes" file (v_mvt_commune): fusions, scissions, changements de code.
+package lineage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"time"
+
+	"french-admin-etl/internal/extractors"
+	"french-admin-etl/internal/infrastructure/entities"
+)
+
+// edge links a commune to a commune resulting from an event.
+type edge struct {
+	date time.Time
+	to   string
+}
+
+// Resolver follows the events of the communes from a geography to a later one.
+// It is safe for concurrent use once built.
+type Resolver struct {
+	edges map[string][]edge // by code of the commune before the event, sorted by date
+}
+
+// NewResolver builds a resolver from the commune events. Only the events between communes (TYPECOM COM)
+// are followed: communes associées and déléguées don't hold figures of their own.
+func NewResolver(events []entities.CommuneEventEntity) *Resolver {
+	r := &Resolver{edges: make(map[string][]edge)}
+	for _, event := range events {
+		if event.TypeAvant != "COM" || event.TypeApres != "COM" {
+			continue
+		}
+		e := edge{date: event.DateEffet, to: event.CodeApres}
+		if !slices.Contains(r.edges[event.CodeAvant], e) {
+			r.edges[event.CodeAvant] = append(r.edges[event.CodeAvant], e)
+		}
+	}
+	for _, edges := range r.edges {
+		sort.SliceStable(edges, func(i, j int) bool { return edges[i].date.Before(edges[j].date) })
+	}
+	return r
+}
+
+// LoadResolver reads a v_mvt_commune CSV file and builds a resolver from its events. Invalid rows are skipped.
+func LoadResolver(ctx context.Context, filePath string) (*Resolver, error) {
+	const batchSize = 1000
+
+	records, err := extractors.NewCSVExtractor(nil).Extract(ctx, filePath, batchSize)
+	if err != nil {
+		return nil, fmt.Errorf("error extracting commune events: %w", err)
+	}
+
+	mapper := entities.NewCommuneEventMapper()
+	var events []entities.CommuneEventEntity
+	for record := range records {
+		event, err := mapper.Map(record)
+		if err != nil {
+			slog.Warn("Skip invalid commune event", "error", err, "record", record)
+			continue
+		}
+		events = append(events, *event)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	slog.Info("Commune events loaded", "file", filePath, "events", len(events))
+	return NewResolver(events), nil
+}
+
+// Resolve returns the codes, in the geography at date to, of the commune identified by code in the
+// geography at date from. Events effective after from and up to to are applied. A commune merged into
+// another resolves to the code of the latter; a commune split resolves to several codes. A code unknown
+// to the events is returned unchanged.
+func (r *Resolver) Resolve(code string, from, to time.Time) []string {
+	codes := make(map[string]struct{})
+	r.resolve(code, from, to, codes)
+
+	resolved := make([]string, 0, len(codes))
+	for code := range codes {
+		resolved = append(resolved, code)
+	}
+	slices.Sort(resolved)
+	return resolved
+}
+
+func (r *Resolver) resolve(code string, from, to time.Time, codes map[string]struct{}) {
+	// First event of the commune after from: the communes resulting from it
+	var date time.Time
+	var targets []string
+	for _, e := range r.edges[code] {
+		if !e.date.After(from) {
+			continue
+		}
+		if e.date.After(to) || (!date.IsZero() && !e.date.Equal(date)) {
+			break
+		}
+		date = e.date
+		targets = append(targets, e.to)
+	}
+
+	if len(targets) == 0 {
+		codes[code] = struct{}{}
+		return
+	}
+	for _, target := range targets {
+		// Dates strictly increase along the way, so the resolution ends
+		r.resolve(target, date, to, codes)
+	}
+}
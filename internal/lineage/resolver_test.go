@@ -0,0 +1,105 @@
+package lineage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"french-admin-etl/internal/infrastructure/entities"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func event(mod int, eff, typeAvant, avant, typeApres, apres string) entities.CommuneEventEntity {
+	return entities.CommuneEventEntity{
+		Mod: mod, DateEffet: date(eff),
+		TypeAvant: typeAvant, CodeAvant: avant,
+		TypeApres: typeApres, CodeApres: apres,
+	}
+}
+
+// testEvents describes:
+//   - 01001 and 01002 merged into the commune nouvelle 01001 on 2019-01-01, 01002 becoming a commune déléguée
+//   - 01003 merged into 01004 on 2016-01-01, 01004 merged into 01005 on 2023-01-01
+//   - 01010 split into 01010 and 01011 on 2020-01-01
+//   - 01020 recoded 02020 on 2018-01-01 (changement de département)
+var testEvents = []entities.CommuneEventEntity{
+	event(32, "2019-01-01", "COM", "01001", "COM", "01001"),
+	event(32, "2019-01-01", "COM", "01002", "COM", "01001"),
+	event(32, "2019-01-01", "COM", "01001", "COMD", "01001"),
+	event(32, "2019-01-01", "COM", "01002", "COMD", "01002"),
+	event(31, "2016-01-01", "COM", "01003", "COM", "01004"),
+	event(31, "2023-01-01", "COM", "01004", "COM", "01005"),
+	event(21, "2020-01-01", "COM", "01010", "COM", "01010"),
+	event(21, "2020-01-01", "COM", "01010", "COM", "01011"),
+	event(41, "2018-01-01", "COM", "01020", "COM", "02020"),
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	resolver := NewResolver(testEvents)
+
+	tests := []struct {
+		name     string
+		code     string
+		from, to string
+		expected []string
+	}{
+		{name: "commune nouvelle, merged commune", code: "01002", from: "2018-01-01", to: "2024-01-01", expected: []string{"01001"}},
+		{name: "commune nouvelle, taking the code", code: "01001", from: "2018-01-01", to: "2024-01-01", expected: []string{"01001"}},
+		{name: "event effective at from is already applied", code: "01002", from: "2019-01-01", to: "2024-01-01", expected: []string{"01002"}},
+		{name: "event after to is not applied", code: "01002", from: "2018-01-01", to: "2018-12-31", expected: []string{"01002"}},
+		{name: "chain of mergers", code: "01003", from: "2015-01-01", to: "2024-01-01", expected: []string{"01005"}},
+		{name: "chain of mergers, stopped at to", code: "01003", from: "2015-01-01", to: "2022-01-01", expected: []string{"01004"}},
+		{name: "split", code: "01010", from: "2019-01-01", to: "2024-01-01", expected: []string{"01010", "01011"}},
+		{name: "code change", code: "01020", from: "2017-01-01", to: "2024-01-01", expected: []string{"02020"}},
+		{name: "unknown code", code: "99999", from: "2017-01-01", to: "2024-01-01", expected: []string{"99999"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolver.Resolve(tt.code, date(tt.from), date(tt.to))
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Resolve(%s, %s, %s) = %v, want %v", tt.code, tt.from, tt.to, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoadResolver(t *testing.T) {
+	content := []byte(`MOD,DATE_EFF,TYPECOM_AV,COM_AV,TNCC_AV,NCC_AV,NCCENR_AV,LIBELLE_AV,TYPECOM_AP,COM_AP,TNCC_AP,NCC_AP,NCCENR_AP,LIBELLE_AP
+32,2019-01-01,COM,01002,1,AMBUTRIX,Ambutrix,Ambutrix,COM,01001,0,NOUVELLE,Nouvelle,Nouvelle
+32,2019-01-01,COM,01001,0,ANCIENNE,Ancienne,Ancienne,COM,01001,0,NOUVELLE,Nouvelle,Nouvelle
+99,2019-01-01,COM,01003,0,INVALIDE,Invalide,Invalide,COM,01001,0,NOUVELLE,Nouvelle,Nouvelle
+`)
+	filePath := filepath.Join(t.TempDir(), "v_mvt_commune_2024.csv")
+	if err := os.WriteFile(filePath, content, 0600); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	resolver, err := LoadResolver(context.Background(), filePath)
+	if err != nil {
+		t.Fatalf("LoadResolver() error = %v", err)
+	}
+
+	if got := resolver.Resolve("01002", date("2018-01-01"), date("2024-01-01")); !reflect.DeepEqual(got, []string{"01001"}) {
+		t.Errorf("Expected 01002 to resolve to 01001, got %v", got)
+	}
+	if got := resolver.Resolve("01003", date("2018-01-01"), date("2024-01-01")); !reflect.DeepEqual(got, []string{"01003"}) {
+		t.Errorf("Expected the invalid event to be skipped, got %v", got)
+	}
+}
+
+func TestLoadResolver_MissingFile(t *testing.T) {
+	if _, err := LoadResolver(context.Background(), filepath.Join(t.TempDir(), "missing.csv")); err == nil {
+		t.Error("Expected error for a missing file, got nil")
+	}
+}
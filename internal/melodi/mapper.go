@@ -0,0 +1,83 @@
+package melodi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"french-admin-etl/internal/infrastructure/entities"
+	"french-admin-etl/internal/model"
+)
+
+// ObservationMapper maps the records of the data file of a dataset to observations, checking their
+// dimensions against the metadata of the dataset.
+type ObservationMapper struct {
+	metadata *Metadata
+}
+
+// NewObservationMapper creates a new mapper for the observations of the dataset described by metadata.
+func NewObservationMapper(metadata *Metadata) *ObservationMapper {
+	return &ObservationMapper{metadata: metadata}
+}
+
+var _ model.Mapper[model.CSVRecord, entities.MelodiObservationEntity] = (*ObservationMapper)(nil)
+
+// Map converts a record to a MelodiObservationEntity. Every dimension of the metadata must have a known
+// modality, and every column must be a dimension of the metadata or a reserved column. A missing
+// GEO_OBJECT defaults to COM, as in the files published before it was added.
+func (m *ObservationMapper) Map(record model.CSVRecord) (*entities.MelodiObservationEntity, error) {
+	geo := record[ColumnGeo]
+	if geo == "" {
+		return nil, fmt.Errorf("missing %s", ColumnGeo)
+	}
+	geoObject := record[ColumnGeoObject]
+	if geoObject == "" {
+		geoObject = "COM"
+	}
+	period := record[ColumnTimePeriod]
+	if period == "" {
+		return nil, fmt.Errorf("missing %s", ColumnTimePeriod)
+	}
+
+	dimensions := make(map[string]string, len(m.metadata.Dimensions))
+	for _, dimension := range m.metadata.Dimensions {
+		modality, ok := record[dimension.Code]
+		if !ok {
+			return nil, fmt.Errorf("missing dimension %s", dimension.Code)
+		}
+		if _, ok := dimension.Modalities[modality]; !ok {
+			return nil, fmt.Errorf("unknown modality %q of dimension %s", modality, dimension.Code)
+		}
+		dimensions[dimension.Code] = modality
+	}
+	for column := range record {
+		if _, ok := dimensions[column]; !ok && isDimension(column) {
+			return nil, fmt.Errorf("column %s is not a dimension of dataset %s", column, m.metadata.Dataset)
+		}
+	}
+	// Keys of a map are marshalled sorted: the same modalities always give the same JSON
+	encoded, err := json.Marshal(dimensions)
+	if err != nil {
+		return nil, err
+	}
+
+	observation := &entities.MelodiObservationEntity{
+		Dataset:    m.metadata.Dataset,
+		GeoObject:  geoObject,
+		Geo:        geo,
+		Periode:    period,
+		Dimensions: string(encoded),
+	}
+	if value := record[ColumnObsValue]; value != "" {
+		parsed, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", "."), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %s, %w", ColumnObsValue, value, err)
+		}
+		observation.Valeur = &parsed
+	}
+	if status := record[ColumnObsStatus]; status != "" {
+		observation.Statut = &status
+	}
+	return observation, nil
+}
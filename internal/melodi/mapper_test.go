@@ -0,0 +1,69 @@
+package melodi
+
+import (
+	"testing"
+
+	"french-admin-etl/internal/infrastructure/entities"
+	"french-admin-etl/internal/model"
+)
+
+var testMetadata = NewMetadata("DS_TEST", []entities.MelodiModalityEntity{
+	{Variable: "SEX", Modalite: "F"}, {Variable: "SEX", Modalite: "M"}, {Variable: "SEX", Modalite: "_T"},
+	{Variable: "AGE", Modalite: "Y_LT15"}, {Variable: "AGE", Modalite: "_T"},
+})
+
+func TestObservationMapper_Map(t *testing.T) {
+	mapper := NewObservationMapper(testMetadata)
+
+	observation, err := mapper.Map(model.CSVRecord{
+		"GEO": "01001", "GEO_OBJECT": "COM", "SEX": "F", "AGE": "Y_LT15", "TIME_PERIOD": "2022",
+		"OBS_VALUE": "123,5", "OBS_STATUS": "A",
+	})
+	if err != nil {
+		t.Fatalf("Map() error = %v", err)
+	}
+	if observation.Dataset != "DS_TEST" || observation.GeoObject != "COM" || observation.Geo != "01001" || observation.Periode != "2022" {
+		t.Errorf("Unexpected observation %+v", observation)
+	}
+	if observation.Dimensions != `{"AGE":"Y_LT15","SEX":"F"}` {
+		t.Errorf("Expected dimensions sorted by code, got %s", observation.Dimensions)
+	}
+	if observation.Valeur == nil || *observation.Valeur != 123.5 {
+		t.Errorf("Expected value 123.5, got %v", observation.Valeur)
+	}
+	if observation.Statut == nil || *observation.Statut != "A" {
+		t.Errorf("Expected status A, got %v", observation.Statut)
+	}
+
+	secret, err := mapper.Map(model.CSVRecord{"GEO": "01001", "SEX": "_T", "AGE": "_T", "TIME_PERIOD": "2022", "OBS_VALUE": ""})
+	if err != nil {
+		t.Fatalf("Map() error = %v", err)
+	}
+	if secret.GeoObject != "COM" || secret.Valeur != nil || secret.Statut != nil {
+		t.Errorf("Expected a COM observation without value nor status, got %+v", secret)
+	}
+}
+
+func TestObservationMapper_MapInvalid(t *testing.T) {
+	mapper := NewObservationMapper(testMetadata)
+
+	tests := []struct {
+		name   string
+		record model.CSVRecord
+	}{
+		{name: "missing GEO", record: model.CSVRecord{"SEX": "F", "AGE": "_T", "TIME_PERIOD": "2022", "OBS_VALUE": "1"}},
+		{name: "missing TIME_PERIOD", record: model.CSVRecord{"GEO": "01001", "SEX": "F", "AGE": "_T", "OBS_VALUE": "1"}},
+		{name: "missing dimension", record: model.CSVRecord{"GEO": "01001", "SEX": "F", "TIME_PERIOD": "2022", "OBS_VALUE": "1"}},
+		{name: "unknown modality", record: model.CSVRecord{"GEO": "01001", "SEX": "X", "AGE": "_T", "TIME_PERIOD": "2022", "OBS_VALUE": "1"}},
+		{name: "unknown dimension", record: model.CSVRecord{"GEO": "01001", "SEX": "F", "AGE": "_T", "PCS": "1", "TIME_PERIOD": "2022", "OBS_VALUE": "1"}},
+		{name: "invalid value", record: model.CSVRecord{"GEO": "01001", "SEX": "F", "AGE": "_T", "TIME_PERIOD": "2022", "OBS_VALUE": "n/a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := mapper.Map(tt.record); err == nil {
+				t.Errorf("Expected error for record %v, got nil", tt.record)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+// Package melodi loads the datasets (cubes DS_*) published by INSEE on its Melodi platform, whatever their
+// dimensions. The data files share a long format: the territory (GEO, GEO_OBJECT), one column per dimension,
+// the period (TIME_PERIOD) and the observation (OBS_VALUE, OBS_STATUS...). The dimensions are read from
+// the metadata file of the dataset, so that a new indicator needs no new code.
+package melodi
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"french-admin-etl/internal/extractors"
+	"french-admin-etl/internal/infrastructure/entities"
+)
+
+// Columns of the data files that are not dimensions.
+const (
+	ColumnGeo        = "GEO"
+	ColumnGeoObject  = "GEO_OBJECT"
+	ColumnTimePeriod = "TIME_PERIOD"
+	ColumnObsValue   = "OBS_VALUE"
+	ColumnObsStatus  = "OBS_STATUS"
+
+	// observationPrefix prefixes the attributes of the observation (OBS_VALUE, OBS_STATUS, OBS_STATUS_FR...)
+	observationPrefix = "OBS_"
+)
+
+// isDimension reports whether a column of a data file, or a variable of a metadata file, is a dimension.
+func isDimension(column string) bool {
+	switch column {
+	case ColumnGeo, ColumnGeoObject, ColumnTimePeriod:
+		return false
+	}
+	return !strings.HasPrefix(column, observationPrefix)
+}
+
+// Dimension is a dimension of a dataset, with the labels of its modalities by code.
+type Dimension struct {
+	Code       string
+	Label      string
+	Modalities map[string]string
+}
+
+// Metadata describes the dimensions of a dataset, in the order of the metadata file.
+type Metadata struct {
+	Dataset    string
+	Dimensions []Dimension
+}
+
+// NewMetadata builds the metadata of a dataset from its modalities. Variables that are not dimensions
+// (territory, period, attributes of the observation) are left out.
+func NewMetadata(dataset string, modalities []entities.MelodiModalityEntity) *Metadata {
+	metadata := &Metadata{Dataset: dataset}
+	for _, modality := range modalities {
+		if !isDimension(modality.Variable) {
+			continue
+		}
+		i := slices.IndexFunc(metadata.Dimensions, func(d Dimension) bool { return d.Code == modality.Variable })
+		if i < 0 {
+			metadata.Dimensions = append(metadata.Dimensions, Dimension{
+				Code:       modality.Variable,
+				Label:      modality.LibelleVariable,
+				Modalities: make(map[string]string),
+			})
+			i = len(metadata.Dimensions) - 1
+		}
+		metadata.Dimensions[i].Modalities[modality.Modalite] = modality.LibelleModalite
+	}
+	return metadata
+}
+
+// Modalities returns the modalities of the dimensions, to be stored with their labels.
+func (m *Metadata) Modalities() []entities.MelodiModalityEntity {
+	var modalities []entities.MelodiModalityEntity
+	for _, dimension := range m.Dimensions {
+		codes := make([]string, 0, len(dimension.Modalities))
+		for code := range dimension.Modalities {
+			codes = append(codes, code)
+		}
+		slices.Sort(codes)
+		for _, code := range codes {
+			modalities = append(modalities, entities.MelodiModalityEntity{
+				Dataset:         m.Dataset,
+				Variable:        dimension.Code,
+				LibelleVariable: dimension.Label,
+				Modalite:        code,
+				LibelleModalite: dimension.Modalities[code],
+			})
+		}
+	}
+	return modalities
+}
+
+// LoadMetadata reads the metadata file of a dataset: a CSV file separated by semicolons, with one row per
+// modality of each variable (COD_VAR, LIB_VAR, COD_MOD, LIB_MOD).
+func LoadMetadata(ctx context.Context, dataset, filePath string) (*Metadata, error) {
+	const batchSize = 1000
+
+	records, err := extractors.NewCSVExtractorWithDelimiter(nil, ';').Extract(ctx, filePath, batchSize)
+	if err != nil {
+		return nil, fmt.Errorf("error extracting metadata of dataset %s: %w", dataset, err)
+	}
+
+	var modalities []entities.MelodiModalityEntity
+	for record := range records {
+		if record["COD_VAR"] == "" || record["COD_MOD"] == "" {
+			slog.Warn("Skip invalid modality", "dataset", dataset, "record", record)
+			continue
+		}
+		modalities = append(modalities, entities.MelodiModalityEntity{
+			Dataset:         dataset,
+			Variable:        record["COD_VAR"],
+			LibelleVariable: record["LIB_VAR"],
+			Modalite:        record["COD_MOD"],
+			LibelleModalite: record["LIB_MOD"],
+		})
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	metadata := NewMetadata(dataset, modalities)
+	if len(metadata.Dimensions) == 0 {
+		return nil, fmt.Errorf("metadata of dataset %s declare no dimension", dataset)
+	}
+	slog.Info("Dataset metadata loaded", "dataset", dataset, "file", filePath, "dimensions", len(metadata.Dimensions))
+	return metadata, nil
+}
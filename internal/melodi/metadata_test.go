@@ -0,0 +1,64 @@
+package melodi
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, name, content string) string {
+	t.Helper()
+	filePath := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filePath, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	return filePath
+}
+
+func TestLoadMetadata(t *testing.T) {
+	filePath := writeTestFile(t, "DS_TEST_metadata.csv", `COD_VAR;LIB_VAR;COD_MOD;LIB_MOD
+GEO;Géographie;2024-COM-01001;L'Abergement-Clémenciat
+SEX;Sexe;F;Femmes
+SEX;Sexe;M;Hommes
+SEX;Sexe;_T;Total
+AGE;Âge;Y_LT15;Moins de 15 ans
+AGE;Âge;_T;Total
+OBS_STATUS;Statut de l'observation;A;Valeur normale
+;Invalide;X;Sans variable
+`)
+
+	metadata, err := LoadMetadata(context.Background(), "DS_TEST", filePath)
+	if err != nil {
+		t.Fatalf("LoadMetadata() error = %v", err)
+	}
+
+	if len(metadata.Dimensions) != 2 {
+		t.Fatalf("Expected the dimensions SEX and AGE, got %+v", metadata.Dimensions)
+	}
+	if sex := metadata.Dimensions[0]; sex.Code != "SEX" || sex.Label != "Sexe" || len(sex.Modalities) != 3 || sex.Modalities["F"] != "Femmes" {
+		t.Errorf("Unexpected first dimension %+v", sex)
+	}
+
+	modalities := metadata.Modalities()
+	if len(modalities) != 5 {
+		t.Fatalf("Expected 5 modalities, got %d", len(modalities))
+	}
+	if first := modalities[0]; first.Dataset != "DS_TEST" || first.Variable != "SEX" || first.Modalite != "F" || first.LibelleVariable != "Sexe" {
+		t.Errorf("Unexpected first modality %+v", first)
+	}
+}
+
+func TestLoadMetadata_NoDimension(t *testing.T) {
+	filePath := writeTestFile(t, "DS_TEST_metadata.csv", "COD_VAR;LIB_VAR;COD_MOD;LIB_MOD\nGEO;Géographie;2024-COM-01001;L'Abergement-Clémenciat\n")
+
+	if _, err := LoadMetadata(context.Background(), "DS_TEST", filePath); err == nil {
+		t.Error("Expected error for metadata without dimension, got nil")
+	}
+}
+
+func TestLoadMetadata_MissingFile(t *testing.T) {
+	if _, err := LoadMetadata(context.Background(), "DS_TEST", filepath.Join(t.TempDir(), "missing.csv")); err == nil {
+		t.Error("Expected error for a missing file, got nil")
+	}
+}
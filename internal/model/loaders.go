@@ -11,3 +11,21 @@ type EntityWithGeoJSONGeometryLoader[T any] interface {
 type EntityLoader[T any] interface {
 	Load(ctx context.Context, entities []T) (int, error)
 }
+
+// LoadFinalizer is implemented by loaders that need a final step once every batch of a run has been loaded.
+// Processors only call Finalize when all batches succeeded.
+type LoadFinalizer interface {
+	Finalize(ctx context.Context) error
+}
+
+// LoadInitializer is implemented by loaders that need to prepare the run before the first batch is loaded.
+type LoadInitializer interface {
+	Initialize(ctx context.Context) error
+}
+
+// LoadCounter is implemented by loaders counting the rows of a run loaded in a degraded state (e.g. with a
+// reference replaced by NULL), or set aside by Load without failing (e.g. recoded, to be loaded by Finalize),
+// which Load counts as handled. Processors add the counts to the results of the run once it is finalized.
+type LoadCounter interface {
+	Counts() map[string]int
+}
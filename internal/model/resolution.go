@@ -0,0 +1,53 @@
+package model
+
+import (
+	"context"
+	"fmt"
+)
+
+// Resolution identifies the precision of a geometry, matching the contours-administratifs files (5m, 100m, 1000m).
+type Resolution string
+
+const (
+	// DefaultResolution stores geometries in the base geometry column.
+	DefaultResolution Resolution = ""
+	// Resolution5m is the most precise resolution, for spatial joins.
+	Resolution5m Resolution = "5m"
+	// Resolution100m is an intermediate resolution, for regional web maps.
+	Resolution100m Resolution = "100m"
+	// Resolution1000m is the coarsest resolution, for national web maps.
+	Resolution1000m Resolution = "1000m"
+)
+
+// ParseResolution converts a string to a Resolution, returning an error for unknown values.
+func ParseResolution(s string) (Resolution, error) {
+	switch r := Resolution(s); r {
+	case DefaultResolution, Resolution5m, Resolution100m, Resolution1000m:
+		return r, nil
+	default:
+		return DefaultResolution, fmt.Errorf("invalid resolution %q, must be one of 5m, 100m, 1000m", s)
+	}
+}
+
+// GeometryColumn returns the name of the column storing geometries of this resolution, derived from the base geometry column (e.g. geom_100m).
+func (r Resolution) GeometryColumn(base string) string {
+	if r == DefaultResolution {
+		return base
+	}
+	return base + "_" + string(r)
+}
+
+type resolutionKey struct{}
+
+// WithResolution returns a copy of ctx carrying the resolution populated by the current run.
+func WithResolution(ctx context.Context, r Resolution) context.Context {
+	return context.WithValue(ctx, resolutionKey{}, r)
+}
+
+// ResolutionFromContext returns the resolution populated by the current run, or DefaultResolution.
+func ResolutionFromContext(ctx context.Context) Resolution {
+	if r, ok := ctx.Value(resolutionKey{}).(Resolution); ok {
+		return r
+	}
+	return DefaultResolution
+}
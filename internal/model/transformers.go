@@ -1,5 +1,7 @@
 package model
 
+import "github.com/twpayne/go-geom"
+
 // Mapper defines the interface for mapping one type to another.
 type Mapper[TInput any, TOutput any] interface {
 	Map(input TInput) (*TOutput, error)
@@ -14,3 +16,11 @@ type CsvRecordTransformer[T any] interface {
 type GeoJSONTransformer[TInput any, TOutput any] interface {
 	Transform(features []GeoJSONFeature[TInput]) ([]EntityWithGeoJSONGeometry[TOutput], error)
 }
+
+// GeometrySimplifier defines the interface for simplifying feature geometries.
+// Index is called for every feature of a dataset before the first call to Simplify, so that
+// implementations can keep the borders shared between features consistent.
+type GeometrySimplifier interface {
+	Index(g geom.T)
+	Simplify(g geom.T) (geom.T, error)
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Vintage is the millésime of a dataset: the year of the administrative geography it describes (as of 1 January).
+type Vintage int
+
+// ParseVintage checks that a year is a plausible vintage.
+func ParseVintage(year int) (Vintage, error) {
+	if year < 1900 || year > 2100 {
+		return 0, fmt.Errorf("invalid vintage %d, must be a year between 1900 and 2100", year)
+	}
+	return Vintage(year), nil
+}
+
+// Date returns the date of the geography described by the vintage, 1 January of its year.
+func (v Vintage) Date() time.Time {
+	return time.Date(int(v), time.January, 1, 0, 0, 0, 0, time.UTC)
+}
+
+type vintageKey struct{}
+
+// WithVintage returns a copy of ctx carrying the vintage loaded by the current run.
+func WithVintage(ctx context.Context, v Vintage) context.Context {
+	return context.WithValue(ctx, vintageKey{}, v)
+}
+
+// VintageFromContext returns the vintage loaded by the current run, false if the run has none.
+func VintageFromContext(ctx context.Context) (Vintage, bool) {
+	v, ok := ctx.Value(vintageKey{}).(Vintage)
+	return v, ok && v != 0
+}
@@ -20,9 +20,11 @@ type CsvETLProcessor[E any] struct {
 	extractor      *extractors.CSVExtractor      // Extractor to read CSV records
 	csvTransformer model.CsvRecordTransformer[E] // Transformer to convert CSV records to entities
 	entityLoader   model.EntityLoader[E]         // Loader to load entities into the database
+	vintage        model.Vintage                 // Vintage loaded by the run, 0 if none
 }
 
-// NewCsvETLProcessor creates a new CsvETLProcessor with the provided configuration, name, delimiter, filter, mapper, and loader.
+// NewCsvETLProcessor creates a new CsvETLProcessor with the provided configuration, name, delimiter, filter, mapper, loader and options.
+// Geometry options (WithGeometrySimplifier, WithResolution) are ignored.
 func NewCsvETLProcessor[E any](
 	config *config.Config,
 	name string,
@@ -30,18 +32,32 @@ func NewCsvETLProcessor[E any](
 	filter model.CsvRecordFilter,
 	mapper model.Mapper[model.CSVRecord, E],
 	loader model.EntityLoader[E],
+	opts ...ProcessorOption,
 ) *CsvETLProcessor[E] {
+	options := newProcessorOptions(opts)
+	extractor := extractors.NewCSVExtractorWithDelimiter(filter, delimiter)
+	extractor.Encoding = options.encoding
+
 	return &CsvETLProcessor[E]{
 		config:         config,
 		name:           name,
-		extractor:      extractors.NewCSVExtractorWithDelimiter(filter, delimiter),
+		extractor:      extractor,
 		csvTransformer: transformers.NewCsvRecordTransformer(mapper),
 		entityLoader:   loader,
+		vintage:        options.vintage,
 	}
 }
 
 // Run executes the ETL process for the given CSV file path, extracting records, transforming them into entities, and loading them into the database using parallel workers.
 func (l *CsvETLProcessor[E]) Run(ctx context.Context, filePath string) error {
+	if l.vintage != 0 {
+		ctx = model.WithVintage(ctx, l.vintage)
+	}
+
+	if err := initialize(ctx, l.name, l.entityLoader); err != nil {
+		return err
+	}
+
 	recordChan, err := l.extractor.Extract(ctx, filePath, l.config.BatchSize)
 	if err != nil {
 		return fmt.Errorf("error extracting CSV records: %w", err)
@@ -64,7 +80,7 @@ func (l *CsvETLProcessor[E]) loadParallelStream(
 	var wg sync.WaitGroup
 
 	// Stats
-	var processed, failed int
+	var processed, failed, failedBatches int
 	var mu sync.Mutex
 
 	// Launch workers
@@ -81,6 +97,7 @@ func (l *CsvETLProcessor[E]) loadParallelStream(
 				processed += n
 				failed += len(batch) - n
 				if err != nil {
+					failedBatches++
 					slog.Error("Batch error", "workerID", workerID, "total", len(batch), "error", err)
 				} else {
 					if n < len(batch) {
@@ -128,8 +145,11 @@ func (l *CsvETLProcessor[E]) loadParallelStream(
 	duration := time.Since(start)
 	rate := float64(processed) / duration.Seconds()
 
-	slog.Info("Results Breakdown", "dataset", l.name, "success", processed, "failed", failed, "duration", duration, "throughput", fmt.Sprintf("%.0f records/sec", rate))
-	return nil
+	// The counts of the loader are complete once it is finalized
+	err := finalize(ctx, l.name, l.entityLoader, failedBatches)
+	results := []any{"dataset", l.name, "success", processed, "failed", failed, "duration", duration, "throughput", fmt.Sprintf("%.0f records/sec", rate)}
+	slog.Info("Results Breakdown", append(results, counts(l.entityLoader)...)...)
+	return err
 }
 
 func (l *CsvETLProcessor[E]) loadBatch(ctx context.Context, records []model.CSVRecord) (int, error) {
@@ -2,11 +2,14 @@ package processor
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
+	"french-admin-etl/internal/extractors"
 	"french-admin-etl/internal/infrastructure/config"
 	"french-admin-etl/internal/infrastructure/entities"
 	"french-admin-etl/internal/model"
@@ -251,6 +254,320 @@ func TestCsvETLProcessor_RunEmptyCSV(t *testing.T) {
 	}
 }
 
+type vintagePopulationLoader struct {
+	mu       sync.Mutex
+	vintages map[model.Vintage]int
+}
+
+func (m *vintagePopulationLoader) Load(ctx context.Context, entities []entities.CommunePopulationPrincEntity) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	vintage, _ := model.VintageFromContext(ctx)
+	m.vintages[vintage] += len(entities)
+	return len(entities), nil
+}
+
+func TestCsvETLProcessor_RunWithVintage(t *testing.T) {
+	config := &config.Config{
+		Workers:   2,
+		BatchSize: 10,
+	}
+
+	loader := &vintagePopulationLoader{vintages: make(map[model.Vintage]int)}
+	processor := NewCsvETLProcessor(
+		config,
+		"Test Population 2024",
+		';',
+		nil,
+		entities.NewCommunePopulationMapper(),
+		loader,
+		WithVintage(2024),
+	)
+
+	if err := processor.Run(context.Background(), "testdata/population.csv"); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(loader.vintages) != 1 || loader.vintages[2024] == 0 {
+		t.Errorf("Expected all entities to be loaded with vintage 2024, got %v", loader.vintages)
+	}
+}
+
+type countingCogCommuneLoader struct {
+	mu    sync.Mutex
+	types map[string]int
+}
+
+func (m *countingCogCommuneLoader) Load(_ context.Context, entities []entities.CogCommuneEntity) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, entity := range entities {
+		m.types[entity.TypeCommune]++
+	}
+	return len(entities), nil
+}
+
+func TestCsvETLProcessor_RunCogCommunes(t *testing.T) {
+	tmpDir := t.TempDir()
+	cogFile := filepath.Join(tmpDir, "v_commune_2024.csv")
+
+	content := []byte(`TYPECOM,COM,REG,DEP,CTCD,ARR,TNCC,NCC,NCCENR,LIBELLE,CAN,COMPARENT
+COM,01001,84,01,01D,012,5,ABERGEMENT CLEMENCIAT,Abergement-Clémenciat,L'Abergement-Clémenciat,0108,
+COMD,01015,,,,,1,ARBIGNIEU,Arbignieu,Arbignieu,,01015
+ARM,75101,,,,,0,PARIS 1ER ARRONDISSEMENT,Paris 1er Arrondissement,Paris 1er Arrondissement,,75056
+XXX,99999,,,,,0,INVALID,Invalid,Invalid,,
+`)
+	if err := writeTestFile(cogFile, content); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	config := &config.Config{
+		Workers:   1,
+		BatchSize: 10,
+	}
+
+	loader := &countingCogCommuneLoader{types: make(map[string]int)}
+	processor := NewCsvETLProcessor(
+		config,
+		"Test COG communes",
+		',',
+		nil,
+		entities.NewCogCommuneMapper(),
+		loader,
+	)
+
+	if err := processor.Run(context.Background(), cogFile); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if loader.types["COM"] != 1 || loader.types["COMD"] != 1 || loader.types["ARM"] != 1 || len(loader.types) != 3 {
+		t.Errorf("Expected one COM, COMD and ARM, the invalid TYPECOM skipped, got %v", loader.types)
+	}
+}
+
+type geoObjectPopulationLoader struct {
+	mu         sync.Mutex
+	geoObjects map[string]int
+}
+
+func (m *geoObjectPopulationLoader) Load(_ context.Context, entities []entities.CommunePopulationPrincEntity) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, entity := range entities {
+		m.geoObjects[entity.GeoObject]++
+	}
+	return len(entities), nil
+}
+
+func TestCsvETLProcessor_RunPopulationArrondissements(t *testing.T) {
+	tmpDir := t.TempDir()
+	populationFile := filepath.Join(tmpDir, "population.csv")
+
+	content := []byte(`AGE;GEO;GEO_OBJECT;RP_MEASURE;SEX;TIME_PERIOD;OBS_VALUE
+_T;75056;COM;POP;_T;2022;2113705
+_T;75101;ARM;POP;_T;2022;15917
+_T;75;DEP;POP;_T;2022;2113705
+`)
+	if err := writeTestFile(populationFile, content); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	config := &config.Config{
+		Workers:   1,
+		BatchSize: 10,
+	}
+
+	loader := &geoObjectPopulationLoader{geoObjects: make(map[string]int)}
+	processor := NewCsvETLProcessor(
+		config,
+		"Test population arrondissements",
+		';',
+		entities.CommunePopulationPrincFilter,
+		entities.NewCommunePopulationMapper(),
+		loader,
+	)
+
+	if err := processor.Run(context.Background(), populationFile); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if loader.geoObjects["COM"] != 1 || loader.geoObjects["ARM"] != 1 || len(loader.geoObjects) != 2 {
+		t.Errorf("Expected one COM and one ARM, the DEP filtered out, got %v", loader.geoObjects)
+	}
+}
+
+type populationByCommuneLoader struct {
+	mu          sync.Mutex
+	populations map[string]string // exact decimal figures
+}
+
+func (m *populationByCommuneLoader) Load(_ context.Context, entities []entities.CommunePopulationPrincEntity) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, entity := range entities {
+		population, err := entity.Population.Value()
+		if err != nil {
+			return 0, err
+		}
+		m.populations[entity.CodeCommune] = population.(string)
+	}
+	return len(entities), nil
+}
+
+func TestCsvETLProcessor_RunPopulationDecimals(t *testing.T) {
+	config := &config.Config{
+		Workers:   1,
+		BatchSize: 10,
+	}
+
+	tests := []struct {
+		name     string
+		mapper   *entities.CommunePopulationMapper
+		expected string
+	}{
+		{name: "rounded", mapper: entities.NewCommunePopulationMapper(), expected: "35"},
+		{name: "decimals", mapper: entities.NewCommunePopulationMapperWithDecimals(), expected: "34.527109"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loader := &populationByCommuneLoader{populations: make(map[string]string)}
+			processor := NewCsvETLProcessor(config, "Test population decimals", ';', nil, tt.mapper, loader)
+
+			if err := processor.Run(context.Background(), "testdata/population.csv"); err != nil {
+				t.Fatalf("Run failed: %v", err)
+			}
+			if got := loader.populations["88244"]; got != tt.expected {
+				t.Errorf("Expected population %v for 88244, got %v", tt.expected, got)
+			}
+			if got := loader.populations["34303"]; got != "18" {
+				t.Errorf("Expected population 18 for 34303, got %v", got)
+			}
+		})
+	}
+}
+
+type capturingIrisPopulationLoader struct {
+	mu       sync.Mutex
+	entities []entities.IrisPopulationEntity
+}
+
+func (m *capturingIrisPopulationLoader) Load(_ context.Context, batch []entities.IrisPopulationEntity) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entities = append(m.entities, batch...)
+	return len(batch), nil
+}
+
+func TestCsvETLProcessor_RunIrisPopulation(t *testing.T) {
+	populationFile := filepath.Join(t.TempDir(), "base-ic-evol-struct-pop-2021.csv")
+	content := []byte(`IRIS;COM;TYP_IRIS;LAB_IRIS;P21_POP;P21_POP0014;P21_POPH;P21_H0014;P21_POP80P
+751010101;75101;A;3;1845.6;120;910;;75.2
+7510101;75101;A;3;1;1;1;1;1
+`)
+	if err := writeTestFile(populationFile, content); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	loader := &capturingIrisPopulationLoader{}
+	processor := NewCsvETLProcessor(
+		&config.Config{Workers: 1, BatchSize: 10},
+		"Test IRIS population",
+		';',
+		nil,
+		entities.NewIrisPopulationMapper(),
+		loader,
+	)
+
+	if err := processor.Run(context.Background(), populationFile); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(loader.entities) != 1 {
+		t.Fatalf("Expected 1 IRIS, the invalid code skipped, got %v", loader.entities)
+	}
+	iris := loader.entities[0]
+	if iris.CodeIris != "751010101" || iris.Annee != 2021 {
+		t.Errorf("Expected IRIS 751010101 in 2021, got %s in %d", iris.CodeIris, iris.Annee)
+	}
+	// Figures by age/sex and exact decimal value
+	figures := make(map[string]bool)
+	for _, figure := range iris.Figures {
+		population, err := figure.Population.Value()
+		if err != nil {
+			t.Fatalf("Value() error = %v", err)
+		}
+		figures[fmt.Sprintf("%s/%s=%v", figure.Age, figure.Sexe, population)] = true
+	}
+	expected := []string{"_T/_T=1846", "Y_LT15/_T=120", "_T/M=910", "Y_GE80/_T=75"}
+	if len(figures) != len(expected) {
+		t.Errorf("Expected %d figures, the empty cell skipped, got %v", len(expected), iris.Figures)
+	}
+	for _, figure := range expected {
+		if !figures[figure] {
+			t.Errorf("Expected figure %s, got %v", figure, figures)
+		}
+	}
+}
+
+type capturingCodePostalLoader struct {
+	mu       sync.Mutex
+	entities []entities.CodePostalEntity
+}
+
+func (m *capturingCodePostalLoader) Load(_ context.Context, batch []entities.CodePostalEntity) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entities = append(m.entities, batch...)
+	return len(batch), nil
+}
+
+func TestCsvETLProcessor_RunCodesPostaux(t *testing.T) {
+	codesPostauxFile := filepath.Join(t.TempDir(), "base-officielle-codes-postaux.csv")
+
+	// ISO-8859-1, as published by La Poste
+	content := []byte("#Code_commune_INSEE;Nom_de_la_commune;Code_postal;Libell\xe9_d_acheminement;Ligne_5\n" +
+		"01033;VALSERHONE;01200;VALSERHONE;VANCHY\n" +
+		"75101;PARIS 01;75001;PARIS;\n" +
+		"42218;ST ETIENNE;4200;ST ETIENNE;\n")
+	if err := writeTestFile(codesPostauxFile, content); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	loader := &capturingCodePostalLoader{}
+	processor := NewCsvETLProcessor(
+		&config.Config{Workers: 1, BatchSize: 10},
+		"Test codes postaux",
+		';',
+		nil,
+		entities.NewCodePostalMapper(),
+		loader,
+		WithEncoding(extractors.Latin1),
+	)
+
+	if err := processor.Run(context.Background(), codesPostauxFile); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(loader.entities) != 2 {
+		t.Fatalf("Expected 2 postal codes, the invalid one skipped, got %v", loader.entities)
+	}
+	for _, entity := range loader.entities {
+		switch entity.CodePostal {
+		case "01200":
+			if entity.CodeCommune != "01033" || entity.Ligne5 != "VANCHY" || entity.LibelleAcheminement != "VALSERHONE" {
+				t.Errorf("Unexpected postal code %+v", entity)
+			}
+		case "75001":
+			if entity.CodeCommune != "75056" || entity.CodeArrondissementMunicipal == nil || *entity.CodeArrondissementMunicipal != "75101" {
+				t.Errorf("Expected 75001 in arrondissement 75101 of Paris, got %+v", entity)
+			}
+		default:
+			t.Errorf("Unexpected postal code %+v", entity)
+		}
+	}
+}
+
 func TestCsvETLProcessor_RunWithDifferentDelimiters(t *testing.T) {
 	// Create a CSV with comma delimiter
 	tmpDir := t.TempDir()
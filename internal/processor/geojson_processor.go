@@ -21,28 +21,57 @@ type GeoJSONETLProcessor[T any, E any] struct {
 	extractor          *extractors.GeoJSONExtractor[T]          // Embedded extractor to read GeoJSON features
 	geoJSONTransformer model.GeoJSONTransformer[T, E]           // Transformer to convert GeoJSON features to entities with WKB
 	entityLoader       model.EntityWithGeoJSONGeometryLoader[E] // Loader to load entities with WKB into the database
+	simplifier         model.GeometrySimplifier                 // Optional simplifier, geometries are indexed in a first pass
+	resolution         model.Resolution                         // Resolution populated by the run
+	vintage            model.Vintage                            // Vintage loaded by the run, 0 if none
 }
 
-// NewGeoJSONETLProcessor creates a new GeoJSONETLProcessor with the provided configuration, name, factory, mapper, and loader.
+// NewGeoJSONETLProcessor creates a new GeoJSONETLProcessor with the provided configuration, name, factory, mapper, loader and options.
 func NewGeoJSONETLProcessor[T any, E any](
 	config *config.Config,
 	name string,
 	factory func() T,
 	mapper model.Mapper[T, E],
 	loader model.EntityWithGeoJSONGeometryLoader[E],
+	opts ...ProcessorOption,
 ) *GeoJSONETLProcessor[T, E] {
+	options := newProcessorOptions(opts)
+
+	geoJSONTransformer := transformers.NewGeoJSONTransformer(mapper)
+	if options.simplifier != nil {
+		geoJSONTransformer = transformers.NewSimplifyingGeoJSONTransformer(geoJSONTransformer, options.simplifier)
+	}
+
 	return &GeoJSONETLProcessor[T, E]{
 		config:             config,
 		name:               name,
 		factory:            factory,
 		extractor:          extractors.NewGeoJSONExtractor[T](),
-		geoJSONTransformer: transformers.NewGeoJSONTransformer(mapper),
+		geoJSONTransformer: geoJSONTransformer,
 		entityLoader:       loader,
+		simplifier:         options.simplifier,
+		resolution:         options.resolution,
+		vintage:            options.vintage,
 	}
 }
 
 // Run executes the ETL process for the given GeoJSON file path, extracting features, transforming them into entities, and loading them into the database using parallel workers.
 func (l *GeoJSONETLProcessor[T, E]) Run(ctx context.Context, filePath string) error {
+	ctx = model.WithResolution(ctx, l.resolution)
+	if l.vintage != 0 {
+		ctx = model.WithVintage(ctx, l.vintage)
+	}
+
+	if err := initialize(ctx, l.name, l.entityLoader); err != nil {
+		return err
+	}
+
+	if l.simplifier != nil {
+		if err := l.indexGeometries(ctx, filePath); err != nil {
+			return err
+		}
+	}
+
 	featureChan, err := l.extractor.Extract(ctx, filePath, l.config.BatchSize, l.factory)
 	if err != nil {
 		return fmt.Errorf("error extracting features: %w", err)
@@ -52,6 +81,33 @@ func (l *GeoJSONETLProcessor[T, E]) Run(ctx context.Context, filePath string) er
 	return l.loadParallelStream(ctx, featureChan)
 }
 
+// indexGeometries reads the whole file once to index every geometry in the simplifier.
+func (l *GeoJSONETLProcessor[T, E]) indexGeometries(ctx context.Context, filePath string) error {
+	start := time.Now()
+
+	featureChan, err := l.extractor.Extract(ctx, filePath, l.config.BatchSize, l.factory)
+	if err != nil {
+		return fmt.Errorf("error extracting features for indexing: %w", err)
+	}
+
+	indexed := 0
+	for feature := range featureChan {
+		if feature.Geometry.Type == "" {
+			continue
+		}
+		g, err := feature.Geometry.Decode()
+		if err != nil {
+			slog.Warn("Skip geometry indexing", "dataset", l.name, "error", err)
+			continue
+		}
+		l.simplifier.Index(g)
+		indexed++
+	}
+
+	slog.Info("Geometries indexed", "dataset", l.name, "features", indexed, "duration", time.Since(start))
+	return nil
+}
+
 func (l *GeoJSONETLProcessor[T, E]) loadParallelStream(
 	ctx context.Context,
 	featureChan <-chan model.GeoJSONFeature[T],
@@ -65,7 +121,7 @@ func (l *GeoJSONETLProcessor[T, E]) loadParallelStream(
 	var wg sync.WaitGroup
 
 	// Stats
-	var processed, failed int
+	var processed, failed, failedBatches int
 	var mu sync.Mutex
 
 	// Launch workers
@@ -82,6 +138,7 @@ func (l *GeoJSONETLProcessor[T, E]) loadParallelStream(
 				processed += n
 				failed += len(batch) - n
 				if err != nil {
+					failedBatches++
 					slog.Error("Batch error", "workerID", workerID, "total", len(batch), "error", err)
 				} else {
 					if n < len(batch) {
@@ -129,8 +186,11 @@ func (l *GeoJSONETLProcessor[T, E]) loadParallelStream(
 	duration := time.Since(start)
 	rate := float64(processed) / duration.Seconds()
 
-	slog.Info("Results Breakdown", "dataset", l.name, "success", processed, "failed", failed, "duration", duration, "throughput", fmt.Sprintf("%.0f features/sec", rate))
-	return nil
+	// The counts of the loader are complete once it is finalized
+	err := finalize(ctx, l.name, l.entityLoader, failedBatches)
+	results := []any{"dataset", l.name, "success", processed, "failed", failed, "duration", duration, "throughput", fmt.Sprintf("%.0f features/sec", rate)}
+	slog.Info("Results Breakdown", append(results, counts(l.entityLoader)...)...)
+	return err
 }
 
 func (l *GeoJSONETLProcessor[T, E]) loadBatch(ctx context.Context, features []model.GeoJSONFeature[T]) (int, error) {
@@ -2,11 +2,15 @@ package processor
 
 import (
 	"context"
+	"errors"
 	"log"
 	"path/filepath"
+	"reflect"
+	"sync"
 	"testing"
 	"time"
 
+	"french-admin-etl/internal/geometry"
 	"french-admin-etl/internal/infrastructure/config"
 	"french-admin-etl/internal/infrastructure/entities"
 	"french-admin-etl/internal/model"
@@ -254,3 +258,245 @@ func TestGeoJSONETLProcessor_RunEmptyFeatureCollection(t *testing.T) {
 // func writeTestFile(path string, content []byte) error {
 // 	return os.WriteFile(path, content, 0644)
 // }
+
+func TestGeoJSONETLProcessor_RunWithGeometrySimplifier(t *testing.T) {
+	config := &config.Config{
+		Workers:   2,
+		BatchSize: 10,
+	}
+
+	simplifier := geometry.NewTopologySimplifier(geometry.DouglasPeucker, 1000)
+	etlprocessor := NewGeoJSONETLProcessor(
+		config,
+		"Test Régions simplifiées",
+		func() entities.RegionProperties {
+			return entities.RegionProperties{}
+		},
+		entities.NewRegionMapper(),
+		NewMockEntityLoader(),
+		WithGeometrySimplifier(simplifier),
+	)
+
+	if etlprocessor.simplifier == nil {
+		t.Fatal("Simplifier not properly set")
+	}
+
+	if err := etlprocessor.Run(context.Background(), "testdata/regions.geojson"); err != nil {
+		t.Errorf("Run with simplifier failed: %v", err)
+	}
+}
+
+type resolutionCapturingLoader struct {
+	mu          sync.Mutex
+	resolutions map[model.Resolution]int
+}
+
+func (m *resolutionCapturingLoader) Load(ctx context.Context, entities []entities.RegionWithGeometry) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.resolutions[model.ResolutionFromContext(ctx)] += len(entities)
+	return len(entities), nil
+}
+
+func TestGeoJSONETLProcessor_RunWithResolution(t *testing.T) {
+	config := &config.Config{
+		Workers:   2,
+		BatchSize: 10,
+	}
+
+	loader := &resolutionCapturingLoader{resolutions: make(map[model.Resolution]int)}
+	etlprocessor := NewGeoJSONETLProcessor(
+		config,
+		"Test Régions 100m",
+		func() entities.RegionProperties {
+			return entities.RegionProperties{}
+		},
+		entities.NewRegionMapper(),
+		loader,
+		WithResolution(model.Resolution100m),
+	)
+
+	if err := etlprocessor.Run(context.Background(), "testdata/regions.geojson"); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(loader.resolutions) != 1 || loader.resolutions[model.Resolution100m] == 0 {
+		t.Errorf("Expected all entities to be loaded with resolution 100m, got %v", loader.resolutions)
+	}
+}
+
+type vintageCapturingLoader struct {
+	mu       sync.Mutex
+	vintages map[model.Vintage]int
+}
+
+func (m *vintageCapturingLoader) Load(ctx context.Context, entities []entities.RegionWithGeometry) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	vintage, _ := model.VintageFromContext(ctx)
+	m.vintages[vintage] += len(entities)
+	return len(entities), nil
+}
+
+func TestGeoJSONETLProcessor_RunWithVintage(t *testing.T) {
+	config := &config.Config{
+		Workers:   2,
+		BatchSize: 10,
+	}
+
+	loader := &vintageCapturingLoader{vintages: make(map[model.Vintage]int)}
+	etlprocessor := NewGeoJSONETLProcessor(
+		config,
+		"Test Régions 2023",
+		func() entities.RegionProperties {
+			return entities.RegionProperties{}
+		},
+		entities.NewRegionMapper(),
+		loader,
+		WithVintage(2023),
+	)
+
+	if err := etlprocessor.Run(context.Background(), "testdata/regions.geojson"); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(loader.vintages) != 1 || loader.vintages[2023] == 0 {
+		t.Errorf("Expected all entities to be loaded with vintage 2023, got %v", loader.vintages)
+	}
+}
+
+type finalizingLoader struct {
+	loadErr     error
+	initialized int
+	finalized   int
+}
+
+func (m *finalizingLoader) Initialize(_ context.Context) error {
+	m.initialized++
+	return nil
+}
+
+func (m *finalizingLoader) Load(_ context.Context, entities []entities.RegionWithGeometry) (int, error) {
+	if m.loadErr != nil {
+		return 0, m.loadErr
+	}
+	return len(entities), nil
+}
+
+func (m *finalizingLoader) Finalize(_ context.Context) error {
+	m.finalized++
+	return nil
+}
+
+func TestGeoJSONETLProcessor_RunFinalize(t *testing.T) {
+	tests := []struct {
+		name      string
+		loadErr   error
+		finalized int
+		wantErr   string
+	}{
+		{name: "finalized after a complete run", finalized: 1},
+		{name: "not finalized after a batch error", loadErr: errors.New("connection lost"), finalized: 0, wantErr: "Test Régions: finalize skipped, 1 batches failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loader := &finalizingLoader{loadErr: tt.loadErr}
+			etlprocessor := NewGeoJSONETLProcessor(
+				&config.Config{Workers: 2, BatchSize: 10},
+				"Test Régions",
+				func() entities.RegionProperties {
+					return entities.RegionProperties{}
+				},
+				entities.NewRegionMapper(),
+				loader,
+			)
+
+			err := etlprocessor.Run(context.Background(), "testdata/regions.geojson")
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Run failed: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Errorf("Expected error %q, got %v", tt.wantErr, err)
+			}
+			if loader.initialized != 1 {
+				t.Errorf("Expected Initialize to be called once, got %d", loader.initialized)
+			}
+			if loader.finalized != tt.finalized {
+				t.Errorf("Expected Finalize to be called %d times, got %d", tt.finalized, loader.finalized)
+			}
+		})
+	}
+}
+
+type countingLoader struct {
+	finalizingLoader
+}
+
+func (m *countingLoader) Counts() map[string]int {
+	return map[string]int{"references_nulled": 2, "references_deferred": m.finalized}
+}
+
+func TestCounts(t *testing.T) {
+	loader := &countingLoader{finalizingLoader{finalized: 1}}
+	expected := []any{"references_deferred", 1, "references_nulled", 2}
+	if attrs := counts(loader); !reflect.DeepEqual(attrs, expected) {
+		t.Errorf("Expected counts %v, got %v", expected, attrs)
+	}
+	if attrs := counts(&finalizingLoader{}); attrs != nil {
+		t.Errorf("Expected no counts for a loader without counter, got %v", attrs)
+	}
+}
+
+type capturingIrisLoader struct {
+	mu   sync.Mutex
+	iris map[string]entities.IrisEntity
+}
+
+func (m *capturingIrisLoader) Load(_ context.Context, entities []entities.IrisWithGeometry) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, entity := range entities {
+		m.iris[entity.Data.Code] = entity.Data
+	}
+	return len(entities), nil
+}
+
+func TestGeoJSONETLProcessor_RunIris(t *testing.T) {
+	const square = `{"type":"MultiPolygon","coordinates":[[[[0,0],[1,0],[1,1],[0,1],[0,0]]]]}`
+	irisFile := filepath.Join(t.TempDir(), "iris.geojson")
+	content := []byte(`{"type":"FeatureCollection","features":[
+{"type":"Feature","properties":{"code":"010010000","nom":"L'Abergement-Clémenciat","type":"Z","commune":"01001"},"geometry":` + square + `},
+{"type":"Feature","properties":{"code":"751010101","nom":"Saint-Germain-l'Auxerrois 1","type":"A","commune":"75101"},"geometry":` + square + `},
+{"type":"Feature","properties":{"code":"751010102","nom":"Invalid","type":"X","commune":"75101"},"geometry":` + square + `}
+]}`)
+	if err := writeTestFile(irisFile, content); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	loader := &capturingIrisLoader{iris: make(map[string]entities.IrisEntity)}
+	etlprocessor := NewGeoJSONETLProcessor(
+		&config.Config{Workers: 1, BatchSize: 10},
+		"Test IRIS",
+		func() entities.IrisProperties {
+			return entities.IrisProperties{}
+		},
+		entities.NewIrisMapper(),
+		loader,
+	)
+
+	if err := etlprocessor.Run(context.Background(), irisFile); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(loader.iris) != 2 {
+		t.Fatalf("Expected 2 IRIS, the invalid type skipped, got %v", loader.iris)
+	}
+	if iris := loader.iris["010010000"]; iris.CodeCommune != "01001" || iris.CodeArrondissementMunicipal != nil {
+		t.Errorf("Expected 010010000 in commune 01001, got %+v", iris)
+	}
+	paris := loader.iris["751010101"]
+	if paris.CodeCommune != "75056" || paris.CodeArrondissementMunicipal == nil || *paris.CodeArrondissementMunicipal != "75101" {
+		t.Errorf("Expected 751010101 in arrondissement 75101 of Paris, got %+v", paris)
+	}
+}
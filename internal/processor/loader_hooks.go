@@ -0,0 +1,60 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"french-admin-etl/internal/model"
+)
+
+// initialize calls the preparation step of the loader, if any, before the first batch is loaded.
+func initialize(ctx context.Context, name string, loader any) error {
+	initializer, ok := loader.(model.LoadInitializer)
+	if !ok {
+		return nil
+	}
+
+	if err := initializer.Initialize(ctx); err != nil {
+		return fmt.Errorf("error initializing %s: %w", name, err)
+	}
+	return nil
+}
+
+// finalize calls the final step of the loader, if any, once every batch has been loaded.
+// It is skipped when a batch failed or the run was cancelled, as the loader hasn't seen the whole source: the
+// run then fails, so that a partial load isn't mistaken for a complete one whose swap, sync or validation ran.
+func finalize(ctx context.Context, name string, loader any, failedBatches int) error {
+	finalizer, ok := loader.(model.LoadFinalizer)
+	if !ok {
+		return nil
+	}
+
+	if failedBatches > 0 {
+		return fmt.Errorf("%s: finalize skipped, %d batches failed", name, failedBatches)
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: finalize skipped: %w", name, err)
+	}
+
+	if err := finalizer.Finalize(ctx); err != nil {
+		return fmt.Errorf("error finalizing %s: %w", name, err)
+	}
+	return nil
+}
+
+// counts returns the counts of the loader, if any, as slog attributes sorted by name, for the results of the run.
+func counts(loader any) []any {
+	counter, ok := loader.(model.LoadCounter)
+	if !ok {
+		return nil
+	}
+
+	c := counter.Counts()
+	attrs := make([]any, 0, 2*len(c))
+	for _, name := range slices.Sorted(maps.Keys(c)) {
+		attrs = append(attrs, name, c[name])
+	}
+	return attrs
+}
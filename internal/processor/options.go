@@ -0,0 +1,56 @@
+package processor
+
+import (
+	"french-admin-etl/internal/extractors"
+	"french-admin-etl/internal/model"
+)
+
+// ProcessorOption configures optional behaviour of a processor.
+type ProcessorOption func(*processorOptions)
+
+type processorOptions struct {
+	simplifier model.GeometrySimplifier
+	resolution model.Resolution
+	vintage    model.Vintage
+	encoding   extractors.Encoding
+}
+
+func newProcessorOptions(opts []ProcessorOption) processorOptions {
+	options := processorOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// WithGeometrySimplifier is an option to simplify geometries before loading them.
+// The file is then read twice: the first pass indexes every geometry so that borders shared between features are simplified identically.
+func WithGeometrySimplifier(simplifier model.GeometrySimplifier) ProcessorOption {
+	return func(o *processorOptions) {
+		o.simplifier = simplifier
+	}
+}
+
+// WithResolution is an option naming the geometry resolution populated by the run.
+// Loaders store the geometries in the matching column (e.g. geom_100m) instead of the default one.
+func WithResolution(resolution model.Resolution) ProcessorOption {
+	return func(o *processorOptions) {
+		o.resolution = resolution
+	}
+}
+
+// WithVintage is an option naming the vintage (millésime) loaded by the run.
+// Loaders of vintaged tables store the rows of each vintage side by side.
+func WithVintage(vintage model.Vintage) ProcessorOption {
+	return func(o *processorOptions) {
+		o.vintage = vintage
+	}
+}
+
+// WithEncoding is an option naming the character encoding of the CSV file, decoded to UTF-8.
+// It is ignored by the GeoJSON processor.
+func WithEncoding(encoding extractors.Encoding) ProcessorOption {
+	return func(o *processorOptions) {
+		o.encoding = encoding
+	}
+}
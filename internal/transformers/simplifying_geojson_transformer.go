@@ -0,0 +1,72 @@
+package transformers
+
+import (
+	"fmt"
+	"french-admin-etl/internal/model"
+	"log/slog"
+
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+)
+
+type simplifyingGeoJSONTransformer[TInput any, TOutput any] struct {
+	transformer model.GeoJSONTransformer[TInput, TOutput]
+	simplifier  model.GeometrySimplifier
+}
+
+// NewSimplifyingGeoJSONTransformer creates a GeoJSONTransformer that simplifies feature geometries before delegating to the provided transformer.
+func NewSimplifyingGeoJSONTransformer[TInput any, TOutput any](
+	transformer model.GeoJSONTransformer[TInput, TOutput],
+	simplifier model.GeometrySimplifier,
+) model.GeoJSONTransformer[TInput, TOutput] {
+	return &simplifyingGeoJSONTransformer[TInput, TOutput]{
+		transformer: transformer,
+		simplifier:  simplifier,
+	}
+}
+
+// Transform simplifies the geometry of each feature, then delegates to the transformer. A feature whose
+// geometry can't be decoded is skipped, and one whose geometry can't be simplified is passed through
+// unsimplified, both with a warning, so that a single bad geometry doesn't fail the batch.
+func (t *simplifyingGeoJSONTransformer[TInput, TOutput]) Transform(features []model.GeoJSONFeature[TInput]) ([]model.EntityWithGeoJSONGeometry[TOutput], error) {
+	simplified := make([]model.GeoJSONFeature[TInput], 0, len(features))
+	for _, feature := range features {
+		// Features without geometry are left to the transformer
+		if feature.Geometry.Type == "" {
+			simplified = append(simplified, feature)
+			continue
+		}
+
+		g, err := feature.Geometry.Decode()
+		if err != nil {
+			slog.Warn("Skip feature, invalid geometry", "feature", feature.Properties, "error", err)
+			continue
+		}
+
+		geometry, err := t.simplify(g)
+		if err != nil {
+			slog.Warn("Keep feature unsimplified", "feature", feature.Properties, "error", err)
+			simplified = append(simplified, feature)
+			continue
+		}
+
+		feature.Geometry = *geometry
+		simplified = append(simplified, feature)
+	}
+
+	return t.transformer.Transform(simplified)
+}
+
+// simplify returns the simplified geometry, encoded back to GeoJSON.
+func (t *simplifyingGeoJSONTransformer[TInput, TOutput]) simplify(g geom.T) (*geojson.Geometry, error) {
+	g, err := t.simplifier.Simplify(g)
+	if err != nil {
+		return nil, fmt.Errorf("error simplifying geometry: %w", err)
+	}
+
+	geometry, err := geojson.Encode(g)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding geometry: %w", err)
+	}
+	return geometry, nil
+}
@@ -0,0 +1,151 @@
+package transformers
+
+import (
+	"errors"
+	"french-admin-etl/internal/geometry"
+	"french-admin-etl/internal/infrastructure/entities"
+	"french-admin-etl/internal/model"
+	"testing"
+
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+)
+
+// mockSimplifier is a mock implementation of model.GeometrySimplifier for testing
+type mockSimplifier struct {
+	simplifyFunc func(geom.T) (geom.T, error)
+	calls        int
+}
+
+func (m *mockSimplifier) Index(_ geom.T) {}
+
+func (m *mockSimplifier) Simplify(g geom.T) (geom.T, error) {
+	m.calls++
+	if m.simplifyFunc != nil {
+		return m.simplifyFunc(g)
+	}
+	return g, nil
+}
+
+func squareFeature(code string) model.GeoJSONFeature[entities.RegionProperties] {
+	return model.GeoJSONFeature[entities.RegionProperties]{
+		Type:       "Feature",
+		Properties: entities.RegionProperties{Code: code, Nom: "Region " + code},
+		Geometry: geojson.Geometry{
+			Type:        "Polygon",
+			Coordinates: jsonRawMessage(`[[[0,0],[0.005,0.00001],[0.01,0],[0.01,0.01],[0,0.01],[0,0]]]`),
+		},
+	}
+}
+
+// TestSimplifyingGeoJSONTransformer_Transform_Success tests that geometries are simplified
+func TestSimplifyingGeoJSONTransformer_Transform_Success(t *testing.T) {
+	simplifier := geometry.NewTopologySimplifier(geometry.DouglasPeucker, 100)
+	features := []model.GeoJSONFeature[entities.RegionProperties]{squareFeature("01")}
+	for _, feature := range features {
+		g, err := feature.Geometry.Decode()
+		if err != nil {
+			t.Fatalf("Failed to decode geometry: %v", err)
+		}
+		simplifier.Index(g)
+	}
+
+	transformer := NewSimplifyingGeoJSONTransformer(
+		NewGeoJSONTransformer[entities.RegionProperties, entities.RegionEntity](&mockGeoJSONMapper{}),
+		simplifier,
+	)
+
+	entities, err := transformer.Transform(features)
+	if err != nil {
+		t.Fatalf("Transform() error = %v", err)
+	}
+	if len(entities) != 1 {
+		t.Fatalf("Expected 1 entity, got %d", len(entities))
+	}
+	if entities[0].Data.Code != "01" {
+		t.Errorf("Expected Code '01', got %q", entities[0].Data.Code)
+	}
+	if contains(entities[0].GeoJSONGeometry, "0.005") {
+		t.Errorf("Expected noise vertex to be removed, got %s", entities[0].GeoJSONGeometry)
+	}
+}
+
+// TestSimplifyingGeoJSONTransformer_Transform_EmptyGeometry tests that features without geometry are passed through
+func TestSimplifyingGeoJSONTransformer_Transform_EmptyGeometry(t *testing.T) {
+	simplifier := &mockSimplifier{}
+	transformer := NewSimplifyingGeoJSONTransformer(
+		NewGeoJSONTransformer[entities.RegionProperties, entities.RegionEntity](&mockGeoJSONMapper{}),
+		simplifier,
+	)
+
+	features := []model.GeoJSONFeature[entities.RegionProperties]{
+		{Type: "Feature", Properties: entities.RegionProperties{Code: "01"}},
+	}
+
+	entities, err := transformer.Transform(features)
+	if err != nil {
+		t.Fatalf("Transform() error = %v", err)
+	}
+	if len(entities) != 1 {
+		t.Errorf("Expected 1 entity, got %d", len(entities))
+	}
+	if simplifier.calls != 0 {
+		t.Errorf("Expected simplifier not to be called, got %d calls", simplifier.calls)
+	}
+}
+
+// TestSimplifyingGeoJSONTransformer_Transform_SimplifierError tests that a geometry the simplifier fails on
+// is kept unsimplified, without failing the other features
+func TestSimplifyingGeoJSONTransformer_Transform_SimplifierError(t *testing.T) {
+	simplifier := &mockSimplifier{
+		simplifyFunc: func(g geom.T) (geom.T, error) {
+			if len(g.FlatCoords()) > 10 {
+				return nil, errors.New("simplification failed")
+			}
+			return g, nil
+		},
+	}
+	transformer := NewSimplifyingGeoJSONTransformer(
+		NewGeoJSONTransformer[entities.RegionProperties, entities.RegionEntity](&mockGeoJSONMapper{}),
+		simplifier,
+	)
+
+	triangle := squareFeature("02")
+	triangle.Geometry.Coordinates = jsonRawMessage(`[[[0,0],[1,0],[0,1],[0,0]]]`)
+	entities, err := transformer.Transform([]model.GeoJSONFeature[entities.RegionProperties]{squareFeature("01"), triangle})
+	if err != nil {
+		t.Fatalf("Transform() error = %v", err)
+	}
+	if len(entities) != 2 {
+		t.Fatalf("Expected 2 entities, got %d", len(entities))
+	}
+	if !contains(entities[0].GeoJSONGeometry, "0.005") {
+		t.Errorf("Expected the geometry of 01 unsimplified, got %s", entities[0].GeoJSONGeometry)
+	}
+}
+
+// TestSimplifyingGeoJSONTransformer_Transform_InvalidGeometry tests that features with an undecodable geometry
+// are skipped, without failing the other features
+func TestSimplifyingGeoJSONTransformer_Transform_InvalidGeometry(t *testing.T) {
+	transformer := NewSimplifyingGeoJSONTransformer(
+		NewGeoJSONTransformer[entities.RegionProperties, entities.RegionEntity](&mockGeoJSONMapper{}),
+		&mockSimplifier{},
+	)
+
+	features := []model.GeoJSONFeature[entities.RegionProperties]{
+		{
+			Type:       "Feature",
+			Properties: entities.RegionProperties{Code: "01"},
+			Geometry:   geojson.Geometry{Type: "Polygon", Coordinates: jsonRawMessage(`"not coordinates"`)},
+		},
+		squareFeature("02"),
+	}
+
+	entities, err := transformer.Transform(features)
+	if err != nil {
+		t.Fatalf("Transform() error = %v", err)
+	}
+	if len(entities) != 1 || entities[0].Data.Code != "02" {
+		t.Errorf("Expected only 02, got %+v", entities)
+	}
+}
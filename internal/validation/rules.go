@@ -0,0 +1,175 @@
+// Package validation checks the internal identities of population figures (men + women = total, age
+// groups adding up to the total...) within a tolerance, and reports the records violating them.
+package validation
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Rule is an identity between the figures of a record, named by their columns: either the parts add up to
+// the total, or, for an inclusion, the single part doesn't exceed the total.
+type Rule struct {
+	Name      string
+	Parts     []string
+	Total     string
+	inclusion bool
+}
+
+// Sum returns the rule parts[0] + parts[1] + ... = total.
+func Sum(total string, parts ...string) Rule {
+	return Rule{Name: strings.Join(parts, " + ") + " = " + total, Parts: parts, Total: total}
+}
+
+// Inclusion returns the rule subset <= superset, e.g. the population over 80 within the population over 65.
+func Inclusion(subset, superset string) Rule {
+	return Rule{Name: subset + " <= " + superset, Parts: []string{subset}, Total: superset, inclusion: true}
+}
+
+// PopulationColumn is a column of the population tables, with the INSEE codes of the age group (AGE) and
+// sex (SEX) of its figures, e.g. pop_LT15_f for Y_LT15 and F.
+type PopulationColumn struct {
+	Name string
+	Age  string
+	Sex  string
+}
+
+// Codes of the population datasets for all ages and both sexes.
+const (
+	allAges    = "_T"
+	bothSexes  = "_T"
+	unboundAge = math.MaxInt
+)
+
+// ageGroup matches the age groups of the population datasets: Y_LT15 (under 15), Y15T24 (15 to 24),
+// Y_GE65 (65 and over).
+var ageGroup = regexp.MustCompile(`^Y(?:_LT(\d+)|(\d+)T(\d+)|_GE(\d+))$`)
+
+// ageRange is the range of ages [from, to) of an age group, to being unboundAge when it has no upper bound.
+type ageRange struct {
+	from, to int
+	column   string
+}
+
+// parseAgeRange returns the range of ages of an age group code, false when the code isn't an age group.
+func parseAgeRange(age string) (ageRange, bool) {
+	if age == allAges {
+		return ageRange{from: 0, to: unboundAge}, true
+	}
+	match := ageGroup.FindStringSubmatch(age)
+	if match == nil {
+		return ageRange{}, false
+	}
+	bound := func(s string) int {
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	switch {
+	case match[1] != "":
+		return ageRange{from: 0, to: bound(match[1])}, true
+	case match[4] != "":
+		return ageRange{from: bound(match[4]), to: unboundAge}, true
+	default:
+		r := ageRange{from: bound(match[2]), to: bound(match[3]) + 1}
+		return r, r.from < r.to
+	}
+}
+
+// contains reports whether the range strictly contains other.
+func (r ageRange) contains(other ageRange) bool {
+	return r.from <= other.from && other.to <= r.to && (r.from != other.from || r.to != other.to)
+}
+
+// PopulationRules returns the identities of the columns of the population tables, derived from their
+// age groups and sexes (see repository.PivotSpec): the sexes add up to both sexes in every age group, the
+// finest and the coarsest partitions of an age group by the other groups add up to it, and an age group
+// sharing a bound with a wider one doesn't exceed it. The age rules apply to both sexes, the figures by
+// sex being checked against them.
+func PopulationRules(columns []PopulationColumn) []Rule {
+	var rules []Rule
+
+	// Sexes by age group, in the order of the columns
+	var ages []string
+	sexes := make(map[string][]PopulationColumn)
+	for _, column := range columns {
+		if _, ok := sexes[column.Age]; !ok {
+			ages = append(ages, column.Age)
+		}
+		sexes[column.Age] = append(sexes[column.Age], column)
+	}
+	for _, age := range ages {
+		total := ""
+		var parts []string
+		for _, column := range sexes[age] {
+			if column.Sex == bothSexes {
+				total = column.Name
+			} else {
+				parts = append(parts, column.Name)
+			}
+		}
+		if total != "" && len(parts) > 1 {
+			rules = append(rules, Sum(total, parts...))
+		}
+	}
+
+	// Age groups of both sexes
+	var ranges []ageRange
+	for _, column := range columns {
+		if r, ok := parseAgeRange(column.Age); ok && column.Sex == bothSexes {
+			r.column = column.Name
+			ranges = append(ranges, r)
+		}
+	}
+	for _, r := range ranges {
+		partitions := agePartitions(r, ranges)
+		if len(partitions) == 0 {
+			continue
+		}
+		finest, coarsest := partitions[0], partitions[0]
+		for _, partition := range partitions[1:] {
+			if len(partition) > len(finest) {
+				finest = partition
+			}
+			if len(partition) < len(coarsest) {
+				coarsest = partition
+			}
+		}
+		rules = append(rules, Sum(r.column, finest...))
+		if len(coarsest) != len(finest) {
+			rules = append(rules, Sum(r.column, coarsest...))
+		}
+	}
+	for _, r := range ranges {
+		if r.from == 0 && r.to == unboundAge {
+			continue
+		}
+		for _, other := range ranges {
+			if r.contains(other) && (other.from == r.from || other.to == r.to) {
+				rules = append(rules, Inclusion(other.column, r.column))
+			}
+		}
+	}
+	return rules
+}
+
+// agePartitions returns the partitions of the range by the narrower ranges, as the columns of their ranges by
+// increasing age, in the order of the ranges.
+func agePartitions(r ageRange, ranges []ageRange) [][]string {
+	var partitions [][]string
+	var walk func(from int, columns []string)
+	walk = func(from int, columns []string) {
+		if from == r.to {
+			partitions = append(partitions, columns)
+			return
+		}
+		for _, next := range ranges {
+			if next.from == from && r.contains(next) {
+				walk(next.to, append(columns[:len(columns):len(columns)], next.column))
+			}
+		}
+	}
+	walk(r.from, nil)
+	return partitions
+}
@@ -0,0 +1,92 @@
+package validation
+
+import (
+	"slices"
+	"testing"
+)
+
+// populationColumns returns the columns of the default population pivot (see
+// repository.DefaultPopulationPivot).
+func populationColumns() []PopulationColumn {
+	ages := []struct{ code, suffix string }{
+		{"_T", ""}, {"Y_LT15", "_LT15"}, {"Y_LT20", "_LT20"}, {"Y15T24", "_15T24"}, {"Y20T64", "_20T64"}, {"Y25T39", "_25T39"},
+		{"Y40T54", "_40T54"}, {"Y55T64", "_55T64"}, {"Y65T79", "_65T79"}, {"Y_GE65", "_GE65"}, {"Y_GE80", "_GE80"},
+	}
+	sexes := []struct{ code, suffix string }{{"_T", ""}, {"M", "_h"}, {"F", "_f"}}
+
+	var columns []PopulationColumn
+	for _, age := range ages {
+		for _, sex := range sexes {
+			columns = append(columns, PopulationColumn{Name: "pop" + age.suffix + sex.suffix, Age: age.code, Sex: sex.code})
+		}
+	}
+	return columns
+}
+
+func ruleNames(rules []Rule) []string {
+	names := make([]string, len(rules))
+	for i, rule := range rules {
+		names[i] = rule.Name
+	}
+	return names
+}
+
+func TestPopulationRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		columns  []PopulationColumn
+		expected []string
+	}{
+		{
+			name:    "default pivot",
+			columns: populationColumns(),
+			expected: []string{
+				"pop_h + pop_f = pop", "pop_LT15_h + pop_LT15_f = pop_LT15", "pop_LT20_h + pop_LT20_f = pop_LT20",
+				"pop_15T24_h + pop_15T24_f = pop_15T24", "pop_20T64_h + pop_20T64_f = pop_20T64",
+				"pop_25T39_h + pop_25T39_f = pop_25T39", "pop_40T54_h + pop_40T54_f = pop_40T54",
+				"pop_55T64_h + pop_55T64_f = pop_55T64", "pop_65T79_h + pop_65T79_f = pop_65T79",
+				"pop_GE65_h + pop_GE65_f = pop_GE65", "pop_GE80_h + pop_GE80_f = pop_GE80",
+				"pop_LT15 + pop_15T24 + pop_25T39 + pop_40T54 + pop_55T64 + pop_65T79 + pop_GE80 = pop",
+				"pop_LT20 + pop_20T64 + pop_GE65 = pop",
+				"pop_65T79 + pop_GE80 = pop_GE65",
+				"pop_LT15 <= pop_LT20", "pop_55T64 <= pop_20T64", "pop_65T79 <= pop_GE65", "pop_GE80 <= pop_GE65",
+			},
+		},
+		{
+			name: "custom pivot",
+			columns: []PopulationColumn{
+				{Name: "total", Age: "_T", Sex: "_T"},
+				{Name: "jeunes", Age: "Y_LT30", Sex: "_T"},
+				{Name: "adultes", Age: "Y30T59", Sex: "_T"},
+				{Name: "seniors", Age: "Y_GE60", Sex: "_T"},
+				{Name: "seniors_h", Age: "Y_GE60", Sex: "M"},
+				{Name: "seniors_f", Age: "Y_GE60", Sex: "F"},
+			},
+			expected: []string{"seniors_h + seniors_f = seniors", "jeunes + adultes + seniors = total"},
+		},
+		{
+			name: "by sex only",
+			columns: []PopulationColumn{
+				{Name: "pop_h", Age: "_T", Sex: "M"},
+				{Name: "pop_f", Age: "_T", Sex: "F"},
+			},
+		},
+		{
+			name: "gap in the age groups",
+			columns: []PopulationColumn{
+				{Name: "pop", Age: "_T", Sex: "_T"},
+				{Name: "pop_LT15", Age: "Y_LT15", Sex: "_T"},
+				{Name: "pop_GE65", Age: "Y_GE65", Sex: "_T"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names := ruleNames(PopulationRules(tt.columns))
+			if !slices.Equal(names, tt.expected) {
+				t.Errorf("PopulationRules() = %q, expected %q", names, tt.expected)
+			}
+		})
+	}
+}
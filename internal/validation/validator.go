@@ -0,0 +1,210 @@
+package validation
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Tolerance is the deviation accepted by a rule: Ratio of the total, plus Absolute for each figure of the
+// rule, 0.5 covering figures rounded to integers.
+type Tolerance struct {
+	Ratio    float64
+	Absolute float64
+}
+
+// DefaultTolerance accepts the rounding of the figures to integers, plus 0.1% of the total.
+var DefaultTolerance = Tolerance{Ratio: 0.001, Absolute: 0.5}
+
+// Violation is a rule not verified by the figures of a record.
+type Violation struct {
+	Code     string
+	Annee    int
+	Rule     string
+	Expected float64 // the total
+	Actual   float64 // the sum of the parts
+}
+
+// ValidatorOption configures optional behaviour of a Validator.
+type ValidatorOption func(*Validator)
+
+// WithReport is an option to write the violations of the run to a CSV file.
+func WithReport(filePath string) ValidatorOption {
+	return func(v *Validator) {
+		v.reportPath = filePath
+	}
+}
+
+// WithMaxViolationRatio is an option to fail the run when more than maxRatio (0 to 1) of the checked records
+// violate a rule.
+func WithMaxViolationRatio(maxRatio float64) ValidatorOption {
+	return func(v *Validator) {
+		v.maxRatio = maxRatio
+		v.blocking = true
+	}
+}
+
+// Validator checks the records of a run against rules, and collects the violations. It is safe for
+// concurrent use.
+type Validator struct {
+	rules      []Rule
+	tolerance  Tolerance
+	reportPath string  // empty when no report is written
+	maxRatio   float64 // ratio of violating records failing the run, when blocking
+	blocking   bool
+
+	mu         sync.Mutex
+	checked    int
+	violating  int
+	violations []Violation
+}
+
+// NewValidator creates a validator of the rules within the tolerance.
+func NewValidator(rules []Rule, tolerance Tolerance, opts ...ValidatorOption) *Validator {
+	v := &Validator{rules: rules, tolerance: tolerance}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// Check checks the figures of a record, by column. Rules involving a figure the record lacks are skipped.
+func (v *Validator) Check(code string, annee int, figures map[string]*float64) {
+	var violations []Violation
+	for _, rule := range v.rules {
+		total := figures[rule.Total]
+		if total == nil {
+			continue
+		}
+		sum, complete := 0.0, true
+		for _, part := range rule.Parts {
+			value := figures[part]
+			if value == nil {
+				complete = false
+				break
+			}
+			sum += *value
+		}
+		if !complete {
+			continue
+		}
+
+		allowed := v.tolerance.Ratio*math.Abs(*total) + v.tolerance.Absolute*float64(len(rule.Parts)+1)
+		deviation := sum - *total
+		if rule.inclusion && deviation <= allowed || !rule.inclusion && math.Abs(deviation) <= allowed {
+			continue
+		}
+		violations = append(violations, Violation{Code: code, Annee: annee, Rule: rule.Name, Expected: *total, Actual: sum})
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.checked++
+	if len(violations) > 0 {
+		v.violating++
+		v.violations = append(v.violations, violations...)
+	}
+}
+
+// Violations returns the violations of the run, sorted by code, year and rule.
+func (v *Validator) Violations() []Violation {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	violations := append([]Violation(nil), v.violations...)
+	sort.Slice(violations, func(i, j int) bool {
+		if violations[i].Code != violations[j].Code {
+			return violations[i].Code < violations[j].Code
+		}
+		if violations[i].Annee != violations[j].Annee {
+			return violations[i].Annee < violations[j].Annee
+		}
+		return violations[i].Rule < violations[j].Rule
+	})
+	return violations
+}
+
+// Finish logs the violations of the run by rule and writes the report. It fails when the ratio of
+// violating records exceeds the maximum, for the caller to abort the commit of the run.
+func (v *Validator) Finish(entity string) error {
+	violations := v.Violations()
+	v.mu.Lock()
+	checked, violating := v.checked, v.violating
+	v.mu.Unlock()
+
+	byRule := make(map[string]int)
+	for _, violation := range violations {
+		byRule[violation.Rule]++
+	}
+	rules := make([]string, 0, len(byRule))
+	for rule := range byRule {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+	for _, rule := range rules {
+		slog.Warn("Consistency rule violated", "entity", entity, "rule", rule, "records", byRule[rule])
+	}
+	slog.Info("Consistency checked", "entity", entity, "records", checked, "violating", violating)
+
+	if v.reportPath != "" {
+		if err := writeReport(v.reportPath, violations); err != nil {
+			return fmt.Errorf("error writing validation report: %w", err)
+		}
+		slog.Info("Validation report written", "file", v.reportPath, "violations", len(violations))
+	}
+
+	if v.blocking && checked > 0 {
+		if ratio := float64(violating) / float64(checked); ratio > v.maxRatio {
+			return fmt.Errorf("%d of %d %s records (%.1f%%) violate the consistency rules, above the %.1f%% threshold",
+				violating, checked, entity, ratio*100, v.maxRatio*100)
+		}
+	}
+	return nil
+}
+
+// Reset forgets the records checked, so that the validator can be used for another run.
+func (v *Validator) Reset() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.checked = 0
+	v.violating = 0
+	v.violations = nil
+}
+
+// writeReport writes the violations to a CSV file separated by semicolons.
+func writeReport(filePath string, violations []Violation) error {
+	// #nosec G304 -- filePath is controlled by the application, not user input
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	writer := csv.NewWriter(file)
+	writer.Comma = ';'
+	if err := writer.Write([]string{"code", "annee", "regle", "attendu", "obtenu", "ecart"}); err != nil {
+		return err
+	}
+	for _, violation := range violations {
+		err := writer.Write([]string{
+			violation.Code,
+			strconv.Itoa(violation.Annee),
+			violation.Rule,
+			strconv.FormatFloat(violation.Expected, 'f', -1, 64),
+			strconv.FormatFloat(violation.Actual, 'f', -1, 64),
+			strconv.FormatFloat(violation.Actual-violation.Expected, 'f', -1, 64),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return file.Close()
+}
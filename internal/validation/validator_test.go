@@ -0,0 +1,82 @@
+package validation
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func figures(values map[string]float64) map[string]*float64 {
+	figures := make(map[string]*float64, len(values))
+	for column, value := range values {
+		figures[column] = &value
+	}
+	return figures
+}
+
+func TestValidator_Check(t *testing.T) {
+	tests := []struct {
+		name     string
+		figures  map[string]float64
+		expected []string
+	}{
+		{name: "consistent", figures: map[string]float64{"pop": 1000, "pop_h": 490, "pop_f": 510}},
+		{name: "rounding", figures: map[string]float64{"pop": 1000, "pop_h": 491, "pop_f": 510}},
+		{name: "sexes don't add up", figures: map[string]float64{"pop": 1000, "pop_h": 400, "pop_f": 510}, expected: []string{"pop_h + pop_f = pop"}},
+		{name: "missing figure skips the rule", figures: map[string]float64{"pop": 1000, "pop_h": 400}},
+		{name: "inclusion", figures: map[string]float64{"pop_GE65": 100, "pop_GE80": 150, "pop_65T79": 80}, expected: []string{"pop_65T79 + pop_GE80 = pop_GE65", "pop_GE80 <= pop_GE65"}},
+		{name: "partition", figures: map[string]float64{"pop": 1000, "pop_LT20": 200, "pop_20T64": 550, "pop_GE65": 150}, expected: []string{"pop_LT20 + pop_20T64 + pop_GE65 = pop"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := NewValidator(PopulationRules(populationColumns()), DefaultTolerance)
+			validator.Check("01001", 2022, figures(tt.figures))
+
+			violations := validator.Violations()
+			rules := make([]string, len(violations))
+			for i, violation := range violations {
+				rules[i] = violation.Rule
+			}
+			if strings.Join(rules, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("Expected violations %v, got %v", tt.expected, rules)
+			}
+		})
+	}
+}
+
+func TestValidator_Finish(t *testing.T) {
+	reportPath := filepath.Join(t.TempDir(), "report.csv")
+	validator := NewValidator(PopulationRules(populationColumns()), DefaultTolerance, WithReport(reportPath), WithMaxViolationRatio(0.4))
+
+	validator.Check("01001", 2022, figures(map[string]float64{"pop": 1000, "pop_h": 490, "pop_f": 510}))
+	validator.Check("01002", 2022, figures(map[string]float64{"pop": 1000, "pop_h": 400, "pop_f": 510}))
+	if err := validator.Finish("commune"); err == nil {
+		t.Error("Expected error for 50% of violating records, got nil")
+	}
+
+	report, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("Failed to read report: %v", err)
+	}
+	expected := "code;annee;regle;attendu;obtenu;ecart\n01002;2022;pop_h + pop_f = pop;1000;910;-90\n"
+	if string(report) != expected {
+		t.Errorf("Expected report:\n%s\ngot:\n%s", expected, report)
+	}
+
+	validator.Reset()
+	validator.Check("01001", 2022, figures(map[string]float64{"pop": 1000, "pop_h": 490, "pop_f": 510}))
+	if err := validator.Finish("commune"); err != nil {
+		t.Errorf("Finish() error = %v after reset", err)
+	}
+}
+
+func TestValidator_Finish_NotBlocking(t *testing.T) {
+	validator := NewValidator(PopulationRules(populationColumns()), DefaultTolerance)
+	validator.Check("01002", 2022, figures(map[string]float64{"pop": 1000, "pop_h": 400, "pop_f": 510}))
+
+	if err := validator.Finish("commune"); err != nil {
+		t.Errorf("Expected no error without maximum ratio, got %v", err)
+	}
+}
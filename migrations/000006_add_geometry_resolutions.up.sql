@@ -0,0 +1,46 @@
+-- Geometries by resolution, side by side with the default geom column
+-- (5m for precise spatial joins, 100m and 1000m for web maps)
+ALTER TABLE ref_admin.regions
+	ADD COLUMN geom_5m geography(multipolygon, 4326) NULL,
+	ADD COLUMN geom_100m geography(multipolygon, 4326) NULL,
+	ADD COLUMN geom_1000m geography(multipolygon, 4326) NULL;
+CREATE INDEX idx_regions_geom_5m ON ref_admin.regions USING gist (geom_5m);
+CREATE INDEX idx_regions_geom_100m ON ref_admin.regions USING gist (geom_100m);
+CREATE INDEX idx_regions_geom_1000m ON ref_admin.regions USING gist (geom_1000m);
+
+ALTER TABLE ref_admin.departements
+	ADD COLUMN geom_5m geography(multipolygon, 4326) NULL,
+	ADD COLUMN geom_100m geography(multipolygon, 4326) NULL,
+	ADD COLUMN geom_1000m geography(multipolygon, 4326) NULL;
+CREATE INDEX idx_departements_geom_5m ON ref_admin.departements USING gist (geom_5m);
+CREATE INDEX idx_departements_geom_100m ON ref_admin.departements USING gist (geom_100m);
+CREATE INDEX idx_departements_geom_1000m ON ref_admin.departements USING gist (geom_1000m);
+
+ALTER TABLE ref_admin.epci
+	ADD COLUMN geom_5m geography(multipolygon, 4326) NULL,
+	ADD COLUMN geom_100m geography(multipolygon, 4326) NULL,
+	ADD COLUMN geom_1000m geography(multipolygon, 4326) NULL;
+CREATE INDEX idx_epci_geom_5m ON ref_admin.epci USING gist (geom_5m);
+CREATE INDEX idx_epci_geom_100m ON ref_admin.epci USING gist (geom_100m);
+CREATE INDEX idx_epci_geom_1000m ON ref_admin.epci USING gist (geom_1000m);
+
+ALTER TABLE ref_admin.communes
+	ADD COLUMN geom_5m geography(multipolygon, 4326) NULL,
+	ADD COLUMN geom_100m geography(multipolygon, 4326) NULL,
+	ADD COLUMN geom_1000m geography(multipolygon, 4326) NULL;
+CREATE INDEX idx_communes_geom_5m ON ref_admin.communes USING spgist (geom_5m);
+CREATE INDEX idx_communes_geom_100m ON ref_admin.communes USING spgist (geom_100m);
+CREATE INDEX idx_communes_geom_1000m ON ref_admin.communes USING spgist (geom_1000m);
+
+COMMENT ON COLUMN ref_admin.regions.geom_5m IS 'contour de la région à 5 m de précision';
+COMMENT ON COLUMN ref_admin.regions.geom_100m IS 'contour de la région à 100 m de précision';
+COMMENT ON COLUMN ref_admin.regions.geom_1000m IS 'contour de la région à 1000 m de précision';
+COMMENT ON COLUMN ref_admin.departements.geom_5m IS 'contour du département à 5 m de précision';
+COMMENT ON COLUMN ref_admin.departements.geom_100m IS 'contour du département à 100 m de précision';
+COMMENT ON COLUMN ref_admin.departements.geom_1000m IS 'contour du département à 1000 m de précision';
+COMMENT ON COLUMN ref_admin.epci.geom_5m IS 'contour de l''EPCI à 5 m de précision';
+COMMENT ON COLUMN ref_admin.epci.geom_100m IS 'contour de l''EPCI à 100 m de précision';
+COMMENT ON COLUMN ref_admin.epci.geom_1000m IS 'contour de l''EPCI à 1000 m de précision';
+COMMENT ON COLUMN ref_admin.communes.geom_5m IS 'contour de la commune à 5 m de précision';
+COMMENT ON COLUMN ref_admin.communes.geom_100m IS 'contour de la commune à 100 m de précision';
+COMMENT ON COLUMN ref_admin.communes.geom_1000m IS 'contour de la commune à 1000 m de précision';
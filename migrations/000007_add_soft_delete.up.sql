@@ -0,0 +1,11 @@
+-- Soft-delete of the entities absent from the source of a sync run
+-- (e.g. communes merged into a commune nouvelle), reset when an entity is loaded again
+ALTER TABLE ref_admin.regions ADD COLUMN supprime_le timestamptz NULL;
+ALTER TABLE ref_admin.departements ADD COLUMN supprime_le timestamptz NULL;
+ALTER TABLE ref_admin.epci ADD COLUMN supprime_le timestamptz NULL;
+ALTER TABLE ref_admin.communes ADD COLUMN supprime_le timestamptz NULL;
+
+COMMENT ON COLUMN ref_admin.regions.supprime_le IS 'date de suppression de la région, absente de la dernière source chargée';
+COMMENT ON COLUMN ref_admin.departements.supprime_le IS 'date de suppression du département, absent de la dernière source chargée';
+COMMENT ON COLUMN ref_admin.epci.supprime_le IS 'date de suppression de l''EPCI, absent de la dernière source chargée';
+COMMENT ON COLUMN ref_admin.communes.supprime_le IS 'date de suppression de la commune, absente de la dernière source chargée';
@@ -0,0 +1,62 @@
+-- Millésime : année de la géographie administrative (au 1er janvier) décrite par chaque ligne,
+-- pour charger plusieurs millésimes côte à côte. Les données existantes proviennent des contours 2024.
+
+-- Foreign keys are recreated on (code, millesime)
+ALTER TABLE demography.population_commune DROP CONSTRAINT population_commune_code_insee_commune_fkey;
+ALTER TABLE ref_admin.communes DROP CONSTRAINT communes_code_insee_epci_fkey;
+ALTER TABLE ref_admin.communes DROP CONSTRAINT communes_code_insee_departement_fkey;
+ALTER TABLE ref_admin.communes DROP CONSTRAINT communes_code_insee_region_fkey;
+ALTER TABLE ref_admin.departements DROP CONSTRAINT departements_code_insee_region_fkey;
+
+ALTER TABLE ref_admin.regions
+	ADD COLUMN millesime smallint NOT NULL DEFAULT 2024 CHECK (millesime >= 1900 AND millesime <= 2100);
+ALTER TABLE ref_admin.regions ALTER COLUMN millesime DROP DEFAULT;
+ALTER TABLE ref_admin.regions DROP CONSTRAINT regions_code_insee_region_key;
+ALTER TABLE ref_admin.regions ADD CONSTRAINT regions_code_insee_region_millesime_key UNIQUE (code_insee_region, millesime);
+CREATE INDEX idx_regions_millesime ON ref_admin.regions (millesime);
+
+ALTER TABLE ref_admin.departements
+	ADD COLUMN millesime smallint NOT NULL DEFAULT 2024 CHECK (millesime >= 1900 AND millesime <= 2100);
+ALTER TABLE ref_admin.departements ALTER COLUMN millesime DROP DEFAULT;
+ALTER TABLE ref_admin.departements DROP CONSTRAINT departements_code_insee_departement_key;
+ALTER TABLE ref_admin.departements ADD CONSTRAINT departements_code_insee_departement_millesime_key UNIQUE (code_insee_departement, millesime);
+CREATE INDEX idx_departements_millesime ON ref_admin.departements (millesime);
+
+ALTER TABLE ref_admin.epci
+	ADD COLUMN millesime smallint NOT NULL DEFAULT 2024 CHECK (millesime >= 1900 AND millesime <= 2100);
+ALTER TABLE ref_admin.epci ALTER COLUMN millesime DROP DEFAULT;
+ALTER TABLE ref_admin.epci DROP CONSTRAINT epci_code_insee_epci_key;
+ALTER TABLE ref_admin.epci ADD CONSTRAINT epci_code_insee_epci_millesime_key UNIQUE (code_insee_epci, millesime);
+CREATE INDEX idx_epci_millesime ON ref_admin.epci (millesime);
+
+ALTER TABLE ref_admin.communes
+	ADD COLUMN millesime smallint NOT NULL DEFAULT 2024 CHECK (millesime >= 1900 AND millesime <= 2100);
+ALTER TABLE ref_admin.communes ALTER COLUMN millesime DROP DEFAULT;
+ALTER TABLE ref_admin.communes DROP CONSTRAINT communes_code_insee_commune_key;
+ALTER TABLE ref_admin.communes ADD CONSTRAINT communes_code_insee_commune_millesime_key UNIQUE (code_insee_commune, millesime);
+CREATE INDEX idx_communes_millesime ON ref_admin.communes (millesime);
+
+-- Population figures refer to the geography of a vintage (e.g. RP 2022 on the 2024 geography)
+ALTER TABLE demography.population_commune
+	ADD COLUMN millesime smallint NOT NULL DEFAULT 2024 CHECK (millesime >= 1900 AND millesime <= 2100);
+ALTER TABLE demography.population_commune ALTER COLUMN millesime DROP DEFAULT;
+ALTER TABLE demography.population_commune DROP CONSTRAINT population_commune_pkey;
+ALTER TABLE demography.population_commune ADD CONSTRAINT population_commune_pkey PRIMARY KEY (code_insee_commune, annee, millesime);
+CREATE INDEX idx_population_commune_millesime ON demography.population_commune (millesime);
+
+ALTER TABLE ref_admin.departements ADD CONSTRAINT departements_code_insee_region_fkey
+	FOREIGN KEY (code_insee_region, millesime) REFERENCES ref_admin.regions(code_insee_region, millesime);
+ALTER TABLE ref_admin.communes ADD CONSTRAINT communes_code_insee_epci_fkey
+	FOREIGN KEY (code_insee_epci, millesime) REFERENCES ref_admin.epci(code_insee_epci, millesime);
+ALTER TABLE ref_admin.communes ADD CONSTRAINT communes_code_insee_departement_fkey
+	FOREIGN KEY (code_insee_departement, millesime) REFERENCES ref_admin.departements(code_insee_departement, millesime);
+ALTER TABLE ref_admin.communes ADD CONSTRAINT communes_code_insee_region_fkey
+	FOREIGN KEY (code_insee_region, millesime) REFERENCES ref_admin.regions(code_insee_region, millesime);
+ALTER TABLE demography.population_commune ADD CONSTRAINT population_commune_code_insee_commune_fkey
+	FOREIGN KEY (code_insee_commune, millesime) REFERENCES ref_admin.communes(code_insee_commune, millesime);
+
+COMMENT ON COLUMN ref_admin.regions.millesime IS 'millésime de la géographie administrative (année au 1er janvier)';
+COMMENT ON COLUMN ref_admin.departements.millesime IS 'millésime de la géographie administrative (année au 1er janvier)';
+COMMENT ON COLUMN ref_admin.epci.millesime IS 'millésime de la géographie administrative (année au 1er janvier)';
+COMMENT ON COLUMN ref_admin.communes.millesime IS 'millésime de la géographie administrative (année au 1er janvier)';
+COMMENT ON COLUMN demography.population_commune.millesime IS 'millésime de la géographie des communes à laquelle se rapportent les chiffres';
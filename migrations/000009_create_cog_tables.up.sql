@@ -0,0 +1,128 @@
+-- Tables of the INSEE Code Officiel Géographique (COG), one row per entity and millésime.
+-- The codes of parents are kept as published, without foreign keys: each file is loaded on its own.
+
+-- ref_admin.cog_regions definition
+CREATE TABLE ref_admin.cog_regions (
+	code_insee_region varchar(3) NOT NULL,
+	code_insee_cheflieu varchar(5) NOT NULL,
+	tncc smallint NOT NULL CHECK (tncc >= 0 AND tncc <= 8),
+	ncc varchar(200) NOT NULL,
+	nccenr varchar(200) NOT NULL,
+	libelle varchar(200) NOT NULL,
+	millesime smallint NOT NULL CHECK (millesime >= 1900 AND millesime <= 2100),
+	supprime_le timestamptz NULL,
+	CONSTRAINT cog_regions_pkey PRIMARY KEY (code_insee_region, millesime)
+);
+CREATE INDEX idx_cog_regions_millesime ON ref_admin.cog_regions (millesime);
+
+COMMENT ON TABLE ref_admin.cog_regions IS 'régions du Code officiel géographique (v_region)';
+
+-- ref_admin.cog_departements definition
+CREATE TABLE ref_admin.cog_departements (
+	code_insee_departement varchar(3) NOT NULL,
+	code_insee_region varchar(3) NOT NULL,
+	code_insee_cheflieu varchar(5) NOT NULL,
+	tncc smallint NOT NULL CHECK (tncc >= 0 AND tncc <= 8),
+	ncc varchar(200) NOT NULL,
+	nccenr varchar(200) NOT NULL,
+	libelle varchar(200) NOT NULL,
+	millesime smallint NOT NULL CHECK (millesime >= 1900 AND millesime <= 2100),
+	supprime_le timestamptz NULL,
+	CONSTRAINT cog_departements_pkey PRIMARY KEY (code_insee_departement, millesime)
+);
+CREATE INDEX idx_cog_departements_millesime ON ref_admin.cog_departements (millesime);
+CREATE INDEX idx_cog_departements_region ON ref_admin.cog_departements (code_insee_region, millesime);
+
+COMMENT ON TABLE ref_admin.cog_departements IS 'départements du Code officiel géographique (v_departement)';
+
+-- ref_admin.cog_arrondissements definition
+CREATE TABLE ref_admin.cog_arrondissements (
+	code_insee_arrondissement varchar(4) NOT NULL,
+	code_insee_departement varchar(3) NOT NULL,
+	code_insee_region varchar(3) NOT NULL,
+	code_insee_cheflieu varchar(5) NOT NULL,
+	tncc smallint NOT NULL CHECK (tncc >= 0 AND tncc <= 8),
+	ncc varchar(200) NOT NULL,
+	nccenr varchar(200) NOT NULL,
+	libelle varchar(200) NOT NULL,
+	millesime smallint NOT NULL CHECK (millesime >= 1900 AND millesime <= 2100),
+	supprime_le timestamptz NULL,
+	CONSTRAINT cog_arrondissements_pkey PRIMARY KEY (code_insee_arrondissement, millesime)
+);
+CREATE INDEX idx_cog_arrondissements_millesime ON ref_admin.cog_arrondissements (millesime);
+CREATE INDEX idx_cog_arrondissements_departement ON ref_admin.cog_arrondissements (code_insee_departement, millesime);
+
+COMMENT ON TABLE ref_admin.cog_arrondissements IS 'arrondissements départementaux du Code officiel géographique (v_arrondissement)';
+
+-- ref_admin.cog_cantons definition
+CREATE TABLE ref_admin.cog_cantons (
+	code_insee_canton varchar(5) NOT NULL,
+	code_insee_departement varchar(3) NOT NULL,
+	code_insee_region varchar(3) NOT NULL,
+	composition_cantonale varchar(1) NULL,
+	code_insee_bureau_centralisateur varchar(5) NULL,
+	tncc smallint NOT NULL CHECK (tncc >= 0 AND tncc <= 8),
+	ncc varchar(200) NOT NULL,
+	nccenr varchar(200) NOT NULL,
+	libelle varchar(200) NOT NULL,
+	type_canton varchar(1) NOT NULL,
+	millesime smallint NOT NULL CHECK (millesime >= 1900 AND millesime <= 2100),
+	supprime_le timestamptz NULL,
+	CONSTRAINT cog_cantons_pkey PRIMARY KEY (code_insee_canton, millesime)
+);
+CREATE INDEX idx_cog_cantons_millesime ON ref_admin.cog_cantons (millesime);
+CREATE INDEX idx_cog_cantons_departement ON ref_admin.cog_cantons (code_insee_departement, millesime);
+
+COMMENT ON TABLE ref_admin.cog_cantons IS 'cantons et pseudo-cantons du Code officiel géographique (v_canton)';
+COMMENT ON COLUMN ref_admin.cog_cantons.composition_cantonale IS 'COMPCT : 1 canton composé de communes entières, 2 canton fractionnant une commune, 3 pseudo-canton';
+COMMENT ON COLUMN ref_admin.cog_cantons.type_canton IS 'TYPECT : C canton, V canton-ville, N hors canton';
+
+-- ref_admin.cog_communes definition
+CREATE TABLE ref_admin.cog_communes (
+	type_commune varchar(4) NOT NULL CHECK (type_commune IN ('COM', 'COMA', 'COMD', 'ARM')),
+	code_insee_commune varchar(5) NOT NULL,
+	code_insee_region varchar(3) NULL,
+	code_insee_departement varchar(3) NULL,
+	code_ctcd varchar(4) NULL,
+	code_insee_arrondissement varchar(4) NULL,
+	tncc smallint NOT NULL CHECK (tncc >= 0 AND tncc <= 8),
+	ncc varchar(200) NOT NULL,
+	nccenr varchar(200) NOT NULL,
+	libelle varchar(200) NOT NULL,
+	code_insee_canton varchar(5) NULL,
+	code_insee_commune_parente varchar(5) NULL,
+	millesime smallint NOT NULL CHECK (millesime >= 1900 AND millesime <= 2100),
+	supprime_le timestamptz NULL,
+	CONSTRAINT cog_communes_pkey PRIMARY KEY (type_commune, code_insee_commune, millesime)
+);
+CREATE INDEX idx_cog_communes_millesime ON ref_admin.cog_communes (millesime);
+CREATE INDEX idx_cog_communes_code ON ref_admin.cog_communes (code_insee_commune, millesime);
+CREATE INDEX idx_cog_communes_parente ON ref_admin.cog_communes (code_insee_commune_parente, millesime);
+CREATE INDEX idx_cog_communes_libelle_trgm ON ref_admin.cog_communes USING gin (libelle gin_trgm_ops);
+
+COMMENT ON TABLE ref_admin.cog_communes IS 'communes, communes associées, communes déléguées et arrondissements municipaux du Code officiel géographique (v_commune)';
+COMMENT ON COLUMN ref_admin.cog_communes.type_commune IS 'TYPECOM : COM commune, COMA commune associée, COMD commune déléguée, ARM arrondissement municipal';
+COMMENT ON COLUMN ref_admin.cog_communes.code_ctcd IS 'code de la collectivité territoriale ayant les compétences départementales';
+COMMENT ON COLUMN ref_admin.cog_communes.code_insee_commune_parente IS 'commune de rattachement des communes associées, déléguées et des arrondissements municipaux';
+
+-- ref_admin.cog_comer definition
+CREATE TABLE ref_admin.cog_comer (
+	code_comer varchar(5) NOT NULL,
+	tncc smallint NOT NULL CHECK (tncc >= 0 AND tncc <= 8),
+	ncc varchar(200) NOT NULL,
+	nccenr varchar(200) NOT NULL,
+	libelle varchar(200) NOT NULL,
+	nature_zonage varchar(3) NOT NULL,
+	code_collectivite varchar(3) NOT NULL,
+	millesime smallint NOT NULL CHECK (millesime >= 1900 AND millesime <= 2100),
+	supprime_le timestamptz NULL,
+	CONSTRAINT cog_comer_pkey PRIMARY KEY (code_comer, millesime)
+);
+CREATE INDEX idx_cog_comer_millesime ON ref_admin.cog_comer (millesime);
+
+COMMENT ON TABLE ref_admin.cog_comer IS 'communes et zonages des collectivités d''outre-mer du Code officiel géographique (v_comer)';
+
+COMMENT ON COLUMN ref_admin.cog_communes.tncc IS 'type de nom en clair : article et charnière du nom';
+COMMENT ON COLUMN ref_admin.cog_communes.ncc IS 'nom en clair, en majuscules';
+COMMENT ON COLUMN ref_admin.cog_communes.nccenr IS 'nom en clair, typographie riche';
+COMMENT ON COLUMN ref_admin.cog_communes.libelle IS 'nom en clair, typographie riche, avec article';
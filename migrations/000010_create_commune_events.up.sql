@@ -0,0 +1,26 @@
+-- ref_admin.commune_events definition
+-- Mouvements des communes du Code officiel géographique (v_mvt_commune) : une ligne par commune avant et après
+-- chaque événement (fusion, scission, changement de nom ou de code), depuis 1943.
+CREATE TABLE ref_admin.commune_events (
+	type_evenement smallint NOT NULL,
+	date_effet date NOT NULL,
+	type_commune_avant varchar(4) NOT NULL CHECK (type_commune_avant IN ('COM', 'COMA', 'COMD', 'ARM')),
+	code_insee_commune_avant varchar(5) NOT NULL,
+	tncc_avant smallint NOT NULL CHECK (tncc_avant >= 0 AND tncc_avant <= 8),
+	ncc_avant varchar(200) NOT NULL,
+	nccenr_avant varchar(200) NOT NULL,
+	libelle_avant varchar(200) NOT NULL,
+	type_commune_apres varchar(4) NOT NULL CHECK (type_commune_apres IN ('COM', 'COMA', 'COMD', 'ARM')),
+	code_insee_commune_apres varchar(5) NOT NULL,
+	tncc_apres smallint NOT NULL CHECK (tncc_apres >= 0 AND tncc_apres <= 8),
+	ncc_apres varchar(200) NOT NULL,
+	nccenr_apres varchar(200) NOT NULL,
+	libelle_apres varchar(200) NOT NULL,
+	CONSTRAINT commune_events_pkey PRIMARY KEY (type_evenement, date_effet, type_commune_avant, code_insee_commune_avant, type_commune_apres, code_insee_commune_apres)
+);
+CREATE INDEX idx_commune_events_avant ON ref_admin.commune_events (code_insee_commune_avant, date_effet);
+CREATE INDEX idx_commune_events_apres ON ref_admin.commune_events (code_insee_commune_apres, date_effet);
+
+COMMENT ON TABLE ref_admin.commune_events IS 'mouvements des communes du Code officiel géographique (v_mvt_commune)';
+COMMENT ON COLUMN ref_admin.commune_events.type_evenement IS 'MOD : 10 changement de nom, 20 création, 21 rétablissement, 30 suppression, 31 fusion simple, 32 création de commune nouvelle, 33 fusion association, 34 transformation de fusion association en fusion simple, 35 suppression de commune déléguée, 41 changement de code dû à un changement de département, 50 changement de code dû à un transfert de chef-lieu, 70 transformation de commune associée en commune déléguée, 71 rétablissement de commune déléguée';
+COMMENT ON COLUMN ref_admin.commune_events.date_effet IS 'date d''effet de l''événement';
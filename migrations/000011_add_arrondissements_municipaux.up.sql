@@ -0,0 +1,81 @@
+-- ref_admin.arrondissements_municipaux definition
+-- Arrondissements municipaux de Paris, Lyon et Marseille, rattachés à leur commune
+CREATE TABLE ref_admin.arrondissements_municipaux (
+	gid serial4 NOT NULL,
+	code_insee_arm varchar(5) NOT NULL,
+	nom_arm varchar(100) NOT NULL,
+	code_insee_commune varchar(5) NULL,
+	millesime smallint NOT NULL CHECK (millesime >= 1900 AND millesime <= 2100),
+	supprime_le timestamptz NULL,
+	geom geography(multipolygon, 4326) NULL,
+	geom_5m geography(multipolygon, 4326) NULL,
+	geom_100m geography(multipolygon, 4326) NULL,
+	geom_1000m geography(multipolygon, 4326) NULL,
+	CONSTRAINT arrondissements_municipaux_code_insee_arm_millesime_key UNIQUE (code_insee_arm, millesime),
+	CONSTRAINT arrondissements_municipaux_pkey PRIMARY KEY (gid)
+);
+CREATE INDEX idx_arrondissements_municipaux_geom ON ref_admin.arrondissements_municipaux USING gist (geom);
+CREATE INDEX idx_arrondissements_municipaux_geom_5m ON ref_admin.arrondissements_municipaux USING gist (geom_5m);
+CREATE INDEX idx_arrondissements_municipaux_geom_100m ON ref_admin.arrondissements_municipaux USING gist (geom_100m);
+CREATE INDEX idx_arrondissements_municipaux_geom_1000m ON ref_admin.arrondissements_municipaux USING gist (geom_1000m);
+CREATE INDEX idx_arrondissements_municipaux_millesime ON ref_admin.arrondissements_municipaux (millesime);
+ALTER TABLE ref_admin.arrondissements_municipaux ADD CONSTRAINT arrondissements_municipaux_code_insee_commune_fkey
+	FOREIGN KEY (code_insee_commune, millesime) REFERENCES ref_admin.communes(code_insee_commune, millesime);
+
+COMMENT ON TABLE ref_admin.arrondissements_municipaux IS 'table des arrondissements municipaux de Paris, Lyon et Marseille';
+COMMENT ON COLUMN ref_admin.arrondissements_municipaux.nom_arm IS 'toponyme de l''arrondissement municipal';
+COMMENT ON COLUMN ref_admin.arrondissements_municipaux.code_insee_commune IS 'code INSEE de la commune de rattachement';
+COMMENT ON COLUMN ref_admin.arrondissements_municipaux.millesime IS 'millésime de la géographie administrative (année au 1er janvier)';
+COMMENT ON COLUMN ref_admin.arrondissements_municipaux.supprime_le IS 'date de suppression de l''arrondissement municipal, absent de la dernière source chargée';
+
+-- demography.population_arrondissement_municipal definition
+-- Mêmes colonnes que demography.population_commune, par arrondissement municipal
+CREATE TABLE demography.population_arrondissement_municipal (
+	code_insee_arm varchar(5) NOT NULL,
+	annee smallint NOT NULL CHECK (annee >= 1900 AND annee <= 2100),
+	pop int4 NULL CHECK (pop >= 0),
+	pop_h int4 NULL CHECK (pop_h >= 0),
+	pop_f int4 NULL CHECK (pop_f >= 0),
+	pop_LT15 int4 NULL CHECK (pop_LT15 >= 0),
+	pop_LT15_h int4 NULL CHECK (pop_LT15_h >= 0),
+	pop_LT15_f int4 NULL CHECK (pop_LT15_f >= 0),
+	pop_LT20 int4 NULL CHECK (pop_LT20 >= 0),
+	pop_LT20_h int4 NULL CHECK (pop_LT20_h >= 0),
+	pop_LT20_f int4 NULL CHECK (pop_LT20_f >= 0),
+	pop_15T24 int4 NULL CHECK (pop_15T24 >= 0),
+	pop_15T24_h int4 NULL CHECK (pop_15T24_h >= 0),
+	pop_15T24_f int4 NULL CHECK (pop_15T24_f >= 0),
+	pop_20T64 int4 NULL CHECK (pop_20T64 >= 0),
+	pop_20T64_h int4 NULL CHECK (pop_20T64_h >= 0),
+	pop_20T64_f int4 NULL CHECK (pop_20T64_f >= 0),
+	pop_25T39 int4 NULL CHECK (pop_25T39 >= 0),
+	pop_25T39_h int4 NULL CHECK (pop_25T39_h >= 0),
+	pop_25T39_f int4 NULL CHECK (pop_25T39_f >= 0),
+	pop_40T54 int4 NULL CHECK (pop_40T54 >= 0),
+	pop_40T54_h int4 NULL CHECK (pop_40T54_h >= 0),
+	pop_40T54_f int4 NULL CHECK (pop_40T54_f >= 0),
+	pop_55T64 int4 NULL CHECK (pop_55T64 >= 0),
+	pop_55T64_h int4 NULL CHECK (pop_55T64_h >= 0),
+	pop_55T64_f int4 NULL CHECK (pop_55T64_f >= 0),
+	pop_65T79 int4 NULL CHECK (pop_65T79 >= 0),
+	pop_65T79_h int4 NULL CHECK (pop_65T79_h >= 0),
+	pop_65T79_f int4 NULL CHECK (pop_65T79_f >= 0),
+	pop_GE65 int4 NULL CHECK (pop_GE65 >= 0),
+	pop_GE65_h int4 NULL CHECK (pop_GE65_h >= 0),
+	pop_GE65_f int4 NULL CHECK (pop_GE65_f >= 0),
+	pop_GE80 int4 NULL CHECK (pop_GE80 >= 0),
+	pop_GE80_h int4 NULL CHECK (pop_GE80_h >= 0),
+	pop_GE80_f int4 NULL CHECK (pop_GE80_f >= 0),
+	millesime smallint NOT NULL CHECK (millesime >= 1900 AND millesime <= 2100),
+
+	CONSTRAINT population_arrondissement_municipal_pkey PRIMARY KEY (code_insee_arm, annee, millesime)
+);
+
+CREATE INDEX idx_population_arrondissement_municipal_annee ON demography.population_arrondissement_municipal (annee);
+CREATE INDEX idx_population_arrondissement_municipal_millesime ON demography.population_arrondissement_municipal (millesime);
+ALTER TABLE demography.population_arrondissement_municipal ADD CONSTRAINT population_arrondissement_municipal_code_insee_arm_fkey
+	FOREIGN KEY (code_insee_arm, millesime) REFERENCES ref_admin.arrondissements_municipaux(code_insee_arm, millesime);
+
+COMMENT ON TABLE demography.population_arrondissement_municipal IS 'table de la population par arrondissement municipal et par année, agrégée dans demography.population_commune pour la commune de rattachement';
+COMMENT ON COLUMN demography.population_arrondissement_municipal.code_insee_arm IS 'code INSEE de l''arrondissement municipal';
+COMMENT ON COLUMN demography.population_arrondissement_municipal.millesime IS 'millésime de la géographie des arrondissements municipaux à laquelle se rapportent les chiffres';
@@ -0,0 +1,66 @@
+-- Niveaux intermédiaires entre la commune et le département, contours IGN ADMIN EXPRESS.
+-- Les communes d'un arrondissement ou d'un canton sont décrites par ref_admin.cog_communes.
+
+-- ref_admin.arrondissements definition
+CREATE TABLE ref_admin.arrondissements (
+	gid serial4 NOT NULL,
+	code_insee_arrondissement varchar(4) NOT NULL,
+	nom_arrondissement varchar(100) NOT NULL,
+	code_insee_departement varchar(3) NULL,
+	code_insee_region varchar(3) NULL,
+	millesime smallint NOT NULL CHECK (millesime >= 1900 AND millesime <= 2100),
+	supprime_le timestamptz NULL,
+	geom geography(multipolygon, 4326) NULL,
+	geom_5m geography(multipolygon, 4326) NULL,
+	geom_100m geography(multipolygon, 4326) NULL,
+	geom_1000m geography(multipolygon, 4326) NULL,
+	CONSTRAINT arrondissements_code_insee_arrondissement_millesime_key UNIQUE (code_insee_arrondissement, millesime),
+	CONSTRAINT arrondissements_pkey PRIMARY KEY (gid)
+);
+CREATE INDEX idx_arrondissements_geom ON ref_admin.arrondissements USING gist (geom);
+CREATE INDEX idx_arrondissements_geom_5m ON ref_admin.arrondissements USING gist (geom_5m);
+CREATE INDEX idx_arrondissements_geom_100m ON ref_admin.arrondissements USING gist (geom_100m);
+CREATE INDEX idx_arrondissements_geom_1000m ON ref_admin.arrondissements USING gist (geom_1000m);
+CREATE INDEX idx_arrondissements_nom_trgm ON ref_admin.arrondissements USING gin (nom_arrondissement gin_trgm_ops);
+CREATE INDEX idx_arrondissements_millesime ON ref_admin.arrondissements (millesime);
+ALTER TABLE ref_admin.arrondissements ADD CONSTRAINT arrondissements_code_insee_departement_fkey
+	FOREIGN KEY (code_insee_departement, millesime) REFERENCES ref_admin.departements(code_insee_departement, millesime);
+ALTER TABLE ref_admin.arrondissements ADD CONSTRAINT arrondissements_code_insee_region_fkey
+	FOREIGN KEY (code_insee_region, millesime) REFERENCES ref_admin.regions(code_insee_region, millesime);
+
+COMMENT ON TABLE ref_admin.arrondissements IS 'table des arrondissements départementaux de France';
+COMMENT ON COLUMN ref_admin.arrondissements.nom_arrondissement IS 'toponyme de l''arrondissement';
+COMMENT ON COLUMN ref_admin.arrondissements.millesime IS 'millésime de la géographie administrative (année au 1er janvier)';
+COMMENT ON COLUMN ref_admin.arrondissements.supprime_le IS 'date de suppression de l''arrondissement, absent de la dernière source chargée';
+
+-- ref_admin.cantons definition
+CREATE TABLE ref_admin.cantons (
+	gid serial4 NOT NULL,
+	code_insee_canton varchar(5) NOT NULL,
+	nom_canton varchar(100) NULL,
+	code_insee_departement varchar(3) NULL,
+	code_insee_region varchar(3) NULL,
+	millesime smallint NOT NULL CHECK (millesime >= 1900 AND millesime <= 2100),
+	supprime_le timestamptz NULL,
+	geom geography(multipolygon, 4326) NULL,
+	geom_5m geography(multipolygon, 4326) NULL,
+	geom_100m geography(multipolygon, 4326) NULL,
+	geom_1000m geography(multipolygon, 4326) NULL,
+	CONSTRAINT cantons_code_insee_canton_millesime_key UNIQUE (code_insee_canton, millesime),
+	CONSTRAINT cantons_pkey PRIMARY KEY (gid)
+);
+CREATE INDEX idx_cantons_geom ON ref_admin.cantons USING gist (geom);
+CREATE INDEX idx_cantons_geom_5m ON ref_admin.cantons USING gist (geom_5m);
+CREATE INDEX idx_cantons_geom_100m ON ref_admin.cantons USING gist (geom_100m);
+CREATE INDEX idx_cantons_geom_1000m ON ref_admin.cantons USING gist (geom_1000m);
+CREATE INDEX idx_cantons_nom_trgm ON ref_admin.cantons USING gin (nom_canton gin_trgm_ops);
+CREATE INDEX idx_cantons_millesime ON ref_admin.cantons (millesime);
+ALTER TABLE ref_admin.cantons ADD CONSTRAINT cantons_code_insee_departement_fkey
+	FOREIGN KEY (code_insee_departement, millesime) REFERENCES ref_admin.departements(code_insee_departement, millesime);
+ALTER TABLE ref_admin.cantons ADD CONSTRAINT cantons_code_insee_region_fkey
+	FOREIGN KEY (code_insee_region, millesime) REFERENCES ref_admin.regions(code_insee_region, millesime);
+
+COMMENT ON TABLE ref_admin.cantons IS 'table des cantons de France (les contours IGN n''ont pas de toponyme, voir ref_admin.cog_cantons)';
+COMMENT ON COLUMN ref_admin.cantons.nom_canton IS 'toponyme du canton';
+COMMENT ON COLUMN ref_admin.cantons.millesime IS 'millésime de la géographie administrative (année au 1er janvier)';
+COMMENT ON COLUMN ref_admin.cantons.supprime_le IS 'date de suppression du canton, absent de la dernière source chargée';
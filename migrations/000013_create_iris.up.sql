@@ -0,0 +1,90 @@
+-- ref_admin.iris definition
+-- IRIS (Ilots Regroupés pour l'Information Statistique) : découpage infra-communal de l'INSEE, contours IGN CONTOURS-IRIS
+CREATE TABLE ref_admin.iris (
+	gid serial4 NOT NULL,
+	code_iris varchar(9) NOT NULL,
+	nom_iris varchar(200) NOT NULL,
+	type_iris varchar(1) NOT NULL CHECK (type_iris IN ('H', 'A', 'D', 'Z')),
+	code_insee_commune varchar(5) NULL,
+	code_insee_arm varchar(5) NULL,
+	millesime smallint NOT NULL CHECK (millesime >= 1900 AND millesime <= 2100),
+	supprime_le timestamptz NULL,
+	geom geography(multipolygon, 4326) NULL,
+	geom_5m geography(multipolygon, 4326) NULL,
+	geom_100m geography(multipolygon, 4326) NULL,
+	geom_1000m geography(multipolygon, 4326) NULL,
+	CONSTRAINT iris_code_iris_millesime_key UNIQUE (code_iris, millesime),
+	CONSTRAINT iris_pkey PRIMARY KEY (gid)
+);
+CREATE INDEX idx_iris_geom ON ref_admin.iris USING gist (geom);
+CREATE INDEX idx_iris_geom_5m ON ref_admin.iris USING gist (geom_5m);
+CREATE INDEX idx_iris_geom_100m ON ref_admin.iris USING gist (geom_100m);
+CREATE INDEX idx_iris_geom_1000m ON ref_admin.iris USING gist (geom_1000m);
+CREATE INDEX idx_iris_nom_trgm ON ref_admin.iris USING gin (nom_iris gin_trgm_ops);
+CREATE INDEX idx_iris_millesime ON ref_admin.iris (millesime);
+CREATE INDEX idx_iris_commune ON ref_admin.iris (code_insee_commune, millesime);
+ALTER TABLE ref_admin.iris ADD CONSTRAINT iris_code_insee_commune_fkey
+	FOREIGN KEY (code_insee_commune, millesime) REFERENCES ref_admin.communes(code_insee_commune, millesime);
+ALTER TABLE ref_admin.iris ADD CONSTRAINT iris_code_insee_arm_fkey
+	FOREIGN KEY (code_insee_arm, millesime) REFERENCES ref_admin.arrondissements_municipaux(code_insee_arm, millesime);
+
+COMMENT ON TABLE ref_admin.iris IS 'table des IRIS (Ilots Regroupés pour l''Information Statistique), découpage infra-communal de l''INSEE';
+COMMENT ON COLUMN ref_admin.iris.nom_iris IS 'toponyme de l''IRIS';
+COMMENT ON COLUMN ref_admin.iris.type_iris IS 'TYP_IRIS : H IRIS d''habitat, A IRIS d''activité, D IRIS divers, Z commune non découpée en IRIS';
+COMMENT ON COLUMN ref_admin.iris.code_insee_commune IS 'code INSEE de la commune de l''IRIS';
+COMMENT ON COLUMN ref_admin.iris.code_insee_arm IS 'code INSEE de l''arrondissement municipal de l''IRIS, à Paris, Lyon et Marseille';
+COMMENT ON COLUMN ref_admin.iris.millesime IS 'millésime de la géographie administrative (année au 1er janvier)';
+COMMENT ON COLUMN ref_admin.iris.supprime_le IS 'date de suppression de l''IRIS, absent de la dernière source chargée';
+
+-- demography.population_iris definition
+-- Mêmes colonnes que demography.population_commune : le fichier IRIS de l'INSEE ne publie que certaines tranches d'âge
+-- par sexe, les autres colonnes restent vides
+CREATE TABLE demography.population_iris (
+	code_iris varchar(9) NOT NULL,
+	annee smallint NOT NULL CHECK (annee >= 1900 AND annee <= 2100),
+	pop int4 NULL CHECK (pop >= 0),
+	pop_h int4 NULL CHECK (pop_h >= 0),
+	pop_f int4 NULL CHECK (pop_f >= 0),
+	pop_LT15 int4 NULL CHECK (pop_LT15 >= 0),
+	pop_LT15_h int4 NULL CHECK (pop_LT15_h >= 0),
+	pop_LT15_f int4 NULL CHECK (pop_LT15_f >= 0),
+	pop_LT20 int4 NULL CHECK (pop_LT20 >= 0),
+	pop_LT20_h int4 NULL CHECK (pop_LT20_h >= 0),
+	pop_LT20_f int4 NULL CHECK (pop_LT20_f >= 0),
+	pop_15T24 int4 NULL CHECK (pop_15T24 >= 0),
+	pop_15T24_h int4 NULL CHECK (pop_15T24_h >= 0),
+	pop_15T24_f int4 NULL CHECK (pop_15T24_f >= 0),
+	pop_20T64 int4 NULL CHECK (pop_20T64 >= 0),
+	pop_20T64_h int4 NULL CHECK (pop_20T64_h >= 0),
+	pop_20T64_f int4 NULL CHECK (pop_20T64_f >= 0),
+	pop_25T39 int4 NULL CHECK (pop_25T39 >= 0),
+	pop_25T39_h int4 NULL CHECK (pop_25T39_h >= 0),
+	pop_25T39_f int4 NULL CHECK (pop_25T39_f >= 0),
+	pop_40T54 int4 NULL CHECK (pop_40T54 >= 0),
+	pop_40T54_h int4 NULL CHECK (pop_40T54_h >= 0),
+	pop_40T54_f int4 NULL CHECK (pop_40T54_f >= 0),
+	pop_55T64 int4 NULL CHECK (pop_55T64 >= 0),
+	pop_55T64_h int4 NULL CHECK (pop_55T64_h >= 0),
+	pop_55T64_f int4 NULL CHECK (pop_55T64_f >= 0),
+	pop_65T79 int4 NULL CHECK (pop_65T79 >= 0),
+	pop_65T79_h int4 NULL CHECK (pop_65T79_h >= 0),
+	pop_65T79_f int4 NULL CHECK (pop_65T79_f >= 0),
+	pop_GE65 int4 NULL CHECK (pop_GE65 >= 0),
+	pop_GE65_h int4 NULL CHECK (pop_GE65_h >= 0),
+	pop_GE65_f int4 NULL CHECK (pop_GE65_f >= 0),
+	pop_GE80 int4 NULL CHECK (pop_GE80 >= 0),
+	pop_GE80_h int4 NULL CHECK (pop_GE80_h >= 0),
+	pop_GE80_f int4 NULL CHECK (pop_GE80_f >= 0),
+	millesime smallint NOT NULL CHECK (millesime >= 1900 AND millesime <= 2100),
+
+	CONSTRAINT population_iris_pkey PRIMARY KEY (code_iris, annee, millesime)
+);
+
+CREATE INDEX idx_population_iris_annee ON demography.population_iris (annee);
+CREATE INDEX idx_population_iris_millesime ON demography.population_iris (millesime);
+ALTER TABLE demography.population_iris ADD CONSTRAINT population_iris_code_iris_fkey
+	FOREIGN KEY (code_iris, millesime) REFERENCES ref_admin.iris(code_iris, millesime);
+
+COMMENT ON TABLE demography.population_iris IS 'table de la population par IRIS et par année';
+COMMENT ON COLUMN demography.population_iris.code_iris IS 'code INSEE de l''IRIS';
+COMMENT ON COLUMN demography.population_iris.millesime IS 'millésime de la géographie des IRIS à laquelle se rapportent les chiffres';
@@ -0,0 +1,29 @@
+-- ref_admin.codes_postaux definition
+-- Base officielle des codes postaux de La Poste : correspondance plusieurs à plusieurs entre codes postaux et communes.
+-- Une commune nouvelle garde les codes postaux de ses communes déléguées, distingués par la ligne 5 de l'adresse.
+CREATE TABLE ref_admin.codes_postaux (
+	code_insee_commune varchar(5) NOT NULL,
+	code_postal varchar(5) NOT NULL CHECK (code_postal ~ '^[0-9]{5}$'),
+	ligne_5 varchar(100) NOT NULL DEFAULT '',
+	nom_commune varchar(100) NOT NULL,
+	libelle_acheminement varchar(100) NOT NULL,
+	code_insee_arm varchar(5) NULL,
+	millesime smallint NOT NULL CHECK (millesime >= 1900 AND millesime <= 2100),
+	supprime_le timestamptz NULL,
+	CONSTRAINT codes_postaux_pkey PRIMARY KEY (code_insee_commune, code_postal, ligne_5, millesime)
+);
+CREATE INDEX idx_codes_postaux_code_postal ON ref_admin.codes_postaux (code_postal, millesime);
+CREATE INDEX idx_codes_postaux_millesime ON ref_admin.codes_postaux (millesime);
+CREATE INDEX idx_codes_postaux_libelle_trgm ON ref_admin.codes_postaux USING gin (libelle_acheminement gin_trgm_ops);
+ALTER TABLE ref_admin.codes_postaux ADD CONSTRAINT codes_postaux_code_insee_commune_fkey
+	FOREIGN KEY (code_insee_commune, millesime) REFERENCES ref_admin.communes(code_insee_commune, millesime);
+ALTER TABLE ref_admin.codes_postaux ADD CONSTRAINT codes_postaux_code_insee_arm_fkey
+	FOREIGN KEY (code_insee_arm, millesime) REFERENCES ref_admin.arrondissements_municipaux(code_insee_arm, millesime);
+
+COMMENT ON TABLE ref_admin.codes_postaux IS 'correspondance entre codes postaux et communes (base officielle des codes postaux de La Poste)';
+COMMENT ON COLUMN ref_admin.codes_postaux.ligne_5 IS 'ligne 5 de l''adresse (lieu-dit, commune déléguée), vide le plus souvent';
+COMMENT ON COLUMN ref_admin.codes_postaux.nom_commune IS 'nom de la commune selon La Poste';
+COMMENT ON COLUMN ref_admin.codes_postaux.libelle_acheminement IS 'libellé d''acheminement du courrier';
+COMMENT ON COLUMN ref_admin.codes_postaux.code_insee_arm IS 'code INSEE de l''arrondissement municipal, à Paris, Lyon et Marseille';
+COMMENT ON COLUMN ref_admin.codes_postaux.millesime IS 'millésime de la géographie des communes à laquelle se rapportent les codes';
+COMMENT ON COLUMN ref_admin.codes_postaux.supprime_le IS 'date de suppression de la ligne, absente de la dernière source chargée';
@@ -0,0 +1,39 @@
+-- demography.melodi_observations definition
+-- Observations des jeux de données Melodi de l'INSEE (cubes DS_*), quelles que soient leurs dimensions :
+-- une ligne par territoire, période et combinaison de modalités des dimensions du jeu.
+CREATE TABLE demography.melodi_observations (
+	dataset varchar(100) NOT NULL,
+	objet_geo varchar(20) NOT NULL,
+	code_geo varchar(20) NOT NULL,
+	periode varchar(20) NOT NULL,
+	dimensions jsonb NOT NULL CHECK (jsonb_typeof(dimensions) = 'object'),
+	valeur numeric NULL,
+	statut varchar(10) NULL,
+	millesime smallint NOT NULL CHECK (millesime >= 1900 AND millesime <= 2100),
+	CONSTRAINT melodi_observations_pkey PRIMARY KEY (dataset, objet_geo, code_geo, periode, dimensions, millesime)
+);
+CREATE INDEX idx_melodi_observations_geo ON demography.melodi_observations (objet_geo, code_geo, millesime);
+CREATE INDEX idx_melodi_observations_dimensions ON demography.melodi_observations USING gin (dimensions jsonb_path_ops);
+
+COMMENT ON TABLE demography.melodi_observations IS 'observations des jeux de données Melodi de l''INSEE, au format long';
+COMMENT ON COLUMN demography.melodi_observations.dataset IS 'identifiant du jeu de données (DS_...)';
+COMMENT ON COLUMN demography.melodi_observations.objet_geo IS 'GEO_OBJECT : niveau géographique du territoire (COM, ARM, DEP, REG...)';
+COMMENT ON COLUMN demography.melodi_observations.code_geo IS 'GEO : code du territoire';
+COMMENT ON COLUMN demography.melodi_observations.periode IS 'TIME_PERIOD : période de l''observation';
+COMMENT ON COLUMN demography.melodi_observations.dimensions IS 'modalités des dimensions du jeu, par code de dimension';
+COMMENT ON COLUMN demography.melodi_observations.valeur IS 'OBS_VALUE : valeur de l''observation, absente si secrète ou non disponible';
+COMMENT ON COLUMN demography.melodi_observations.statut IS 'OBS_STATUS : statut de l''observation';
+COMMENT ON COLUMN demography.melodi_observations.millesime IS 'millésime de la géographie à laquelle se rapportent les chiffres';
+
+-- demography.melodi_modalites definition
+-- Libellés des dimensions et de leurs modalités, d'après le fichier de métadonnées de chaque jeu.
+CREATE TABLE demography.melodi_modalites (
+	dataset varchar(100) NOT NULL,
+	variable varchar(50) NOT NULL,
+	libelle_variable varchar(500) NOT NULL,
+	modalite varchar(50) NOT NULL,
+	libelle_modalite varchar(500) NOT NULL,
+	CONSTRAINT melodi_modalites_pkey PRIMARY KEY (dataset, variable, modalite)
+);
+
+COMMENT ON TABLE demography.melodi_modalites IS 'libellés des dimensions et des modalités des jeux de données Melodi de l''INSEE';
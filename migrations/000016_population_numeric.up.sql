@@ -0,0 +1,18 @@
+-- Chiffres de population décimaux
+-- Les populations du recensement sont des estimations pondérées (ex. 34.527109) : les colonnes de population
+-- passent en numeric pour conserver les décimales, de sorte que la somme des tranches d'âge corresponde au total.
+-- L'arrondi est laissé à la présentation.
+DO $$
+DECLARE
+	population_table text;
+	alterations text;
+BEGIN
+	FOREACH population_table IN ARRAY ARRAY['population_commune', 'population_arrondissement_municipal', 'population_iris'] LOOP
+		SELECT string_agg(format('ALTER COLUMN %I TYPE numeric', column_name), ', ' ORDER BY ordinal_position)
+		INTO alterations
+		FROM information_schema.columns
+		WHERE table_schema = 'demography' AND table_name = population_table AND column_name LIKE 'pop%';
+
+		EXECUTE format('ALTER TABLE demography.%I %s', population_table, alterations);
+	END LOOP;
+END $$;
@@ -0,0 +1,160 @@
+-- Population agrégée par EPCI, département et région
+-- Calculée à la fin de chaque chargement de la population, à partir de demography.population_commune (arrondissements
+-- municipaux compris, agrégés dans leur commune) et des codes parents de ref_admin.communes du même millésime.
+-- nb_communes compte les communes agrégées, pour le rapprochement avec demography.population_commune.
+
+-- demography.population_epci definition
+CREATE TABLE demography.population_epci (
+	code_insee_epci varchar(10) NOT NULL,
+	annee smallint NOT NULL CHECK (annee >= 1900 AND annee <= 2100),
+	pop numeric NULL CHECK (pop >= 0),
+	pop_h numeric NULL CHECK (pop_h >= 0),
+	pop_f numeric NULL CHECK (pop_f >= 0),
+	pop_LT15 numeric NULL CHECK (pop_LT15 >= 0),
+	pop_LT15_h numeric NULL CHECK (pop_LT15_h >= 0),
+	pop_LT15_f numeric NULL CHECK (pop_LT15_f >= 0),
+	pop_LT20 numeric NULL CHECK (pop_LT20 >= 0),
+	pop_LT20_h numeric NULL CHECK (pop_LT20_h >= 0),
+	pop_LT20_f numeric NULL CHECK (pop_LT20_f >= 0),
+	pop_15T24 numeric NULL CHECK (pop_15T24 >= 0),
+	pop_15T24_h numeric NULL CHECK (pop_15T24_h >= 0),
+	pop_15T24_f numeric NULL CHECK (pop_15T24_f >= 0),
+	pop_20T64 numeric NULL CHECK (pop_20T64 >= 0),
+	pop_20T64_h numeric NULL CHECK (pop_20T64_h >= 0),
+	pop_20T64_f numeric NULL CHECK (pop_20T64_f >= 0),
+	pop_25T39 numeric NULL CHECK (pop_25T39 >= 0),
+	pop_25T39_h numeric NULL CHECK (pop_25T39_h >= 0),
+	pop_25T39_f numeric NULL CHECK (pop_25T39_f >= 0),
+	pop_40T54 numeric NULL CHECK (pop_40T54 >= 0),
+	pop_40T54_h numeric NULL CHECK (pop_40T54_h >= 0),
+	pop_40T54_f numeric NULL CHECK (pop_40T54_f >= 0),
+	pop_55T64 numeric NULL CHECK (pop_55T64 >= 0),
+	pop_55T64_h numeric NULL CHECK (pop_55T64_h >= 0),
+	pop_55T64_f numeric NULL CHECK (pop_55T64_f >= 0),
+	pop_65T79 numeric NULL CHECK (pop_65T79 >= 0),
+	pop_65T79_h numeric NULL CHECK (pop_65T79_h >= 0),
+	pop_65T79_f numeric NULL CHECK (pop_65T79_f >= 0),
+	pop_GE65 numeric NULL CHECK (pop_GE65 >= 0),
+	pop_GE65_h numeric NULL CHECK (pop_GE65_h >= 0),
+	pop_GE65_f numeric NULL CHECK (pop_GE65_f >= 0),
+	pop_GE80 numeric NULL CHECK (pop_GE80 >= 0),
+	pop_GE80_h numeric NULL CHECK (pop_GE80_h >= 0),
+	pop_GE80_f numeric NULL CHECK (pop_GE80_f >= 0),
+	nb_communes int4 NOT NULL CHECK (nb_communes > 0),
+	millesime smallint NOT NULL CHECK (millesime >= 1900 AND millesime <= 2100),
+
+	CONSTRAINT population_epci_pkey PRIMARY KEY (code_insee_epci, annee, millesime)
+);
+
+CREATE INDEX idx_population_epci_millesime ON demography.population_epci (millesime);
+ALTER TABLE demography.population_epci ADD CONSTRAINT population_epci_code_insee_epci_fkey
+	FOREIGN KEY (code_insee_epci, millesime) REFERENCES ref_admin.epci(code_insee_epci, millesime);
+
+COMMENT ON TABLE demography.population_epci IS 'table de la population par EPCI à fiscalité propre et par année, somme des communes, mêmes colonnes que demography.population_commune';
+COMMENT ON COLUMN demography.population_epci.code_insee_epci IS 'code INSEE de l''EPCI';
+COMMENT ON COLUMN demography.population_epci.nb_communes IS 'nombre de communes agrégées';
+COMMENT ON COLUMN demography.population_epci.millesime IS 'millésime de la géographie à laquelle se rapportent les chiffres';
+
+-- demography.population_departement definition
+CREATE TABLE demography.population_departement (
+	code_insee_departement varchar(3) NOT NULL,
+	annee smallint NOT NULL CHECK (annee >= 1900 AND annee <= 2100),
+	pop numeric NULL CHECK (pop >= 0),
+	pop_h numeric NULL CHECK (pop_h >= 0),
+	pop_f numeric NULL CHECK (pop_f >= 0),
+	pop_LT15 numeric NULL CHECK (pop_LT15 >= 0),
+	pop_LT15_h numeric NULL CHECK (pop_LT15_h >= 0),
+	pop_LT15_f numeric NULL CHECK (pop_LT15_f >= 0),
+	pop_LT20 numeric NULL CHECK (pop_LT20 >= 0),
+	pop_LT20_h numeric NULL CHECK (pop_LT20_h >= 0),
+	pop_LT20_f numeric NULL CHECK (pop_LT20_f >= 0),
+	pop_15T24 numeric NULL CHECK (pop_15T24 >= 0),
+	pop_15T24_h numeric NULL CHECK (pop_15T24_h >= 0),
+	pop_15T24_f numeric NULL CHECK (pop_15T24_f >= 0),
+	pop_20T64 numeric NULL CHECK (pop_20T64 >= 0),
+	pop_20T64_h numeric NULL CHECK (pop_20T64_h >= 0),
+	pop_20T64_f numeric NULL CHECK (pop_20T64_f >= 0),
+	pop_25T39 numeric NULL CHECK (pop_25T39 >= 0),
+	pop_25T39_h numeric NULL CHECK (pop_25T39_h >= 0),
+	pop_25T39_f numeric NULL CHECK (pop_25T39_f >= 0),
+	pop_40T54 numeric NULL CHECK (pop_40T54 >= 0),
+	pop_40T54_h numeric NULL CHECK (pop_40T54_h >= 0),
+	pop_40T54_f numeric NULL CHECK (pop_40T54_f >= 0),
+	pop_55T64 numeric NULL CHECK (pop_55T64 >= 0),
+	pop_55T64_h numeric NULL CHECK (pop_55T64_h >= 0),
+	pop_55T64_f numeric NULL CHECK (pop_55T64_f >= 0),
+	pop_65T79 numeric NULL CHECK (pop_65T79 >= 0),
+	pop_65T79_h numeric NULL CHECK (pop_65T79_h >= 0),
+	pop_65T79_f numeric NULL CHECK (pop_65T79_f >= 0),
+	pop_GE65 numeric NULL CHECK (pop_GE65 >= 0),
+	pop_GE65_h numeric NULL CHECK (pop_GE65_h >= 0),
+	pop_GE65_f numeric NULL CHECK (pop_GE65_f >= 0),
+	pop_GE80 numeric NULL CHECK (pop_GE80 >= 0),
+	pop_GE80_h numeric NULL CHECK (pop_GE80_h >= 0),
+	pop_GE80_f numeric NULL CHECK (pop_GE80_f >= 0),
+	nb_communes int4 NOT NULL CHECK (nb_communes > 0),
+	millesime smallint NOT NULL CHECK (millesime >= 1900 AND millesime <= 2100),
+
+	CONSTRAINT population_departement_pkey PRIMARY KEY (code_insee_departement, annee, millesime)
+);
+
+CREATE INDEX idx_population_departement_millesime ON demography.population_departement (millesime);
+ALTER TABLE demography.population_departement ADD CONSTRAINT population_departement_code_insee_departement_fkey
+	FOREIGN KEY (code_insee_departement, millesime) REFERENCES ref_admin.departements(code_insee_departement, millesime);
+
+COMMENT ON TABLE demography.population_departement IS 'table de la population par département et par année, somme des communes, mêmes colonnes que demography.population_commune';
+COMMENT ON COLUMN demography.population_departement.code_insee_departement IS 'code INSEE du département';
+COMMENT ON COLUMN demography.population_departement.nb_communes IS 'nombre de communes agrégées';
+COMMENT ON COLUMN demography.population_departement.millesime IS 'millésime de la géographie à laquelle se rapportent les chiffres';
+
+-- demography.population_region definition
+CREATE TABLE demography.population_region (
+	code_insee_region varchar(3) NOT NULL,
+	annee smallint NOT NULL CHECK (annee >= 1900 AND annee <= 2100),
+	pop numeric NULL CHECK (pop >= 0),
+	pop_h numeric NULL CHECK (pop_h >= 0),
+	pop_f numeric NULL CHECK (pop_f >= 0),
+	pop_LT15 numeric NULL CHECK (pop_LT15 >= 0),
+	pop_LT15_h numeric NULL CHECK (pop_LT15_h >= 0),
+	pop_LT15_f numeric NULL CHECK (pop_LT15_f >= 0),
+	pop_LT20 numeric NULL CHECK (pop_LT20 >= 0),
+	pop_LT20_h numeric NULL CHECK (pop_LT20_h >= 0),
+	pop_LT20_f numeric NULL CHECK (pop_LT20_f >= 0),
+	pop_15T24 numeric NULL CHECK (pop_15T24 >= 0),
+	pop_15T24_h numeric NULL CHECK (pop_15T24_h >= 0),
+	pop_15T24_f numeric NULL CHECK (pop_15T24_f >= 0),
+	pop_20T64 numeric NULL CHECK (pop_20T64 >= 0),
+	pop_20T64_h numeric NULL CHECK (pop_20T64_h >= 0),
+	pop_20T64_f numeric NULL CHECK (pop_20T64_f >= 0),
+	pop_25T39 numeric NULL CHECK (pop_25T39 >= 0),
+	pop_25T39_h numeric NULL CHECK (pop_25T39_h >= 0),
+	pop_25T39_f numeric NULL CHECK (pop_25T39_f >= 0),
+	pop_40T54 numeric NULL CHECK (pop_40T54 >= 0),
+	pop_40T54_h numeric NULL CHECK (pop_40T54_h >= 0),
+	pop_40T54_f numeric NULL CHECK (pop_40T54_f >= 0),
+	pop_55T64 numeric NULL CHECK (pop_55T64 >= 0),
+	pop_55T64_h numeric NULL CHECK (pop_55T64_h >= 0),
+	pop_55T64_f numeric NULL CHECK (pop_55T64_f >= 0),
+	pop_65T79 numeric NULL CHECK (pop_65T79 >= 0),
+	pop_65T79_h numeric NULL CHECK (pop_65T79_h >= 0),
+	pop_65T79_f numeric NULL CHECK (pop_65T79_f >= 0),
+	pop_GE65 numeric NULL CHECK (pop_GE65 >= 0),
+	pop_GE65_h numeric NULL CHECK (pop_GE65_h >= 0),
+	pop_GE65_f numeric NULL CHECK (pop_GE65_f >= 0),
+	pop_GE80 numeric NULL CHECK (pop_GE80 >= 0),
+	pop_GE80_h numeric NULL CHECK (pop_GE80_h >= 0),
+	pop_GE80_f numeric NULL CHECK (pop_GE80_f >= 0),
+	nb_communes int4 NOT NULL CHECK (nb_communes > 0),
+	millesime smallint NOT NULL CHECK (millesime >= 1900 AND millesime <= 2100),
+
+	CONSTRAINT population_region_pkey PRIMARY KEY (code_insee_region, annee, millesime)
+);
+
+CREATE INDEX idx_population_region_millesime ON demography.population_region (millesime);
+ALTER TABLE demography.population_region ADD CONSTRAINT population_region_code_insee_region_fkey
+	FOREIGN KEY (code_insee_region, millesime) REFERENCES ref_admin.regions(code_insee_region, millesime);
+
+COMMENT ON TABLE demography.population_region IS 'table de la population par région et par année, somme des communes, mêmes colonnes que demography.population_commune';
+COMMENT ON COLUMN demography.population_region.code_insee_region IS 'code INSEE de la région';
+COMMENT ON COLUMN demography.population_region.nb_communes IS 'nombre de communes agrégées';
+COMMENT ON COLUMN demography.population_region.millesime IS 'millésime de la géographie à laquelle se rapportent les chiffres';
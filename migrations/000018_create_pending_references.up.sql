@@ -0,0 +1,23 @@
+-- ref_admin.etl_pending_references definition
+-- Références différées par la politique defer : une ligne chargée avec une référence vers une ligne absente
+-- (ex. l'EPCI d'une commune, chargé après elle) garde la référence ici, reportée une fois la ligne chargée.
+CREATE TABLE ref_admin.etl_pending_references (
+	table_name text NOT NULL,
+	key_columns text[] NOT NULL,
+	key_values text[] NOT NULL,
+	column_name text NOT NULL,
+	ref_table text NOT NULL,
+	ref_column text NOT NULL,
+	reference text NOT NULL,
+	millesime smallint NULL CHECK (millesime >= 1900 AND millesime <= 2100),
+	deferred_at timestamptz NOT NULL DEFAULT now(),
+	CONSTRAINT etl_pending_references_pkey PRIMARY KEY (table_name, column_name, key_values)
+);
+CREATE INDEX idx_etl_pending_references_ref_table ON ref_admin.etl_pending_references (ref_table);
+
+COMMENT ON TABLE ref_admin.etl_pending_references IS 'références non résolues au chargement, en attente de la ligne référencée';
+COMMENT ON COLUMN ref_admin.etl_pending_references.table_name IS 'table de la ligne portant la référence';
+COMMENT ON COLUMN ref_admin.etl_pending_references.key_values IS 'valeurs de la clé de la ligne, en texte, dans l''ordre de key_columns';
+COMMENT ON COLUMN ref_admin.etl_pending_references.column_name IS 'colonne de la référence, chargée à NULL';
+COMMENT ON COLUMN ref_admin.etl_pending_references.reference IS 'code référencé, absent de ref_table au chargement';
+COMMENT ON COLUMN ref_admin.etl_pending_references.millesime IS 'millésime de la ligne, NULL pour une table sans millésime';
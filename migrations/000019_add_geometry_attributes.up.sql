@@ -0,0 +1,99 @@
+-- Attributs dérivés des contours, calculés par l'ETL après chaque chargement sur le contour le plus précis
+-- (geom_5m, geom_100m, geom puis geom_1000m) : colonnes ordinaires plutôt que générées, pour rester
+-- copiables par les tables fantômes.
+ALTER TABLE ref_admin.regions
+	ADD COLUMN surface_km2 double precision NULL,
+	ADD COLUMN perimetre_km double precision NULL,
+	ADD COLUMN point_label geography(point, 4326) NULL,
+	ADD COLUMN centroide geography(point, 4326) NULL,
+	ADD COLUMN bbox geometry(polygon, 4326) NULL;
+
+ALTER TABLE ref_admin.departements
+	ADD COLUMN surface_km2 double precision NULL,
+	ADD COLUMN perimetre_km double precision NULL,
+	ADD COLUMN point_label geography(point, 4326) NULL,
+	ADD COLUMN centroide geography(point, 4326) NULL,
+	ADD COLUMN bbox geometry(polygon, 4326) NULL;
+
+ALTER TABLE ref_admin.epci
+	ADD COLUMN surface_km2 double precision NULL,
+	ADD COLUMN perimetre_km double precision NULL,
+	ADD COLUMN point_label geography(point, 4326) NULL,
+	ADD COLUMN centroide geography(point, 4326) NULL,
+	ADD COLUMN bbox geometry(polygon, 4326) NULL;
+
+ALTER TABLE ref_admin.communes
+	ADD COLUMN surface_km2 double precision NULL,
+	ADD COLUMN perimetre_km double precision NULL,
+	ADD COLUMN point_label geography(point, 4326) NULL,
+	ADD COLUMN centroide geography(point, 4326) NULL,
+	ADD COLUMN bbox geometry(polygon, 4326) NULL;
+
+ALTER TABLE ref_admin.arrondissements
+	ADD COLUMN surface_km2 double precision NULL,
+	ADD COLUMN perimetre_km double precision NULL,
+	ADD COLUMN point_label geography(point, 4326) NULL,
+	ADD COLUMN centroide geography(point, 4326) NULL,
+	ADD COLUMN bbox geometry(polygon, 4326) NULL;
+
+ALTER TABLE ref_admin.cantons
+	ADD COLUMN surface_km2 double precision NULL,
+	ADD COLUMN perimetre_km double precision NULL,
+	ADD COLUMN point_label geography(point, 4326) NULL,
+	ADD COLUMN centroide geography(point, 4326) NULL,
+	ADD COLUMN bbox geometry(polygon, 4326) NULL;
+
+ALTER TABLE ref_admin.arrondissements_municipaux
+	ADD COLUMN surface_km2 double precision NULL,
+	ADD COLUMN perimetre_km double precision NULL,
+	ADD COLUMN point_label geography(point, 4326) NULL,
+	ADD COLUMN centroide geography(point, 4326) NULL,
+	ADD COLUMN bbox geometry(polygon, 4326) NULL;
+
+ALTER TABLE ref_admin.iris
+	ADD COLUMN surface_km2 double precision NULL,
+	ADD COLUMN perimetre_km double precision NULL,
+	ADD COLUMN point_label geography(point, 4326) NULL,
+	ADD COLUMN centroide geography(point, 4326) NULL,
+	ADD COLUMN bbox geometry(polygon, 4326) NULL;
+
+COMMENT ON COLUMN ref_admin.regions.surface_km2 IS 'surface de la région en km²';
+COMMENT ON COLUMN ref_admin.regions.perimetre_km IS 'périmètre de la région en km';
+COMMENT ON COLUMN ref_admin.regions.point_label IS 'point d''étiquette, toujours à l''intérieur du contour de la région';
+COMMENT ON COLUMN ref_admin.regions.centroide IS 'centroïde de la région, éventuellement hors du contour';
+COMMENT ON COLUMN ref_admin.regions.bbox IS 'rectangle englobant de la région';
+COMMENT ON COLUMN ref_admin.departements.surface_km2 IS 'surface du département en km²';
+COMMENT ON COLUMN ref_admin.departements.perimetre_km IS 'périmètre du département en km';
+COMMENT ON COLUMN ref_admin.departements.point_label IS 'point d''étiquette, toujours à l''intérieur du contour du département';
+COMMENT ON COLUMN ref_admin.departements.centroide IS 'centroïde du département, éventuellement hors du contour';
+COMMENT ON COLUMN ref_admin.departements.bbox IS 'rectangle englobant du département';
+COMMENT ON COLUMN ref_admin.epci.surface_km2 IS 'surface de l''EPCI en km²';
+COMMENT ON COLUMN ref_admin.epci.perimetre_km IS 'périmètre de l''EPCI en km';
+COMMENT ON COLUMN ref_admin.epci.point_label IS 'point d''étiquette, toujours à l''intérieur du contour de l''EPCI';
+COMMENT ON COLUMN ref_admin.epci.centroide IS 'centroïde de l''EPCI, éventuellement hors du contour';
+COMMENT ON COLUMN ref_admin.epci.bbox IS 'rectangle englobant de l''EPCI';
+COMMENT ON COLUMN ref_admin.communes.surface_km2 IS 'surface de la commune en km²';
+COMMENT ON COLUMN ref_admin.communes.perimetre_km IS 'périmètre de la commune en km';
+COMMENT ON COLUMN ref_admin.communes.point_label IS 'point d''étiquette, toujours à l''intérieur du contour de la commune';
+COMMENT ON COLUMN ref_admin.communes.centroide IS 'centroïde de la commune, éventuellement hors du contour';
+COMMENT ON COLUMN ref_admin.communes.bbox IS 'rectangle englobant de la commune';
+COMMENT ON COLUMN ref_admin.arrondissements.surface_km2 IS 'surface de l''arrondissement en km²';
+COMMENT ON COLUMN ref_admin.arrondissements.perimetre_km IS 'périmètre de l''arrondissement en km';
+COMMENT ON COLUMN ref_admin.arrondissements.point_label IS 'point d''étiquette, toujours à l''intérieur du contour de l''arrondissement';
+COMMENT ON COLUMN ref_admin.arrondissements.centroide IS 'centroïde de l''arrondissement, éventuellement hors du contour';
+COMMENT ON COLUMN ref_admin.arrondissements.bbox IS 'rectangle englobant de l''arrondissement';
+COMMENT ON COLUMN ref_admin.cantons.surface_km2 IS 'surface du canton en km²';
+COMMENT ON COLUMN ref_admin.cantons.perimetre_km IS 'périmètre du canton en km';
+COMMENT ON COLUMN ref_admin.cantons.point_label IS 'point d''étiquette, toujours à l''intérieur du contour du canton';
+COMMENT ON COLUMN ref_admin.cantons.centroide IS 'centroïde du canton, éventuellement hors du contour';
+COMMENT ON COLUMN ref_admin.cantons.bbox IS 'rectangle englobant du canton';
+COMMENT ON COLUMN ref_admin.arrondissements_municipaux.surface_km2 IS 'surface de l''arrondissement municipal en km²';
+COMMENT ON COLUMN ref_admin.arrondissements_municipaux.perimetre_km IS 'périmètre de l''arrondissement municipal en km';
+COMMENT ON COLUMN ref_admin.arrondissements_municipaux.point_label IS 'point d''étiquette, toujours à l''intérieur du contour de l''arrondissement municipal';
+COMMENT ON COLUMN ref_admin.arrondissements_municipaux.centroide IS 'centroïde de l''arrondissement municipal, éventuellement hors du contour';
+COMMENT ON COLUMN ref_admin.arrondissements_municipaux.bbox IS 'rectangle englobant de l''arrondissement municipal';
+COMMENT ON COLUMN ref_admin.iris.surface_km2 IS 'surface de l''IRIS en km²';
+COMMENT ON COLUMN ref_admin.iris.perimetre_km IS 'périmètre de l''IRIS en km';
+COMMENT ON COLUMN ref_admin.iris.point_label IS 'point d''étiquette, toujours à l''intérieur du contour de l''IRIS';
+COMMENT ON COLUMN ref_admin.iris.centroide IS 'centroïde de l''IRIS, éventuellement hors du contour';
+COMMENT ON COLUMN ref_admin.iris.bbox IS 'rectangle englobant de l''IRIS';
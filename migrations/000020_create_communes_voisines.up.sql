@@ -0,0 +1,36 @@
+-- Graphe d'adjacence des communes et des départements, mis à jour par l'ETL après leur chargement.
+-- Deux lignes sont voisines quand elles partagent une frontière de longueur non nulle (un point de contact ne
+-- suffit pas). Chaque paire est stockée dans les deux sens, pour lister les voisins d'un code par son index.
+
+-- ref_admin.communes_voisines definition
+CREATE TABLE ref_admin.communes_voisines (
+	niveau varchar(12) NOT NULL CHECK (niveau IN ('commune', 'departement')),
+	millesime smallint NOT NULL CHECK (millesime >= 1900 AND millesime <= 2100),
+	code varchar(10) NOT NULL,
+	code_voisin varchar(10) NOT NULL,
+	frontiere_km double precision NOT NULL CHECK (frontiere_km > 0),
+	CONSTRAINT communes_voisines_pkey PRIMARY KEY (niveau, millesime, code, code_voisin),
+	CONSTRAINT communes_voisines_check CHECK (code <> code_voisin)
+);
+CREATE INDEX idx_communes_voisines_code_voisin ON ref_admin.communes_voisines (niveau, millesime, code_voisin);
+
+COMMENT ON TABLE ref_admin.communes_voisines IS 'communes et départements voisins, partageant une frontière';
+COMMENT ON COLUMN ref_admin.communes_voisines.niveau IS 'niveau des codes : commune ou departement';
+COMMENT ON COLUMN ref_admin.communes_voisines.millesime IS 'millésime de la géographie';
+COMMENT ON COLUMN ref_admin.communes_voisines.code IS 'code INSEE de la commune ou du département';
+COMMENT ON COLUMN ref_admin.communes_voisines.code_voisin IS 'code INSEE du voisin';
+COMMENT ON COLUMN ref_admin.communes_voisines.frontiere_km IS 'longueur de la frontière commune en km';
+
+-- ref_admin.etl_adjacency_fingerprints definition
+-- Empreinte du contour de chaque ligne à partir duquel le graphe a été calculé : seuls les voisins des lignes
+-- dont le contour a changé (ou qui ont été ajoutées ou supprimées) sont recalculés.
+CREATE TABLE ref_admin.etl_adjacency_fingerprints (
+	niveau varchar(12) NOT NULL,
+	millesime smallint NOT NULL CHECK (millesime >= 1900 AND millesime <= 2100),
+	code varchar(10) NOT NULL,
+	fingerprint text NOT NULL,
+	CONSTRAINT etl_adjacency_fingerprints_pkey PRIMARY KEY (niveau, millesime, code)
+);
+
+COMMENT ON TABLE ref_admin.etl_adjacency_fingerprints IS 'empreintes des contours du dernier calcul de ref_admin.communes_voisines';
+COMMENT ON COLUMN ref_admin.etl_adjacency_fingerprints.fingerprint IS 'md5 du contour, au format WKB';
@@ -0,0 +1,14 @@
+-- Arrondissement et canton des communes, absents des contours Etalab : ils sont repris du COG (v_commune).
+
+ALTER TABLE ref_admin.communes
+	ADD COLUMN code_insee_arrondissement varchar(4) NULL,
+	ADD COLUMN code_insee_canton varchar(5) NULL;
+CREATE INDEX idx_communes_arrondissement ON ref_admin.communes (code_insee_arrondissement, millesime);
+CREATE INDEX idx_communes_canton ON ref_admin.communes (code_insee_canton, millesime);
+ALTER TABLE ref_admin.communes ADD CONSTRAINT communes_code_insee_arrondissement_fkey
+	FOREIGN KEY (code_insee_arrondissement, millesime) REFERENCES ref_admin.arrondissements(code_insee_arrondissement, millesime);
+ALTER TABLE ref_admin.communes ADD CONSTRAINT communes_code_insee_canton_fkey
+	FOREIGN KEY (code_insee_canton, millesime) REFERENCES ref_admin.cantons(code_insee_canton, millesime);
+
+COMMENT ON COLUMN ref_admin.communes.code_insee_arrondissement IS 'code INSEE de l''arrondissement départemental de la commune, issu du COG';
+COMMENT ON COLUMN ref_admin.communes.code_insee_canton IS 'code INSEE du canton de la commune, issu du COG (NULL pour une commune répartie entre plusieurs cantons)';
@@ -0,0 +1,9 @@
+-- Les références remplacées par NULL (politique null) sont aussi gardées dans ref_admin.etl_pending_references,
+-- pour que le contrôle d'intégrité les distingue des lignes sans référence légitimes (ex. les îles sans EPCI).
+-- Seules les références différées (politique defer) sont reportées une fois la ligne référencée chargée.
+
+ALTER TABLE ref_admin.etl_pending_references
+	ADD COLUMN policy text NOT NULL DEFAULT 'defer' CHECK (policy IN ('null', 'defer'));
+
+COMMENT ON TABLE ref_admin.etl_pending_references IS 'références non résolues au chargement, remplacées par NULL ou en attente de la ligne référencée';
+COMMENT ON COLUMN ref_admin.etl_pending_references.policy IS 'politique appliquée à la référence : null (abandonnée) ou defer (reportée au chargement de la ligne référencée)';